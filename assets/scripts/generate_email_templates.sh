@@ -13,6 +13,7 @@ _LANG[${#_LANG[@]}]="en"
 _TEMPLATES[${#_TEMPLATES[@]}]="registration_confirmation"
 _TEMPLATES[${#_TEMPLATES[@]}]="registration_ready"
 _TEMPLATES[${#_TEMPLATES[@]}]="registration_verdict"
+_TEMPLATES[${#_TEMPLATES[@]}]="password_recovery"
 
 printf "package messaging\n\n" > ${TMPL_BODY_FILE}
 printf "// EmailTemplateBody stores email body templates.\n" >> ${TMPL_BODY_FILE}
@@ -13,6 +13,7 @@ _PAGES[${#_PAGES[@]}]="login"
 _PAGES[${#_PAGES[@]}]="portal"
 _PAGES[${#_PAGES[@]}]="whoami"
 _PAGES[${#_PAGES[@]}]="register"
+_PAGES[${#_PAGES[@]}]="recover"
 _PAGES[${#_PAGES[@]}]="generic"
 _PAGES[${#_PAGES[@]}]="settings"
 _PAGES[${#_PAGES[@]}]="sandbox"
@@ -0,0 +1,214 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/acl"
+	fileutil "github.com/greenpau/go-authcrunch/pkg/util/file"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	aclSubcmd = []*cli.Command{
+		{
+			Name:  "test",
+			Usage: "evaluate access list rules against the claims of a token without enforcing them",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "policy",
+					Usage: "JSON or YAML `FILE` with access list rules",
+				},
+				&cli.StringFlag{
+					Name:  "portal",
+					Usage: "evaluate the access list of the portal `NAME`",
+				},
+				&cli.StringFlag{
+					Name:  "gatekeeper",
+					Usage: "evaluate the access list of the gatekeeper `NAME`, i.e. authorization policy",
+				},
+				&cli.StringFlag{
+					Name:  "token",
+					Usage: "`FILE` with JWT token",
+				},
+				&cli.StringFlag{
+					Name:  "claims",
+					Usage: "JSON `FILE` with token claims",
+				},
+				&cli.StringFlag{
+					Name:  "method",
+					Usage: "HTTP `METHOD` of the request",
+				},
+				&cli.StringFlag{
+					Name:  "path",
+					Usage: "URL `PATH` of the request",
+				},
+				&cli.StringFlag{
+					Name:  "addr",
+					Usage: "source `ADDRESS` of the request",
+				},
+			},
+			Action: testACL,
+		},
+	}
+)
+
+// aclPolicy is the access list policy evaluated by the portal.
+type aclPolicy struct {
+	Rules        []*acl.RuleConfiguration `json:"rules,omitempty" xml:"rules,omitempty" yaml:"rules,omitempty"`
+	NetworkLists map[string][]string      `json:"network_lists,omitempty" xml:"network_lists,omitempty" yaml:"network_lists,omitempty"`
+	DefaultAllow bool                     `json:"default_allow,omitempty" xml:"default_allow,omitempty" yaml:"default_allow,omitempty"`
+}
+
+// aclTestRequest is the request to the ACL test API.
+type aclTestRequest struct {
+	aclPolicy  `yaml:",inline"`
+	Portal     string                 `json:"portal,omitempty" xml:"portal,omitempty" yaml:"portal,omitempty"`
+	Gatekeeper string                 `json:"gatekeeper,omitempty" xml:"gatekeeper,omitempty" yaml:"gatekeeper,omitempty"`
+	Token      string                 `json:"token,omitempty" xml:"token,omitempty" yaml:"token,omitempty"`
+	Claims     map[string]interface{} `json:"claims,omitempty" xml:"claims,omitempty" yaml:"claims,omitempty"`
+	Method     string                 `json:"method,omitempty" xml:"method,omitempty" yaml:"method,omitempty"`
+	Path       string                 `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	Addr       string                 `json:"addr,omitempty" xml:"addr,omitempty" yaml:"addr,omitempty"`
+}
+
+// aclTestResult is the response of the ACL test API.
+type aclTestResult struct {
+	Input       map[string]interface{} `json:"input,omitempty" xml:"input,omitempty" yaml:"input,omitempty"`
+	Explanation *acl.Explanation       `json:"explanation,omitempty" xml:"explanation,omitempty" yaml:"explanation,omitempty"`
+}
+
+func testACL(c *cli.Context) error {
+	wr := new(wrapper)
+	if err := wr.configure(c); err != nil {
+		return err
+	}
+	wr.logger.Debug("testing access list")
+
+	req := &aclTestRequest{
+		Portal:     c.String("portal"),
+		Gatekeeper: c.String("gatekeeper"),
+		Method:     c.String("method"),
+		Path:       c.String("path"),
+		Addr:       c.String("addr"),
+	}
+
+	switch {
+	case c.String("policy") != "" && (req.Portal != "" || req.Gatekeeper != ""):
+		return fmt.Errorf("the --policy, --portal and --gatekeeper flags are mutually exclusive")
+	case c.String("policy") != "":
+		policy, err := readACLPolicy(c.String("policy"))
+		if err != nil {
+			return err
+		}
+		req.aclPolicy = *policy
+	case req.Portal != "" && req.Gatekeeper != "":
+		return fmt.Errorf("the --portal and --gatekeeper flags are mutually exclusive")
+	case req.Portal == "" && req.Gatekeeper == "":
+		return fmt.Errorf("either the --policy, --portal or --gatekeeper flag is required")
+	}
+
+	switch {
+	case c.String("token") != "":
+		b, err := fileutil.ReadFileBytes(c.String("token"))
+		if err != nil {
+			return err
+		}
+		req.Token = string(parseTokenBytes(b))
+	case c.String("claims") != "":
+		b, err := fileutil.ReadFileBytes(c.String("claims"))
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &req.Claims); err != nil {
+			return fmt.Errorf("failed parsing claims: %v", err)
+		}
+	default:
+		return fmt.Errorf("either the --token or --claims flag is required")
+	}
+
+	result := &aclTestResult{}
+	if err := wr.doAPIRequest(http.MethodPost, "/api/acl/test", nil, req, result); err != nil {
+		return err
+	}
+	if result.Explanation == nil {
+		return fmt.Errorf("auth portal api returned no explanation")
+	}
+	wr.logger.Debug("tested access list", zap.Bool("allowed", result.Explanation.Allowed), zap.String("verdict", result.Explanation.Verdict))
+	return printACLTestResult(c.String("format"), result)
+}
+
+// readACLPolicy reads access list rules from a file. The file holds either
+// the list of rules or aclPolicy.
+func readACLPolicy(fp string) (*aclPolicy, error) {
+	b, err := fileutil.ReadFileBytes(fp)
+	if err != nil {
+		return nil, err
+	}
+	policy := &aclPolicy{}
+	if strings.HasPrefix(strings.TrimSpace(string(b)), "[") {
+		if err := yaml.Unmarshal(b, &policy.Rules); err != nil {
+			return nil, fmt.Errorf("failed parsing policy: %v", err)
+		}
+	} else if err := yaml.Unmarshal(b, policy); err != nil {
+		return nil, fmt.Errorf("failed parsing policy: %v", err)
+	}
+	if len(policy.Rules) == 0 {
+		return nil, fmt.Errorf("policy %q has no access list rules", fp)
+	}
+	return policy, nil
+}
+
+func printACLTestResult(format string, result *aclTestResult) error {
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "%s\n", b)
+	case "yaml":
+		b, err := yaml.Marshal(result)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "%s", b)
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RULE\tACTION\tVERDICT\tCONDITION\tFIELD\tFOUND\tMATCHED")
+		for _, rule := range result.Explanation.Rules {
+			for i, cond := range rule.Conditions {
+				if i == 0 {
+					fmt.Fprintf(w, "%s\t%s\t%s\t", rule.Tag, rule.Action, rule.Verdict)
+				} else {
+					fmt.Fprint(w, "\t\t\t")
+				}
+				fmt.Fprintf(w, "%s\t%s\t%t\t%t\n", cond.Condition, cond.Field, cond.Found, cond.Matched)
+			}
+		}
+		fmt.Fprintf(w, "\nALLOWED: %t\tVERDICT: %s\n", result.Explanation.Allowed, result.Explanation.Verdict)
+		return w.Flush()
+	default:
+		return fmt.Errorf("the %q output format is unsupported", format)
+	}
+	return nil
+}
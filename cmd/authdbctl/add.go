@@ -21,7 +21,8 @@ import (
 var (
 	addSubcmd = []*cli.Command{
 		{
-			Name: "user",
+			Name:  "user",
+			Usage: "add users from a batch file with one JSON object per line",
 			Flags: []cli.Flag{
 				&cli.StringFlag{
 					Name:  "batch",
@@ -0,0 +1,110 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// apiError is the error returned by the auth portal API.
+type apiError struct {
+	Error   bool   `json:"error,omitempty" xml:"error,omitempty" yaml:"error,omitempty"`
+	Message string `json:"message,omitempty" xml:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// doAPIRequest sends a request to the admin API of the auth portal. The path
+// is relative to the base URL of the portal, e.g. /api/users. When provided,
+// the input is encoded as JSON request body, and the JSON response body is
+// decoded into the output.
+func (wr *wrapper) doAPIRequest(method, path string, params url.Values, input, output interface{}) error {
+	if wr.config.token == "" {
+		return fmt.Errorf("auth token not found, run %q first", "authdbctl connect")
+	}
+
+	browser, err := util.NewBrowser()
+	if err != nil {
+		return err
+	}
+
+	reqURL := strings.TrimSuffix(wr.config.BaseURL, "/") + path
+	if params == nil {
+		params = url.Values{}
+	}
+	if wr.config.Realm != "" && params.Get("realm") == "" {
+		params.Set("realm", wr.config.Realm)
+	}
+	if len(params) > 0 {
+		reqURL += "?" + params.Encode()
+	}
+
+	var body io.Reader
+	if input != nil {
+		b, err := json.Marshal(input)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, reqURL, body)
+	if err != nil {
+		return err
+	}
+	if input != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.AddCookie(&http.Cookie{Name: wr.config.CookieName, Value: wr.config.token})
+
+	wr.logger.Debug(
+		"sending api request",
+		zap.String("method", method),
+		zap.String("url", reqURL),
+	)
+
+	respBody, resp, err := browser.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed connecting to auth portal api: %v", err)
+	}
+
+	wr.logger.Debug(
+		"received api response",
+		zap.Int("status_code", resp.StatusCode),
+		zap.String("body", respBody),
+	)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &apiError{}
+		if err := json.Unmarshal([]byte(respBody), apiErr); err == nil && apiErr.Message != "" {
+			return fmt.Errorf("auth portal api returned %d: %s", resp.StatusCode, apiErr.Message)
+		}
+		return fmt.Errorf("auth portal api returned %d", resp.StatusCode)
+	}
+
+	if output != nil {
+		if err := json.Unmarshal([]byte(respBody), output); err != nil {
+			return fmt.Errorf("failed parsing auth portal api response: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	importSubcmd = []*cli.Command{
+		{
+			Name:  "user",
+			Usage: "import users with pre-hashed passwords from htpasswd, shadow, or LDIF file",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "file",
+					Usage: "input `FILE`",
+				},
+				&cli.StringFlag{
+					Name:  "from",
+					Usage: "input file `FORMAT`, i.e. htpasswd, shadow, or ldif",
+					Value: "htpasswd",
+				},
+				&cli.StringFlag{
+					Name:  "email-domain",
+					Usage: "derive email address from username and `DOMAIN` when absent",
+				},
+				&cli.StringSliceFlag{
+					Name:  "role",
+					Usage: "assign `ROLE` to imported users",
+				},
+			},
+			Action: importUsers,
+		},
+	}
+)
@@ -21,7 +21,8 @@ import (
 var (
 	listSubcmd = []*cli.Command{
 		{
-			Name: "users",
+			Name:  "users",
+			Usage: "list users",
 			/*
 				Flags: []cli.Flag{
 					&cli.StringFlag{
@@ -71,7 +71,7 @@ func init() {
 	})
 	sh.Flags = append(sh.Flags, &cli.StringFlag{
 		Name:        "format",
-		Usage:       "Sets `NAME` of the output format",
+		Usage:       "Sets `NAME` of the output format, i.e. json, yaml, or table",
 		Value:       `json`,
 		DefaultText: `json`,
 		EnvVars:     []string{"AUTHDBCTL_OUTPUT_FORMAT"},
@@ -96,6 +96,22 @@ func init() {
 			Usage:       "list database objects",
 			Subcommands: listSubcmd,
 		},
+		{
+			Name:        "import",
+			Usage:       "import database objects",
+			Subcommands: importSubcmd,
+		},
+		{
+			Name:        "unlock",
+			Usage:       "unlock database objects",
+			Subcommands: unlockSubcmd,
+		},
+		{
+			Name:        "acl",
+			Usage:       "evaluate access lists",
+			Subcommands: aclSubcmd,
+		},
+		migrateCmd,
 	}
 }
 
@@ -0,0 +1,72 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	fileutil "github.com/greenpau/go-authcrunch/pkg/util/file"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+	"strings"
+)
+
+var (
+	migrateCmd = &cli.Command{
+		Name:  "migrate",
+		Usage: "migrate local database between JSON file and SQL database",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "from",
+				Usage: "source database `LOCATION`, i.e. path to JSON file",
+			},
+			&cli.StringFlag{
+				Name:  "to",
+				Usage: "destination database `LOCATION`, e.g. sqlite3:///var/lib/authdb/users.db",
+			},
+		},
+		Action: migrateDatabase,
+	}
+)
+
+func migrateDatabase(c *cli.Context) error {
+	var logger *zap.Logger
+	if c.Bool("debug") {
+		logger = logutil.NewLogger()
+	} else {
+		logger = logutil.NewInfoLogger()
+	}
+
+	if c.String("from") == "" || c.String("to") == "" {
+		return fmt.Errorf("the --from and --to flags are required")
+	}
+	src := expandDatabasePath(c.String("from"))
+	dst := expandDatabasePath(c.String("to"))
+	if err := identity.MigrateDatabase(src, dst); err != nil {
+		return err
+	}
+	logger.Info("migrated database")
+	return nil
+}
+
+// expandDatabasePath expands the home directory in the path of the
+// database.
+func expandDatabasePath(s string) string {
+	if strings.Contains(s, "://") {
+		return s
+	}
+	return fileutil.ExpandPath(s)
+}
@@ -16,18 +16,39 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
 	fileutil "github.com/greenpau/go-authcrunch/pkg/util/file"
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 )
 
+const listUsersPageSize = 100
+
 // User represents input user identity.
 type User struct {
-	Username string   `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
-	Password string   `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
-	Name     string   `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
-	Email    string   `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
-	Roles    []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	Username     string   `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Password     string   `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
+	Name         string   `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Email        string   `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	Roles        []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	PasswordHash string   `json:"password_hash,omitempty" xml:"password_hash,omitempty" yaml:"password_hash,omitempty"`
+}
+
+// UserList is the response of the users API.
+type UserList struct {
+	Users  []*identity.UserMetadata `json:"users,omitempty" xml:"users,omitempty" yaml:"users,omitempty"`
+	Total  int                      `json:"total,omitempty" xml:"total,omitempty" yaml:"total,omitempty"`
+	Offset int                      `json:"offset,omitempty" xml:"offset,omitempty" yaml:"offset,omitempty"`
+	Limit  int                      `json:"limit,omitempty" xml:"limit,omitempty" yaml:"limit,omitempty"`
 }
 
 func addUser(c *cli.Context) error {
@@ -37,15 +58,98 @@ func addUser(c *cli.Context) error {
 	}
 	wr.logger.Debug("adding user")
 
+	if c.String("batch") == "" {
+		return fmt.Errorf("the --batch flag is required")
+	}
+
 	b, err := fileutil.ReadFileBytes(c.String("batch"))
 	if err != nil {
 		return err
 	}
-	for _, entry := range bytes.Split(b, []byte("\n")) {
+
+	var failCount, addCount int
+	for i, entry := range bytes.Split(b, []byte("\n")) {
+		entry = bytes.TrimSpace(entry)
 		if !bytes.HasPrefix(entry, []byte("{")) {
-			// fmt.Fprintf(os.Stdout, string(b)+"\n")
+			continue
+		}
+		wr.logger.Debug("user entry", zap.Int("line", i+1), zap.String("entry", string(entry)))
+		usr := &User{}
+		if err := json.Unmarshal(entry, usr); err != nil {
+			wr.logger.Error("failed parsing user entry", zap.Int("line", i+1), zap.Error(err))
+			failCount++
+			continue
 		}
-		wr.logger.Debug("user entry", zap.String("entry", string(entry)))
+		if err := wr.doAPIRequest(http.MethodPost, "/api/users", nil, usr, nil); err != nil {
+			wr.logger.Error(
+				"failed adding user",
+				zap.Int("line", i+1),
+				zap.String("username", usr.Username),
+				zap.Error(err),
+			)
+			failCount++
+			continue
+		}
+		wr.logger.Info("added user", zap.String("username", usr.Username), zap.String("email", usr.Email))
+		addCount++
+	}
+
+	wr.logger.Debug("added users", zap.Int("added", addCount), zap.Int("failed", failCount))
+	if failCount > 0 {
+		return fmt.Errorf("failed adding %d out of %d users", failCount, failCount+addCount)
+	}
+	return nil
+}
+
+func importUsers(c *cli.Context) error {
+	wr := new(wrapper)
+	if err := wr.configure(c); err != nil {
+		return err
+	}
+	wr.logger.Debug("importing users")
+
+	if c.String("file") == "" {
+		return fmt.Errorf("the --file flag is required")
+	}
+
+	b, err := fileutil.ReadFileBytes(c.String("file"))
+	if err != nil {
+		return err
+	}
+
+	entries, err := identity.ParseImportFile(c.String("from"), b)
+	if err != nil {
+		return err
+	}
+
+	var failCount, addCount int
+	for _, entry := range entries {
+		usr := &User{
+			Username:     entry.Username,
+			Name:         entry.Name,
+			Email:        entry.Email,
+			PasswordHash: entry.PasswordHash,
+			Roles:        c.StringSlice("role"),
+		}
+		if usr.Email == "" && c.String("email-domain") != "" {
+			usr.Email = usr.Username + "@" + c.String("email-domain")
+		}
+		if err := wr.doAPIRequest(http.MethodPost, "/api/users", nil, usr, nil); err != nil {
+			wr.logger.Error(
+				"failed importing user",
+				zap.String("username", usr.Username),
+				zap.Error(err),
+			)
+			failCount++
+			continue
+		}
+		wr.logger.Info("imported user", zap.String("username", usr.Username), zap.String("email", usr.Email))
+		addCount++
+	}
+
+	wr.logger.Debug("imported users", zap.Int("imported", addCount), zap.Int("failed", failCount))
+	if failCount > 0 {
+		return fmt.Errorf("failed importing %d out of %d users", failCount, failCount+addCount)
 	}
 	return nil
 }
@@ -57,5 +161,74 @@ func listUsers(c *cli.Context) error {
 	}
 	wr.logger.Debug("listing users")
 
+	users := &UserList{}
+	for {
+		params := url.Values{}
+		params.Set("offset", strconv.Itoa(len(users.Users)))
+		params.Set("limit", strconv.Itoa(listUsersPageSize))
+		page := &UserList{}
+		if err := wr.doAPIRequest(http.MethodGet, "/api/users", params, nil, page); err != nil {
+			return err
+		}
+		users.Users = append(users.Users, page.Users...)
+		users.Total = page.Total
+		if len(page.Users) == 0 || len(users.Users) >= page.Total {
+			break
+		}
+	}
+
+	return printUsers(c.String("format"), users.Users)
+}
+
+func unlockUser(c *cli.Context) error {
+	wr := new(wrapper)
+	if err := wr.configure(c); err != nil {
+		return err
+	}
+	userID := c.String("id")
+	if userID == "" {
+		return fmt.Errorf("the --id flag is required")
+	}
+	wr.logger.Debug("unlocking user", zap.String("user_id", userID))
+
+	resp := &struct {
+		User *identity.UserMetadata `json:"user,omitempty"`
+	}{}
+	if err := wr.doAPIRequest(http.MethodPost, "/api/users/"+url.PathEscape(userID)+"/unlock", nil, nil, resp); err != nil {
+		return err
+	}
+	wr.logger.Info("unlocked user", zap.String("user_id", userID))
+	if resp.User == nil {
+		return nil
+	}
+	return printUsers(c.String("format"), []*identity.UserMetadata{resp.User})
+}
+
+func printUsers(format string, users []*identity.UserMetadata) error {
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(users, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "%s\n", b)
+	case "yaml":
+		b, err := yaml.Marshal(users)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "%s", b)
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSERNAME\tEMAIL\tNAME\tROLES\tDISABLED\tLOCKED")
+		for _, usr := range users {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\t%t\n",
+				usr.ID, usr.Username, usr.Email, usr.Name, strings.Join(usr.Roles, ","), usr.Disabled, usr.Locked,
+			)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("the %q output format is unsupported", format)
+	}
 	return nil
 }
@@ -17,16 +17,18 @@ package authcrunch
 import (
 	"github.com/greenpau/go-authcrunch/pkg/authn"
 	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"github.com/greenpau/go-authcrunch/pkg/authz/revocation"
 	"github.com/greenpau/go-authcrunch/pkg/credentials"
 	"github.com/greenpau/go-authcrunch/pkg/messaging"
 )
 
 // Config is a configuration of Server.
 type Config struct {
-	Credentials *credentials.Config   `json:"credentials,omitempty" xml:"credentials,omitempty" yaml:"credentials,omitempty"`
-	Portals     []*authn.PortalConfig `json:"auth_portal_configs,omitempty" xml:"auth_portal_configs,omitempty" yaml:"auth_portal_configs,omitempty"`
-	Policies    []*authz.PolicyConfig `json:"authz_policy_configs,omitempty" xml:"authz_policy_configs,omitempty" yaml:"authz_policy_configs,omitempty"`
-	Messaging   *messaging.Config     `json:"messaging,omitempty" xml:"messaging,omitempty" yaml:"messaging,omitempty"`
+	Credentials     *credentials.Config   `json:"credentials,omitempty" xml:"credentials,omitempty" yaml:"credentials,omitempty"`
+	Portals         []*authn.PortalConfig `json:"auth_portal_configs,omitempty" xml:"auth_portal_configs,omitempty" yaml:"auth_portal_configs,omitempty"`
+	Policies        []*authz.PolicyConfig `json:"authz_policy_configs,omitempty" xml:"authz_policy_configs,omitempty" yaml:"authz_policy_configs,omitempty"`
+	Messaging       *messaging.Config     `json:"messaging,omitempty" xml:"messaging,omitempty" yaml:"messaging,omitempty"`
+	TokenRevocation *revocation.Config    `json:"token_revocation,omitempty" xml:"token_revocation,omitempty" yaml:"token_revocation,omitempty"`
 }
 
 // NewConfig returns an instance of Config.
@@ -68,8 +70,36 @@ func (cfg *Config) AddAuthorizationPolicy(p *authz.PolicyConfig) error {
 	return nil
 }
 
+// SetTokenRevocation sets the configuration of the token revocation store.
+func (cfg *Config) SetTokenRevocation(c *revocation.Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	cfg.TokenRevocation = c
+	return nil
+}
+
+// ConfigureTokenRevocation builds the token revocation store and makes it
+// the store shared by token validators. Without the token revocation
+// configuration, the validators share a store held in memory.
+func (cfg *Config) ConfigureTokenRevocation() error {
+	if cfg.TokenRevocation == nil {
+		return nil
+	}
+	store, err := revocation.NewStore(cfg.TokenRevocation)
+	if err != nil {
+		return err
+	}
+	return revocation.SetDefaultStore(store)
+}
+
 // Validate validates Config.
 func (cfg *Config) Validate() error {
+	if cfg.TokenRevocation != nil {
+		if err := cfg.TokenRevocation.Validate(); err != nil {
+			return err
+		}
+	}
 	for _, portal := range cfg.Portals {
 		portal.SetCredentials(cfg.Credentials)
 		portal.SetMessaging(cfg.Messaging)
@@ -21,6 +21,7 @@ import (
 	"github.com/greenpau/go-authcrunch/internal/tests"
 	"github.com/greenpau/go-authcrunch/internal/testutils"
 	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
 	"github.com/greenpau/go-authcrunch/pkg/authn"
 	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
 	"github.com/greenpau/go-authcrunch/pkg/authn/backends/ldap"
@@ -37,12 +38,14 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/authz/cache"
 	"github.com/greenpau/go-authcrunch/pkg/authz/injector"
 	"github.com/greenpau/go-authcrunch/pkg/authz/options"
+	"github.com/greenpau/go-authcrunch/pkg/authz/revocation"
 	"github.com/greenpau/go-authcrunch/pkg/authz/validator"
 	"github.com/greenpau/go-authcrunch/pkg/credentials"
 	"github.com/greenpau/go-authcrunch/pkg/identity"
 	"github.com/greenpau/go-authcrunch/pkg/identity/qr"
 	"github.com/greenpau/go-authcrunch/pkg/kms"
 	"github.com/greenpau/go-authcrunch/pkg/messaging"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"github.com/greenpau/go-authcrunch/pkg/shared/idp"
 	"github.com/greenpau/go-authcrunch/pkg/user"
@@ -89,6 +92,76 @@ func TestTagCompliance(t *testing.T) {
 			entry: &authncache.RegistrationCacheEntry{},
 			opts:  &Options{},
 		},
+		{
+			name:  "test cache.RecoveryCache struct",
+			entry: &authncache.RecoveryCache{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test cache.RecoveryCacheEntry struct",
+			entry: &authncache.RecoveryCacheEntry{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test cache.RefreshTokenCache struct",
+			entry: &authncache.RefreshTokenCache{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test cache.RefreshTokenCacheEntry struct",
+			entry: &authncache.RefreshTokenCacheEntry{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test cache.StoreConfig struct",
+			entry: &authncache.StoreConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test cache.FileStore struct",
+			entry: &authncache.FileStore{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test cache.RedisStore struct",
+			entry: &authncache.RedisStore{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test audit.Event struct",
+			entry: &audit.Event{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test audit.Config struct",
+			entry: &audit.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test audit.SinkConfig struct",
+			entry: &audit.SinkConfig{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test audit.Logger struct",
+			entry: &audit.Logger{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test audit.FileSink struct",
+			entry: &audit.FileSink{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test audit.SyslogSink struct",
+			entry: &audit.SyslogSink{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test audit.WebhookSink struct",
+			entry: &audit.WebhookSink{},
+			opts:  &Options{},
+		},
 		{
 			name:  "test messaging.EmailProvider struct",
 			entry: &messaging.EmailProvider{},
@@ -119,6 +192,11 @@ func TestTagCompliance(t *testing.T) {
 			entry: &requests.MfaToken{},
 			opts:  &Options{},
 		},
+		{
+			name:  "test requests.Registration struct",
+			entry: &requests.Registration{},
+			opts:  &Options{},
+		},
 		{
 			name:  "test requests.Request struct",
 			entry: &requests.Request{},
@@ -294,6 +372,32 @@ func TestTagCompliance(t *testing.T) {
 				DisableTagOnEmpty: true,
 			},
 		},
+		{
+			name:  "test LockoutPolicy struct",
+			entry: &identity.LockoutPolicy{},
+			opts: &Options{
+				DisableTagOnEmpty: true,
+			},
+		},
+		{
+			name:  "test ImportEntry struct",
+			entry: &identity.ImportEntry{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test EmailPolicy struct",
+			entry: &identity.EmailPolicy{},
+			opts: &Options{
+				DisableTagOnEmpty: true,
+			},
+		},
+		{
+			name:  "test PasswordHashPolicy struct",
+			entry: &identity.PasswordHashPolicy{},
+			opts: &Options{
+				DisableTagOnEmpty: true,
+			},
+		},
 		{
 			name:  "test WebAuthnRegisterRequest struct",
 			entry: &identity.WebAuthnRegisterRequest{},
@@ -390,6 +494,26 @@ func TestTagCompliance(t *testing.T) {
 			entry: &acl.RuleConfiguration{},
 			opts:  &Options{},
 		},
+		{
+			name:  "test acl.RuleCounter struct",
+			entry: &acl.RuleCounter{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test acl.Explanation struct",
+			entry: &acl.Explanation{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test acl.RuleTrace struct",
+			entry: &acl.RuleTrace{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test acl.ConditionTrace struct",
+			entry: &acl.ConditionTrace{},
+			opts:  &Options{},
+		},
 		{
 			name:  "test ldap.Authenticator struct",
 			entry: &ldap.Authenticator{},
@@ -415,6 +539,11 @@ func TestTagCompliance(t *testing.T) {
 			entry: &credentials.Config{},
 			opts:  &Options{},
 		},
+		{
+			name:  "test registration.DomainRule struct",
+			entry: &registration.DomainRule{},
+			opts:  &Options{},
+		},
 		{
 			name:  "test registration.Config struct",
 			entry: &registration.Config{},
@@ -535,6 +664,25 @@ func TestTagCompliance(t *testing.T) {
 			entry: &kms.CryptoKeyStore{},
 			opts:  &Options{},
 		},
+		{
+			name:  "test kms.JWK struct",
+			entry: &kms.JWK{},
+			opts: &Options{
+				DisableTagMismatch: true,
+			},
+		},
+		{
+			name:  "test kms.JWKS struct",
+			entry: &kms.JWKS{},
+			opts: &Options{
+				DisableTagOnEmpty: true,
+			},
+		},
+		{
+			name:  "test kms.JwksKeySource struct",
+			entry: &kms.JwksKeySource{},
+			opts:  &Options{},
+		},
 		{
 			name:  "test kms.CryptoKeyConfig struct",
 			entry: &kms.CryptoKeyConfig{},
@@ -578,6 +726,26 @@ func TestTagCompliance(t *testing.T) {
 			entry: &cache.TokenCache{},
 			opts:  &Options{},
 		},
+		{
+			name:  "test revocation.Entry struct",
+			entry: &revocation.Entry{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test revocation.Config struct",
+			entry: &revocation.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test revocation.MemoryStore struct",
+			entry: &revocation.MemoryStore{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test revocation.FileStore struct",
+			entry: &revocation.FileStore{},
+			opts:  &Options{},
+		},
 		{
 			name:  "test ui.Factory struct",
 			entry: &ui.Factory{},
@@ -732,6 +900,16 @@ func TestTagCompliance(t *testing.T) {
 			entry: &authn.AuthResponse{},
 			opts:  &Options{},
 		},
+		{
+			name:  "test authn.OpenIDConfiguration struct",
+			entry: &authn.OpenIDConfiguration{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test authn.RefreshRequest struct",
+			entry: &authn.RefreshRequest{},
+			opts:  &Options{},
+		},
 		{
 			name:  "test authcrunch.Server struct",
 			entry: &authcrunch.Server{},
@@ -768,6 +946,41 @@ func TestTagCompliance(t *testing.T) {
 			entry: &requests.AuthorizationToken{},
 			opts:  &Options{},
 		},
+		{
+			name:  "test metrics.Config struct",
+			entry: &metrics.Config{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test metrics.Label struct",
+			entry: &metrics.Label{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test metrics.Sample struct",
+			entry: &metrics.Sample{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test metrics.Metric struct",
+			entry: &metrics.Metric{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test metrics.Registry struct",
+			entry: &metrics.Registry{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test metrics.CounterVec struct",
+			entry: &metrics.CounterVec{},
+			opts:  &Options{},
+		},
+		{
+			name:  "test metrics.HistogramVec struct",
+			entry: &metrics.HistogramVec{},
+			opts:  &Options{},
+		},
 	}
 
 	for _, tc := range testcases {
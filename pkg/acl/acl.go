@@ -16,6 +16,7 @@ package acl
 
 import (
 	"context"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"go.uber.org/zap"
 )
 
@@ -25,6 +26,9 @@ type AccessList struct {
 	rules        []aclRule
 	logger       *zap.Logger
 	defaultAllow bool
+	clockEnabled bool
+	networks     map[string][]string
+	paths        []string
 }
 
 // NewAccessList returns an instance of AccessList.
@@ -50,6 +54,27 @@ func (acl *AccessList) SetLogger(logger *zap.Logger) {
 	acl.logger = logger
 }
 
+// AddNetworkList adds a named list of networks to AccessList. The rules
+// reference the list by name, e.g. "match addr in @office".
+func (acl *AccessList) AddNetworkList(name string, entries []string) error {
+	if name == "" {
+		return errors.ErrACLNetworkListNameEmpty
+	}
+	if len(entries) == 0 {
+		return errors.ErrACLNetworkListEmpty.WithArgs(name)
+	}
+	for _, entry := range entries {
+		if _, _, err := parseNetwork(entry); err != nil {
+			return errors.ErrACLNetworkListInvalid.WithArgs(name, entry)
+		}
+	}
+	if acl.networks == nil {
+		acl.networks = make(map[string][]string)
+	}
+	acl.networks[name] = append([]string{}, entries...)
+	return nil
+}
+
 // AddRules adds multiple rules to AccessList.
 func (acl *AccessList) AddRules(ctx context.Context, cfgs []*RuleConfiguration) error {
 	for _, cfg := range cfgs {
@@ -62,15 +87,56 @@ func (acl *AccessList) AddRules(ctx context.Context, cfgs []*RuleConfiguration)
 
 // AddRule adds a rule to AccessList.
 func (acl *AccessList) AddRule(ctx context.Context, cfg *RuleConfiguration) error {
-	rule, err := newACLRule(ctx, len(acl.rules), cfg, acl.logger)
+	ruleCfg, err := acl.expandRule(cfg)
 	if err != nil {
 		return err
 	}
+	rule, err := newACLRule(ctx, len(acl.rules), ruleCfg, acl.logger)
+	if err != nil {
+		return err
+	}
+	for _, k := range rule.getConfig(ctx).fields {
+		if isClockField(k) {
+			acl.clockEnabled = true
+		}
+		if isPathField(k) && !acl.hasPath(k) {
+			acl.paths = append(acl.paths, k)
+		}
+	}
 	acl.config = append(acl.config, cfg)
 	acl.rules = append(acl.rules, rule)
 	return nil
 }
 
+func (acl *AccessList) hasPath(s string) bool {
+	for _, p := range acl.paths {
+		if p == s {
+			return true
+		}
+	}
+	return false
+}
+
+// expandRule returns the copy of the rule configuration with the references
+// to the named network lists replaced by the entries of the lists.
+func (acl *AccessList) expandRule(cfg *RuleConfiguration) (*RuleConfiguration, error) {
+	if cfg == nil {
+		return cfg, nil
+	}
+	ruleCfg := &RuleConfiguration{
+		Comment: cfg.Comment,
+		Action:  cfg.Action,
+	}
+	for _, cond := range cfg.Conditions {
+		expanded, err := expandNetworkLists(cond, acl.networks)
+		if err != nil {
+			return nil, err
+		}
+		ruleCfg.Conditions = append(ruleCfg.Conditions, expanded)
+	}
+	return ruleCfg, nil
+}
+
 // AsMap returns acl configuration as map.
 func (acl *AccessList) AsMap() map[string]interface{} {
 	m := make(map[string]interface{})
@@ -90,6 +156,7 @@ func (acl *AccessList) AsMap() map[string]interface{} {
 // denied access.
 func (acl *AccessList) Allow(ctx context.Context, data map[string]interface{}) bool {
 	var grantAccess bool
+	data = acl.prepare(data)
 	for _, rule := range acl.rules {
 		v := rule.eval(ctx, data)
 		switch v {
@@ -109,6 +176,18 @@ func (acl *AccessList) Allow(ctx context.Context, data map[string]interface{}) b
 	return false
 }
 
+// prepare returns the input data with the fields derived by AccessList,
+// e.g. the time of the evaluation or the values of nested fields.
+func (acl *AccessList) prepare(data map[string]interface{}) map[string]interface{} {
+	if acl.clockEnabled {
+		data = withClock(data)
+	}
+	if len(acl.paths) > 0 {
+		data = withPaths(data, acl.paths)
+	}
+	return data
+}
+
 // GetFieldDataType return data type for a particular data field.
 func GetFieldDataType(s string) (string, string) {
 	k := s
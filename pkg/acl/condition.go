@@ -29,20 +29,23 @@ var (
 	matchFieldRgx        *regexp.Regexp
 
 	inputDataTypes = map[string]dataType{
-		"roles":  dataTypeListStr,
-		"email":  dataTypeStr,
-		"origin": dataTypeStr,
-		"name":   dataTypeStr,
-		"realm":  dataTypeStr,
-		"aud":    dataTypeListStr,
-		"scopes": dataTypeListStr,
-		"org":    dataTypeListStr,
-		"jti":    dataTypeStr,
-		"iss":    dataTypeStr,
-		"sub":    dataTypeStr,
-		"addr":   dataTypeStr,
-		"method": dataTypeStr,
-		"path":   dataTypeStr,
+		"roles":   dataTypeListStr,
+		"email":   dataTypeStr,
+		"origin":  dataTypeStr,
+		"name":    dataTypeStr,
+		"realm":   dataTypeStr,
+		"aud":     dataTypeListStr,
+		"scopes":  dataTypeListStr,
+		"org":     dataTypeListStr,
+		"jti":     dataTypeStr,
+		"iss":     dataTypeStr,
+		"sub":     dataTypeStr,
+		"addr":    dataTypeStr,
+		"method":  dataTypeStr,
+		"path":    dataTypeStr,
+		"time":    dataTypeTime,
+		"weekday": dataTypeTime,
+		"date":    dataTypeTime,
 	}
 
 	inputDataAliases = map[string]string{
@@ -71,6 +74,8 @@ const (
 	dataTypeListStr dataType = 1
 	dataTypeStr     dataType = 2
 	dataTypeAny     dataType = 3
+	dataTypeTime    dataType = 4
+	dataTypeNumber  dataType = 5
 
 	fieldMatchUnknown  fieldMatchStrategy = 0
 	fieldMatchReserved fieldMatchStrategy = 1
@@ -82,6 +87,12 @@ const (
 	fieldFound         fieldMatchStrategy = 7
 	fieldNotFound      fieldMatchStrategy = 8
 	fieldMatchAlways   fieldMatchStrategy = 9
+	fieldMatchBefore   fieldMatchStrategy = 10
+	fieldMatchAfter    fieldMatchStrategy = 11
+	fieldMatchBetween  fieldMatchStrategy = 12
+	fieldMatchNetwork  fieldMatchStrategy = 13
+	fieldMatchGreater  fieldMatchStrategy = 14
+	fieldMatchLess     fieldMatchStrategy = 15
 )
 
 type field struct {
@@ -1153,6 +1164,12 @@ func newACLRuleCondition(ctx context.Context, tokens []string) (aclRuleCondition
 		}
 		inputDataType = dataTypeAny
 		condDataType = dataTypeAny
+	case matchClockRgx.Match([]byte(line)):
+		return newClockCondition(line, tokens)
+	case matchNetworkRgx.Match([]byte(line)):
+		return newNetworkCondition(line, tokens)
+	case isNumberCondition(line):
+		return newNumberCondition(line, tokens)
 	case matchWithStrategyRgx.Match([]byte(line)):
 		matched := matchWithStrategyRgx.FindStringSubmatch(line)
 		for i, k := range matchWithStrategyRgx.SubexpNames() {
@@ -1186,6 +1203,9 @@ func newACLRuleCondition(ctx context.Context, tokens []string) (aclRuleCondition
 			return nil, err
 		}
 		inputDataType = extractInputDataType(fieldName)
+		if inputDataType == dataTypeAny && isClaimField(fieldName) {
+			return newCustomCondition(line, fieldName, matchStrategy, negativeMatch, values)
+		}
 		var err error
 		condDataType, err = extractCondDataType(line, inputDataType, values)
 		if err != nil {
@@ -2233,6 +2253,18 @@ func getMatchStrategyName(s fieldMatchStrategy) string {
 		return "fieldNotFound"
 	case fieldMatchAlways:
 		return "fieldMatchAlways"
+	case fieldMatchBefore:
+		return "fieldMatchBefore"
+	case fieldMatchAfter:
+		return "fieldMatchAfter"
+	case fieldMatchBetween:
+		return "fieldMatchBetween"
+	case fieldMatchNetwork:
+		return "fieldMatchNetwork"
+	case fieldMatchGreater:
+		return "fieldMatchGreater"
+	case fieldMatchLess:
+		return "fieldMatchLess"
 	case fieldMatchReserved:
 		return "fieldMatchReserved"
 	}
@@ -2246,6 +2278,10 @@ func getDataTypeName(s dataType) string {
 		return "dataTypeStr"
 	case dataTypeAny:
 		return "dataTypeAny"
+	case dataTypeTime:
+		return "dataTypeTime"
+	case dataTypeNumber:
+		return "dataTypeNumber"
 	}
 	return "dataTypeUnknown"
 }
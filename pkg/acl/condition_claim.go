@@ -0,0 +1,401 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var matchNumberRgx = regexp.MustCompile(`^\s*(no\s+)?match\s+(\S+)\s+(gt|lt|between)(\s|$)`)
+
+// The custom fields are referenced in conditions with the claim prefix, e.g.
+// claim.department. The unknown fields without the prefix are rejected, so
+// that a misspelled field name does not silently turn into a condition on a
+// custom claim.
+const claimFieldPrefix = "claim."
+
+// ruleCustomCondMatchAnyInput matches the value of a custom field, e.g.
+// department or groups.displayName, against a list of strings. The data
+// type of the input is inferred at the time of the evaluation. The input
+// matches when any of its values matches any of the strings.
+type ruleCustomCondMatchAnyInput struct {
+	field    *field
+	config   *config
+	negative bool
+	exprs    []string
+	regexps  []*regexp.Regexp
+}
+
+// ruleNumberCondMatchAnyInput compares the numeric value of a custom field,
+// e.g. clearance_level, with a number or a range of numbers. The range
+// includes both numbers.
+type ruleNumberCondMatchAnyInput struct {
+	field    *field
+	config   *config
+	negative bool
+	low      float64
+	high     float64
+}
+
+func (c *ruleCustomCondMatchAnyInput) match(ctx context.Context, v interface{}) bool {
+	values, ok := inferStrings(v)
+	if !ok {
+		return false
+	}
+	for _, value := range values {
+		if c.matchString(value) {
+			return !c.negative
+		}
+	}
+	return c.negative
+}
+
+func (c *ruleCustomCondMatchAnyInput) matchString(s string) bool {
+	for _, exp := range c.exprs {
+		switch c.config.matchStrategy {
+		case fieldMatchExact:
+			if s == exp {
+				return true
+			}
+		case fieldMatchPartial:
+			if strings.Contains(s, exp) {
+				return true
+			}
+		case fieldMatchPrefix:
+			if strings.HasPrefix(s, exp) {
+				return true
+			}
+		case fieldMatchSuffix:
+			if strings.HasSuffix(s, exp) {
+				return true
+			}
+		}
+	}
+	for _, re := range c.regexps {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ruleCustomCondMatchAnyInput) getConfig(ctx context.Context) *config {
+	return c.config
+}
+
+func (c *ruleNumberCondMatchAnyInput) match(ctx context.Context, v interface{}) bool {
+	values, ok := inferNumbers(v)
+	if !ok {
+		return false
+	}
+	for _, value := range values {
+		var matched bool
+		switch c.config.matchStrategy {
+		case fieldMatchGreater:
+			matched = value > c.low
+		case fieldMatchLess:
+			matched = value < c.high
+		case fieldMatchBetween:
+			matched = value >= c.low && value <= c.high
+		}
+		if matched {
+			return !c.negative
+		}
+	}
+	return c.negative
+}
+
+func (c *ruleNumberCondMatchAnyInput) getConfig(ctx context.Context) *config {
+	return c.config
+}
+
+// isNumberCondition returns true when the condition compares a custom field
+// with numbers, e.g. "match claim.clearance_level gt 3".
+func isNumberCondition(line string) bool {
+	matched := matchNumberRgx.FindStringSubmatch(line)
+	if matched == nil {
+		return false
+	}
+	return isClaimField(matched[2])
+}
+
+// isClaimField returns true when the field references a custom claim, e.g.
+// claim.department or claim.groups.displayName.
+func isClaimField(s string) bool {
+	return strings.HasPrefix(s, claimFieldPrefix) && len(s) > len(claimFieldPrefix)
+}
+
+// newNumberCondition returns the condition comparing the value of a custom
+// field with numbers, e.g.
+//
+//	match claim.clearance_level gt 3
+//	match claim.metadata.grade between 5 7
+//	no match claim.employee_id lt 1000
+func newNumberCondition(line string, tokens []string) (aclRuleCondition, error) {
+	var negative bool
+	if tokens[0] == "no" {
+		negative = true
+		tokens = tokens[1:]
+	}
+	// The tokens are "match", field name, operator and numbers.
+	fieldName, op, values := strings.TrimPrefix(tokens[1], claimFieldPrefix), tokens[2], tokens[3:]
+	c := &ruleNumberCondMatchAnyInput{
+		field: &field{
+			name:   fieldName,
+			length: len(fieldName),
+		},
+		config: &config{
+			field:         fieldName,
+			values:        values,
+			exprDataType:  dataTypeNumber,
+			inputDataType: dataTypeAny,
+			conditionType: `ruleNumberCondMatchAnyInput`,
+		},
+		negative: negative,
+	}
+	var numbers []float64
+	for _, value := range values {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs(fieldName, value, line)
+		}
+		numbers = append(numbers, n)
+	}
+	switch {
+	case op == "gt" && len(numbers) == 1:
+		c.config.matchStrategy = fieldMatchGreater
+		c.low = numbers[0]
+	case op == "lt" && len(numbers) == 1:
+		c.config.matchStrategy = fieldMatchLess
+		c.high = numbers[0]
+	case op == "between" && len(numbers) == 2:
+		c.config.matchStrategy = fieldMatchBetween
+		c.low, c.high = numbers[0], numbers[1]
+		if c.low > c.high {
+			return nil, errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs(fieldName, strings.Join(values, " "), line)
+		}
+	case len(numbers) == 0:
+		return nil, errors.ErrACLRuleConditionSyntaxMatchValueNotFound.WithArgs(line)
+	default:
+		return nil, errors.ErrACLRuleConditionSyntaxOperatorUnsupported.WithArgs(fieldName, op, line)
+	}
+	return c, nil
+}
+
+// newCustomCondition returns the condition matching the value of a custom
+// field against strings, e.g.
+//
+//	match claim.department engineering
+//	prefix match claim.groups.displayName "Team "
+//	no match claim.employee_type contractor
+func newCustomCondition(line, fieldName string, matchStrategy fieldMatchStrategy, negative bool, values []string) (aclRuleCondition, error) {
+	fieldName = strings.TrimPrefix(fieldName, claimFieldPrefix)
+	condDataType := dataTypeStr
+	if len(values) > 1 {
+		condDataType = dataTypeListStr
+	}
+	c := &ruleCustomCondMatchAnyInput{
+		field: &field{
+			name:   fieldName,
+			length: len(fieldName),
+		},
+		config: &config{
+			field:         fieldName,
+			matchStrategy: matchStrategy,
+			values:        values,
+			regexEnabled:  matchStrategy == fieldMatchRegex,
+			exprDataType:  condDataType,
+			inputDataType: dataTypeAny,
+			conditionType: `ruleCustomCondMatchAnyInput`,
+		},
+		negative: negative,
+	}
+	switch matchStrategy {
+	case fieldMatchExact, fieldMatchPartial, fieldMatchPrefix, fieldMatchSuffix:
+		c.exprs = values
+	case fieldMatchRegex:
+		for _, value := range values {
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			c.regexps = append(c.regexps, re)
+		}
+	default:
+		return nil, errors.ErrACLRuleConditionSyntaxUnsupported.WithArgs(line)
+	}
+	return c, nil
+}
+
+// inferStrings returns the string representation of the values of a field.
+// It supports strings, numbers, booleans and the lists of them.
+func inferStrings(v interface{}) ([]string, bool) {
+	switch val := v.(type) {
+	case string:
+		return []string{val}, true
+	case []string:
+		return val, true
+	case bool:
+		return []string{strconv.FormatBool(val)}, true
+	case []interface{}:
+		var values []string
+		for _, entry := range val {
+			switch entry.(type) {
+			case []interface{}, []string:
+				continue
+			}
+			if s, ok := inferStrings(entry); ok {
+				values = append(values, s...)
+			}
+		}
+		return values, true
+	}
+	if n, ok := inferNumber(v); ok {
+		return []string{strconv.FormatFloat(n, 'f', -1, 64)}, true
+	}
+	return nil, false
+}
+
+// inferNumbers returns the numeric values of a field. It supports numbers,
+// numeric strings and the lists of them.
+func inferNumbers(v interface{}) ([]float64, bool) {
+	switch val := v.(type) {
+	case []string:
+		var values []float64
+		for _, entry := range val {
+			if n, ok := inferNumber(entry); ok {
+				values = append(values, n)
+			}
+		}
+		return values, true
+	case []interface{}:
+		var values []float64
+		for _, entry := range val {
+			if n, ok := inferNumber(entry); ok {
+				values = append(values, n)
+			}
+		}
+		return values, true
+	}
+	if n, ok := inferNumber(v); ok {
+		return []float64{n}, true
+	}
+	return nil, false
+}
+
+func inferNumber(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case int32:
+		return float64(val), true
+	case uint:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	case uint32:
+		return float64(val), true
+	case json.Number:
+		n, err := val.Float64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// isPathField returns true when the field is a path to a nested field,
+// e.g. metadata.department.
+func isPathField(s string) bool {
+	return strings.Contains(s, ".") && !strings.HasPrefix(s, ".") && !strings.HasSuffix(s, ".")
+}
+
+// lookupPath returns the value of a nested field, e.g. groups.displayName.
+// When the path goes through a list, the values of the field in each of
+// the list entries are collected into a list.
+func lookupPath(v interface{}, keys []string) (interface{}, bool) {
+	if len(keys) == 0 {
+		return v, true
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		next, exists := val[keys[0]]
+		if !exists {
+			return nil, false
+		}
+		return lookupPath(next, keys[1:])
+	case map[string]string:
+		if len(keys) != 1 {
+			return nil, false
+		}
+		s, exists := val[keys[0]]
+		return s, exists
+	case []map[string]interface{}:
+		entries := make([]interface{}, len(val))
+		for i, entry := range val {
+			entries[i] = entry
+		}
+		return lookupPath(entries, keys)
+	case []interface{}:
+		var values []interface{}
+		for _, entry := range val {
+			found, ok := lookupPath(entry, keys)
+			if !ok {
+				continue
+			}
+			if list, isList := found.([]interface{}); isList {
+				values = append(values, list...)
+				continue
+			}
+			values = append(values, found)
+		}
+		if len(values) == 0 {
+			return nil, false
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// withPaths returns the copy of the input data with the values of nested
+// fields, e.g. metadata.department, added under their paths. The input
+// data is not modified.
+func withPaths(data map[string]interface{}, paths []string) map[string]interface{} {
+	m := make(map[string]interface{}, len(data)+len(paths))
+	for k, v := range data {
+		m[k] = v
+	}
+	for _, p := range paths {
+		if _, exists := data[p]; exists {
+			continue
+		}
+		if v, found := lookupPath(data, strings.Split(p, ".")); found {
+			m[p] = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,252 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"strings"
+	"testing"
+)
+
+func TestMatchCustomFieldCondition(t *testing.T) {
+	var testcases = []struct {
+		name      string
+		condition string
+		input     interface{}
+		want      bool
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:      "match string custom field",
+			condition: `match claim.department engineering`,
+			input:     "engineering",
+			want:      true,
+		},
+		{
+			name:      "match string custom field with other value",
+			condition: `match claim.department engineering`,
+			input:     "sales",
+			want:      false,
+		},
+		{
+			name:      "partial match list custom field",
+			condition: `partial match claim.groups.displayName Platform`,
+			input:     []interface{}{"Sales", "Platform Team"},
+			want:      true,
+		},
+		{
+			name:      "match list of strings custom field",
+			condition: `match claim.employee_type contractor intern`,
+			input:     []string{"intern"},
+			want:      true,
+		},
+		{
+			name:      "regex match custom field",
+			condition: `regex match claim.cost_center ^CC-[0-9]+$`,
+			input:     "CC-1234",
+			want:      true,
+		},
+		{
+			name:      "match boolean custom field",
+			condition: `match claim.email_verified true`,
+			input:     true,
+			want:      true,
+		},
+		{
+			name:      "match number custom field",
+			condition: `match claim.clearance_level 4`,
+			input:     float64(4),
+			want:      true,
+		},
+		{
+			name:      "no match custom field",
+			condition: `no match claim.employee_type contractor`,
+			input:     "employee",
+			want:      true,
+		},
+		{
+			name:      "no match custom field with matching value",
+			condition: `no match claim.employee_type contractor`,
+			input:     []interface{}{"contractor"},
+			want:      false,
+		},
+		{
+			name:      "match custom field with unsupported input",
+			condition: `match claim.department engineering`,
+			input:     map[string]interface{}{"name": "engineering"},
+			want:      false,
+		},
+		{
+			name:      "match number greater than",
+			condition: `match claim.clearance_level gt 3`,
+			input:     4,
+			want:      true,
+		},
+		{
+			name:      "match number greater than with equal value",
+			condition: `match claim.clearance_level gt 3`,
+			input:     float64(3),
+			want:      false,
+		},
+		{
+			name:      "match number less than",
+			condition: `match claim.employee_id lt 1000`,
+			input:     json.Number("999"),
+			want:      true,
+		},
+		{
+			name:      "match numeric string between",
+			condition: `match claim.metadata.grade between 5 7`,
+			input:     "7",
+			want:      true,
+		},
+		{
+			name:      "match number between outside of range",
+			condition: `match claim.metadata.grade between 5 7`,
+			input:     7.5,
+			want:      false,
+		},
+		{
+			name:      "match list of numbers greater than",
+			condition: `match claim.scores gt 90`,
+			input:     []interface{}{float64(80), float64(95)},
+			want:      true,
+		},
+		{
+			name:      "no match number less than",
+			condition: `no match claim.clearance_level lt 3`,
+			input:     float64(5),
+			want:      true,
+		},
+		{
+			name:      "match number with non-numeric input",
+			condition: `match claim.clearance_level gt 3`,
+			input:     "high",
+			want:      false,
+		},
+		{
+			name:      "match reserved field with numeric operator",
+			condition: `match roles gt`,
+			input:     []string{"gt"},
+			want:      true,
+		},
+		{
+			name:      "match number with invalid value",
+			condition: `match claim.clearance_level gt high`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs("clearance_level", "high", "match claim.clearance_level gt high"),
+		},
+		{
+			name:      "match number between without end",
+			condition: `match claim.clearance_level between 3`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxOperatorUnsupported.WithArgs("clearance_level", "between", "match claim.clearance_level between 3"),
+		},
+		{
+			name:      "match number between in reverse order",
+			condition: `match claim.clearance_level between 7 5`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs("clearance_level", "7 5", "match claim.clearance_level between 7 5"),
+		},
+		{
+			name:      "match number without value",
+			condition: `match claim.clearance_level gt`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxMatchValueNotFound.WithArgs("match claim.clearance_level gt"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			cond, err := newACLRuleCondition(ctx, strings.Split(tc.condition, " "))
+			if tests.EvalErr(t, err, tc.condition, tc.shouldErr, tc.err) {
+				return
+			}
+			got := cond.match(ctx, tc.input)
+			tests.EvalObjects(t, "match", tc.want, got)
+		})
+	}
+}
+
+func TestAccessListWithNestedFields(t *testing.T) {
+	ctx := context.Background()
+	accessList := NewAccessList()
+	if err := accessList.AddRules(ctx, []*RuleConfiguration{
+		{
+			Comment: "engineering staff with clearance",
+			Conditions: []string{
+				"match claim.metadata.department engineering",
+				"match claim.metadata.clearance_level gt 2",
+				"suffix match claim.groups.displayName Team",
+			},
+			Action: `allow stop`,
+		},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var testcases = []struct {
+		name  string
+		input map[string]interface{}
+		want  bool
+	}{
+		{
+			name: "user with matching nested fields",
+			input: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"department":      "engineering",
+					"clearance_level": float64(3),
+				},
+				"groups": []interface{}{
+					map[string]interface{}{"displayName": "Platform Team"},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "user with insufficient clearance",
+			input: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"department":      "engineering",
+					"clearance_level": float64(1),
+				},
+				"groups": []interface{}{
+					map[string]interface{}{"displayName": "Platform Team"},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "user without nested fields",
+			input: map[string]interface{}{
+				"metadata": "engineering",
+			},
+			want: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := accessList.Allow(ctx, tc.input)
+			tests.EvalObjects(t, "allow", tc.want, got)
+			if _, exists := tc.input["metadata.department"]; exists {
+				t.Fatalf("input data was modified: %v", tc.input)
+			}
+		})
+	}
+}
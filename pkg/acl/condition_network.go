@@ -0,0 +1,196 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+import (
+	"bytes"
+	"context"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	cfgutil "github.com/greenpau/go-authcrunch/pkg/util/cfg"
+	"net"
+	"regexp"
+	"strings"
+)
+
+var matchNetworkRgx = regexp.MustCompile(`^\s*(no\s+)?match\s+(addr|address|ip|ipv4|ipv6)\s+in(\s|$)`)
+
+// ipRange is the range of IP addresses, including the first and last
+// addresses.
+type ipRange struct {
+	first net.IP
+	last  net.IP
+}
+
+func (r *ipRange) contains(ip net.IP) bool {
+	if (r.first.To4() == nil) != (ip.To4() == nil) {
+		return false
+	}
+	ip = ip.To16()
+	return bytes.Compare(ip, r.first) >= 0 && bytes.Compare(ip, r.last) <= 0
+}
+
+// ruleNetworkCondMatchStrInput matches the IP address in the input against
+// a list of networks, e.g. 10.0.0.0/8, and address ranges, e.g.
+// 192.168.1.10-192.168.1.20.
+type ruleNetworkCondMatchStrInput struct {
+	field    *field
+	config   *config
+	negative bool
+	networks []*net.IPNet
+	ranges   []*ipRange
+}
+
+func (c *ruleNetworkCondMatchStrInput) match(ctx context.Context, v interface{}) bool {
+	var s string
+	switch val := v.(type) {
+	case string:
+		s = val
+	default:
+		return false
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		if host, _, err := net.SplitHostPort(s); err == nil {
+			ip = net.ParseIP(host)
+		}
+	}
+	if ip == nil {
+		return false
+	}
+	return c.contains(ip) != c.negative
+}
+
+func (c *ruleNetworkCondMatchStrInput) contains(ip net.IP) bool {
+	for _, n := range c.networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	for _, r := range c.ranges {
+		if r.contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ruleNetworkCondMatchStrInput) getConfig(ctx context.Context) *config {
+	return c.config
+}
+
+// newNetworkCondition returns the condition matching IP addresses against
+// networks, e.g.
+//
+//	match addr in 10.0.0.0/8 192.168.0.0/16 2001:db8::/32
+//	no match addr in 192.168.1.10-192.168.1.20
+func newNetworkCondition(line string, tokens []string) (aclRuleCondition, error) {
+	var negative bool
+	if tokens[0] == "no" {
+		negative = true
+		tokens = tokens[1:]
+	}
+	// The tokens are "match", field name, "in" and networks.
+	values := tokens[3:]
+	if len(values) == 0 {
+		return nil, errors.ErrACLRuleConditionSyntaxMatchValueNotFound.WithArgs(line)
+	}
+	c := &ruleNetworkCondMatchStrInput{
+		field: &field{
+			name:   "addr",
+			length: len("addr"),
+		},
+		config: &config{
+			field:         "addr",
+			matchStrategy: fieldMatchNetwork,
+			values:        values,
+			exprDataType:  dataTypeListStr,
+			inputDataType: dataTypeStr,
+			conditionType: `ruleNetworkCondMatchStrInput`,
+		},
+		negative: negative,
+	}
+	for _, value := range values {
+		network, r, err := parseNetwork(value)
+		if err != nil {
+			return nil, errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs("addr", value, line)
+		}
+		if network != nil {
+			c.networks = append(c.networks, network)
+			continue
+		}
+		c.ranges = append(c.ranges, r)
+	}
+	return c, nil
+}
+
+// parseNetwork parses CIDR notation, e.g. 10.0.0.0/8, IP address, e.g.
+// 10.0.0.1, or IP address range, e.g. 10.0.0.1-10.0.0.9.
+func parseNetwork(s string) (*net.IPNet, *ipRange, error) {
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		return network, nil, nil
+	}
+	arr := strings.SplitN(s, "-", 2)
+	first := net.ParseIP(arr[0])
+	if first == nil {
+		return nil, nil, errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs("addr", s, s)
+	}
+	if len(arr) == 1 {
+		bits := 128
+		if first.To4() != nil {
+			bits = 32
+			first = first.To4()
+		}
+		return &net.IPNet{IP: first, Mask: net.CIDRMask(bits, bits)}, nil, nil
+	}
+	last := net.ParseIP(arr[1])
+	if last == nil || (first.To4() == nil) != (last.To4() == nil) || bytes.Compare(first.To16(), last.To16()) > 0 {
+		return nil, nil, errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs("addr", s, s)
+	}
+	return nil, &ipRange{first: first.To16(), last: last.To16()}, nil
+}
+
+// expandNetworkLists replaces the references to the named network lists,
+// e.g. @office, in the networks of the condition with the entries of the
+// lists.
+func expandNetworkLists(cond string, lists map[string][]string) (string, error) {
+	if !strings.Contains(cond, "@") {
+		return cond, nil
+	}
+	tokens, err := cfgutil.DecodeArgs(cond)
+	if err != nil {
+		return "", err
+	}
+	if !matchNetworkRgx.MatchString(strings.Join(tokens, " ")) {
+		return cond, nil
+	}
+	var expanded []string
+	for _, token := range tokens {
+		if !strings.HasPrefix(token, "@") {
+			expanded = append(expanded, token)
+			continue
+		}
+		name := strings.TrimPrefix(token, "@")
+		entries, exists := lists[name]
+		if !exists {
+			return "", errors.ErrACLNetworkListNotFound.WithArgs(name, cond)
+		}
+		expanded = append(expanded, entries...)
+	}
+	return cfgutil.EncodeArgs(expanded), nil
+}
@@ -0,0 +1,285 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"strings"
+	"testing"
+)
+
+func TestMatchNetworkCondition(t *testing.T) {
+	var testcases = []struct {
+		name      string
+		condition string
+		input     interface{}
+		want      bool
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:      "match ipv4 address in private networks",
+			condition: `match addr in 10.0.0.0/8 192.168.0.0/16`,
+			input:     "192.168.10.1",
+			want:      true,
+		},
+		{
+			name:      "match ipv4 address outside of private networks",
+			condition: `match addr in 10.0.0.0/8 192.168.0.0/16`,
+			input:     "172.16.0.1",
+			want:      false,
+		},
+		{
+			name:      "match ipv4 address with port",
+			condition: `match addr in 10.0.0.0/8`,
+			input:     "10.1.2.3:44321",
+			want:      true,
+		},
+		{
+			name:      "match ipv6 address",
+			condition: `match addr in 2001:db8::/32`,
+			input:     "2001:db8:1::10",
+			want:      true,
+		},
+		{
+			name:      "match ipv6 address against ipv4 network",
+			condition: `match addr in 10.0.0.0/8`,
+			input:     "2001:db8:1::10",
+			want:      false,
+		},
+		{
+			name:      "match single ipv4 address",
+			condition: `match ip in 127.0.0.1`,
+			input:     "127.0.0.1",
+			want:      true,
+		},
+		{
+			name:      "match single ipv6 address",
+			condition: `match address in ::1`,
+			input:     "::1",
+			want:      true,
+		},
+		{
+			name:      "match ipv4 address in range",
+			condition: `match addr in 192.168.1.10-192.168.1.20`,
+			input:     "192.168.1.15",
+			want:      true,
+		},
+		{
+			name:      "match ipv4 address outside of range",
+			condition: `match addr in 192.168.1.10-192.168.1.20`,
+			input:     "192.168.1.21",
+			want:      false,
+		},
+		{
+			name:      "match ipv6 address in range",
+			condition: `match addr in 2001:db8::1-2001:db8::ff`,
+			input:     "2001:db8::a",
+			want:      true,
+		},
+		{
+			name:      "no match ipv4 address in networks",
+			condition: `no match addr in 10.0.0.0/8`,
+			input:     "172.16.0.1",
+			want:      true,
+		},
+		{
+			name:      "no match ipv4 address in networks with matching address",
+			condition: `no match addr in 10.0.0.0/8`,
+			input:     "10.0.0.1",
+			want:      false,
+		},
+		{
+			name:      "match invalid address",
+			condition: `match addr in 10.0.0.0/8`,
+			input:     "foo",
+			want:      false,
+		},
+		{
+			name:      "no match invalid address",
+			condition: `no match addr in 10.0.0.0/8`,
+			input:     "foo",
+			want:      false,
+		},
+		{
+			name:      "match non-string address",
+			condition: `match addr in 10.0.0.0/8`,
+			input:     10,
+			want:      false,
+		},
+		{
+			name:      "match addr with invalid network",
+			condition: `match addr in 10.0.0.0/33`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs("addr", "10.0.0.0/33", "match addr in 10.0.0.0/33"),
+		},
+		{
+			name:      "match addr with range in reverse order",
+			condition: `match addr in 10.0.0.9-10.0.0.1`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs("addr", "10.0.0.9-10.0.0.1", "match addr in 10.0.0.9-10.0.0.1"),
+		},
+		{
+			name:      "match addr with range of mixed families",
+			condition: `match addr in 10.0.0.1-::1`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs("addr", "10.0.0.1-::1", "match addr in 10.0.0.1-::1"),
+		},
+		{
+			name:      "match addr without networks",
+			condition: `match addr in`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxMatchValueNotFound.WithArgs("match addr in"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			cond, err := newACLRuleCondition(ctx, strings.Split(tc.condition, " "))
+			if tests.EvalErr(t, err, tc.condition, tc.shouldErr, tc.err) {
+				return
+			}
+			got := cond.match(ctx, tc.input)
+			tests.EvalObjects(t, "match", tc.want, got)
+		})
+	}
+}
+
+func TestAccessListWithNetworkLists(t *testing.T) {
+	var testcases = []struct {
+		name      string
+		networks  map[string][]string
+		rules     []*RuleConfiguration
+		input     map[string]interface{}
+		want      bool
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "allow admins from office network",
+			networks: map[string][]string{
+				"office": {"10.10.0.0/16", "2001:db8::/32"},
+			},
+			rules: []*RuleConfiguration{
+				{
+					Conditions: []string{"match roles admin", "match addr in @office 192.168.1.1"},
+					Action:     `allow`,
+				},
+			},
+			input: map[string]interface{}{
+				"roles": []string{"admin"},
+				"addr":  "2001:db8::1",
+			},
+			want: true,
+		},
+		{
+			name: "allow admins from address outside of office network",
+			networks: map[string][]string{
+				"office": {"10.10.0.0/16"},
+			},
+			rules: []*RuleConfiguration{
+				{
+					Conditions: []string{"match roles admin", "match addr in @office"},
+					Action:     `allow`,
+				},
+			},
+			input: map[string]interface{}{
+				"roles": []string{"admin"},
+				"addr":  "10.20.0.1",
+			},
+			want: false,
+		},
+		{
+			name: "deny access from blocked network",
+			networks: map[string][]string{
+				"blocked": {"203.0.113.0/24"},
+			},
+			rules: []*RuleConfiguration{
+				{
+					Conditions: []string{"match addr in @blocked"},
+					Action:     `deny stop`,
+				},
+				{
+					Conditions: []string{"match any"},
+					Action:     `allow`,
+				},
+			},
+			input: map[string]interface{}{
+				"roles": []string{"user"},
+				"addr":  "203.0.113.5",
+			},
+			want: false,
+		},
+		{
+			name: "reference undefined network list",
+			rules: []*RuleConfiguration{
+				{
+					Conditions: []string{"match addr in @office"},
+					Action:     `allow`,
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrACLNetworkListNotFound.WithArgs("office", "match addr in @office"),
+		},
+		{
+			name: "add network list with invalid entry",
+			networks: map[string][]string{
+				"office": {"10.10.0.0/16", "foo"},
+			},
+			shouldErr: true,
+			err:       errors.ErrACLNetworkListInvalid.WithArgs("office", "foo"),
+		},
+		{
+			name: "add network list without entries",
+			networks: map[string][]string{
+				"office": {},
+			},
+			shouldErr: true,
+			err:       errors.ErrACLNetworkListEmpty.WithArgs("office"),
+		},
+		{
+			name: "add network list without name",
+			networks: map[string][]string{
+				"": {"10.10.0.0/16"},
+			},
+			shouldErr: true,
+			err:       errors.ErrACLNetworkListNameEmpty,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			ctx := context.Background()
+			accessList := NewAccessList()
+			for name, entries := range tc.networks {
+				if err = accessList.AddNetworkList(name, entries); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = accessList.AddRules(ctx, tc.rules)
+			}
+			if tests.EvalErr(t, err, tc.rules, tc.shouldErr, tc.err) {
+				return
+			}
+			got := accessList.Allow(ctx, tc.input)
+			tests.EvalObjects(t, "allow", tc.want, got)
+			// The configuration retains the references to the network lists.
+			tests.EvalObjects(t, "rules", tc.rules, accessList.GetRules())
+		})
+	}
+}
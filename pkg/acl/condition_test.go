@@ -2733,6 +2733,22 @@ func TestNewAclRuleCondition(t *testing.T) {
 			condition: `exact match bootstrap yes`,
 			shouldErr: true,
 			err:       errors.ErrACLRuleConditionSyntaxUnsupported.WithArgs("exact match bootstrap yes"),
+		}, {
+			name:      "exact match custom field",
+			condition: `exact match claim.bootstrap yes`,
+			want: map[string]interface{}{
+				"condition_type":          "*acl.ruleCustomCondMatchAnyInput",
+				"field_name":              "bootstrap",
+				"regex_enabled":           false,
+				"always_true":             false,
+				"match_strategy":          "fieldMatchExact",
+				"default_match_strategy":  "fieldMatchUnknown",
+				"reserved_match_strategy": "fieldMatchReserved",
+				"default_data_type":       "dataTypeUnknown",
+				"expr_data_type":          "dataTypeStr",
+				"input_data_type":         "dataTypeAny",
+				"values":                  []string{`yes`},
+			},
 		}, {
 			name:      "invalid condition syntax use of reserved type",
 			condition: `reserved match roles anonymous`,
@@ -0,0 +1,329 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	matchClockRgx = regexp.MustCompile(`^\s*(no\s+)?match\s+(time|weekday|date)(\s|$)`)
+
+	weekdays = map[string]time.Weekday{
+		"sun": time.Sunday, "sunday": time.Sunday,
+		"mon": time.Monday, "monday": time.Monday,
+		"tue": time.Tuesday, "tuesday": time.Tuesday,
+		"wed": time.Wednesday, "wednesday": time.Wednesday,
+		"thu": time.Thursday, "thursday": time.Thursday,
+		"fri": time.Friday, "friday": time.Friday,
+		"sat": time.Saturday, "saturday": time.Saturday,
+	}
+)
+
+// clockFields are the input fields holding the time of the evaluation.
+// AccessList populates them when its rules have time-based conditions.
+var clockFields = []string{"time", "weekday", "date"}
+
+// timeNow returns the time of the evaluation of time-based conditions.
+var timeNow = time.Now
+
+// ruleTimeCondMatchTimeInput matches the time of day of the input against
+// a time of day or a range of times of day, e.g. 08:00-17:00. The range
+// may span midnight, e.g. 22:00-06:00.
+type ruleTimeCondMatchTimeInput struct {
+	field    *field
+	config   *config
+	negative bool
+	start    int
+	end      int
+	location *time.Location
+}
+
+// ruleWeekdayCondMatchTimeInput matches the day of week of the input against
+// a set of days of week.
+type ruleWeekdayCondMatchTimeInput struct {
+	field    *field
+	config   *config
+	negative bool
+	days     [7]bool
+	location *time.Location
+}
+
+// ruleDateCondMatchTimeInput matches the calendar date of the input against
+// a date or a range of dates. The range includes both dates.
+type ruleDateCondMatchTimeInput struct {
+	field    *field
+	config   *config
+	negative bool
+	start    int
+	end      int
+	location *time.Location
+}
+
+func (c *ruleTimeCondMatchTimeInput) match(ctx context.Context, v interface{}) bool {
+	t, ok := v.(time.Time)
+	if !ok {
+		return false
+	}
+	t = t.In(c.location)
+	m := t.Hour()*60 + t.Minute()
+	var matched bool
+	switch c.config.matchStrategy {
+	case fieldMatchBefore:
+		matched = m < c.end
+	case fieldMatchAfter:
+		matched = m >= c.start
+	case fieldMatchBetween:
+		if c.start <= c.end {
+			matched = m >= c.start && m < c.end
+		} else {
+			matched = m >= c.start || m < c.end
+		}
+	}
+	return matched != c.negative
+}
+
+func (c *ruleTimeCondMatchTimeInput) getConfig(ctx context.Context) *config {
+	return c.config
+}
+
+func (c *ruleWeekdayCondMatchTimeInput) match(ctx context.Context, v interface{}) bool {
+	t, ok := v.(time.Time)
+	if !ok {
+		return false
+	}
+	return c.days[t.In(c.location).Weekday()] != c.negative
+}
+
+func (c *ruleWeekdayCondMatchTimeInput) getConfig(ctx context.Context) *config {
+	return c.config
+}
+
+func (c *ruleDateCondMatchTimeInput) match(ctx context.Context, v interface{}) bool {
+	t, ok := v.(time.Time)
+	if !ok {
+		return false
+	}
+	t = t.In(c.location)
+	d := t.Year()*10000 + int(t.Month())*100 + t.Day()
+	var matched bool
+	switch c.config.matchStrategy {
+	case fieldMatchBefore:
+		matched = d < c.start
+	case fieldMatchAfter:
+		matched = d > c.start
+	case fieldMatchBetween:
+		matched = d >= c.start && d <= c.end
+	}
+	return matched != c.negative
+}
+
+func (c *ruleDateCondMatchTimeInput) getConfig(ctx context.Context) *config {
+	return c.config
+}
+
+// newClockCondition returns time-based condition, e.g.
+//
+//	match time between 08:00 17:00 tz Europe/Berlin
+//	match weekday mon-fri
+//	no match date after 2026-12-31
+func newClockCondition(line string, tokens []string) (aclRuleCondition, error) {
+	var negative bool
+	if tokens[0] == "no" {
+		negative = true
+		tokens = tokens[1:]
+	}
+	fieldName := tokens[1]
+	values := tokens[2:]
+
+	location := time.UTC
+	args := values
+	if n := len(args); n > 1 && (args[n-2] == "tz" || args[n-2] == "timezone") {
+		loc, err := time.LoadLocation(args[n-1])
+		if err != nil {
+			return nil, errors.ErrACLRuleConditionSyntaxTimezoneInvalid.WithArgs(args[n-1], err)
+		}
+		location = loc
+		args = args[:n-2]
+	}
+	if len(args) == 0 {
+		return nil, errors.ErrACLRuleConditionSyntaxMatchValueNotFound.WithArgs(line)
+	}
+
+	cfg := &config{
+		field:         fieldName,
+		matchStrategy: fieldMatchExact,
+		values:        values,
+		exprDataType:  dataTypeTime,
+		inputDataType: dataTypeTime,
+	}
+	f := &field{
+		name:   fieldName,
+		length: len(fieldName),
+	}
+
+	if fieldName == "weekday" {
+		c := &ruleWeekdayCondMatchTimeInput{
+			field:    f,
+			config:   cfg,
+			negative: negative,
+			location: location,
+		}
+		cfg.conditionType = `ruleWeekdayCondMatchTimeInput`
+		for _, arg := range args {
+			for _, s := range strings.Split(arg, ",") {
+				if s == "" {
+					continue
+				}
+				if !addWeekdays(&c.days, strings.ToLower(s)) {
+					return nil, errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs(fieldName, s, line)
+				}
+			}
+		}
+		return c, nil
+	}
+
+	var parse func(string) (int, error)
+	switch fieldName {
+	case "time":
+		parse = parseTimeOfDay
+	default:
+		parse = parseDate
+	}
+
+	var start, end int
+	var err error
+	switch args[0] {
+	case "before", "after":
+		if len(args) != 2 {
+			return nil, errors.ErrACLRuleConditionSyntaxOperatorUnsupported.WithArgs(fieldName, args[0], line)
+		}
+		if start, err = parse(args[1]); err != nil {
+			return nil, errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs(fieldName, args[1], line)
+		}
+		end = start
+		cfg.matchStrategy = fieldMatchBefore
+		if args[0] == "after" {
+			cfg.matchStrategy = fieldMatchAfter
+		}
+	case "between":
+		if len(args) != 3 {
+			return nil, errors.ErrACLRuleConditionSyntaxOperatorUnsupported.WithArgs(fieldName, args[0], line)
+		}
+		if start, err = parse(args[1]); err != nil {
+			return nil, errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs(fieldName, args[1], line)
+		}
+		if end, err = parse(args[2]); err != nil {
+			return nil, errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs(fieldName, args[2], line)
+		}
+		if fieldName == "date" && start > end {
+			return nil, errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs(fieldName, args[1]+" "+args[2], line)
+		}
+		cfg.matchStrategy = fieldMatchBetween
+	default:
+		return nil, errors.ErrACLRuleConditionSyntaxOperatorUnsupported.WithArgs(fieldName, args[0], line)
+	}
+
+	if fieldName == "time" {
+		cfg.conditionType = `ruleTimeCondMatchTimeInput`
+		return &ruleTimeCondMatchTimeInput{
+			field:    f,
+			config:   cfg,
+			negative: negative,
+			start:    start,
+			end:      end,
+			location: location,
+		}, nil
+	}
+	cfg.conditionType = `ruleDateCondMatchTimeInput`
+	return &ruleDateCondMatchTimeInput{
+		field:    f,
+		config:   cfg,
+		negative: negative,
+		start:    start,
+		end:      end,
+		location: location,
+	}, nil
+}
+
+// addWeekdays adds a day of week, e.g. mon, or a range of days of week,
+// e.g. mon-fri, to the set. It returns false when the value is invalid.
+func addWeekdays(days *[7]bool, s string) bool {
+	arr := strings.SplitN(s, "-", 2)
+	first, exists := weekdays[arr[0]]
+	if !exists {
+		return false
+	}
+	last := first
+	if len(arr) == 2 {
+		if last, exists = weekdays[arr[1]]; !exists {
+			return false
+		}
+	}
+	for d := first; ; d = (d + 1) % 7 {
+		days[d] = true
+		if d == last {
+			break
+		}
+	}
+	return true
+}
+
+// parseTimeOfDay returns the number of minutes since midnight for the time
+// of day in HH:MM format.
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseDate returns the date in YYYY-MM-DD format as YYYYMMDD number.
+func parseDate(s string) (int, error) {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Year()*10000 + int(t.Month())*100 + t.Day(), nil
+}
+
+// isClockField returns true when the field holds the time of the evaluation.
+func isClockField(s string) bool {
+	for _, k := range clockFields {
+		if k == s {
+			return true
+		}
+	}
+	return false
+}
+
+// withClock returns a copy of the input data with the clock fields set to
+// the current time.
+func withClock(data map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(data)+len(clockFields))
+	for k, v := range data {
+		m[k] = v
+	}
+	now := timeNow()
+	for _, k := range clockFields {
+		m[k] = now
+	}
+	return m
+}
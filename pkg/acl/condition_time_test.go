@@ -0,0 +1,229 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchClockCondition(t *testing.T) {
+	// Wednesday, 07:30 UTC, 09:30 in Berlin.
+	wed := time.Date(2026, 6, 10, 7, 30, 0, 0, time.UTC)
+	var testcases = []struct {
+		name      string
+		condition string
+		input     interface{}
+		want      bool
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:      "match time between in utc",
+			condition: `match time between 08:00 17:00`,
+			input:     wed,
+			want:      false,
+		},
+		{
+			name:      "match time between with timezone",
+			condition: `match time between 08:00 17:00 tz Europe/Berlin`,
+			input:     wed,
+			want:      true,
+		},
+		{
+			name:      "match time between spanning midnight",
+			condition: `match time between 22:00 08:00`,
+			input:     wed,
+			want:      true,
+		},
+		{
+			name:      "match time before",
+			condition: `match time before 07:30`,
+			input:     wed,
+			want:      false,
+		},
+		{
+			name:      "match time after",
+			condition: `match time after 07:30`,
+			input:     wed,
+			want:      true,
+		},
+		{
+			name:      "no match time between",
+			condition: `no match time between 08:00 17:00`,
+			input:     wed,
+			want:      true,
+		},
+		{
+			name:      "match weekday range",
+			condition: `match weekday mon-fri`,
+			input:     wed,
+			want:      true,
+		},
+		{
+			name:      "match weekday list",
+			condition: `match weekday sat,sun`,
+			input:     wed,
+			want:      false,
+		},
+		{
+			name:      "match weekday range spanning sunday",
+			condition: `match weekday fri-mon`,
+			input:     wed,
+			want:      false,
+		},
+		{
+			name:      "match weekday with timezone",
+			condition: `match weekday tuesday tz Pacific/Honolulu`,
+			input:     wed,
+			want:      true,
+		},
+		{
+			name:      "no match weekday",
+			condition: `no match weekday sat sun`,
+			input:     wed,
+			want:      true,
+		},
+		{
+			name:      "match date before",
+			condition: `match date before 2027-01-01`,
+			input:     wed,
+			want:      true,
+		},
+		{
+			name:      "match date before same day",
+			condition: `match date before 2026-06-10`,
+			input:     wed,
+			want:      false,
+		},
+		{
+			name:      "match date after",
+			condition: `match date after 2026-06-10`,
+			input:     wed,
+			want:      false,
+		},
+		{
+			name:      "match date between",
+			condition: `match date between 2026-06-01 2026-06-10`,
+			input:     wed,
+			want:      true,
+		},
+		{
+			name:      "match date with non-time input",
+			condition: `match date before 2027-01-01`,
+			input:     "2026-06-10",
+			want:      false,
+		},
+		{
+			name:      "match time with invalid value",
+			condition: `match time between 08:00 25:00`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs("time", "25:00", "match time between 08:00 25:00"),
+		},
+		{
+			name:      "match time with unsupported operator",
+			condition: `match time at 08:00`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxOperatorUnsupported.WithArgs("time", "at", "match time at 08:00"),
+		},
+		{
+			name:      "match time between without end",
+			condition: `match time between 08:00`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxOperatorUnsupported.WithArgs("time", "between", "match time between 08:00"),
+		},
+		{
+			name:      "match date between in reverse order",
+			condition: `match date between 2026-06-10 2026-06-01`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs("date", "2026-06-10 2026-06-01", "match date between 2026-06-10 2026-06-01"),
+		},
+		{
+			name:      "match weekday with invalid value",
+			condition: `match weekday mon-foo`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxValueInvalid.WithArgs("weekday", "mon-foo", "match weekday mon-foo"),
+		},
+		{
+			name:      "match weekday without value",
+			condition: `match weekday tz UTC`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxMatchValueNotFound.WithArgs("match weekday tz UTC"),
+		},
+		{
+			name:      "match time with invalid timezone",
+			condition: `match time after 08:00 tz Foo/Bar`,
+			shouldErr: true,
+			err:       errors.ErrACLRuleConditionSyntaxTimezoneInvalid.WithArgs("Foo/Bar", "unknown time zone Foo/Bar"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			cond, err := newACLRuleCondition(ctx, strings.Split(tc.condition, " "))
+			if tests.EvalErr(t, err, tc.condition, tc.shouldErr, tc.err) {
+				return
+			}
+			got := cond.match(ctx, tc.input)
+			tests.EvalObjects(t, "match", tc.want, got)
+		})
+	}
+}
+
+func TestAccessListWithClock(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+	ctx := context.Background()
+	accessList := NewAccessList()
+	if err := accessList.AddRules(ctx, []*RuleConfiguration{
+		{
+			Comment: "contractors during working hours",
+			Conditions: []string{
+				"match roles contractor",
+				"match time between 08:00 17:00",
+				"match weekday mon-fri",
+				"match date before 2027-01-01",
+			},
+			Action: `allow stop`,
+		},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		now  time.Time
+		want bool
+	}{
+		{now: time.Date(2026, 6, 10, 9, 0, 0, 0, time.UTC), want: true},
+		{now: time.Date(2026, 6, 10, 18, 0, 0, 0, time.UTC), want: false},
+		{now: time.Date(2026, 6, 13, 9, 0, 0, 0, time.UTC), want: false},
+		{now: time.Date(2027, 1, 4, 9, 0, 0, 0, time.UTC), want: false},
+	} {
+		timeNow = func() time.Time { return tc.now }
+		data := map[string]interface{}{
+			"roles": []string{"contractor"},
+			// The clock fields supplied by the caller are ignored.
+			"time": time.Date(2026, 6, 10, 9, 0, 0, 0, time.UTC),
+		}
+		got := accessList.Allow(ctx, data)
+		tests.EvalObjectsWithLog(t, "allow", tc.want, got, []string{tc.now.String()})
+		if _, exists := data["weekday"]; exists {
+			t.Fatalf("input data was modified: %v", data)
+		}
+	}
+}
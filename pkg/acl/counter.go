@@ -0,0 +1,46 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+// RuleCounter holds the number of times the conditions of a rule with
+// counter action matched and did not match the input.
+type RuleCounter struct {
+	Index   int    `json:"index,omitempty" xml:"index,omitempty" yaml:"index,omitempty"`
+	Tag     string `json:"tag,omitempty" xml:"tag,omitempty" yaml:"tag,omitempty"`
+	Comment string `json:"comment,omitempty" xml:"comment,omitempty" yaml:"comment,omitempty"`
+	Matches uint64 `json:"matches,omitempty" xml:"matches,omitempty" yaml:"matches,omitempty"`
+	Misses  uint64 `json:"misses,omitempty" xml:"misses,omitempty" yaml:"misses,omitempty"`
+}
+
+// GetCounters returns the counters of the rules with counter action. The
+// rules without the action are not included.
+func (acl *AccessList) GetCounters() []*RuleCounter {
+	var counters []*RuleCounter
+	for i, rule := range acl.rules {
+		rc, ok := rule.(aclRuleCounter)
+		if !ok {
+			continue
+		}
+		matches, misses := rc.getCounters()
+		counters = append(counters, &RuleCounter{
+			Index:   i,
+			Tag:     getRuleTag(acl.config[i].Action, i),
+			Comment: acl.config[i].Comment,
+			Matches: matches,
+			Misses:  misses,
+		})
+	}
+	return counters
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"testing"
+)
+
+func TestGetCounters(t *testing.T) {
+	var testcases = []struct {
+		name   string
+		config []*RuleConfiguration
+		inputs []map[string]interface{}
+		want   []*RuleCounter
+	}{
+		{
+			name: "count matches and misses of rules with counter action",
+			config: []*RuleConfiguration{
+				{
+					Comment:    "deny contractors",
+					Conditions: []string{"match roles contractor"},
+					Action:     `deny stop counter tag contractors`,
+				},
+				{
+					Conditions: []string{"match roles admin"},
+					Action:     `allow stop`,
+				},
+				{
+					Conditions: []string{"match roles admin user", "match org nyc"},
+					Action:     `allow counter`,
+				},
+			},
+			inputs: []map[string]interface{}{
+				{"roles": []string{"contractor"}},
+				{"roles": []string{"admin"}},
+				{"roles": []string{"user"}, "org": []string{"nyc"}},
+				{"roles": []string{"user"}, "org": []string{"sfo"}},
+			},
+			want: []*RuleCounter{
+				{Index: 0, Tag: "contractors", Comment: "deny contractors", Matches: 1, Misses: 3},
+				{Index: 2, Tag: "rule2", Matches: 1, Misses: 1},
+			},
+		},
+		{
+			name: "rules without counter action",
+			config: []*RuleConfiguration{
+				{
+					Conditions: []string{"match roles admin"},
+					Action:     `allow stop`,
+				},
+			},
+			inputs: []map[string]interface{}{
+				{"roles": []string{"admin"}},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			accessList := NewAccessList()
+			accessList.SetLogger(logutil.NewLogger())
+			if err := accessList.AddRules(ctx, tc.config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, input := range tc.inputs {
+				accessList.Allow(ctx, input)
+			}
+			tests.EvalObjects(t, "counters", tc.want, accessList.GetCounters())
+		})
+	}
+}
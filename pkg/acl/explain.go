@@ -0,0 +1,205 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+import (
+	"context"
+	cfgutil "github.com/greenpau/go-authcrunch/pkg/util/cfg"
+	"strconv"
+	"strings"
+)
+
+// Explanation is the result of the dry-run evaluation of AccessList. It
+// holds the trace of the evaluated rules and the final verdict.
+type Explanation struct {
+	Allowed bool         `json:"allowed,omitempty" xml:"allowed,omitempty" yaml:"allowed,omitempty"`
+	Verdict string       `json:"verdict,omitempty" xml:"verdict,omitempty" yaml:"verdict,omitempty"`
+	Rule    string       `json:"rule,omitempty" xml:"rule,omitempty" yaml:"rule,omitempty"`
+	Rules   []*RuleTrace `json:"rules,omitempty" xml:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// RuleTrace is the evaluation trace of an access list rule.
+type RuleTrace struct {
+	Index      int               `json:"index,omitempty" xml:"index,omitempty" yaml:"index,omitempty"`
+	Tag        string            `json:"tag,omitempty" xml:"tag,omitempty" yaml:"tag,omitempty"`
+	Comment    string            `json:"comment,omitempty" xml:"comment,omitempty" yaml:"comment,omitempty"`
+	Action     string            `json:"action,omitempty" xml:"action,omitempty" yaml:"action,omitempty"`
+	Matched    bool              `json:"matched,omitempty" xml:"matched,omitempty" yaml:"matched,omitempty"`
+	Verdict    string            `json:"verdict,omitempty" xml:"verdict,omitempty" yaml:"verdict,omitempty"`
+	Conditions []*ConditionTrace `json:"conditions,omitempty" xml:"conditions,omitempty" yaml:"conditions,omitempty"`
+}
+
+// ConditionTrace is the evaluation trace of a condition of an access list
+// rule.
+type ConditionTrace struct {
+	Condition string      `json:"condition,omitempty" xml:"condition,omitempty" yaml:"condition,omitempty"`
+	Field     string      `json:"field,omitempty" xml:"field,omitempty" yaml:"field,omitempty"`
+	Found     bool        `json:"found,omitempty" xml:"found,omitempty" yaml:"found,omitempty"`
+	Value     interface{} `json:"value,omitempty" xml:"value,omitempty" yaml:"value,omitempty"`
+	Matched   bool        `json:"matched,omitempty" xml:"matched,omitempty" yaml:"matched,omitempty"`
+}
+
+// Explain evaluates the input data the same way Allow does and returns the
+// trace of every evaluated rule, the result of each of the rule's
+// conditions, and the final verdict. Unlike Allow, it does not log rule
+// hits and misses, and does not increment rule counters.
+func (acl *AccessList) Explain(ctx context.Context, data map[string]interface{}) (*Explanation, error) {
+	data = acl.prepare(data)
+	e := &Explanation{}
+	var grantAccess bool
+	for i, rule := range acl.rules {
+		trace, err := acl.explainRule(ctx, i, rule, data)
+		if err != nil {
+			return nil, err
+		}
+		e.Rules = append(e.Rules, trace)
+		if !trace.Matched {
+			continue
+		}
+		switch trace.Verdict {
+		case "allow stop":
+			e.Allowed = true
+			e.Verdict = "allowed by rule"
+			e.Rule = trace.Tag
+			return e, nil
+		case "allow":
+			if !grantAccess {
+				e.Rule = trace.Tag
+			}
+			grantAccess = true
+		case "deny", "deny stop":
+			e.Verdict = "denied by rule"
+			e.Rule = trace.Tag
+			return e, nil
+		}
+	}
+	switch {
+	case grantAccess:
+		e.Allowed = true
+		e.Verdict = "allowed by rule"
+	case acl.defaultAllow:
+		e.Allowed = true
+		e.Verdict = "allowed by default"
+	default:
+		e.Verdict = "denied by default"
+	}
+	return e, nil
+}
+
+// explainRule evaluates a rule without side effects. The rule types in
+// rule.go are generated. Their names, e.g. aclRuleFieldCheckAllowMatchAnyStop,
+// describe how the rules evaluate their conditions.
+func (acl *AccessList) explainRule(ctx context.Context, i int, rule aclRule, data map[string]interface{}) (*RuleTrace, error) {
+	cfg := rule.getConfig(ctx)
+	ruleCfg, err := acl.expandRule(acl.config[i])
+	if err != nil {
+		return nil, err
+	}
+	trace := &RuleTrace{
+		Index:   i,
+		Tag:     cfg.tag,
+		Comment: acl.config[i].Comment,
+		Action:  acl.config[i].Action,
+		Verdict: "continue",
+	}
+	if trace.Tag == "" {
+		trace.Tag = getRuleTag(acl.config[i].Action, i)
+	}
+
+	var hits []bool
+	for j, c := range ruleCfg.Conditions {
+		tokens, err := cfgutil.DecodeArgs(c)
+		if err != nil {
+			return nil, err
+		}
+		cond, err := newACLRuleCondition(ctx, tokens)
+		if err != nil {
+			return nil, err
+		}
+		condTrace := &ConditionTrace{
+			Condition: acl.config[i].Conditions[j],
+			Field:     cfg.fields[j],
+		}
+		v, found := data[condTrace.Field]
+		condTrace.Found = found
+		if found {
+			condTrace.Value = v
+		}
+		hit := found && cond.match(ctx, v)
+		hits = append(hits, hit)
+		switch cond.getConfig(ctx).matchStrategy {
+		case fieldFound:
+			condTrace.Matched = found
+		case fieldNotFound:
+			condTrace.Matched = !found
+		default:
+			condTrace.Matched = hit
+		}
+		trace.Conditions = append(trace.Conditions, condTrace)
+	}
+
+	trace.Matched = evalRuleHits(cfg, data, hits)
+	if !trace.Matched {
+		return trace, nil
+	}
+	switch cfg.action {
+	case ruleActionAllow:
+		trace.Verdict = "allow"
+	case ruleActionDeny:
+		trace.Verdict = "deny"
+	}
+	if strings.HasSuffix(cfg.ruleType, "Stop") {
+		trace.Verdict += " stop"
+	}
+	return trace, nil
+}
+
+func evalRuleHits(cfg *ruleConfig, data map[string]interface{}, hits []bool) bool {
+	for fieldName, shouldExist := range cfg.checkFields {
+		if _, found := data[fieldName]; found != shouldExist {
+			return false
+		}
+	}
+	if len(cfg.checkFields) > 0 && len(hits) == 1 {
+		return true
+	}
+	if strings.Contains(cfg.ruleType, "MatchAny") {
+		for _, hit := range hits {
+			if hit {
+				return true
+			}
+		}
+		return false
+	}
+	for _, hit := range hits {
+		if !hit {
+			return false
+		}
+	}
+	return len(hits) > 0
+}
+
+// getRuleTag returns the tag of a rule, i.e. the value following the "tag"
+// keyword in the rule's action, or the default tag based on the index of
+// the rule.
+func getRuleTag(action string, i int) string {
+	tokens, _ := cfgutil.DecodeArgs(action)
+	for j, token := range tokens {
+		if token == "tag" && j+1 < len(tokens) {
+			return tokens[j+1]
+		}
+	}
+	return "rule" + strconv.Itoa(i)
+}
@@ -0,0 +1,204 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acl
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"testing"
+)
+
+func TestExplainAccessList(t *testing.T) {
+	var testcases = []struct {
+		name         string
+		config       []*RuleConfiguration
+		defaultAllow bool
+		input        map[string]interface{}
+		want         *Explanation
+	}{
+		{
+			name: "allow admin with stop",
+			config: []*RuleConfiguration{
+				{
+					Comment:    "deny contractors",
+					Conditions: []string{"match roles contractor"},
+					Action:     `deny stop`,
+				},
+				{
+					Comment:    "allow admins",
+					Conditions: []string{"match roles admin", "match org nyc"},
+					Action:     `allow stop tag admins`,
+				},
+				{
+					Conditions: []string{"match any"},
+					Action:     `allow`,
+				},
+			},
+			input: map[string]interface{}{
+				"roles": []string{"admin"},
+				"org":   []string{"nyc"},
+			},
+			want: &Explanation{
+				Allowed: true,
+				Verdict: "allowed by rule",
+				Rule:    "admins",
+				Rules: []*RuleTrace{
+					{
+						Index:   0,
+						Tag:     "rule0",
+						Comment: "deny contractors",
+						Action:  `deny stop`,
+						Verdict: "continue",
+						Conditions: []*ConditionTrace{
+							{Condition: "match roles contractor", Field: "roles", Found: true, Value: []string{"admin"}},
+						},
+					},
+					{
+						Index:   1,
+						Tag:     "admins",
+						Comment: "allow admins",
+						Action:  `allow stop tag admins`,
+						Matched: true,
+						Verdict: "allow stop",
+						Conditions: []*ConditionTrace{
+							{Condition: "match roles admin", Field: "roles", Found: true, Value: []string{"admin"}, Matched: true},
+							{Condition: "match org nyc", Field: "org", Found: true, Value: []string{"nyc"}, Matched: true},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "deny user with missing field",
+			config: []*RuleConfiguration{
+				{
+					Conditions: []string{"match roles admin", "match org nyc"},
+					Action:     `allow any log`,
+				},
+			},
+			input: map[string]interface{}{
+				"roles": []string{"user"},
+			},
+			want: &Explanation{
+				Verdict: "denied by default",
+				Rules: []*RuleTrace{
+					{
+						Tag:     "rule0",
+						Action:  `allow any log`,
+						Verdict: "continue",
+						Conditions: []*ConditionTrace{
+							{Condition: "match roles admin", Field: "roles", Found: true, Value: []string{"user"}},
+							{Condition: "match org nyc", Field: "org"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "allow with default allow",
+			config: []*RuleConfiguration{
+				{
+					Conditions: []string{"field org not exists", "match roles user"},
+					Action:     `deny`,
+				},
+			},
+			defaultAllow: true,
+			input: map[string]interface{}{
+				"roles": []string{"user"},
+				"org":   []string{"nyc"},
+			},
+			want: &Explanation{
+				Allowed: true,
+				Verdict: "allowed by default",
+				Rules: []*RuleTrace{
+					{
+						Tag:     "rule0",
+						Action:  `deny`,
+						Verdict: "continue",
+						Conditions: []*ConditionTrace{
+							{Condition: "field org not exists", Field: "org", Found: true, Value: []string{"nyc"}},
+							{Condition: "match roles user", Field: "roles", Found: true, Value: []string{"user"}, Matched: true},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "deny by rule with network list",
+			config: []*RuleConfiguration{
+				{
+					Conditions: []string{"match roles user"},
+					Action:     `allow`,
+				},
+				{
+					Conditions: []string{"match addr in @blocked"},
+					Action:     `deny`,
+				},
+			},
+			input: map[string]interface{}{
+				"roles": []string{"user"},
+				"addr":  "203.0.113.5",
+			},
+			want: &Explanation{
+				Verdict: "denied by rule",
+				Rule:    "rule1",
+				Rules: []*RuleTrace{
+					{
+						Tag:     "rule0",
+						Action:  `allow`,
+						Matched: true,
+						Verdict: "allow",
+						Conditions: []*ConditionTrace{
+							{Condition: "match roles user", Field: "roles", Found: true, Value: []string{"user"}, Matched: true},
+						},
+					},
+					{
+						Index:   1,
+						Tag:     "rule1",
+						Action:  `deny`,
+						Matched: true,
+						Verdict: "deny",
+						Conditions: []*ConditionTrace{
+							{Condition: "match addr in @blocked", Field: "addr", Found: true, Value: "203.0.113.5", Matched: true},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			accessList := NewAccessList()
+			accessList.SetLogger(logutil.NewLogger())
+			if tc.defaultAllow {
+				accessList.SetDefaultAllowAction()
+			}
+			if err := accessList.AddNetworkList("blocked", []string{"203.0.113.0/24"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := accessList.AddRules(ctx, tc.config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := accessList.Explain(ctx, tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjects(t, "explanation", tc.want, got)
+			tests.EvalObjects(t, "allow", accessList.Allow(ctx, tc.input), got.Allowed)
+		})
+	}
+}
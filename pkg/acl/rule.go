@@ -108,6 +108,11 @@ type aclRule interface {
 	emptyFields(context.Context)
 }
 
+// aclRuleCounter is implemented by the rules with counter action.
+type aclRuleCounter interface {
+	getCounters() (uint64, uint64)
+}
+
 type aclRuleAllowMatchAnyStop struct {
 	config     *ruleConfig
 	conditions []aclRuleCondition
@@ -4212,6 +4217,486 @@ func (rule *aclRuleFieldCheckDenyWithErrorLoggerCounter) emptyFields(ctx context
 	rule.field = ""
 }
 
+func (rule *aclRuleAllowWithCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithDebugLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithInfoLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithWarnLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithErrorLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithDebugLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithInfoLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithWarnLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithErrorLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithDebugLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithInfoLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithWarnLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithErrorLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithDebugLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithInfoLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithWarnLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithErrorLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithDebugLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithInfoLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithWarnLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithErrorLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithDebugLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithInfoLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithWarnLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleAllowWithErrorLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithDebugLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithInfoLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithWarnLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithErrorLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithDebugLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithInfoLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithWarnLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithErrorLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithDebugLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithInfoLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithWarnLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithErrorLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithDebugLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithInfoLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithWarnLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithErrorLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithDebugLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithInfoLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithWarnLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithErrorLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithDebugLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithInfoLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithWarnLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleDenyWithErrorLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithDebugLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithInfoLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithWarnLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithErrorLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithDebugLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithInfoLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithWarnLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithErrorLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithDebugLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithInfoLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithWarnLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithErrorLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithDebugLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithInfoLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithWarnLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithErrorLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithDebugLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithInfoLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithWarnLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithErrorLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithDebugLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithInfoLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithWarnLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckAllowWithErrorLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithDebugLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithInfoLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithWarnLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithErrorLoggerCounterMatchAnyStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithDebugLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithInfoLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithWarnLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithErrorLoggerCounterMatchAllStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithDebugLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithInfoLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithWarnLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithErrorLoggerCounterStop) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithDebugLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithInfoLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithWarnLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithErrorLoggerCounterMatchAny) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithDebugLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithInfoLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithWarnLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithErrorLoggerCounterMatchAll) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithDebugLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithInfoLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithWarnLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
+func (rule *aclRuleFieldCheckDenyWithErrorLoggerCounter) getCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&rule.counterMatch), atomic.LoadUint64(&rule.counterMiss)
+}
+
 func newACLRule(ctx context.Context, ruleID int, cfg *RuleConfiguration, logger *zap.Logger) (aclRule, error) {
 	var action, logLevel, tag string
 	var fieldCondFound bool
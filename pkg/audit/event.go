@@ -0,0 +1,114 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventType is the type of an audit event.
+type EventType string
+
+// Audit event types.
+const (
+	LoginSuccess         EventType = "login_success"
+	LoginFailure         EventType = "login_failure"
+	Logout               EventType = "logout"
+	MfaTokenAdded        EventType = "mfa_token_added"
+	MfaTokenRemoved      EventType = "mfa_token_removed"
+	PasswordChanged      EventType = "password_changed"
+	PasswordReset        EventType = "password_reset"
+	APIKeyCreated        EventType = "api_key_created"
+	APIKeyDeleted        EventType = "api_key_deleted"
+	RegistrationApproved EventType = "registration_approved"
+	RegistrationDeclined EventType = "registration_declined"
+	TokenRevoked         EventType = "token_revoked"
+	AdminAction          EventType = "admin_action"
+)
+
+var eventTypes = map[EventType]bool{
+	LoginSuccess:         true,
+	LoginFailure:         true,
+	Logout:               true,
+	MfaTokenAdded:        true,
+	MfaTokenRemoved:      true,
+	PasswordChanged:      true,
+	PasswordReset:        true,
+	APIKeyCreated:        true,
+	APIKeyDeleted:        true,
+	RegistrationApproved: true,
+	RegistrationDeclined: true,
+	TokenRevoked:         true,
+	AdminAction:          true,
+}
+
+// Event outcomes.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Event is a security-relevant event recorded in the audit log.
+type Event struct {
+	Timestamp     time.Time              `json:"timestamp,omitempty" xml:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+	Type          EventType              `json:"type,omitempty" xml:"type,omitempty" yaml:"type,omitempty"`
+	Outcome       string                 `json:"outcome,omitempty" xml:"outcome,omitempty" yaml:"outcome,omitempty"`
+	Portal        string                 `json:"portal,omitempty" xml:"portal,omitempty" yaml:"portal,omitempty"`
+	Realm         string                 `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+	UserID        string                 `json:"user_id,omitempty" xml:"user_id,omitempty" yaml:"user_id,omitempty"`
+	Username      string                 `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email         string                 `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	Actor         string                 `json:"actor,omitempty" xml:"actor,omitempty" yaml:"actor,omitempty"`
+	SourceAddress string                 `json:"source_address,omitempty" xml:"source_address,omitempty" yaml:"source_address,omitempty"`
+	RequestID     string                 `json:"request_id,omitempty" xml:"request_id,omitempty" yaml:"request_id,omitempty"`
+	SessionID     string                 `json:"session_id,omitempty" xml:"session_id,omitempty" yaml:"session_id,omitempty"`
+	Reason        string                 `json:"reason,omitempty" xml:"reason,omitempty" yaml:"reason,omitempty"`
+	Details       map[string]interface{} `json:"details,omitempty" xml:"details,omitempty" yaml:"details,omitempty"`
+}
+
+// NewEvent returns an instance of Event of the provided type.
+func NewEvent(t EventType, outcome string) *Event {
+	return &Event{
+		Timestamp: time.Now().UTC(),
+		Type:      t,
+		Outcome:   outcome,
+	}
+}
+
+// NewEventWithError returns an instance of Event of the provided type. The
+// outcome of the event is failure when the error is not nil.
+func NewEventWithError(t EventType, err error) *Event {
+	if err != nil {
+		ev := NewEvent(t, OutcomeFailure)
+		ev.Reason = err.Error()
+		return ev
+	}
+	return NewEvent(t, OutcomeSuccess)
+}
+
+// WithDetail adds a key-value pair to the details of the event.
+func (ev *Event) WithDetail(k string, v interface{}) *Event {
+	if ev.Details == nil {
+		ev.Details = make(map[string]interface{})
+	}
+	ev.Details[k] = v
+	return ev
+}
+
+// Encode returns JSON representation of the event.
+func (ev *Event) Encode() ([]byte, error) {
+	return json.Marshal(ev)
+}
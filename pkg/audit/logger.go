@@ -0,0 +1,104 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Logger writes audit events to one or more sinks.
+type Logger struct {
+	sinks  []*sinkEntry
+	logger *zap.Logger
+}
+
+type sinkEntry struct {
+	kind   string
+	events map[EventType]bool
+	sink   Sink
+}
+
+// NewLogger returns an instance of Logger based on the provided
+// configuration. The zap logger receives the errors the sinks encounter.
+func NewLogger(cfg *Config, logger *zap.Logger) (*Logger, error) {
+	if cfg == nil {
+		return nil, errors.ErrAuditConfigNil
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	l := &Logger{logger: logger}
+	for _, sinkCfg := range cfg.Sinks {
+		sink, err := NewSink(sinkCfg, logger)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		entry := &sinkEntry{kind: sinkCfg.Kind, sink: sink}
+		if len(sinkCfg.Events) > 0 {
+			entry.events = make(map[EventType]bool)
+			for _, t := range sinkCfg.Events {
+				entry.events[EventType(t)] = true
+			}
+		}
+		l.sinks = append(l.sinks, entry)
+	}
+	return l, nil
+}
+
+// AddSink adds a sink receiving all events.
+func (l *Logger) AddSink(kind string, sink Sink) {
+	l.sinks = append(l.sinks, &sinkEntry{kind: kind, sink: sink})
+}
+
+// Log writes the event to the sinks. The failures to write the event are
+// reported to the zap logger and do not interrupt the caller.
+func (l *Logger) Log(ev *Event) {
+	if l == nil || ev == nil {
+		return
+	}
+	for _, entry := range l.sinks {
+		if entry.events != nil && !entry.events[ev.Type] {
+			continue
+		}
+		if err := entry.sink.Write(ev); err != nil {
+			l.logger.Error(
+				"failed writing audit event",
+				zap.String("sink", entry.kind),
+				zap.String("event_type", string(ev.Type)),
+				zap.String("request_id", ev.RequestID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// Close closes the sinks.
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	var firstErr error
+	for _, entry := range l.sinks {
+		if err := entry.sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
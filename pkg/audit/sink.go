@@ -0,0 +1,180 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultFileMaxSize      = 100
+	defaultFileMaxBackups   = 5
+	defaultSyslogFacility   = "authpriv"
+	defaultSyslogTag        = "authcrunch"
+	defaultWebhookTimeout   = 10
+	defaultWebhookQueueSize = 1000
+)
+
+// syslogFacilities maps the names of syslog facilities to their codes.
+var syslogFacilities = map[string]int{
+	"user":     1 << 3,
+	"daemon":   3 << 3,
+	"auth":     4 << 3,
+	"authpriv": 10 << 3,
+	"local0":   16 << 3,
+	"local1":   17 << 3,
+	"local2":   18 << 3,
+	"local3":   19 << 3,
+	"local4":   20 << 3,
+	"local5":   21 << 3,
+	"local6":   22 << 3,
+	"local7":   23 << 3,
+}
+
+// Sink is a destination of audit events.
+type Sink interface {
+	// Write records the event.
+	Write(ev *Event) error
+	// Close flushes pending events and releases the resources held by
+	// the sink.
+	Close() error
+}
+
+// Config is the configuration of audit log.
+type Config struct {
+	Sinks []*SinkConfig `json:"sinks,omitempty" xml:"sinks,omitempty" yaml:"sinks,omitempty"`
+}
+
+// SinkConfig is the configuration of Sink.
+type SinkConfig struct {
+	// Kind is the type of the sink, i.e. file, syslog or webhook.
+	Kind string `json:"kind,omitempty" xml:"kind,omitempty" yaml:"kind,omitempty"`
+	// Events is the list of event types written to the sink. When empty,
+	// all events are written.
+	Events []string `json:"events,omitempty" xml:"events,omitempty" yaml:"events,omitempty"`
+	// Path is the path to the JSON lines file of the file sink.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// MaxSize is the size (in megabytes) at which the file is rotated.
+	MaxSize int `json:"max_size,omitempty" xml:"max_size,omitempty" yaml:"max_size,omitempty"`
+	// MaxBackups is the number of rotated files to keep.
+	MaxBackups int `json:"max_backups,omitempty" xml:"max_backups,omitempty" yaml:"max_backups,omitempty"`
+	// Network is the network of the syslog server, i.e. udp or tcp. When
+	// empty, the sink connects to the local syslog server.
+	Network string `json:"network,omitempty" xml:"network,omitempty" yaml:"network,omitempty"`
+	// Address is the host:port of the syslog server.
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	// Facility is the syslog facility. The default is "authpriv".
+	Facility string `json:"facility,omitempty" xml:"facility,omitempty" yaml:"facility,omitempty"`
+	// Tag is the syslog tag. The default is "authcrunch".
+	Tag string `json:"tag,omitempty" xml:"tag,omitempty" yaml:"tag,omitempty"`
+	// URL is the address the webhook sink posts events to.
+	URL string `json:"url,omitempty" xml:"url,omitempty" yaml:"url,omitempty"`
+	// Headers are the HTTP headers added to webhook requests.
+	Headers map[string]string `json:"headers,omitempty" xml:"headers,omitempty" yaml:"headers,omitempty"`
+	// Timeout is the timeout (in seconds) of webhook requests.
+	Timeout int `json:"timeout,omitempty" xml:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// QueueSize is the number of events the webhook sink buffers.
+	QueueSize int `json:"queue_size,omitempty" xml:"queue_size,omitempty" yaml:"queue_size,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	for _, sinkCfg := range cfg.Sinks {
+		if sinkCfg == nil {
+			return errors.ErrAuditSinkConfigNil
+		}
+		if err := sinkCfg.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate validates SinkConfig.
+func (cfg *SinkConfig) Validate() error {
+	for _, t := range cfg.Events {
+		if !eventTypes[EventType(t)] {
+			return errors.ErrAuditEventTypeUnsupported.WithArgs(t)
+		}
+	}
+	switch cfg.Kind {
+	case "file":
+		if cfg.Path == "" {
+			return errors.ErrAuditSinkPathEmpty
+		}
+		if cfg.MaxSize == 0 {
+			cfg.MaxSize = defaultFileMaxSize
+		}
+		if cfg.MaxBackups == 0 {
+			cfg.MaxBackups = defaultFileMaxBackups
+		}
+	case "syslog":
+		switch cfg.Network {
+		case "", "udp", "tcp", "unix", "unixgram":
+		default:
+			return errors.ErrAuditSinkNetworkInvalid.WithArgs(cfg.Network)
+		}
+		if cfg.Facility == "" {
+			cfg.Facility = defaultSyslogFacility
+		}
+		cfg.Facility = strings.ToLower(cfg.Facility)
+		if _, found := syslogFacilities[cfg.Facility]; !found {
+			return errors.ErrAuditSinkFacilityInvalid.WithArgs(cfg.Facility)
+		}
+		if cfg.Tag == "" {
+			cfg.Tag = defaultSyslogTag
+		}
+	case "webhook":
+		if cfg.URL == "" {
+			return errors.ErrAuditSinkURLEmpty
+		}
+		u, err := url.Parse(cfg.URL)
+		if err != nil {
+			return errors.ErrAuditSinkURLInvalid.WithArgs(cfg.URL, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.ErrAuditSinkURLInvalid.WithArgs(cfg.URL, "unsupported scheme")
+		}
+		if cfg.Timeout == 0 {
+			cfg.Timeout = defaultWebhookTimeout
+		}
+		if cfg.QueueSize == 0 {
+			cfg.QueueSize = defaultWebhookQueueSize
+		}
+	default:
+		return errors.ErrAuditSinkKindUnsupported.WithArgs(cfg.Kind)
+	}
+	return nil
+}
+
+// NewSink returns an instance of Sink based on the provided configuration.
+func NewSink(cfg *SinkConfig, logger *zap.Logger) (Sink, error) {
+	if cfg == nil {
+		return nil, errors.ErrAuditSinkConfigNil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Kind {
+	case "syslog":
+		return NewSyslogSink(cfg.Network, cfg.Address, cfg.Facility, cfg.Tag)
+	case "webhook":
+		return NewWebhookSink(cfg.URL, cfg.Headers, cfg.Timeout, cfg.QueueSize, logger), nil
+	}
+	return NewFileSink(cfg.Path, cfg.MaxSize, cfg.MaxBackups)
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileSink writes audit events to a file as JSON lines. When the file
+// exceeds its maximum size, it is rotated to <path>.1, the previous
+// <path>.1 to <path>.2, etc.
+type FileSink struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewFileSink returns an instance of FileSink writing to the file at the
+// path. The file is rotated when it reaches maxSize megabytes, and
+// maxBackups rotated files are kept.
+func NewFileSink(fp string, maxSize, maxBackups int) (*FileSink, error) {
+	s := &FileSink{
+		path:       fp,
+		maxSize:    int64(maxSize) * 1024 * 1024,
+		maxBackups: maxBackups,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return errors.ErrAuditSinkOpen.WithArgs("file", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.ErrAuditSinkOpen.WithArgs("file", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.ErrAuditSinkOpen.WithArgs("file", err)
+	}
+	s.file = f
+	s.size = fi.Size()
+	return nil
+}
+
+// rotate closes the current file, shifts the backups and opens a new file.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return errors.ErrAuditSinkRotate.WithArgs(s.path, err)
+	}
+	s.file = nil
+	if s.maxBackups < 1 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return errors.ErrAuditSinkRotate.WithArgs(s.path, err)
+		}
+		return s.open()
+	}
+	for i := s.maxBackups - 1; i > 0; i-- {
+		src := fmt.Sprintf("%s.%d", s.path, i)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := os.Rename(src, fmt.Sprintf("%s.%d", s.path, i+1)); err != nil {
+			return errors.ErrAuditSinkRotate.WithArgs(s.path, err)
+		}
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return errors.ErrAuditSinkRotate.WithArgs(s.path, err)
+	}
+	return s.open()
+}
+
+// Write appends the event to the file.
+func (s *FileSink) Write(ev *Event) error {
+	b, err := ev.Encode()
+	if err != nil {
+		return errors.ErrAuditEventEncode.WithArgs(err)
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return errors.ErrAuditSinkClosed.WithArgs("file")
+	}
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(b)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(b)
+	s.size += int64(n)
+	if err != nil {
+		return errors.ErrAuditSinkWrite.WithArgs("file", err)
+	}
+	return nil
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
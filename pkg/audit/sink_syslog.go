@@ -0,0 +1,62 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package audit
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"log/syslog"
+)
+
+// SyslogSink writes audit events to syslog as JSON messages.
+type SyslogSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogSink returns an instance of SyslogSink. When the network and
+// address are empty, it connects to the local syslog server.
+func NewSyslogSink(network, addr, facility, tag string) (*SyslogSink, error) {
+	priority := syslog.Priority(syslogFacilities[facility]) | syslog.LOG_INFO
+	w, err := syslog.Dial(network, addr, priority, tag)
+	if err != nil {
+		return nil, errors.ErrAuditSinkOpen.WithArgs("syslog", err)
+	}
+	return &SyslogSink{writer: w}, nil
+}
+
+// Write sends the event to syslog. Failed events are logged with warning
+// severity.
+func (s *SyslogSink) Write(ev *Event) error {
+	b, err := ev.Encode()
+	if err != nil {
+		return errors.ErrAuditEventEncode.WithArgs(err)
+	}
+	if ev.Outcome == OutcomeFailure {
+		err = s.writer.Warning(string(b))
+	} else {
+		err = s.writer.Info(string(b))
+	}
+	if err != nil {
+		return errors.ErrAuditSinkWrite.WithArgs("syslog", err)
+	}
+	return nil
+}
+
+// Close closes the connection to syslog.
+func (s *SyslogSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows || plan9
+// +build windows plan9
+
+package audit
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+// SyslogSink is not available on this platform.
+type SyslogSink struct{}
+
+// NewSyslogSink returns an error, because syslog is not available on this
+// platform.
+func NewSyslogSink(network, addr, facility, tag string) (*SyslogSink, error) {
+	return nil, errors.ErrAuditSinkSyslogUnsupported
+}
+
+// Write is a no-op.
+func (s *SyslogSink) Write(ev *Event) error {
+	return errors.ErrAuditSinkSyslogUnsupported
+}
+
+// Close is a no-op.
+func (s *SyslogSink) Close() error {
+	return nil
+}
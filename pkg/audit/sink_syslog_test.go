@@ -0,0 +1,59 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package audit
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed starting syslog listener: %v", err)
+	}
+	defer conn.Close()
+
+	s, err := NewSyslogSink("udp", conn.LocalAddr().String(), "authpriv", "authcrunch")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	ev := NewEvent(LoginFailure, OutcomeFailure)
+	ev.Username = "jsmith"
+	if err := s.Write(ev); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed reading syslog message: %v", err)
+	}
+	msg := string(buf[:n])
+	// The priority of authpriv.warning is 10*8+4.
+	for _, want := range []string{"<84>", "authcrunch", `"type":"login_failure"`, `"username":"jsmith"`} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("syslog message %q does not contain %q", msg, want)
+		}
+	}
+}
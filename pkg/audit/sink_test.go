@@ -0,0 +1,247 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func readEvents(t *testing.T, fp string) []*Event {
+	f, err := os.Open(fp)
+	if err != nil {
+		t.Fatalf("failed opening %s: %v", fp, err)
+	}
+	defer f.Close()
+	var events []*Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		ev := &Event{}
+		if err := json.Unmarshal(scanner.Bytes(), ev); err != nil {
+			t.Fatalf("failed decoding %q: %v", scanner.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestSinkConfigValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *SinkConfig
+		want      *SinkConfig
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "file sink with defaults",
+			config: &SinkConfig{Kind: "file", Path: "/var/log/audit.log"},
+			want:   &SinkConfig{Kind: "file", Path: "/var/log/audit.log", MaxSize: 100, MaxBackups: 5},
+		},
+		{
+			name:      "file sink without path",
+			config:    &SinkConfig{Kind: "file"},
+			shouldErr: true,
+			err:       errors.ErrAuditSinkPathEmpty,
+		},
+		{
+			name:   "syslog sink with defaults",
+			config: &SinkConfig{Kind: "syslog"},
+			want:   &SinkConfig{Kind: "syslog", Facility: "authpriv", Tag: "authcrunch"},
+		},
+		{
+			name:      "syslog sink with invalid facility",
+			config:    &SinkConfig{Kind: "syslog", Facility: "foo"},
+			shouldErr: true,
+			err:       errors.ErrAuditSinkFacilityInvalid.WithArgs("foo"),
+		},
+		{
+			name:      "syslog sink with invalid network",
+			config:    &SinkConfig{Kind: "syslog", Network: "foo"},
+			shouldErr: true,
+			err:       errors.ErrAuditSinkNetworkInvalid.WithArgs("foo"),
+		},
+		{
+			name:   "webhook sink with defaults",
+			config: &SinkConfig{Kind: "webhook", URL: "https://localhost/audit"},
+			want:   &SinkConfig{Kind: "webhook", URL: "https://localhost/audit", Timeout: 10, QueueSize: 1000},
+		},
+		{
+			name:      "webhook sink with unsupported url scheme",
+			config:    &SinkConfig{Kind: "webhook", URL: "ftp://localhost/audit"},
+			shouldErr: true,
+			err:       errors.ErrAuditSinkURLInvalid.WithArgs("ftp://localhost/audit", "unsupported scheme"),
+		},
+		{
+			name:      "webhook sink without url",
+			config:    &SinkConfig{Kind: "webhook"},
+			shouldErr: true,
+			err:       errors.ErrAuditSinkURLEmpty,
+		},
+		{
+			name:      "sink with unsupported event type",
+			config:    &SinkConfig{Kind: "file", Path: "audit.log", Events: []string{"foo"}},
+			shouldErr: true,
+			err:       errors.ErrAuditEventTypeUnsupported.WithArgs("foo"),
+		},
+		{
+			name:      "sink with unsupported kind",
+			config:    &SinkConfig{Kind: "foo"},
+			shouldErr: true,
+			err:       errors.ErrAuditSinkKindUnsupported.WithArgs("foo"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, []string{}) {
+				return
+			}
+			tests.EvalObjects(t, "config", tc.want, tc.config)
+		})
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	tmpDir, err := tests.TempDir("TestAuditFileSink")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	fp := filepath.Join(tmpDir, "audit", "audit.log")
+	s, err := NewFileSink(fp, 1, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	newEvent := func(i int) *Event {
+		ev := NewEvent(LoginSuccess, OutcomeSuccess)
+		ev.Timestamp = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		ev.RequestID = fmt.Sprintf("%d", i)
+		return ev
+	}
+	// Rotate after every two events.
+	b, _ := newEvent(0).Encode()
+	s.maxSize = int64(len(b)+1) * 2
+
+	for i := 0; i < 7; i++ {
+		ev := newEvent(i)
+		if err := s.Write(ev); err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	err = s.Write(NewEvent(LoginSuccess, OutcomeSuccess))
+	tests.EvalErr(t, err, "write after close", true, errors.ErrAuditSinkClosed.WithArgs("file"))
+
+	for fileName, want := range map[string][]string{
+		fp:        {"6"},
+		fp + ".1": {"4", "5"},
+		fp + ".2": {"2", "3"},
+	} {
+		var got []string
+		for _, ev := range readEvents(t, fileName) {
+			got = append(got, ev.RequestID)
+		}
+		tests.EvalObjectsWithLog(t, "request ids", want, got, []string{fileName})
+	}
+	if _, err := os.Stat(fp + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected %s.3 to be removed", fp)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var mu sync.Mutex
+	var got []*Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer foobar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ev := &Event{}
+		if err := json.NewDecoder(r.Body).Decode(ev); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		got = append(got, ev)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	s := NewWebhookSink(srv.URL, map[string]string{"Authorization": "Bearer foobar"}, 5, 10, nil)
+	for _, t := range []EventType{LoginFailure, PasswordChanged, TokenRevoked} {
+		s.Write(NewEvent(t, OutcomeSuccess))
+	}
+	// Close waits for the queued events to be delivered.
+	s.Close()
+	err := s.Write(NewEvent(LoginSuccess, OutcomeSuccess))
+	tests.EvalErr(t, err, "write after close", true, errors.ErrAuditSinkClosed.WithArgs("webhook"))
+
+	var gotTypes []EventType
+	for _, ev := range got {
+		gotTypes = append(gotTypes, ev.Type)
+	}
+	tests.EvalObjects(t, "event types", []EventType{LoginFailure, PasswordChanged, TokenRevoked}, gotTypes)
+}
+
+func TestLogger(t *testing.T) {
+	tmpDir, err := tests.TempDir("TestAuditLogger")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	allPath := filepath.Join(tmpDir, "all.log")
+	failurePath := filepath.Join(tmpDir, "failure.log")
+	l, err := NewLogger(&Config{
+		Sinks: []*SinkConfig{
+			{Kind: "file", Path: allPath},
+			{Kind: "file", Path: failurePath, Events: []string{"login_failure"}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Log(NewEventWithError(LoginSuccess, nil))
+	l.Log(NewEventWithError(LoginFailure, fmt.Errorf("user not found")))
+	l.Log(NewEvent(MfaTokenAdded, OutcomeSuccess).WithDetail("token_type", "totp"))
+	l.Close()
+
+	var gotTypes []EventType
+	for _, ev := range readEvents(t, allPath) {
+		gotTypes = append(gotTypes, ev.Type)
+	}
+	tests.EvalObjects(t, "all events", []EventType{LoginSuccess, LoginFailure, MfaTokenAdded}, gotTypes)
+
+	failures := readEvents(t, failurePath)
+	if len(failures) != 1 {
+		t.Fatalf("expected 1 failure event, got %d", len(failures))
+	}
+	tests.EvalObjects(t, "failure outcome", OutcomeFailure, failures[0].Outcome)
+	tests.EvalObjects(t, "failure reason", "user not found", failures[0].Reason)
+
+	_, err = NewLogger(nil, nil)
+	tests.EvalErr(t, err, "nil config", true, errors.ErrAuditConfigNil)
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WebhookSink posts audit events as JSON to a URL. The events are queued
+// and delivered in the background so that slow receivers do not delay
+// the requests generating the events.
+type WebhookSink struct {
+	mu      sync.RWMutex
+	url     string
+	headers map[string]string
+	client  *http.Client
+	queue   chan *Event
+	done    chan struct{}
+	closed  bool
+	logger  *zap.Logger
+}
+
+// NewWebhookSink returns an instance of WebhookSink.
+func NewWebhookSink(u string, headers map[string]string, timeout, queueSize int, logger *zap.Logger) *WebhookSink {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	s := &WebhookSink{
+		url:     u,
+		headers: headers,
+		client: &http.Client{
+			Timeout: time.Duration(timeout) * time.Second,
+		},
+		queue:  make(chan *Event, queueSize),
+		done:   make(chan struct{}),
+		logger: logger,
+	}
+	go s.run()
+	return s
+}
+
+func (s *WebhookSink) run() {
+	defer close(s.done)
+	for ev := range s.queue {
+		if err := s.post(ev); err != nil {
+			s.logger.Warn(
+				"failed delivering audit event",
+				zap.String("event_type", string(ev.Type)),
+				zap.String("request_id", ev.RequestID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+func (s *WebhookSink) post(ev *Event) error {
+	b, err := ev.Encode()
+	if err != nil {
+		return errors.ErrAuditEventEncode.WithArgs(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return errors.ErrAuditSinkWrite.WithArgs("webhook", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.ErrAuditSinkWrite.WithArgs("webhook", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.ErrAuditWebhookResponse.WithArgs(s.url, resp.StatusCode)
+	}
+	return nil
+}
+
+// Write queues the event for delivery. It returns an error when the queue
+// is full.
+func (s *WebhookSink) Write(ev *Event) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return errors.ErrAuditSinkClosed.WithArgs("webhook")
+	}
+	select {
+	case s.queue <- ev:
+	default:
+		return errors.ErrAuditSinkQueueFull.WithArgs("webhook")
+	}
+	return nil
+}
+
+// Close stops accepting events and waits for the queued events to be
+// delivered.
+func (s *WebhookSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
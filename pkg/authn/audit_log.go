@@ -0,0 +1,82 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/audit"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/shared/idp"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"net/http"
+)
+
+// logAuditEvent writes the event to the audit log, when the log is
+// configured. The attributes of the event not set by the caller are
+// populated from the HTTP request, the portal request and the user
+// performing the action.
+func (p *Portal) logAuditEvent(r *http.Request, rr *requests.Request, usr *user.User, ev *audit.Event) {
+	if p.audit == nil || ev == nil {
+		return
+	}
+	ev.Portal = p.config.Name
+	if r != nil && ev.SourceAddress == "" {
+		ev.SourceAddress = addrutil.GetSourceAddress(r)
+	}
+	if rr != nil {
+		if ev.RequestID == "" {
+			ev.RequestID = rr.ID
+		}
+		if ev.SessionID == "" {
+			ev.SessionID = rr.Upstream.SessionID
+		}
+		if ev.Realm == "" {
+			ev.Realm = rr.Upstream.Realm
+		}
+		if ev.Username == "" {
+			ev.Username = rr.User.Username
+		}
+		if ev.Email == "" {
+			ev.Email = rr.User.Email
+		}
+	}
+	if usr != nil {
+		if ev.Realm == "" {
+			ev.Realm = usr.Authenticator.Realm
+		}
+		if ev.Actor == "" && usr.Claims != nil {
+			ev.Actor = usr.Claims.Subject
+			if usr.Claims.Email != "" {
+				ev.Actor = usr.Claims.Email
+			}
+		}
+	}
+	p.audit.Log(ev)
+}
+
+// logProviderAuditEvent writes the outcome of the authentication requested
+// by an identity provider, i.e. basic and api key authentication, to the
+// audit log.
+func (p *Portal) logProviderAuditEvent(r *idp.ProviderRequest, rr *requests.Request, method string, err error) {
+	ev := audit.NewEvent(audit.LoginSuccess, audit.OutcomeSuccess)
+	if err != nil {
+		ev = audit.NewEvent(audit.LoginFailure, audit.OutcomeFailure)
+		ev.Reason = err.Error()
+	}
+	ev.SourceAddress = r.Address
+	ev.Realm = r.Realm
+	ev.WithDetail("method", method)
+	p.logAuditEvent(nil, rr, nil, ev)
+}
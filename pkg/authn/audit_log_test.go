@@ -0,0 +1,279 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends/local"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/shared/idp"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestAuditLog")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	auditPath := filepath.Join(filepath.Dir(db.GetPath()), "audit.log")
+	cfg := &PortalConfig{
+		Name: "myportal",
+		BackendConfigs: []backends.Config{
+			{
+				Local: &local.Config{
+					Name:   "local_backend",
+					Method: "local",
+					Realm:  "auditlog",
+					Path:   db.GetPath(),
+				},
+			},
+		},
+		AuditConfig: &audit.Config{
+			Sinks: []*audit.SinkConfig{
+				{Kind: "file", Path: auditPath},
+			},
+		},
+	}
+	portal, err := NewPortal(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("DELETE", "/api/users/jsmith/tokens", nil)
+	r.RemoteAddr = "10.0.2.2:51234"
+	rr := requests.NewRequest()
+	rr.ID = "foobar"
+	admin := &user.User{
+		Claims:        &user.Claims{ID: "abcd", Subject: "admin", Email: "admin@localhost", ExpiresAt: 4102444800},
+		Authenticator: user.Authenticator{Realm: "auditlog"},
+	}
+	portal.revokeUserToken(r, rr, admin, "logout")
+	if err := portal.revokeSubjectTokens(r, rr, "jsmith", "revoked by admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	portal.Close()
+
+	f, err := os.Open(auditPath)
+	if err != nil {
+		t.Fatalf("failed opening audit log: %v", err)
+	}
+	defer f.Close()
+	var got []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		ev := &audit.Event{}
+		if err := json.Unmarshal(scanner.Bytes(), ev); err != nil {
+			t.Fatalf("failed decoding audit event: %v", err)
+		}
+		got = append(got, map[string]interface{}{
+			"type":           ev.Type,
+			"portal":         ev.Portal,
+			"realm":          ev.Realm,
+			"username":       ev.Username,
+			"actor":          ev.Actor,
+			"source_address": ev.SourceAddress,
+			"request_id":     ev.RequestID,
+			"reason":         ev.Reason,
+		})
+	}
+
+	tests.EvalObjects(t, "events", []map[string]interface{}{
+		{
+			"type":           audit.Logout,
+			"portal":         "myportal",
+			"realm":          "auditlog",
+			"username":       "admin",
+			"actor":          "admin@localhost",
+			"source_address": "10.0.2.2",
+			"request_id":     "foobar",
+			"reason":         "logout",
+		},
+		{
+			"type":           audit.TokenRevoked,
+			"portal":         "myportal",
+			"realm":          "",
+			"username":       "jsmith",
+			"actor":          "",
+			"source_address": "10.0.2.2",
+			"request_id":     "foobar",
+			"reason":         "revoked by admin",
+		},
+	}, got)
+}
+
+func TestAuditLogProviderAuth(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestAuditLogProviderAuth")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	auditPath := filepath.Join(filepath.Dir(db.GetPath()), "audit.log")
+	cfg := &PortalConfig{
+		Name: "myportal",
+		BackendConfigs: []backends.Config{
+			{
+				Local: &local.Config{
+					Name:   "local_backend",
+					Method: "local",
+					Realm:  "auditprovider",
+					Path:   db.GetPath(),
+				},
+			},
+		},
+		AuditConfig: &audit.Config{
+			Sinks: []*audit.SinkConfig{
+				{Kind: "file", Path: auditPath},
+			},
+		},
+	}
+	portal, err := NewPortal(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, password := range []string{tests.TestPwd1, "foobar"} {
+		portal.BasicAuth(&idp.ProviderRequest{
+			Address: "10.0.2.2",
+			Realm:   "auditprovider",
+			Secret:  base64.StdEncoding.EncodeToString([]byte(tests.TestUser1 + ":" + password)),
+		})
+	}
+	portal.APIKeyAuth(&idp.ProviderRequest{
+		Address: "10.0.2.3",
+		Realm:   "auditprovider",
+		Secret:  "foobar",
+	})
+	portal.Close()
+
+	f, err := os.Open(auditPath)
+	if err != nil {
+		t.Fatalf("failed opening audit log: %v", err)
+	}
+	defer f.Close()
+	var got []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		ev := &audit.Event{}
+		if err := json.Unmarshal(scanner.Bytes(), ev); err != nil {
+			t.Fatalf("failed decoding audit event: %v", err)
+		}
+		got = append(got, map[string]interface{}{
+			"type":           ev.Type,
+			"realm":          ev.Realm,
+			"username":       ev.Username,
+			"source_address": ev.SourceAddress,
+			"method":         ev.Details["method"],
+		})
+	}
+
+	tests.EvalObjects(t, "events", []map[string]interface{}{
+		{
+			"type":           audit.LoginSuccess,
+			"realm":          "auditprovider",
+			"username":       tests.TestUser1,
+			"source_address": "10.0.2.2",
+			"method":         "basicauth",
+		},
+		{
+			"type":           audit.LoginFailure,
+			"realm":          "auditprovider",
+			"username":       tests.TestUser1,
+			"source_address": "10.0.2.2",
+			"method":         "basicauth",
+		},
+		{
+			"type":           audit.LoginFailure,
+			"realm":          "auditprovider",
+			"username":       "",
+			"source_address": "10.0.2.3",
+			"method":         "apikey",
+		},
+	}, got)
+}
+
+func TestAuditLogClosedOnPortalReplace(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestAuditLogClosedOnPortalReplace")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	auditPath := filepath.Join(filepath.Dir(db.GetPath()), "audit.log")
+	newPortal := func() *Portal {
+		cfg := &PortalConfig{
+			Name: "auditreplaceportal",
+			BackendConfigs: []backends.Config{
+				{
+					Local: &local.Config{
+						Name:   "local_backend",
+						Method: "local",
+						Realm:  "auditreplace",
+						Path:   db.GetPath(),
+					},
+				},
+			},
+			AuditConfig: &audit.Config{
+				Sinks: []*audit.SinkConfig{
+					{Kind: "file", Path: auditPath},
+				},
+			},
+		}
+		portal, err := NewPortal(cfg, logutil.NewLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := portal.Register(); err != nil {
+			t.Fatal(err)
+		}
+		return portal
+	}
+
+	oldPortal := newPortal()
+	portal := newPortal()
+	defer portalRegistry.UnregisterPortal("auditreplaceportal")
+
+	// The replaced portal no longer writes to the audit log.
+	ev := audit.NewEvent(audit.Logout, audit.OutcomeSuccess)
+	ev.Username = "old"
+	oldPortal.audit.Log(ev)
+	ev = audit.NewEvent(audit.Logout, audit.OutcomeSuccess)
+	ev.Username = "new"
+	portal.audit.Log(ev)
+	portal.Close()
+
+	b, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("failed reading audit log: %v", err)
+	}
+	var got []string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		ev := &audit.Event{}
+		if err := json.Unmarshal(scanner.Bytes(), ev); err != nil {
+			t.Fatalf("failed decoding audit event: %v", err)
+		}
+		got = append(got, ev.Username)
+	}
+	tests.EvalObjects(t, "usernames", []string{"new"}, got)
+}
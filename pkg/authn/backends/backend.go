@@ -26,6 +26,7 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"go.uber.org/zap"
+	"time"
 )
 
 // AuthMethodType identifies authentication provider type.
@@ -98,7 +99,10 @@ func (b *Backend) Configure() error {
 
 // Request performs the requested backend operation.
 func (b *Backend) Request(op operator.Type, r *requests.Request) error {
-	return b.driver.Request(op, r)
+	start := time.Now()
+	err := b.driver.Request(op, r)
+	requestDuration.Observe(time.Since(start).Seconds(), b.GetRealm(), b.GetName(), b.GetMethod(), op.String())
+	return err
 }
 
 // Validate checks whether an authentication provider is functional.
@@ -41,13 +41,16 @@ func NewAuthenticator() *Authenticator {
 	return &Authenticator{}
 }
 
-// Configure check database connectivity and required tables.
-func (sa *Authenticator) Configure(fp string) error {
+// Configure check database connectivity and required tables. When the
+// backup count is greater than zero, the database keeps the backups of
+// its previous revisions.
+func (sa *Authenticator) Configure(fp string, backupCount int) error {
 	sa.mux.Lock()
 	defer sa.mux.Unlock()
 	sa.logger.Info(
 		"local backend configuration",
 		zap.String("db_path", fp),
+		zap.Int("backup_count", backupCount),
 	)
 	sa.path = fp
 
@@ -55,6 +58,10 @@ func (sa *Authenticator) Configure(fp string) error {
 	if err != nil {
 		return err
 	}
+	if err := db.SetBackupCount(backupCount); err != nil {
+		db.Close()
+		return err
+	}
 	sa.db = db
 	if len(sa.db.Users) == 0 {
 		req := &requests.Request{
@@ -114,6 +121,62 @@ func (sa *Authenticator) DeleteUser(r *requests.Request) error {
 	return sa.db.DeleteUser(r)
 }
 
+// ListUsers retrieves a page of users from database.
+func (sa *Authenticator) ListUsers(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.ListUsers(r)
+}
+
+// LookupUser retrieves a specific user by user id, username, or email address.
+func (sa *Authenticator) LookupUser(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.LookupUser(r)
+}
+
+// DisableUser disables a specific user.
+func (sa *Authenticator) DisableUser(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.DisableUser(r)
+}
+
+// EnableUser enables a specific user.
+func (sa *Authenticator) EnableUser(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.EnableUser(r)
+}
+
+// UnlockUser clears the lockout of a specific user.
+func (sa *Authenticator) UnlockUser(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.UnlockUser(r)
+}
+
+// UpdateUserRoles replaces the roles of a specific user.
+func (sa *Authenticator) UpdateUserRoles(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.UpdateUserRoles(r)
+}
+
+// AddEmailAddress adds an email address for a user.
+func (sa *Authenticator) AddEmailAddress(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.AddEmailAddress(r)
+}
+
+// ConfirmEmailAddress confirms an email address of a user.
+func (sa *Authenticator) ConfirmEmailAddress(r *requests.Request) error {
+	sa.mux.Lock()
+	defer sa.mux.Unlock()
+	return sa.db.ConfirmEmailAddress(r)
+}
+
 // ChangePassword changes password for a user.
 func (sa *Authenticator) ChangePassword(r *requests.Request) error {
 	sa.mux.Lock()
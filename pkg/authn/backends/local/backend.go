@@ -19,6 +19,7 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"go.uber.org/zap"
+	"strconv"
 	"strings"
 )
 
@@ -28,6 +29,10 @@ type Config struct {
 	Method string `json:"method,omitempty" xml:"method,omitempty" yaml:"method,omitempty"`
 	Realm  string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
 	Path   string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// BackupCount is the number of the previous revisions of the database
+	// kept as backups, e.g. user_db.json.1. The backups are disabled by
+	// default.
+	BackupCount int `json:"backup_count,omitempty" xml:"backup_count,omitempty" yaml:"backup_count,omitempty"`
 }
 
 // Backend represents authentication provider with local backend.
@@ -102,6 +107,22 @@ func (b *Backend) Request(op operator.Type, r *requests.Request) error {
 		return b.authenticator.DeleteUser(r)
 	case operator.LookupAPIKey:
 		return b.authenticator.LookupAPIKey(r)
+	case operator.ListUsers:
+		return b.authenticator.ListUsers(r)
+	case operator.LookupUser:
+		return b.authenticator.LookupUser(r)
+	case operator.DisableUser:
+		return b.authenticator.DisableUser(r)
+	case operator.EnableUser:
+		return b.authenticator.EnableUser(r)
+	case operator.UnlockUser:
+		return b.authenticator.UnlockUser(r)
+	case operator.UpdateUserRoles:
+		return b.authenticator.UpdateUserRoles(r)
+	case operator.AddEmailAddress:
+		return b.authenticator.AddEmailAddress(r)
+	case operator.ConfirmEmailAddress:
+		return b.authenticator.ConfirmEmailAddress(r)
 	}
 
 	b.logger.Error(
@@ -139,7 +160,7 @@ func (b *Backend) Configure() error {
 		}
 	}
 	b.authenticator.logger = b.logger
-	return b.authenticator.Configure(b.config.Path)
+	return b.authenticator.Configure(b.config.Path, b.config.BackupCount)
 }
 
 // Validate checks whether Backend is functional.
@@ -158,6 +179,9 @@ func (b *Backend) GetConfig() string {
 	sb.WriteString("method " + b.config.Method + "\n")
 	sb.WriteString("realm " + b.config.Realm + "\n")
 	sb.WriteString("path " + b.config.Path + "")
+	if b.config.BackupCount > 0 {
+		sb.WriteString("\nbackup_count " + strconv.Itoa(b.config.BackupCount))
+	}
 	return sb.String()
 }
 
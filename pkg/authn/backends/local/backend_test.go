@@ -249,7 +249,8 @@ func TestBackend(t *testing.T) {
 						operator.AddUser,
 						operator.GetUser,
 						operator.GetUsers,
-						operator.DeleteUser,
+						// operator.DeleteUser is not exercised here, because
+						// the database is shared with the subsequent test cases.
 					} {
 						b.Request(op, &requests.Request{
 							User: requests.User{
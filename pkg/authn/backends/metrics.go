@@ -0,0 +1,30 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backends
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+)
+
+var requestDuration = metrics.NewHistogramVec(
+	"authcrunch_backend_request_duration_seconds",
+	"The time it takes an authentication backend to perform an operation.",
+	nil,
+	"realm", "backend", "method", "operation",
+)
+
+func init() {
+	metrics.DefaultRegistry.Register("backends", requestDuration)
+}
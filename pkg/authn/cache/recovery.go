@@ -0,0 +1,438 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// The default recovery cleanup interval is 5 minutes.
+	defaultRecoveryCleanupInternal int = 300
+	minRecoveryCleanupInternal     int = 0
+	// The default lifetime of a recovery entry is 15 minutes.
+	defaultRecoveryMaxEntryLifetime int = 900
+	// The minimum lifetime of a recovery entry is 5 minutes.
+	minRecoveryMaxEntryLifetime int = 300
+)
+
+// RecoveryCacheEntry is an entry in RecoveryCache.
+type RecoveryCacheEntry struct {
+	recoveryID string
+	createdAt  time.Time
+	user       map[string]string
+	// When set to true, the entry is no longer active.
+	expired bool
+}
+
+// RecoveryCache contains cached password recovery requests.
+type RecoveryCache struct {
+	mu sync.RWMutex
+	// The interval (in seconds) at which cache maintenance task are being triggered.
+	// The default is 5 minutes (300 seconds)
+	cleanupInternal int
+	// The maximum number of seconds the cached entry is available to a user.
+	maxEntryLifetime int
+	// If set to true, then the cache is being managed.
+	managed bool
+	// exit channel
+	exit chan bool
+	// When set, the entries are kept in the store instead of Entries.
+	store   Store
+	Entries map[string]*RecoveryCacheEntry `json:"entries,omitempty" xml:"entries,omitempty" yaml:"entries,omitempty"`
+}
+
+// NewRecoveryCache returns RecoveryCache instance.
+func NewRecoveryCache() *RecoveryCache {
+	return &RecoveryCache{
+		cleanupInternal:  defaultRecoveryCleanupInternal,
+		maxEntryLifetime: defaultRecoveryMaxEntryLifetime,
+		Entries:          make(map[string]*RecoveryCacheEntry),
+		exit:             make(chan bool),
+	}
+}
+
+// SetCleanupInterval sets cache management interval.
+func (c *RecoveryCache) SetCleanupInterval(i int) error {
+	if i < 1 {
+		return fmt.Errorf("recovery cache cleanup interval must be equal to or greater than %d", minRecoveryCleanupInternal)
+	}
+	c.cleanupInternal = i
+	return nil
+}
+
+// SetMaxEntryLifetime sets cache management max entry lifetime in seconds.
+func (c *RecoveryCache) SetMaxEntryLifetime(i int) error {
+	if i < minRecoveryMaxEntryLifetime {
+		return fmt.Errorf("recovery cache max entry lifetime must be equal to or greater than %d seconds", minRecoveryMaxEntryLifetime)
+	}
+	c.maxEntryLifetime = i
+	return nil
+}
+
+func manageRecoveryCache(c *RecoveryCache) {
+	c.managed = true
+	intervals := time.NewTicker(time.Second * time.Duration(c.cleanupInternal))
+	for range intervals.C {
+		if c == nil {
+			continue
+		}
+		c.mu.Lock()
+		select {
+		case <-c.exit:
+			c.managed = false
+			break
+		default:
+			break
+		}
+		if !c.managed {
+			c.mu.Unlock()
+			break
+		}
+		if c.Entries == nil {
+			c.mu.Unlock()
+			continue
+		}
+		deleteList := []string{}
+		for recoveryID, entry := range c.Entries {
+			if err := entry.Valid(c.maxEntryLifetime); err != nil {
+				deleteList = append(deleteList, recoveryID)
+				continue
+			}
+		}
+		if len(deleteList) > 0 {
+			for _, recoveryID := range deleteList {
+				delete(c.Entries, recoveryID)
+			}
+		}
+		c.mu.Unlock()
+	}
+	return
+}
+
+// Run starts management of RecoveryCache instance.
+func (c *RecoveryCache) Run() {
+	if c.managed {
+		return
+	}
+	go manageRecoveryCache(c)
+}
+
+// Stop stops management of RecoveryCache instance.
+func (c *RecoveryCache) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.managed = false
+}
+
+// GetCleanupInterval returns cleanup interval.
+func (c *RecoveryCache) GetCleanupInterval() int {
+	return c.cleanupInternal
+}
+
+// GetMaxEntryLifetime returns max entry lifetime.
+func (c *RecoveryCache) GetMaxEntryLifetime() int {
+	return c.maxEntryLifetime
+}
+
+// SetStore sets the store backing the cache.
+func (c *RecoveryCache) SetStore(s Store) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = s
+}
+
+// Size returns the number of entries in the cache. When the cache is
+// backed by a store, the entries in the store are counted.
+func (c *RecoveryCache) Size() int {
+	if c.store != nil {
+		ids, err := c.store.List(recoveryBucket)
+		if err != nil {
+			return 0
+		}
+		return len(ids)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.Entries)
+}
+
+// Add adds a password recovery request to the cache. Any outstanding
+// recovery requests for the same user and realm are discarded, i.e. only
+// the most recent recovery link remains usable.
+func (c *RecoveryCache) Add(recoveryID string, u map[string]string) error {
+	if err := parseCacheID(recoveryID); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Entries == nil && c.store == nil {
+		return errors.New("recovery cache is not available")
+	}
+
+	for _, field := range []string{"username", "email", "realm"} {
+		if _, exists := u[field]; !exists {
+			return fmt.Errorf("input entry has no %s field", field)
+		}
+	}
+
+	entries := c.Entries
+	if c.store != nil {
+		storedEntries, err := c.getStoredEntries()
+		if err != nil {
+			return err
+		}
+		entries = storedEntries
+	}
+
+	for id, m := range entries {
+		if m.user == nil {
+			continue
+		}
+		if m.user["username"] == u["username"] && m.user["realm"] == u["realm"] {
+			if c.store != nil {
+				c.store.Delete(recoveryBucket, id)
+				continue
+			}
+			delete(c.Entries, id)
+		}
+	}
+
+	entry := &RecoveryCacheEntry{
+		recoveryID: recoveryID,
+		createdAt:  time.Now().UTC(),
+		user:       u,
+	}
+	if c.store != nil {
+		return c.putStored(entry)
+	}
+	c.Entries[recoveryID] = entry
+	return nil
+}
+
+// Delete removes cached recovery entry.
+func (c *RecoveryCache) Delete(recoveryID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store != nil {
+		if err := c.store.Delete(recoveryBucket, recoveryID); err != nil {
+			if isStoreEntryNotFound(err) {
+				return errors.New("cached recovery id not found")
+			}
+			return err
+		}
+		return nil
+	}
+	if c.Entries == nil {
+		return errors.New("recovery cache is not available")
+	}
+	_, exists := c.Entries[recoveryID]
+	if !exists {
+		return errors.New("cached recovery id not found")
+	}
+	delete(c.Entries, recoveryID)
+	return nil
+}
+
+// Get returns cached recovery entry.
+func (c *RecoveryCache) Get(recoveryID string) (map[string]string, error) {
+	if err := parseCacheID(recoveryID); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.store != nil {
+		entry, err := c.getStored(recoveryID)
+		if err != nil {
+			return nil, err
+		}
+		if err := entry.Valid(c.maxEntryLifetime); err != nil {
+			return nil, err
+		}
+		return entry.user, nil
+	}
+	if entry, exists := c.Entries[recoveryID]; exists {
+		if err := entry.Valid(c.maxEntryLifetime); err != nil {
+			return nil, err
+		}
+		return entry.user, nil
+	}
+	return nil, errors.New("cached recovery id not found")
+}
+
+// Take returns cached recovery entry and removes it from the cache. Since
+// the lookup and the removal happen at once, only one of the concurrent
+// callers presenting the same recovery id receives the entry. When the cache
+// is backed by a store, the caller succeeding in the removal of the entry
+// from the store receives it. The entry could be returned to the cache with
+// Restore.
+func (c *RecoveryCache) Take(recoveryID string) (*RecoveryCacheEntry, error) {
+	if err := parseCacheID(recoveryID); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store != nil {
+		entry, err := c.getStored(recoveryID)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.store.Delete(recoveryBucket, recoveryID); err != nil {
+			if isStoreEntryNotFound(err) {
+				return nil, errors.New("cached recovery id not found")
+			}
+			return nil, err
+		}
+		if err := entry.Valid(c.maxEntryLifetime); err != nil {
+			return nil, err
+		}
+		return entry, nil
+	}
+	entry, exists := c.Entries[recoveryID]
+	if !exists {
+		return nil, errors.New("cached recovery id not found")
+	}
+	delete(c.Entries, recoveryID)
+	if err := entry.Valid(c.maxEntryLifetime); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Restore returns the recovery entry taken from the cache with Take, e.g.
+// when the password change failed. The entry keeps its creation time, i.e.
+// the link expires as if it had never been taken. The entry is not being
+// restored when another recovery request for the same user and realm has
+// been made in the meantime.
+func (c *RecoveryCache) Restore(entry *RecoveryCacheEntry) error {
+	if entry == nil {
+		return errors.New("recovery cached entry is nil")
+	}
+	if err := entry.Valid(c.maxEntryLifetime); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Entries == nil && c.store == nil {
+		return errors.New("recovery cache is not available")
+	}
+
+	entries := c.Entries
+	if c.store != nil {
+		storedEntries, err := c.getStoredEntries()
+		if err != nil {
+			return err
+		}
+		entries = storedEntries
+	}
+	for _, m := range entries {
+		if m.user == nil {
+			continue
+		}
+		if m.user["username"] == entry.user["username"] && m.user["realm"] == entry.user["realm"] {
+			return errors.New("recovery cached entry superseded")
+		}
+	}
+
+	if c.store != nil {
+		return c.putStored(entry)
+	}
+	c.Entries[entry.recoveryID] = entry
+	return nil
+}
+
+// Expire expires a particular recovery entry.
+func (c *RecoveryCache) Expire(recoveryID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store != nil {
+		if entry, err := c.getStored(recoveryID); err == nil {
+			entry.expired = true
+			c.putStored(entry)
+		}
+		return
+	}
+	if entry, exists := c.Entries[recoveryID]; exists {
+		entry.expired = true
+	}
+	return
+}
+
+func (c *RecoveryCache) getStored(recoveryID string) (*RecoveryCacheEntry, error) {
+	stored, err := getStoredEntry(c.store, recoveryBucket, recoveryID)
+	if err != nil {
+		if isStoreEntryNotFound(err) {
+			return nil, errors.New("cached recovery id not found")
+		}
+		return nil, err
+	}
+	entry := &RecoveryCacheEntry{
+		recoveryID: recoveryID,
+		createdAt:  stored.CreatedAt,
+		user:       stored.Fields,
+		expired:    stored.Expired,
+	}
+	return entry, nil
+}
+
+func (c *RecoveryCache) getStoredEntries() (map[string]*RecoveryCacheEntry, error) {
+	recoveryIDs, err := c.store.List(recoveryBucket)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]*RecoveryCacheEntry)
+	for _, recoveryID := range recoveryIDs {
+		entry, err := c.getStored(recoveryID)
+		if err != nil {
+			// The entry expired after it had been listed.
+			continue
+		}
+		entries[recoveryID] = entry
+	}
+	return entries, nil
+}
+
+func (c *RecoveryCache) putStored(entry *RecoveryCacheEntry) error {
+	lifetime := getRemainingLifetime(entry.createdAt, c.maxEntryLifetime)
+	if lifetime < 1 {
+		return errors.New("recovery cached entry expired")
+	}
+	stored := &storedEntry{
+		CreatedAt: entry.createdAt,
+		Expired:   entry.expired,
+		Fields:    entry.user,
+	}
+	return putStoredEntry(c.store, recoveryBucket, entry.recoveryID, stored, lifetime)
+}
+
+// GetUser returns the user fields of RecoveryCacheEntry.
+func (e *RecoveryCacheEntry) GetUser() map[string]string {
+	return e.user
+}
+
+// Valid checks whether RecoveryCacheEntry is non-expired.
+func (e *RecoveryCacheEntry) Valid(max int) error {
+	if e.expired {
+		return errors.New("recovery cached entry is no longer in use")
+	}
+	diff := time.Now().UTC().Unix() - e.createdAt.Unix()
+	if diff > int64(max) {
+		return errors.New("recovery cached entry expired")
+	}
+	return nil
+}
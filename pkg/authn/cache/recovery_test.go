@@ -0,0 +1,201 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+func TestRecoveryCache(t *testing.T) {
+	firstID := util.GetRandomStringFromRange(64, 96)
+	secondID := util.GetRandomStringFromRange(64, 96)
+	entry := map[string]string{
+		"username": "jsmith",
+		"email":    "jsmith@localhost.localdomain",
+		"realm":    "local",
+	}
+
+	testcases := []struct {
+		name      string
+		entries   map[string]map[string]string
+		op        string
+		id        string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "get valid recovery entry",
+			entries: map[string]map[string]string{
+				firstID: entry,
+			},
+			op: "get",
+			id: firstID,
+			want: map[string]interface{}{
+				"username": "jsmith",
+				"email":    "jsmith@localhost.localdomain",
+				"realm":    "local",
+			},
+		},
+		{
+			name: "get recovery entry superseded by newer request",
+			entries: map[string]map[string]string{
+				firstID:  entry,
+				secondID: entry,
+			},
+			op:        "get",
+			id:        firstID,
+			shouldErr: true,
+			err:       errors.New("cached recovery id not found"),
+		},
+		{
+			name: "get deleted recovery entry",
+			entries: map[string]map[string]string{
+				firstID: entry,
+			},
+			op:        "delete",
+			id:        firstID,
+			shouldErr: true,
+			err:       errors.New("cached recovery id not found"),
+		},
+		{
+			name: "get taken recovery entry",
+			entries: map[string]map[string]string{
+				firstID: entry,
+			},
+			op:        "take",
+			id:        firstID,
+			shouldErr: true,
+			err:       errors.New("cached recovery id not found"),
+		},
+		{
+			name: "get expired recovery entry",
+			entries: map[string]map[string]string{
+				firstID: entry,
+			},
+			op:        "expire",
+			id:        firstID,
+			shouldErr: true,
+			err:       errors.New("recovery cached entry is no longer in use"),
+		},
+		{
+			name:      "get recovery entry with malformed id",
+			op:        "get",
+			id:        "foobar",
+			shouldErr: true,
+			err:       errors.New("cached id length is outside of 32-96 character range"),
+		},
+		{
+			name: "add recovery entry without realm",
+			entries: map[string]map[string]string{
+				firstID: {
+					"username": "jsmith",
+					"email":    "jsmith@localhost.localdomain",
+				},
+			},
+			op:        "get",
+			id:        firstID,
+			shouldErr: true,
+			err:       errors.New("input entry has no realm field"),
+		},
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			c := NewRecoveryCache()
+			for _, id := range []string{firstID, secondID} {
+				u, exists := tc.entries[id]
+				if !exists {
+					continue
+				}
+				if err := c.Add(id, u); err != nil {
+					if tests.EvalErrWithLog(t, err, "recovery cache", tc.shouldErr, tc.err, msgs) {
+						return
+					}
+				}
+			}
+			switch tc.op {
+			case "delete":
+				if err := c.Delete(tc.id); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			case "expire":
+				c.Expire(tc.id)
+			case "take":
+				if _, err := c.Take(tc.id); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if _, err := c.Take(tc.id); err == nil {
+					t.Fatalf("expected error when taking recovery entry twice")
+				}
+			}
+			u, err := c.Get(tc.id)
+			if tests.EvalErrWithLog(t, err, "recovery cache", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := make(map[string]interface{})
+			for k, v := range u {
+				got[k] = v
+			}
+			tests.EvalObjectsWithLog(t, "recovery cache", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestRecoveryCacheRestore(t *testing.T) {
+	firstID := util.GetRandomStringFromRange(64, 96)
+	secondID := util.GetRandomStringFromRange(64, 96)
+	entry := map[string]string{
+		"username": "jsmith",
+		"email":    "jsmith@localhost.localdomain",
+		"realm":    "local",
+	}
+	c := NewRecoveryCache()
+	if err := c.Add(firstID, entry); err != nil {
+		t.Fatalf("unexpected add error: %v", err)
+	}
+	createdAt := c.Entries[firstID].createdAt
+
+	taken, err := c.Take(firstID)
+	if err != nil {
+		t.Fatalf("unexpected take error: %v", err)
+	}
+	if err := c.Restore(taken); err != nil {
+		t.Fatalf("unexpected restore error: %v", err)
+	}
+	if _, err := c.Get(firstID); err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	tests.EvalObjects(t, "created at", createdAt, c.Entries[firstID].createdAt)
+
+	// The entry superseded by a newer request is not being restored.
+	taken, err = c.Take(firstID)
+	if err != nil {
+		t.Fatalf("unexpected take error: %v", err)
+	}
+	if err := c.Add(secondID, entry); err != nil {
+		t.Fatalf("unexpected add error: %v", err)
+	}
+	tests.EvalErr(t, c.Restore(taken), "restore", true, errors.New("recovery cached entry superseded"))
+	if _, err := c.Get(firstID); err == nil {
+		t.Fatalf("expected superseded recovery entry to remain removed")
+	}
+}
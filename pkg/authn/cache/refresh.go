@@ -0,0 +1,431 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"sync"
+	"time"
+)
+
+const (
+	// The default refresh token cleanup interval is 5 minutes.
+	defaultRefreshTokenCleanupInternal int = 300
+	minRefreshTokenCleanupInternal     int = 0
+)
+
+// RefreshTokenCacheEntry is an entry in RefreshTokenCache. The entries
+// sharing the same family id originate from the same login. The token id
+// of the entry is the hash of the refresh token, rather than the token.
+type RefreshTokenCacheEntry struct {
+	tokenID   string
+	familyID  string
+	createdAt time.Time
+	expiresAt time.Time
+	// The time after which no refresh token of the family is valid,
+	// regardless of the rotations.
+	familyExpiresAt time.Time
+	user            *user.User
+	// When set to true, the refresh token has been exchanged already.
+	used bool
+}
+
+// RefreshTokenCache contains refresh tokens issued by the portal.
+type RefreshTokenCache struct {
+	mu sync.RWMutex
+	// The interval (in seconds) at which cache maintenance task are being triggered.
+	// The default is 5 minutes (300 seconds)
+	cleanupInternal int
+	// If set to true, then the cache is being managed.
+	managed bool
+	// exit channel
+	exit chan bool
+	// When set, the entries are kept in the store instead of Entries.
+	store   Store
+	Entries map[string]*RefreshTokenCacheEntry `json:"entries,omitempty" xml:"entries,omitempty" yaml:"entries,omitempty"`
+}
+
+// NewRefreshTokenCache returns RefreshTokenCache instance.
+func NewRefreshTokenCache() *RefreshTokenCache {
+	return &RefreshTokenCache{
+		cleanupInternal: defaultRefreshTokenCleanupInternal,
+		Entries:         make(map[string]*RefreshTokenCacheEntry),
+		exit:            make(chan bool),
+	}
+}
+
+// SetCleanupInterval sets cache management interval.
+func (c *RefreshTokenCache) SetCleanupInterval(i int) error {
+	if i < 1 {
+		return fmt.Errorf("refresh token cache cleanup interval must be equal to or greater than %d", minRefreshTokenCleanupInternal)
+	}
+	c.cleanupInternal = i
+	return nil
+}
+
+func manageRefreshTokenCache(c *RefreshTokenCache) {
+	c.managed = true
+	intervals := time.NewTicker(time.Second * time.Duration(c.cleanupInternal))
+	for range intervals.C {
+		if c == nil {
+			continue
+		}
+		c.mu.Lock()
+		select {
+		case <-c.exit:
+			c.managed = false
+			break
+		default:
+			break
+		}
+		if !c.managed {
+			c.mu.Unlock()
+			break
+		}
+		if c.Entries == nil {
+			c.mu.Unlock()
+			continue
+		}
+		// The used entries are kept until they expire, because they are
+		// required for the detection of refresh token reuse.
+		now := time.Now().UTC()
+		for tokenID, entry := range c.Entries {
+			if entry.expiresAt.Before(now) {
+				delete(c.Entries, tokenID)
+			}
+		}
+		c.mu.Unlock()
+	}
+	return
+}
+
+// Run starts management of RefreshTokenCache instance.
+func (c *RefreshTokenCache) Run() {
+	if c.managed {
+		return
+	}
+	go manageRefreshTokenCache(c)
+}
+
+// Stop stops management of RefreshTokenCache instance.
+func (c *RefreshTokenCache) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.managed = false
+}
+
+// GetCleanupInterval returns cleanup interval.
+func (c *RefreshTokenCache) GetCleanupInterval() int {
+	return c.cleanupInternal
+}
+
+// SetStore sets the store backing the cache.
+func (c *RefreshTokenCache) SetStore(s Store) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = s
+}
+
+// Size returns the number of entries in the cache. When the cache is
+// backed by a store, the entries in the store are counted.
+func (c *RefreshTokenCache) Size() int {
+	if c.store != nil {
+		ids, err := c.store.List(refreshTokenBucket)
+		if err != nil {
+			return 0
+		}
+		return len(ids)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.Entries)
+}
+
+// Add adds a refresh token to the cache. The lifetime is in seconds. The
+// refresh tokens obtained by rotating the token expire no later than it.
+func (c *RefreshTokenCache) Add(tokenID, familyID string, usr *user.User, lifetime int) error {
+	if err := parseCacheID(tokenID); err != nil {
+		return errors.ErrRefreshTokenInvalid.WithArgs(err)
+	}
+	if usr == nil {
+		return errors.ErrCacheNilUser
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Entries == nil && c.store == nil {
+		return errors.ErrRefreshTokenCacheUnavailable
+	}
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Duration(lifetime) * time.Second)
+	return c.add(hashRefreshTokenID(tokenID), familyID, usr, now, expiresAt, expiresAt)
+}
+
+func (c *RefreshTokenCache) add(tokenID, familyID string, usr *user.User, createdAt, expiresAt, familyExpiresAt time.Time) error {
+	entry := &RefreshTokenCacheEntry{
+		tokenID:         tokenID,
+		familyID:        familyID,
+		createdAt:       createdAt,
+		expiresAt:       expiresAt,
+		familyExpiresAt: familyExpiresAt,
+		user:            usr,
+	}
+	return c.putEntry(entry)
+}
+
+// hashRefreshTokenID returns the hash of the refresh token the cache keeps
+// in place of the token.
+func hashRefreshTokenID(tokenID string) string {
+	h := sha256.Sum256([]byte(tokenID))
+	return hex.EncodeToString(h[:])
+}
+
+// Rotate exchanges a refresh token for a new one in the same family and
+// returns the user associated with the family. A refresh token may be
+// exchanged only once. When a previously exchanged refresh token is
+// presented again, the whole family of refresh tokens is invalidated and
+// the user associated with the family is returned along with the error.
+// The new refresh token expires no later than the family does.
+func (c *RefreshTokenCache) Rotate(tokenID, newTokenID string, lifetime int) (*user.User, error) {
+	if err := parseCacheID(tokenID); err != nil {
+		return nil, errors.ErrRefreshTokenInvalid.WithArgs(err)
+	}
+	if err := parseCacheID(newTokenID); err != nil {
+		return nil, errors.ErrRefreshTokenInvalid.WithArgs(err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Entries == nil && c.store == nil {
+		return nil, errors.ErrRefreshTokenCacheUnavailable
+	}
+	tokenID = hashRefreshTokenID(tokenID)
+	entry, err := c.getEntry(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.used {
+		c.deleteFamily(entry.familyID)
+		return entry.user, errors.ErrRefreshTokenReused
+	}
+	now := time.Now().UTC()
+	if entry.expiresAt.Before(now) || !entry.familyExpiresAt.After(now) {
+		c.deleteEntry(tokenID)
+		return nil, errors.ErrRefreshTokenExpired
+	}
+	if c.store != nil {
+		// The removal of the entry from the store succeeds for only one of
+		// the portal instances exchanging the same refresh token at once.
+		if err := c.deleteEntry(tokenID); err != nil {
+			return nil, err
+		}
+	}
+	entry.used = true
+	if err := c.putEntry(entry); err != nil {
+		return nil, err
+	}
+	expiresAt := now.Add(time.Duration(lifetime) * time.Second)
+	if expiresAt.After(entry.familyExpiresAt) {
+		expiresAt = entry.familyExpiresAt
+	}
+	if err := c.add(hashRefreshTokenID(newTokenID), entry.familyID, entry.user, now, expiresAt, entry.familyExpiresAt); err != nil {
+		return nil, err
+	}
+	return entry.user, nil
+}
+
+// Update replaces the user associated with the refresh token, e.g. the one
+// holding the claims of the access token issued along with the refresh token.
+func (c *RefreshTokenCache) Update(tokenID string, usr *user.User) error {
+	if usr == nil {
+		return errors.ErrCacheNilUser
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, err := c.getEntry(hashRefreshTokenID(tokenID))
+	if err != nil {
+		return err
+	}
+	entry.user = usr
+	return c.putEntry(entry)
+}
+
+// DeleteFamily removes the refresh token and all the other refresh tokens
+// of its family. It returns the number of the removed tokens.
+func (c *RefreshTokenCache) DeleteFamily(tokenID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, err := c.getEntry(hashRefreshTokenID(tokenID))
+	if err != nil {
+		return 0
+	}
+	return c.deleteFamily(entry.familyID)
+}
+
+// DeleteByAccessTokenID removes the families of refresh tokens which issued
+// the access token with the provided token id (jti), e.g. on logout. It
+// returns the number of the removed tokens.
+func (c *RefreshTokenCache) DeleteByAccessTokenID(jti string) int {
+	if jti == "" {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var count int
+	for _, familyID := range c.getFamilies(func(usr *user.User) bool { return usr.Claims.ID == jti }) {
+		count += c.deleteFamily(familyID)
+	}
+	return count
+}
+
+// DeleteSubject removes the families of refresh tokens issued to the
+// subject, e.g. when the user is disabled. It returns the number of the
+// removed tokens.
+func (c *RefreshTokenCache) DeleteSubject(subject string) int {
+	if subject == "" {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var count int
+	for _, familyID := range c.getFamilies(func(usr *user.User) bool { return usr.Claims.Subject == subject }) {
+		count += c.deleteFamily(familyID)
+	}
+	return count
+}
+
+func (c *RefreshTokenCache) deleteFamily(familyID string) int {
+	var count int
+	for tokenID, entry := range c.getEntries() {
+		if entry.familyID == familyID {
+			if err := c.deleteEntry(tokenID); err == nil {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// getFamilies returns the ids of the families having a refresh token whose
+// user matches the provided function.
+func (c *RefreshTokenCache) getFamilies(match func(*user.User) bool) []string {
+	var familyIDs []string
+	seen := make(map[string]bool)
+	for _, entry := range c.getEntries() {
+		if seen[entry.familyID] || entry.user == nil || entry.user.Claims == nil {
+			continue
+		}
+		if match(entry.user) {
+			seen[entry.familyID] = true
+			familyIDs = append(familyIDs, entry.familyID)
+		}
+	}
+	return familyIDs
+}
+
+func (c *RefreshTokenCache) getEntry(tokenID string) (*RefreshTokenCacheEntry, error) {
+	if c.store == nil {
+		entry, exists := c.Entries[tokenID]
+		if !exists {
+			return nil, errors.ErrRefreshTokenNotFound
+		}
+		return entry, nil
+	}
+	stored, err := getStoredEntry(c.store, refreshTokenBucket, tokenID)
+	if err != nil {
+		if isStoreEntryNotFound(err) {
+			return nil, errors.ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+	if stored.User == nil {
+		return nil, errors.ErrRefreshTokenNotFound
+	}
+	usr, err := stored.User.getUser()
+	if err != nil {
+		return nil, err
+	}
+	entry := &RefreshTokenCacheEntry{
+		tokenID:         tokenID,
+		familyID:        stored.FamilyID,
+		createdAt:       stored.CreatedAt,
+		expiresAt:       stored.ExpiresAt,
+		familyExpiresAt: stored.FamilyExpiresAt,
+		user:            usr,
+		used:            stored.Used,
+	}
+	return entry, nil
+}
+
+// getEntries returns the entries of the cache. When the cache is backed by
+// a store, the entries are being loaded from the store.
+func (c *RefreshTokenCache) getEntries() map[string]*RefreshTokenCacheEntry {
+	if c.store == nil {
+		return c.Entries
+	}
+	entries := make(map[string]*RefreshTokenCacheEntry)
+	tokenIDs, err := c.store.List(refreshTokenBucket)
+	if err != nil {
+		return entries
+	}
+	for _, tokenID := range tokenIDs {
+		entry, err := c.getEntry(tokenID)
+		if err != nil {
+			// The entry expired after it had been listed.
+			continue
+		}
+		entries[tokenID] = entry
+	}
+	return entries
+}
+
+func (c *RefreshTokenCache) putEntry(entry *RefreshTokenCacheEntry) error {
+	if c.store == nil {
+		c.Entries[entry.tokenID] = entry
+		return nil
+	}
+	lifetime := int(entry.expiresAt.Unix() - time.Now().UTC().Unix())
+	if lifetime < 1 {
+		return errors.ErrRefreshTokenExpired
+	}
+	stored := &storedEntry{
+		CreatedAt:       entry.createdAt,
+		ExpiresAt:       entry.expiresAt,
+		FamilyID:        entry.familyID,
+		FamilyExpiresAt: entry.familyExpiresAt,
+		Used:            entry.used,
+		User:            newStoredUser(entry.user),
+	}
+	return putStoredEntry(c.store, refreshTokenBucket, entry.tokenID, stored, lifetime)
+}
+
+func (c *RefreshTokenCache) deleteEntry(tokenID string) error {
+	if c.store == nil {
+		if _, exists := c.Entries[tokenID]; !exists {
+			return errors.ErrRefreshTokenNotFound
+		}
+		delete(c.Entries, tokenID)
+		return nil
+	}
+	if err := c.store.Delete(refreshTokenBucket, tokenID); err != nil {
+		if isStoreEntryNotFound(err) {
+			return errors.ErrRefreshTokenNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+func TestRefreshTokenCache(t *testing.T) {
+	firstID := util.GetRandomStringFromRange(64, 96)
+	secondID := util.GetRandomStringFromRange(64, 96)
+	thirdID := util.GetRandomStringFromRange(64, 96)
+	familyID := util.GetRandomString(32)
+
+	testcases := []struct {
+		name     string
+		lifetime int
+		// The sequence of rotations, each is a pair of old and new token ids.
+		rotations [][]string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "rotate refresh token",
+			lifetime: 900,
+			rotations: [][]string{
+				{firstID, secondID},
+			},
+			want: map[string]interface{}{
+				"entry_count": 2,
+			},
+		},
+		{
+			name:     "rotate refresh token twice",
+			lifetime: 900,
+			rotations: [][]string{
+				{firstID, secondID},
+				{secondID, thirdID},
+			},
+			want: map[string]interface{}{
+				"entry_count": 3,
+			},
+		},
+		{
+			name:     "reuse of refresh token invalidates family",
+			lifetime: 900,
+			rotations: [][]string{
+				{firstID, secondID},
+				{firstID, thirdID},
+			},
+			want: map[string]interface{}{
+				"entry_count": 0,
+			},
+			shouldErr: true,
+			err:       errors.ErrRefreshTokenReused,
+		},
+		{
+			name:     "rotate expired refresh token",
+			lifetime: -1,
+			rotations: [][]string{
+				{firstID, secondID},
+			},
+			want: map[string]interface{}{
+				"entry_count": 0,
+			},
+			shouldErr: true,
+			err:       errors.ErrRefreshTokenExpired,
+		},
+		{
+			name:     "rotate unknown refresh token",
+			lifetime: 900,
+			rotations: [][]string{
+				{secondID, thirdID},
+			},
+			want: map[string]interface{}{
+				"entry_count": 1,
+			},
+			shouldErr: true,
+			err:       errors.ErrRefreshTokenNotFound,
+		},
+		{
+			name:     "rotate malformed refresh token",
+			lifetime: 900,
+			rotations: [][]string{
+				{"foobar", secondID},
+			},
+			want: map[string]interface{}{
+				"entry_count": 1,
+			},
+			shouldErr: true,
+			err:       errors.ErrRefreshTokenInvalid.WithArgs(fmt.Errorf("cached id length is outside of 32-96 character range")),
+		},
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			c := NewRefreshTokenCache()
+			usr := testutils.NewTestUser()
+			if err := c.Add(firstID, familyID, usr, tc.lifetime); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var err error
+			for _, rotation := range tc.rotations {
+				_, err = c.Rotate(rotation[0], rotation[1], tc.lifetime)
+				if err != nil {
+					break
+				}
+			}
+			got := map[string]interface{}{
+				"entry_count": len(c.Entries),
+			}
+			tests.EvalObjectsWithLog(t, "refresh token cache", tc.want, got, msgs)
+			tests.EvalErrWithLog(t, err, "refresh token cache", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
+
+func TestRefreshTokenCacheFamilyExpiry(t *testing.T) {
+	firstID := util.GetRandomStringFromRange(64, 96)
+	secondID := util.GetRandomStringFromRange(64, 96)
+	c := NewRefreshTokenCache()
+	if err := c.Add(firstID, "family", testutils.NewTestUser(), 900); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	familyExpiresAt := c.Entries[hashRefreshTokenID(firstID)].expiresAt
+
+	// The refresh token obtained by the rotation does not outlive the family.
+	if _, err := c.Rotate(firstID, secondID, 3600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entry := c.Entries[hashRefreshTokenID(secondID)]
+	tests.EvalObjects(t, "expires at", familyExpiresAt, entry.expiresAt)
+	tests.EvalObjects(t, "family expires at", familyExpiresAt, entry.familyExpiresAt)
+
+	// The family expired.
+	entry.familyExpiresAt = time.Now().UTC().Add(-time.Second)
+	_, err := c.Rotate(secondID, util.GetRandomStringFromRange(64, 96), 3600)
+	tests.EvalErr(t, err, "expired family", true, errors.ErrRefreshTokenExpired)
+}
+
+func TestRefreshTokenCacheHashedTokens(t *testing.T) {
+	tokenID := util.GetRandomStringFromRange(64, 96)
+	c := NewRefreshTokenCache()
+	if err := c.Add(tokenID, "family", testutils.NewTestUser(), 900); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, exists := c.Entries[tokenID]; exists {
+		t.Fatalf("refresh token kept in the cache in plaintext")
+	}
+	if _, exists := c.Entries[hashRefreshTokenID(tokenID)]; !exists {
+		t.Fatalf("refresh token hash not found in the cache")
+	}
+}
+
+func TestRefreshTokenCacheDelete(t *testing.T) {
+	newUser := func(jti, sub string) *user.User {
+		usr, err := user.NewUser(map[string]interface{}{
+			"jti":   jti,
+			"sub":   sub,
+			"email": sub + "@localhost",
+			"exp":   time.Now().Add(10 * time.Minute).Unix(),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return usr
+	}
+
+	testcases := []struct {
+		name  string
+		op    string
+		value string
+		want  map[string]interface{}
+	}{
+		{
+			name:  "delete families by access token id",
+			op:    "jti",
+			value: "jsmith-session-2",
+			want: map[string]interface{}{
+				"deleted":     2,
+				"entry_count": 2,
+			},
+		},
+		{
+			name:  "delete families by subject",
+			op:    "sub",
+			value: "jsmith",
+			want: map[string]interface{}{
+				"deleted":     3,
+				"entry_count": 1,
+			},
+		},
+		{
+			name:  "delete families by unknown subject",
+			op:    "sub",
+			value: "foobar",
+			want: map[string]interface{}{
+				"deleted":     0,
+				"entry_count": 4,
+			},
+		},
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			c := NewRefreshTokenCache()
+			ids := []string{}
+			for i := 0; i < 4; i++ {
+				ids = append(ids, util.GetRandomStringFromRange(64, 96))
+			}
+			// The first family was rotated once and its newest refresh token
+			// holds the user of the refreshed access token.
+			c.Add(ids[0], "family-1", newUser("jsmith-session-1", "jsmith"), 900)
+			if _, err := c.Rotate(ids[0], ids[1], 900); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := c.Update(ids[1], newUser("jsmith-session-2", "jsmith")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c.Add(ids[2], "family-2", newUser("jsmith-session-3", "jsmith"), 900)
+			c.Add(ids[3], "family-3", newUser("bsmith-session-1", "bsmith"), 900)
+
+			var deleted int
+			switch tc.op {
+			case "jti":
+				deleted = c.DeleteByAccessTokenID(tc.value)
+			case "sub":
+				deleted = c.DeleteSubject(tc.value)
+			}
+			got := map[string]interface{}{
+				"deleted":     deleted,
+				"entry_count": c.Size(),
+			}
+			tests.EvalObjectsWithLog(t, "refresh token cache", tc.want, got, msgs)
+		})
+	}
+}
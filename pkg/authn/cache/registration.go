@@ -51,7 +51,9 @@ type RegistrationCache struct {
 	// If set to true, then the cache is being managed.
 	managed bool
 	// exit channel
-	exit    chan bool
+	exit chan bool
+	// When set, the entries are kept in the store instead of Entries.
+	store   Store
 	Entries map[string]*RegistrationCacheEntry `json:"entries,omitempty" xml:"entries,omitempty" yaml:"entries,omitempty"`
 }
 
@@ -148,11 +150,33 @@ func (c *RegistrationCache) GetMaxEntryLifetime() int {
 	return c.maxEntryLifetime
 }
 
+// SetStore sets the store backing the cache.
+func (c *RegistrationCache) SetStore(s Store) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = s
+}
+
+// Size returns the number of entries in the cache. When the cache is
+// backed by a store, the entries in the store are counted.
+func (c *RegistrationCache) Size() int {
+	if c.store != nil {
+		ids, err := c.store.List(registrationBucket)
+		if err != nil {
+			return 0
+		}
+		return len(ids)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.Entries)
+}
+
 // Add adds user to the cache.
 func (c *RegistrationCache) Add(registrationID string, u map[string]string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.Entries == nil {
+	if c.Entries == nil && c.store == nil {
 		return errors.New("registration cache is not available")
 	}
 
@@ -162,7 +186,16 @@ func (c *RegistrationCache) Add(registrationID string, u map[string]string) erro
 		}
 	}
 
-	for _, m := range c.Entries {
+	entries := c.Entries
+	if c.store != nil {
+		storedEntries, err := c.getStoredEntries()
+		if err != nil {
+			return err
+		}
+		entries = storedEntries
+	}
+
+	for _, m := range entries {
 		if m.user == nil {
 			continue
 		}
@@ -173,11 +206,15 @@ func (c *RegistrationCache) Add(registrationID string, u map[string]string) erro
 		}
 	}
 
-	c.Entries[registrationID] = &RegistrationCacheEntry{
+	entry := &RegistrationCacheEntry{
 		registrationID: registrationID,
 		createdAt:      time.Now().UTC(),
 		user:           u,
 	}
+	if c.store != nil {
+		return c.putStored(entry)
+	}
+	c.Entries[registrationID] = entry
 	return nil
 }
 
@@ -185,6 +222,15 @@ func (c *RegistrationCache) Add(registrationID string, u map[string]string) erro
 func (c *RegistrationCache) Delete(registrationID string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.store != nil {
+		if err := c.store.Delete(registrationBucket, registrationID); err != nil {
+			if isStoreEntryNotFound(err) {
+				return errors.New("cached registration id not found")
+			}
+			return err
+		}
+		return nil
+	}
 	if c.Entries == nil {
 		return errors.New("registration cache is not available")
 	}
@@ -203,6 +249,16 @@ func (c *RegistrationCache) Get(registrationID string) (map[string]string, error
 	}
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	if c.store != nil {
+		entry, err := c.getStored(registrationID)
+		if err != nil {
+			return nil, err
+		}
+		if err := entry.Valid(c.maxEntryLifetime); err != nil {
+			return nil, err
+		}
+		return entry.user, nil
+	}
 	if entry, exists := c.Entries[registrationID]; exists {
 		if err := entry.Valid(c.maxEntryLifetime); err != nil {
 			return nil, err
@@ -216,12 +272,66 @@ func (c *RegistrationCache) Get(registrationID string) (map[string]string, error
 func (c *RegistrationCache) Expire(registrationID string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.store != nil {
+		if entry, err := c.getStored(registrationID); err == nil {
+			entry.expired = true
+			c.putStored(entry)
+		}
+		return
+	}
 	if entry, exists := c.Entries[registrationID]; exists {
 		entry.expired = true
 	}
 	return
 }
 
+func (c *RegistrationCache) getStored(registrationID string) (*RegistrationCacheEntry, error) {
+	stored, err := getStoredEntry(c.store, registrationBucket, registrationID)
+	if err != nil {
+		if isStoreEntryNotFound(err) {
+			return nil, errors.New("cached registration id not found")
+		}
+		return nil, err
+	}
+	entry := &RegistrationCacheEntry{
+		registrationID: registrationID,
+		createdAt:      stored.CreatedAt,
+		user:           stored.Fields,
+		expired:        stored.Expired,
+	}
+	return entry, nil
+}
+
+func (c *RegistrationCache) getStoredEntries() (map[string]*RegistrationCacheEntry, error) {
+	registrationIDs, err := c.store.List(registrationBucket)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]*RegistrationCacheEntry)
+	for _, registrationID := range registrationIDs {
+		entry, err := c.getStored(registrationID)
+		if err != nil {
+			// The entry expired after it had been listed.
+			continue
+		}
+		entries[registrationID] = entry
+	}
+	return entries, nil
+}
+
+func (c *RegistrationCache) putStored(entry *RegistrationCacheEntry) error {
+	lifetime := getRemainingLifetime(entry.createdAt, c.maxEntryLifetime)
+	if lifetime < 1 {
+		return errors.New("registration cached entry expired")
+	}
+	stored := &storedEntry{
+		CreatedAt: entry.createdAt,
+		Expired:   entry.expired,
+		Fields:    entry.user,
+	}
+	return putStoredEntry(c.store, registrationBucket, entry.registrationID, stored, lifetime)
+}
+
 // Valid checks whether RegistrationCacheEntry is non-expired.
 func (e *RegistrationCacheEntry) Valid(max int) error {
 	if e.expired {
@@ -48,7 +48,9 @@ type SandboxCache struct {
 	// If set to true, then the cache is being managed.
 	managed bool
 	// exit channel
-	exit    chan bool
+	exit chan bool
+	// When set, the entries are kept in the store instead of Entries.
+	store   Store
 	Entries map[string]*SandboxCacheEntry `json:"entries,omitempty" xml:"entries,omitempty" yaml:"entries,omitempty"`
 }
 
@@ -145,10 +147,39 @@ func (c *SandboxCache) GetMaxEntryLifetime() int {
 	return c.maxEntryLifetime
 }
 
+// SetStore sets the store backing the cache.
+func (c *SandboxCache) SetStore(s Store) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = s
+}
+
+// Size returns the number of entries in the cache. When the cache is
+// backed by a store, the entries in the store are counted.
+func (c *SandboxCache) Size() int {
+	if c.store != nil {
+		ids, err := c.store.List(sandboxBucket)
+		if err != nil {
+			return 0
+		}
+		return len(ids)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.Entries)
+}
+
 // Add adds user to the cache.
 func (c *SandboxCache) Add(sandboxID string, u *user.User) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.store != nil {
+		entry := &storedEntry{
+			CreatedAt: time.Now().UTC(),
+			User:      newStoredUser(u),
+		}
+		return putStoredEntry(c.store, sandboxBucket, sandboxID, entry, c.maxEntryLifetime)
+	}
 	if c.Entries == nil {
 		return errors.New("sandbox cache is not available")
 	}
@@ -164,6 +195,15 @@ func (c *SandboxCache) Add(sandboxID string, u *user.User) error {
 func (c *SandboxCache) Delete(sandboxID string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.store != nil {
+		if err := c.store.Delete(sandboxBucket, sandboxID); err != nil {
+			if isStoreEntryNotFound(err) {
+				return errors.New("cached sandbox id not found")
+			}
+			return err
+		}
+		return nil
+	}
 	if c.Entries == nil {
 		return errors.New("sandbox cache is not available")
 	}
@@ -182,6 +222,16 @@ func (c *SandboxCache) Get(sandboxID string) (*user.User, error) {
 	}
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	if c.store != nil {
+		entry, err := c.getStored(sandboxID)
+		if err != nil {
+			return nil, err
+		}
+		if err := entry.Valid(c.maxEntryLifetime); err != nil {
+			return nil, err
+		}
+		return entry.user, nil
+	}
 	if entry, exists := c.Entries[sandboxID]; exists {
 		if err := entry.Valid(c.maxEntryLifetime); err != nil {
 			return nil, err
@@ -191,16 +241,81 @@ func (c *SandboxCache) Get(sandboxID string) (*user.User, error) {
 	return nil, errors.New("cached sandbox id not found")
 }
 
+// Update saves the changes made to the user of a particular sandbox entry,
+// e.g. the progress through authorization checkpoints.
+func (c *SandboxCache) Update(sandboxID string, u *user.User) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store != nil {
+		entry, err := c.getStored(sandboxID)
+		if err != nil {
+			return err
+		}
+		entry.user = u
+		return c.putStored(entry)
+	}
+	entry, exists := c.Entries[sandboxID]
+	if !exists {
+		return errors.New("cached sandbox id not found")
+	}
+	entry.user = u
+	return nil
+}
+
 // Expire expires a particular sandbox entry.
 func (c *SandboxCache) Expire(sandboxID string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.store != nil {
+		if entry, err := c.getStored(sandboxID); err == nil {
+			entry.expired = true
+			c.putStored(entry)
+		}
+		return
+	}
 	if entry, exists := c.Entries[sandboxID]; exists {
 		entry.expired = true
 	}
 	return
 }
 
+func (c *SandboxCache) getStored(sandboxID string) (*SandboxCacheEntry, error) {
+	stored, err := getStoredEntry(c.store, sandboxBucket, sandboxID)
+	if err != nil {
+		if isStoreEntryNotFound(err) {
+			return nil, errors.New("cached sandbox id not found")
+		}
+		return nil, err
+	}
+	if stored.User == nil {
+		return nil, errors.New("cached sandbox id not found")
+	}
+	u, err := stored.User.getUser()
+	if err != nil {
+		return nil, err
+	}
+	entry := &SandboxCacheEntry{
+		sandboxID: sandboxID,
+		createdAt: stored.CreatedAt,
+		user:      u,
+		expired:   stored.Expired,
+	}
+	return entry, nil
+}
+
+func (c *SandboxCache) putStored(entry *SandboxCacheEntry) error {
+	lifetime := getRemainingLifetime(entry.createdAt, c.maxEntryLifetime)
+	if lifetime < 1 {
+		return errors.New("sandbox cached entry expired")
+	}
+	stored := &storedEntry{
+		CreatedAt: entry.createdAt,
+		Expired:   entry.expired,
+		User:      newStoredUser(entry.user),
+	}
+	return putStoredEntry(c.store, sandboxBucket, entry.sandboxID, stored, lifetime)
+}
+
 // Valid checks whether SandboxCacheEntry is non-expired.
 func (e *SandboxCacheEntry) Valid(max int) error {
 	if e.expired {
@@ -44,7 +44,9 @@ type SessionCache struct {
 	// If set to true, then the cache is being managed.
 	managed bool
 	// exit channel
-	exit    chan bool
+	exit chan bool
+	// When set, the entries are kept in the store instead of Entries.
+	store   Store
 	Entries map[string]*SessionCacheEntry `json:"entries,omitempty" xml:"entries,omitempty" yaml:"entries,omitempty"`
 }
 
@@ -127,10 +129,41 @@ func (c *SessionCache) GetCleanupInterval() int {
 	return c.cleanupInternal
 }
 
+// SetStore sets the store backing the cache.
+func (c *SessionCache) SetStore(s Store) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = s
+}
+
+// Size returns the number of entries in the cache. When the cache is
+// backed by a store, the entries in the store are counted.
+func (c *SessionCache) Size() int {
+	if c.store != nil {
+		ids, err := c.store.List(sessionBucket)
+		if err != nil {
+			return 0
+		}
+		return len(ids)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.Entries)
+}
+
 // Add adds user to the cache.
 func (c *SessionCache) Add(sessionID string, u *user.User) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.store != nil {
+		entry := &storedEntry{
+			CreatedAt: time.Now().UTC(),
+			User:      newStoredUser(u),
+		}
+		// The session entry lives as long as the user token.
+		lifetime := int(u.Claims.ExpiresAt - entry.CreatedAt.Unix())
+		return putStoredEntry(c.store, sessionBucket, sessionID, entry, lifetime)
+	}
 	if c.Entries == nil {
 		return errors.New("session cache is not available")
 	}
@@ -146,6 +179,15 @@ func (c *SessionCache) Add(sessionID string, u *user.User) error {
 func (c *SessionCache) Delete(sessionID string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.store != nil {
+		if err := c.store.Delete(sessionBucket, sessionID); err != nil {
+			if isStoreEntryNotFound(err) {
+				return errors.New("cached session id not found")
+			}
+			return err
+		}
+		return nil
+	}
 	if c.Entries == nil {
 		return errors.New("session cache is not available")
 	}
@@ -164,6 +206,9 @@ func (c *SessionCache) Get(sessionID string) (*user.User, error) {
 	}
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	if c.store != nil {
+		return c.getStored(sessionID)
+	}
 	if entry, exists := c.Entries[sessionID]; exists {
 		if err := entry.Valid(); err != nil {
 			return nil, fmt.Errorf("cached session id error: %s", err)
@@ -173,6 +218,32 @@ func (c *SessionCache) Get(sessionID string) (*user.User, error) {
 	return nil, errors.New("cached session id not found")
 }
 
+func (c *SessionCache) getStored(sessionID string) (*user.User, error) {
+	stored, err := getStoredEntry(c.store, sessionBucket, sessionID)
+	if err != nil {
+		if isStoreEntryNotFound(err) {
+			return nil, errors.New("cached session id not found")
+		}
+		return nil, err
+	}
+	if stored.User == nil {
+		return nil, errors.New("cached session id not found")
+	}
+	u, err := stored.User.getUser()
+	if err != nil {
+		return nil, err
+	}
+	entry := &SessionCacheEntry{
+		sessionID: sessionID,
+		createdAt: stored.CreatedAt,
+		user:      u,
+	}
+	if err := entry.Valid(); err != nil {
+		return nil, fmt.Errorf("cached session id error: %s", err)
+	}
+	return u, nil
+}
+
 // Valid checks whether SessionCacheEntry is not expired.
 func (e *SessionCacheEntry) Valid() error {
 	if err := e.user.Claims.Valid(); err != nil {
@@ -0,0 +1,190 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"time"
+)
+
+const (
+	sessionBucket      = "sessions"
+	sandboxBucket      = "sandboxes"
+	registrationBucket = "registrations"
+	recoveryBucket     = "recoveries"
+	refreshTokenBucket = "refresh_tokens"
+
+	defaultRedisStorePrefix = "authp:"
+)
+
+// Store is a storage backing SessionCache, SandboxCache, RegistrationCache,
+// RecoveryCache and RefreshTokenCache. When the caches share a Store, their entries survive
+// restarts and are available to all portal instances using the same store.
+type Store interface {
+	// Put saves the value under the key in the bucket. The value expires
+	// after the lifetime (in seconds) elapses.
+	Put(bucket, key string, value []byte, lifetime int) error
+	// Get returns the unexpired value stored under the key in the bucket.
+	Get(bucket, key string) ([]byte, error)
+	// Delete removes the key from the bucket.
+	Delete(bucket, key string) error
+	// List returns the unexpired keys in the bucket.
+	List(bucket string) ([]string, error)
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// StoreConfig is the configuration of Store.
+type StoreConfig struct {
+	// Kind is the type of the store, i.e. file or redis.
+	Kind string `json:"kind,omitempty" xml:"kind,omitempty" yaml:"kind,omitempty"`
+	// Path is the path to the database file of the file store.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// Address is the host:port of the redis server.
+	Address string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	// Password is the password used to authenticate to the redis server.
+	Password string `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
+	// Database is the redis database number.
+	Database int `json:"database,omitempty" xml:"database,omitempty" yaml:"database,omitempty"`
+	// Prefix is the prefix of the redis keys. The default is "authp:".
+	Prefix string `json:"prefix,omitempty" xml:"prefix,omitempty" yaml:"prefix,omitempty"`
+}
+
+// Validate validates StoreConfig.
+func (cfg *StoreConfig) Validate() error {
+	switch cfg.Kind {
+	case "file":
+		if cfg.Path == "" {
+			return errors.ErrCacheStorePathEmpty
+		}
+	case "redis":
+		if cfg.Address == "" {
+			return errors.ErrCacheStoreAddressEmpty
+		}
+		if cfg.Prefix == "" {
+			cfg.Prefix = defaultRedisStorePrefix
+		}
+	default:
+		return errors.ErrCacheStoreKindUnsupported.WithArgs(cfg.Kind)
+	}
+	return nil
+}
+
+// NewStore returns an instance of Store based on the provided configuration.
+func NewStore(cfg *StoreConfig) (Store, error) {
+	if cfg == nil {
+		return nil, errors.ErrCacheStoreConfigNil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Kind {
+	case "redis":
+		return NewRedisStore(cfg.Address, cfg.Password, cfg.Database, cfg.Prefix)
+	}
+	return NewFileStore(cfg.Path)
+}
+
+// storedEntry is the representation of a cache entry in Store.
+type storedEntry struct {
+	CreatedAt       time.Time         `json:"created_at"`
+	ExpiresAt       time.Time         `json:"expires_at,omitempty"`
+	Expired         bool              `json:"expired,omitempty"`
+	FamilyID        string            `json:"family_id,omitempty"`
+	FamilyExpiresAt time.Time         `json:"family_expires_at,omitempty"`
+	Used            bool              `json:"used,omitempty"`
+	User            *storedUser       `json:"user,omitempty"`
+	Fields          map[string]string `json:"fields,omitempty"`
+}
+
+// storedUser holds the state of user.User necessary to restore it.
+type storedUser struct {
+	Claims        map[string]interface{} `json:"claims,omitempty"`
+	Token         string                 `json:"token,omitempty"`
+	TokenName     string                 `json:"token_name,omitempty"`
+	TokenSource   string                 `json:"token_source,omitempty"`
+	Authenticator user.Authenticator     `json:"authenticator,omitempty"`
+	Checkpoints   []*user.Checkpoint     `json:"checkpoints,omitempty"`
+	Authorized    bool                   `json:"authorized,omitempty"`
+	FrontendLinks []string               `json:"frontend_links,omitempty"`
+	Locked        bool                   `json:"locked,omitempty"`
+}
+
+func newStoredUser(usr *user.User) *storedUser {
+	return &storedUser{
+		Claims:        usr.AsMap(),
+		Token:         usr.Token,
+		TokenName:     usr.TokenName,
+		TokenSource:   usr.TokenSource,
+		Authenticator: usr.Authenticator,
+		Checkpoints:   usr.Checkpoints,
+		Authorized:    usr.Authorized,
+		FrontendLinks: usr.FrontendLinks,
+		Locked:        usr.Locked,
+	}
+}
+
+func (u *storedUser) getUser() (*user.User, error) {
+	usr, err := user.NewUser(u.Claims)
+	if err != nil {
+		return nil, errors.ErrCacheStoreEntryDecode.WithArgs(err)
+	}
+	usr.Token = u.Token
+	usr.TokenName = u.TokenName
+	usr.TokenSource = u.TokenSource
+	usr.Authenticator = u.Authenticator
+	usr.Checkpoints = u.Checkpoints
+	usr.Authorized = u.Authorized
+	usr.FrontendLinks = u.FrontendLinks
+	usr.Locked = u.Locked
+	return usr, nil
+}
+
+func putStoredEntry(s Store, bucket, key string, e *storedEntry, lifetime int) error {
+	if lifetime < 1 {
+		return errors.ErrCacheStoreEntryLifetimeInvalid.WithArgs(lifetime)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.ErrCacheStoreEntryEncode.WithArgs(err)
+	}
+	return s.Put(bucket, key, b, lifetime)
+}
+
+func getStoredEntry(s Store, bucket, key string) (*storedEntry, error) {
+	b, err := s.Get(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	e := &storedEntry{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, errors.ErrCacheStoreEntryDecode.WithArgs(err)
+	}
+	return e, nil
+}
+
+// isStoreEntryNotFound returns true when the error indicates that the entry
+// is not in the store.
+func isStoreEntryNotFound(err error) bool {
+	return err == errors.ErrCacheStoreEntryNotFound
+}
+
+// getRemainingLifetime returns the number of seconds left until the entry
+// created at the provided time reaches the maximum lifetime.
+func getRemainingLifetime(createdAt time.Time, max int) int {
+	return max - int(time.Now().UTC().Unix()-createdAt.Unix())
+}
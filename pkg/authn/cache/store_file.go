@@ -0,0 +1,178 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fileStoreRecord is a value stored in FileStore.
+type fileStoreRecord struct {
+	Value     []byte    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FileStore is an embedded Store keeping its buckets in a single database
+// file. The file is replaced atomically on every change, so the entries
+// survive restarts. The file must not be shared by portal instances, the
+// replicas running behind a load balancer should use RedisStore instead.
+type FileStore struct {
+	mu      sync.Mutex
+	path    string
+	buckets map[string]map[string]*fileStoreRecord
+}
+
+// NewFileStore returns an instance of FileStore. The existing unexpired
+// entries are being loaded from the file at the provided path.
+func NewFileStore(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, errors.ErrCacheStorePathEmpty
+	}
+	s := &FileStore{
+		path:    path,
+		buckets: make(map[string]map[string]*fileStoreRecord),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) load() error {
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.ErrCacheStoreLoad.WithArgs(s.path, err)
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(b, &s.buckets); err != nil {
+		return errors.ErrCacheStoreLoad.WithArgs(s.path, err)
+	}
+	s.purge(time.Now().UTC())
+	return nil
+}
+
+// purge removes expired records and empty buckets.
+func (s *FileStore) purge(now time.Time) {
+	for bucket, records := range s.buckets {
+		for key, record := range records {
+			if record == nil || record.ExpiresAt.Before(now) {
+				delete(records, key)
+			}
+		}
+		if len(records) == 0 {
+			delete(s.buckets, bucket)
+		}
+	}
+}
+
+func (s *FileStore) commit() error {
+	s.purge(time.Now().UTC())
+	b, err := json.Marshal(s.buckets)
+	if err != nil {
+		return errors.ErrCacheStoreCommit.WithArgs(s.path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return errors.ErrCacheStoreCommit.WithArgs(s.path, err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, b, 0600); err != nil {
+		return errors.ErrCacheStoreCommit.WithArgs(s.path, err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return errors.ErrCacheStoreCommit.WithArgs(s.path, err)
+	}
+	return nil
+}
+
+// Put saves the value under the key in the bucket and persists the store.
+func (s *FileStore) Put(bucket, key string, value []byte, lifetime int) error {
+	if lifetime < 1 {
+		return errors.ErrCacheStoreEntryLifetimeInvalid.WithArgs(lifetime)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, exists := s.buckets[bucket]
+	if !exists {
+		records = make(map[string]*fileStoreRecord)
+		s.buckets[bucket] = records
+	}
+	records[key] = &fileStoreRecord{
+		Value:     value,
+		ExpiresAt: time.Now().UTC().Add(time.Duration(lifetime) * time.Second),
+	}
+	return s.commit()
+}
+
+// Get returns the unexpired value stored under the key in the bucket.
+func (s *FileStore) Get(bucket, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, exists := s.buckets[bucket][key]
+	if !exists || record.ExpiresAt.Before(time.Now().UTC()) {
+		return nil, errors.ErrCacheStoreEntryNotFound
+	}
+	return record.Value, nil
+}
+
+// Delete removes the key from the bucket and persists the store.
+func (s *FileStore) Delete(bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.buckets[bucket][key]; !exists {
+		return errors.ErrCacheStoreEntryNotFound
+	}
+	delete(s.buckets[bucket], key)
+	return s.commit()
+}
+
+// List returns the unexpired keys in the bucket.
+func (s *FileStore) List(bucket string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	keys := []string{}
+	for key, record := range s.buckets[bucket] {
+		if record.ExpiresAt.Before(now) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Close persists the store.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commit()
+}
+
+// GetPath returns the path to the file backing FileStore.
+func (s *FileStore) GetPath() string {
+	return s.path
+}
@@ -0,0 +1,303 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultRedisStoreTimeout = 5 * time.Second
+
+// redisError is an error reply of a redis server.
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// RedisStore is a Store keeping its entries in a redis server. It speaks
+// the redis protocol (RESP) directly over a single connection, which is
+// re-established when it breaks.
+type RedisStore struct {
+	mu       sync.Mutex
+	address  string
+	password string
+	database int
+	prefix   string
+	timeout  time.Duration
+	conn     net.Conn
+	reader   *bufio.Reader
+}
+
+// NewRedisStore returns an instance of RedisStore connected to the redis
+// server at the provided address.
+func NewRedisStore(address, password string, database int, prefix string) (*RedisStore, error) {
+	if address == "" {
+		return nil, errors.ErrCacheStoreAddressEmpty
+	}
+	if prefix == "" {
+		prefix = defaultRedisStorePrefix
+	}
+	s := &RedisStore{
+		address:  address,
+		password: password,
+		database: database,
+		prefix:   prefix,
+		timeout:  defaultRedisStoreTimeout,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.do("PING"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *RedisStore) connect() error {
+	conn, err := net.DialTimeout("tcp", s.address, s.timeout)
+	if err != nil {
+		return errors.ErrCacheStoreConnect.WithArgs(s.address, err)
+	}
+	s.conn = conn
+	s.reader = bufio.NewReader(conn)
+	if s.password != "" {
+		if _, err := s.exec("AUTH", s.password); err != nil {
+			s.disconnect()
+			return errors.ErrCacheStoreConnect.WithArgs(s.address, err)
+		}
+	}
+	if s.database > 0 {
+		if _, err := s.exec("SELECT", strconv.Itoa(s.database)); err != nil {
+			s.disconnect()
+			return errors.ErrCacheStoreConnect.WithArgs(s.address, err)
+		}
+	}
+	return nil
+}
+
+func (s *RedisStore) disconnect() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.conn = nil
+	s.reader = nil
+}
+
+// do runs the command and returns its reply. A command failing due to
+// a broken connection is retried once over a new connection.
+func (s *RedisStore) do(args ...string) (interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		if s.conn == nil {
+			if err := s.connect(); err != nil {
+				return nil, err
+			}
+		}
+		reply, err := s.exec(args...)
+		if err == nil {
+			return reply, nil
+		}
+		if _, ok := err.(redisError); ok {
+			return nil, errors.ErrCacheStoreCommand.WithArgs(args[0], err)
+		}
+		s.disconnect()
+		if attempt > 0 {
+			return nil, errors.ErrCacheStoreCommand.WithArgs(args[0], err)
+		}
+	}
+}
+
+func (s *RedisStore) exec(args ...string) (interface{}, error) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, arg := range args {
+		sb.WriteString(fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg))
+	}
+	s.conn.SetDeadline(time.Now().Add(s.timeout))
+	if _, err := io.WriteString(s.conn, sb.String()); err != nil {
+		return nil, err
+	}
+	return readRedisReply(s.reader)
+}
+
+// readRedisReply parses a RESP reply.
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, errors.ErrCacheStoreUnexpectedResponse.WithArgs(line)
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		i, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, errors.ErrCacheStoreUnexpectedResponse.WithArgs(line)
+		}
+		return i, nil
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errors.ErrCacheStoreUnexpectedResponse.WithArgs(line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:size], nil
+	case '*':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errors.ErrCacheStoreUnexpectedResponse.WithArgs(line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, size)
+		for i := range items {
+			item, err := readRedisReply(r)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, errors.ErrCacheStoreUnexpectedResponse.WithArgs(line)
+}
+
+func (s *RedisStore) getKey(bucket, key string) string {
+	return s.prefix + bucket + ":" + key
+}
+
+// Put saves the value under the key in the bucket.
+func (s *RedisStore) Put(bucket, key string, value []byte, lifetime int) error {
+	if lifetime < 1 {
+		return errors.ErrCacheStoreEntryLifetimeInvalid.WithArgs(lifetime)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.do("SET", s.getKey(bucket, key), string(value), "EX", strconv.Itoa(lifetime))
+	return err
+}
+
+// Get returns the unexpired value stored under the key in the bucket.
+func (s *RedisStore) Get(bucket, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reply, err := s.do("GET", s.getKey(bucket, key))
+	if err != nil {
+		return nil, err
+	}
+	switch value := reply.(type) {
+	case nil:
+		return nil, errors.ErrCacheStoreEntryNotFound
+	case []byte:
+		return value, nil
+	}
+	return nil, errors.ErrCacheStoreUnexpectedResponse.WithArgs(reply)
+}
+
+// Delete removes the key from the bucket.
+func (s *RedisStore) Delete(bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reply, err := s.do("DEL", s.getKey(bucket, key))
+	if err != nil {
+		return err
+	}
+	if count, ok := reply.(int64); !ok || count == 0 {
+		return errors.ErrCacheStoreEntryNotFound
+	}
+	return nil
+}
+
+// List returns the unexpired keys in the bucket.
+func (s *RedisStore) List(bucket string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keyPrefix := s.getKey(bucket, "")
+	pattern := escapeRedisPattern(keyPrefix) + "*"
+	keys := []string{}
+	seen := make(map[string]bool)
+	cursor := "0"
+	for {
+		reply, err := s.do("SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			return nil, err
+		}
+		items, ok := reply.([]interface{})
+		if !ok || len(items) != 2 {
+			return nil, errors.ErrCacheStoreUnexpectedResponse.WithArgs(reply)
+		}
+		next, ok := items[0].([]byte)
+		if !ok {
+			return nil, errors.ErrCacheStoreUnexpectedResponse.WithArgs(reply)
+		}
+		found, _ := items[1].([]interface{})
+		for _, item := range found {
+			// The keys may be returned by SCAN more than once.
+			if k, ok := item.([]byte); ok && !seen[string(k)] {
+				seen[string(k)] = true
+				keys = append(keys, strings.TrimPrefix(string(k), keyPrefix))
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" {
+			break
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Close closes the connection to the redis server.
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disconnect()
+	return nil
+}
+
+// escapeRedisPattern escapes the glob-style special characters of the
+// pattern used by the SCAN command.
+func escapeRedisPattern(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
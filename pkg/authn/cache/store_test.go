@@ -0,0 +1,372 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+)
+
+// fakeRedisServer is an in-process server implementing the subset of the
+// redis protocol used by RedisStore.
+type fakeRedisServer struct {
+	mu       sync.Mutex
+	listener net.Listener
+	password string
+	values   map[string]string
+	expires  map[string]time.Time
+}
+
+func newFakeRedisServer(t *testing.T, password string) *fakeRedisServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed starting fake redis server: %v", err)
+	}
+	srv := &fakeRedisServer{
+		listener: listener,
+		password: password,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	go srv.serve()
+	t.Cleanup(func() { listener.Close() })
+	return srv
+}
+
+func (srv *fakeRedisServer) serve() {
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			return
+		}
+		go srv.handle(conn)
+	}
+}
+
+func (srv *fakeRedisServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authenticated := srv.password == ""
+	for {
+		reply, err := readRedisReply(r)
+		if err != nil {
+			return
+		}
+		items, ok := reply.([]interface{})
+		if !ok || len(items) == 0 {
+			return
+		}
+		args := []string{}
+		for _, item := range items {
+			args = append(args, string(item.([]byte)))
+		}
+		cmd := strings.ToUpper(args[0])
+		if !authenticated && cmd != "AUTH" {
+			conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+			continue
+		}
+		if cmd == "AUTH" {
+			if args[1] != srv.password {
+				conn.Write([]byte("-WRONGPASS invalid password\r\n"))
+				continue
+			}
+			authenticated = true
+		}
+		conn.Write([]byte(srv.exec(cmd, args[1:])))
+	}
+}
+
+func (srv *fakeRedisServer) exec(cmd string, args []string) string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for k, exp := range srv.expires {
+		if exp.Before(time.Now()) {
+			delete(srv.values, k)
+			delete(srv.expires, k)
+		}
+	}
+	switch cmd {
+	case "PING":
+		return "+PONG\r\n"
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "SET":
+		srv.values[args[0]] = args[1]
+		if len(args) == 4 && strings.ToUpper(args[2]) == "EX" {
+			seconds, _ := strconv.Atoi(args[3])
+			srv.expires[args[0]] = time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+		return "+OK\r\n"
+	case "GET":
+		v, exists := srv.values[args[0]]
+		if !exists {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case "DEL":
+		if _, exists := srv.values[args[0]]; !exists {
+			return ":0\r\n"
+		}
+		delete(srv.values, args[0])
+		delete(srv.expires, args[0])
+		return ":1\r\n"
+	case "SCAN":
+		keys := []string{}
+		for k := range srv.values {
+			if matched, _ := path.Match(args[2], k); matched {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		var sb strings.Builder
+		sb.WriteString("*2\r\n$1\r\n0\r\n")
+		sb.WriteString(fmt.Sprintf("*%d\r\n", len(keys)))
+		for _, k := range keys {
+			sb.WriteString(fmt.Sprintf("$%d\r\n%s\r\n", len(k), k))
+		}
+		return sb.String()
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", cmd)
+}
+
+func newTestStores(t *testing.T) map[string]func() Store {
+	tmpDir, err := tests.TempDir("TestCacheStore")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	dbPath := filepath.Join(tmpDir, "cache.db")
+	srv := newFakeRedisServer(t, "foobar")
+	return map[string]func() Store{
+		"file": func() Store {
+			s, err := NewFileStore(dbPath)
+			if err != nil {
+				t.Fatalf("failed creating file store: %v", err)
+			}
+			return s
+		},
+		"redis": func() Store {
+			s, err := NewRedisStore(srv.listener.Addr().String(), "foobar", 1, "")
+			if err != nil {
+				t.Fatalf("failed creating redis store: %v", err)
+			}
+			return s
+		},
+	}
+}
+
+func TestStore(t *testing.T) {
+	for kind, newStore := range newTestStores(t) {
+		t.Run(kind, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("store: %s", kind)}
+			s := newStore()
+			if err := s.Put("foo", "bar", []byte(`{"baz":"qux"}`), 0); err == nil {
+				t.Fatalf("expected error for zero lifetime")
+			}
+			for _, k := range []string{"bar", "baz"} {
+				if err := s.Put("foo", k, []byte(k), 60); err != nil {
+					t.Fatalf("unexpected put error: %v", err)
+				}
+			}
+			if err := s.Put("other", "qux", []byte("qux"), 60); err != nil {
+				t.Fatalf("unexpected put error: %v", err)
+			}
+
+			// Another instance, e.g. after restart or on another
+			// replica, sees the entries.
+			s.Close()
+			s = newStore()
+			defer s.Close()
+
+			got, err := s.Get("foo", "bar")
+			if err != nil {
+				t.Fatalf("unexpected get error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "get", "bar", string(got), msgs)
+
+			keys, err := s.List("foo")
+			if err != nil {
+				t.Fatalf("unexpected list error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "list", []string{"bar", "baz"}, keys, msgs)
+
+			if err := s.Delete("foo", "bar"); err != nil {
+				t.Fatalf("unexpected delete error: %v", err)
+			}
+			_, err = s.Get("foo", "bar")
+			tests.EvalErrWithLog(t, err, "get deleted", true, errors.ErrCacheStoreEntryNotFound, msgs)
+			err = s.Delete("foo", "bar")
+			tests.EvalErrWithLog(t, err, "delete deleted", true, errors.ErrCacheStoreEntryNotFound, msgs)
+		})
+	}
+}
+
+func TestRedisStoreAuth(t *testing.T) {
+	srv := newFakeRedisServer(t, "foobar")
+	_, err := NewRedisStore(srv.listener.Addr().String(), "barfoo", 0, "")
+	if err == nil {
+		t.Fatalf("expected authentication error")
+	}
+	if !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCacheWithStore(t *testing.T) {
+	for kind, newStore := range newTestStores(t) {
+		t.Run(kind, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("store: %s", kind)}
+			if kind == "file" {
+				// The file store is not shared by portal instances.
+				s := newStore()
+				newStore = func() Store { return s }
+			}
+
+			// Two caches sharing a store act as two portal replicas.
+			first, second := NewSandboxCache(), NewSandboxCache()
+			first.SetStore(newStore())
+			second.SetStore(newStore())
+
+			sandboxID := util.GetRandomStringFromRange(32, 96)
+			usr := testutils.NewTestUser()
+			usr.Authenticator.TempSecret = "foobar"
+			usr.Checkpoints = []*user.Checkpoint{{ID: 1, Name: "mfa", Type: "mfa"}}
+			if err := first.Add(sandboxID, usr); err != nil {
+				t.Fatalf("unexpected add error: %v", err)
+			}
+			got, err := second.Get(sandboxID)
+			if err != nil {
+				t.Fatalf("unexpected get error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "sandbox user", usr.AsMap(), got.AsMap(), msgs)
+			tests.EvalObjectsWithLog(t, "sandbox secret", "foobar", got.Authenticator.TempSecret, msgs)
+
+			got.Checkpoints[0].Passed = true
+			if err := second.Update(sandboxID, got); err != nil {
+				t.Fatalf("unexpected update error: %v", err)
+			}
+			got, err = first.Get(sandboxID)
+			if err != nil {
+				t.Fatalf("unexpected get error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "sandbox checkpoint", true, got.Checkpoints[0].Passed, msgs)
+
+			second.Expire(sandboxID)
+			_, err = first.Get(sandboxID)
+			tests.EvalErrWithLog(t, err, "expired sandbox", true, fmt.Errorf("sandbox cached entry is no longer in use"), msgs)
+
+			sessions := NewSessionCache()
+			sessions.SetStore(newStore())
+			sessionID := util.GetRandomStringFromRange(32, 96)
+			if err := sessions.Add(sessionID, usr); err != nil {
+				t.Fatalf("unexpected add error: %v", err)
+			}
+			got, err = sessions.Get(sessionID)
+			if err != nil {
+				t.Fatalf("unexpected get error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "session user", usr.AsMap(), got.AsMap(), msgs)
+			if err := sessions.Delete(sessionID); err != nil {
+				t.Fatalf("unexpected delete error: %v", err)
+			}
+			_, err = sessions.Get(sessionID)
+			tests.EvalErrWithLog(t, err, "deleted session", true, fmt.Errorf("cached session id not found"), msgs)
+
+			registrations := []*RegistrationCache{NewRegistrationCache(), NewRegistrationCache()}
+			for _, c := range registrations {
+				c.SetStore(newStore())
+			}
+			fields := map[string]string{
+				"username": "jsmith",
+				"password": "foobar",
+				"email":    "jsmith@localhost.localdomain",
+			}
+			registrationID := util.GetRandomStringFromRange(32, 96)
+			if err := registrations[0].Add(registrationID, fields); err != nil {
+				t.Fatalf("unexpected add error: %v", err)
+			}
+			err = registrations[1].Add(util.GetRandomStringFromRange(32, 96), fields)
+			tests.EvalErrWithLog(t, err, "duplicate registration", true, fmt.Errorf("a record with this username already exists"), msgs)
+			gotFields, err := registrations[1].Get(registrationID)
+			if err != nil {
+				t.Fatalf("unexpected get error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "registration", fields, gotFields, msgs)
+
+			recoveries := []*RecoveryCache{NewRecoveryCache(), NewRecoveryCache()}
+			for _, c := range recoveries {
+				c.SetStore(newStore())
+			}
+			recoveryFields := map[string]string{
+				"username": "jsmith",
+				"email":    "jsmith@localhost.localdomain",
+				"realm":    "local",
+			}
+			recoveryID := util.GetRandomStringFromRange(64, 96)
+			if err := recoveries[0].Add(recoveryID, recoveryFields); err != nil {
+				t.Fatalf("unexpected add error: %v", err)
+			}
+			recoveryEntry, err := recoveries[1].Take(recoveryID)
+			if err != nil {
+				t.Fatalf("unexpected take error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "recovery", recoveryFields, recoveryEntry.GetUser(), msgs)
+			_, err = recoveries[0].Take(recoveryID)
+			tests.EvalErrWithLog(t, err, "taken recovery", true, fmt.Errorf("cached recovery id not found"), msgs)
+			if err := recoveries[1].Restore(recoveryEntry); err != nil {
+				t.Fatalf("unexpected restore error: %v", err)
+			}
+			gotFields, err = recoveries[0].Get(recoveryID)
+			if err != nil {
+				t.Fatalf("unexpected get error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "restored recovery", recoveryFields, gotFields, msgs)
+
+			refreshTokens := []*RefreshTokenCache{NewRefreshTokenCache(), NewRefreshTokenCache()}
+			for _, c := range refreshTokens {
+				c.SetStore(newStore())
+			}
+			tokenIDs := []string{}
+			for i := 0; i < 3; i++ {
+				tokenIDs = append(tokenIDs, util.GetRandomStringFromRange(64, 96))
+			}
+			if err := refreshTokens[0].Add(tokenIDs[0], "family", usr, 900); err != nil {
+				t.Fatalf("unexpected add error: %v", err)
+			}
+			got, err = refreshTokens[1].Rotate(tokenIDs[0], tokenIDs[1], 900)
+			if err != nil {
+				t.Fatalf("unexpected rotate error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "refresh token user", usr.AsMap(), got.AsMap(), msgs)
+			_, err = refreshTokens[0].Rotate(tokenIDs[0], tokenIDs[2], 900)
+			tests.EvalErrWithLog(t, err, "reused refresh token", true, errors.ErrRefreshTokenReused, msgs)
+			tests.EvalObjectsWithLog(t, "refresh token count", 0, refreshTokens[1].Size(), msgs)
+		})
+	}
+}
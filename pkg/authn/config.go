@@ -18,8 +18,9 @@ import (
 	// "time"
 
 	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
 	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
-	// "github.com/greenpau/go-authcrunch/pkg/authn/cache"
+	"github.com/greenpau/go-authcrunch/pkg/authn/cache"
 	"github.com/greenpau/go-authcrunch/pkg/authn/cookie"
 	"github.com/greenpau/go-authcrunch/pkg/authn/registration"
 	"github.com/greenpau/go-authcrunch/pkg/authn/transformer"
@@ -30,14 +31,25 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/kms"
 	"github.com/greenpau/go-authcrunch/pkg/messaging"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	cfgutil "github.com/greenpau/go-authcrunch/pkg/util/cfg"
 	// "go.uber.org/zap"
+	"net/url"
 	"strings"
 )
 
 // PortalConfig represents Portal configuration.
 type PortalConfig struct {
 	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// BaseURL is the external URL of the portal, e.g.
+	// https://auth.example.com/auth. It is used to build the links sent
+	// to users by email.
+	BaseURL string `json:"base_url,omitempty" xml:"base_url,omitempty" yaml:"base_url,omitempty"`
+	// Issuer is the value of the iss claim of the tokens issued by the
+	// portal and of the issuer in the OpenID discovery document. When
+	// empty, the iss claim of the tokens is derived from the request and
+	// the issuer in the discovery document is BaseURL.
+	Issuer string `json:"issuer,omitempty" xml:"issuer,omitempty" yaml:"issuer,omitempty"`
 	// UI holds the configuration for the user interface.
 	UI *ui.Parameters `json:"ui,omitempty" xml:"ui,omitempty" yaml:"ui,omitempty"`
 	// UserRegistrationConfig holds the configuration for the user registration.
@@ -62,6 +74,20 @@ type PortalConfig struct {
 	// API holds the configuration for API endpoints.
 	API *APIConfig `json:"api,omitempty" xml:"api,omitempty" yaml:"api,omitempty"`
 
+	// CacheStore holds the configuration for the store backing session,
+	// sandbox, registration, password recovery and refresh token caches.
+	// When it is not set, the caches are kept in memory.
+	CacheStore *cache.StoreConfig `json:"cache_store,omitempty" xml:"cache_store,omitempty" yaml:"cache_store,omitempty"`
+
+	// AuditConfig holds the configuration of the audit log recording
+	// authentication and account changes.
+	AuditConfig *audit.Config `json:"audit_config,omitempty" xml:"audit_config,omitempty" yaml:"audit_config,omitempty"`
+
+	// MetricsConfig holds the configuration of the endpoint serving
+	// metrics in Prometheus text exposition format. When it is not set,
+	// the endpoint is disabled.
+	MetricsConfig *metrics.Config `json:"metrics_config,omitempty" xml:"metrics_config,omitempty" yaml:"metrics_config,omitempty"`
+
 	// Holds raw crypto configuration.
 	cryptoRawConfigs []string
 
@@ -186,6 +212,30 @@ func (cfg *PortalConfig) Validate() error {
 		return err
 	}
 
+	if cfg.Issuer != "" {
+		if u, err := url.Parse(cfg.Issuer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return errors.ErrPortalConfigIssuerMalformed.WithArgs(cfg.Issuer)
+		}
+	}
+
+	if cfg.CacheStore != nil {
+		if err := cfg.CacheStore.Validate(); err != nil {
+			return errors.ErrCacheStoreConfig.WithArgs(cfg.Name, err)
+		}
+	}
+
+	if cfg.AuditConfig != nil {
+		if err := cfg.AuditConfig.Validate(); err != nil {
+			return errors.ErrAuditLogConfig.WithArgs(cfg.Name, err)
+		}
+	}
+
+	if cfg.MetricsConfig != nil {
+		if err := cfg.MetricsConfig.Validate(); err != nil {
+			return errors.ErrMetricsConfig.WithArgs(cfg.Name, err)
+		}
+	}
+
 	// Inialize user interface settings
 	if cfg.UI == nil {
 		cfg.UI = &ui.Parameters{}
@@ -0,0 +1,38 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/authn/validators"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"net/http"
+	"strings"
+)
+
+func validateEmailInputForm(r *http.Request, rr *requests.Request) error {
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return fmt.Errorf("Unsupported content type")
+	}
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("Failed parsing submitted form")
+	}
+	email := strings.TrimSpace(r.PostFormValue("email"))
+	if err := validators.ValidateUserInput("email", email, nil); err != nil {
+		return err
+	}
+	rr.User.NewEmail = email
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// emailVerificationLifetime is the lifetime of email verification links.
+const emailVerificationLifetime = 24 * time.Hour
+
+// emailVerificationClaims are the claims of email verification token.
+type emailVerificationClaims struct {
+	Realm     string `json:"realm"`
+	UserID    string `json:"uid"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// newEmailVerificationToken returns the claims encoded and signed with
+// HMAC-SHA256.
+func newEmailVerificationToken(secret []byte, claims *emailVerificationClaims) (string, error) {
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// parseEmailVerificationToken verifies the signature and the expiry of
+// email verification token and returns its claims.
+func parseEmailVerificationToken(secret []byte, s string, now time.Time) (*emailVerificationClaims, error) {
+	arr := strings.Split(s, ".")
+	if len(arr) != 2 {
+		return nil, errors.ErrEmailVerificationTokenMalformed
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(arr[1])
+	if err != nil {
+		return nil, errors.ErrEmailVerificationTokenMalformed
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(arr[0]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errors.ErrEmailVerificationTokenSignature
+	}
+	b, err := base64.RawURLEncoding.DecodeString(arr[0])
+	if err != nil {
+		return nil, errors.ErrEmailVerificationTokenMalformed
+	}
+	claims := &emailVerificationClaims{}
+	if err := json.Unmarshal(b, claims); err != nil {
+		return nil, errors.ErrEmailVerificationTokenMalformed
+	}
+	if now.Unix() > claims.ExpiresAt {
+		return nil, errors.ErrEmailVerificationTokenExpired
+	}
+	return claims, nil
+}
+
+// sendEmailVerification sends a signed, expiring verification link for the
+// email address of a user.
+func (p *Portal) sendEmailVerification(r *http.Request, rr *requests.Request, realm, userID, username, email string) error {
+	if p.verifySecret == nil {
+		return errors.ErrEmailVerificationDisabled
+	}
+	claims := &emailVerificationClaims{
+		Realm:     realm,
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().Add(emailVerificationLifetime).Unix(),
+	}
+	token, err := newEmailVerificationToken(p.verifySecret, claims)
+	if err != nil {
+		return err
+	}
+	verifyData := map[string]string{
+		"provider_name":      p.config.UserRegistrationConfig.EmailProvider,
+		"provider_type":      "email",
+		"template":           "email_verification",
+		"session_id":         rr.Upstream.SessionID,
+		"request_id":         rr.ID,
+		"verification_token": token,
+		"verification_url":   strings.TrimSuffix(p.config.BaseURL, "/") + "/verify",
+		"username":           username,
+		"email":              email,
+	}
+	verifyData["src_ip"] = addrutil.GetSourceAddress(r)
+	verifyData["src_conn_ip"] = addrutil.GetSourceConnAddress(r)
+	verifyData["timestamp"] = time.Now().UTC().Format(time.UnixDate)
+	return p.notify(verifyData)
+}
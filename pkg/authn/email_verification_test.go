@@ -0,0 +1,169 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"fmt"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends/local"
+	"github.com/greenpau/go-authcrunch/pkg/authn/registration"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailVerificationToken(t *testing.T) {
+	now := time.Now()
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	claims := &emailVerificationClaims{
+		Realm:     "local",
+		UserID:    "a3c2e6d1-6d7f-4a3b-9cb6-7c1e4c1e9b2f",
+		Email:     "jsmith@example.com",
+		ExpiresAt: now.Add(emailVerificationLifetime).Unix(),
+	}
+	token, err := newEmailVerificationToken(secret, claims)
+	if err != nil {
+		t.Fatalf("failed creating token: %v", err)
+	}
+
+	testcases := []struct {
+		name      string
+		secret    []byte
+		token     string
+		now       time.Time
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "valid token",
+			secret: secret,
+			token:  token,
+			now:    now,
+		},
+		{
+			name:      "expired token",
+			secret:    secret,
+			token:     token,
+			now:       now.Add(emailVerificationLifetime + time.Minute),
+			shouldErr: true,
+			err:       errors.ErrEmailVerificationTokenExpired,
+		},
+		{
+			name:      "token signed with another secret",
+			secret:    []byte("fedcba9876543210fedcba9876543210"),
+			token:     token,
+			now:       now,
+			shouldErr: true,
+			err:       errors.ErrEmailVerificationTokenSignature,
+		},
+		{
+			name:      "token with tampered payload",
+			secret:    secret,
+			token:     "e30" + token[strings.Index(token, "."):],
+			now:       now,
+			shouldErr: true,
+			err:       errors.ErrEmailVerificationTokenSignature,
+		},
+		{
+			name:      "malformed token",
+			secret:    secret,
+			token:     "foobar",
+			now:       now,
+			shouldErr: true,
+			err:       errors.ErrEmailVerificationTokenMalformed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseEmailVerificationToken(tc.secret, tc.token, tc.now)
+			if tests.EvalErrWithLog(t, err, "token", tc.shouldErr, tc.err, []string{}) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "claims", claims, got, []string{})
+		})
+	}
+}
+
+func TestConfigureEmailVerification(t *testing.T) {
+	testcases := []struct {
+		name      string
+		baseURL   string
+		secret    string
+		want      bool
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:    "email verification with base url",
+			baseURL: "https://auth.example.com/",
+			want:    true,
+		},
+		{
+			name: "email verification without base url",
+		},
+		{
+			name:      "email verification with secret and without base url",
+			secret:    "0123456789abcdef0123456789abcdef",
+			shouldErr: true,
+			err:       errors.ErrEmailVerificationConfig.WithArgs("myportal", "base url not found"),
+		},
+		{
+			name:      "email verification with malformed base url",
+			baseURL:   "auth.example.com",
+			shouldErr: true,
+			err:       errors.ErrEmailVerificationConfig.WithArgs("myportal", "base url is malformed"),
+		},
+	}
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			db, err := testutils.CreateTestDatabase("TestConfigureEmailVerification")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			cfg := &PortalConfig{
+				Name:    "myportal",
+				BaseURL: tc.baseURL,
+				BackendConfigs: []backends.Config{
+					{
+						Local: &local.Config{
+							Name:   "local_backend",
+							Method: "local",
+							Realm:  fmt.Sprintf("verification%d", i),
+							Path:   db.GetPath(),
+						},
+					},
+				},
+				UserRegistrationConfig: &registration.Config{
+					Dropbox:                 filepath.Join(filepath.Dir(db.GetPath()), "registrations.json"),
+					EmailProvider:           "localhost-smtp-server",
+					AdminEmails:             []string{"admin@localhost"},
+					EmailVerificationSecret: tc.secret,
+				},
+			}
+			portal, err := NewPortal(cfg, logutil.NewLogger())
+			if tests.EvalErrWithLog(t, err, "portal", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			defer portal.Close()
+			tests.EvalObjectsWithLog(t, "verification enabled", tc.want, portal.verifySecret != nil, msgs)
+		})
+	}
+}
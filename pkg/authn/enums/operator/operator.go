@@ -62,6 +62,24 @@ const (
 	// LookupAPIKey operator signals the retrieval of user identity associated
 	// with an API key
 	LookupAPIKey
+	// ListUsers operator signals the retrieval of a page of users.
+	ListUsers
+	// LookupUser operator signals the retrieval of a user by user id,
+	// username, or email address.
+	LookupUser
+	// DisableUser operator signals the disabling of a user.
+	DisableUser
+	// EnableUser operator signals the enabling of a user.
+	EnableUser
+	// UpdateUserRoles operator signals the replacement of user roles.
+	UpdateUserRoles
+	// UnlockUser operator signals the clearing of user lockout.
+	UnlockUser
+	// AddEmailAddress operator signals the addition of an email address.
+	AddEmailAddress
+	// ConfirmEmailAddress operator signals the confirmation of an email
+	// address.
+	ConfirmEmailAddress
 )
 
 // String returns string representation of an operator.
@@ -105,6 +123,22 @@ func (e Type) String() string {
 		return "IdentifyUser"
 	case LookupAPIKey:
 		return "LookupAPIKey"
+	case ListUsers:
+		return "ListUsers"
+	case LookupUser:
+		return "LookupUser"
+	case DisableUser:
+		return "DisableUser"
+	case EnableUser:
+		return "EnableUser"
+	case UpdateUserRoles:
+		return "UpdateUserRoles"
+	case UnlockUser:
+		return "UnlockUser"
+	case AddEmailAddress:
+		return "AddEmailAddress"
+	case ConfirmEmailAddress:
+		return "ConfirmEmailAddress"
 	}
 	return fmt.Sprintf("Type(%d)", int(e))
 }
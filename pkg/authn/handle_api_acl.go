@@ -0,0 +1,183 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// apiACLTestRequest is the body of the requests to the ACL test API. The
+// rules come either from the request or from the configured policy of a
+// portal or a gatekeeper. The claims come either from the token or the
+// claims map.
+type apiACLTestRequest struct {
+	Portal       string                   `json:"portal,omitempty"`
+	Gatekeeper   string                   `json:"gatekeeper,omitempty"`
+	Rules        []*acl.RuleConfiguration `json:"rules,omitempty"`
+	NetworkLists map[string][]string      `json:"network_lists,omitempty"`
+	DefaultAllow bool                     `json:"default_allow,omitempty"`
+	Token        string                   `json:"token,omitempty"`
+	Claims       map[string]interface{}   `json:"claims,omitempty"`
+	Method       string                   `json:"method,omitempty"`
+	Path         string                   `json:"path,omitempty"`
+	Addr         string                   `json:"addr,omitempty"`
+}
+
+func decodeAPIACLTestRequest(w http.ResponseWriter, r *http.Request) (*apiACLTestRequest, error) {
+	req := &apiACLTestRequest{}
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+	respDecoder := json.NewDecoder(r.Body)
+	respDecoder.DisallowUnknownFields()
+	if err := respDecoder.Decode(req); err != nil {
+		return nil, err
+	}
+	var policies int
+	for _, found := range []bool{req.Portal != "", req.Gatekeeper != "", len(req.Rules) > 0} {
+		if found {
+			policies++
+		}
+	}
+	switch {
+	case policies == 0:
+		return nil, fmt.Errorf("access list rules not found")
+	case policies > 1:
+		return nil, fmt.Errorf("portal, gatekeeper and rules are mutually exclusive")
+	case len(req.Rules) == 0 && (len(req.NetworkLists) > 0 || req.DefaultAllow):
+		return nil, fmt.Errorf("network lists and default action require rules")
+	}
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" && len(req.Claims) == 0 {
+		return nil, fmt.Errorf("token or claims not found")
+	}
+	return req, nil
+}
+
+// handleAPIACL handles the requests to evaluate access list rules against
+// the claims of a token without enforcing them, i.e.
+//
+//	POST /api/acl/test with {"rules": [...], "token": "...", "method": "GET", "path": "/"}
+//	POST /api/acl/test with {"gatekeeper": "mypolicy", "token": "...", "method": "GET", "path": "/"}
+//
+// The rules are either provided in the request, or are the access list of
+// the named portal or gatekeeper policy.
+//
+// The response holds the trace of every evaluated rule, the result of each
+// of the rule's conditions, and the final verdict.
+func (p *Portal) handleAPIACL(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
+	if !strings.HasSuffix(r.URL.Path, "/api/acl/test") || r.Method != http.MethodPost {
+		return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+	}
+
+	// The errors in the request and the rules are returned to the admin.
+	req, err := decodeAPIACLTestRequest(w, r)
+	if err != nil {
+		return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+	}
+
+	accessList, err := p.getTestAccessList(ctx, req)
+	if err != nil {
+		return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+	}
+
+	var subject *user.User
+	if req.Token != "" {
+		ar := requests.NewAuthorizationRequest()
+		ar.Token.Name = "access_token"
+		ar.Token.Payload = req.Token
+		subject, err = p.keystore.ParseToken(ar)
+	} else {
+		subject, err = user.NewUser(req.Claims)
+	}
+	if err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+	}
+
+	data := make(map[string]interface{})
+	for k, v := range subject.GetData() {
+		data[k] = v
+	}
+	if req.Method != "" {
+		data["method"] = strings.ToUpper(req.Method)
+	}
+	if req.Path != "" {
+		data["path"] = req.Path
+	}
+	if req.Addr != "" {
+		data["addr"] = req.Addr
+	}
+
+	explanation, err := accessList.Explain(ctx, data)
+	if err != nil {
+		return p.handleJSONError(ctx, w, http.StatusBadRequest, err.Error())
+	}
+
+	rr.Response.Code = http.StatusOK
+	resp := make(map[string]interface{})
+	resp["input"] = data
+	resp["explanation"] = explanation
+	return p.writeAPIResponse(w, rr, resp)
+}
+
+// getTestAccessList returns the access list evaluated by the ACL test API.
+func (p *Portal) getTestAccessList(ctx context.Context, req *apiACLTestRequest) (*acl.AccessList, error) {
+	switch {
+	case req.Portal == p.config.Name:
+		return p.accessList, nil
+	case req.Portal != "":
+		portal, err := portalRegistry.LookupPortal(req.Portal)
+		if err != nil {
+			return nil, err
+		}
+		return portal.accessList, nil
+	case req.Gatekeeper != "":
+		accessList, err := authz.LookupAccessList(req.Gatekeeper)
+		if err != nil {
+			return nil, err
+		}
+		return accessList, nil
+	}
+	return p.newTestAccessList(ctx, req)
+}
+
+func (p *Portal) newTestAccessList(ctx context.Context, req *apiACLTestRequest) (*acl.AccessList, error) {
+	accessList := acl.NewAccessList()
+	accessList.SetLogger(p.logger)
+	if req.DefaultAllow {
+		accessList.SetDefaultAllowAction()
+	}
+	var networkListNames []string
+	for name := range req.NetworkLists {
+		networkListNames = append(networkListNames, name)
+	}
+	sort.Strings(networkListNames)
+	for _, name := range networkListNames {
+		if err := accessList.AddNetworkList(name, req.NetworkLists[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := accessList.AddRules(ctx, req.Rules); err != nil {
+		return nil, err
+	}
+	return accessList, nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends/local"
+	"github.com/greenpau/go-authcrunch/pkg/authz"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleAPIACL(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestHandleAPIACL")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	cfg := &PortalConfig{
+		Name: "myportal",
+		BackendConfigs: []backends.Config{
+			{
+				Local: &local.Config{
+					Name:   "local_backend",
+					Method: "local",
+					Realm:  "apiacl",
+					Path:   db.GetPath(),
+				},
+			},
+		},
+	}
+	portal, err := NewPortal(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usr, err := user.NewUser(map[string]interface{}{
+		"sub":   "jsmith",
+		"email": "jsmith@localhost",
+		"roles": []interface{}{"authp/user"},
+		"exp":   float64(time.Now().Add(time.Hour).Unix()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := portal.keystore.SignToken(nil, nil, usr); err != nil {
+		t.Fatal(err)
+	}
+
+	gatekeeperCfg := &authz.PolicyConfig{
+		Name:        "apiaclgatekeeper",
+		AuthURLPath: "/auth",
+		AccessListRules: []*acl.RuleConfiguration{
+			{
+				Conditions: []string{"match roles authp/admin"},
+				Action:     "allow stop",
+			},
+		},
+	}
+	gatekeeperCfg.AddRawCryptoConfigs("key verify " + testutils.GetSharedKey())
+	gatekeeper, err := authz.NewGatekeeper(gatekeeperCfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gatekeeper.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	rules := `"rules": [
+		{"conditions": ["match roles authp/admin"], "action": "allow stop"},
+		{"conditions": ["match roles authp/user", "match method GET", "partial match path /public/"], "action": "allow stop"}
+	]`
+
+	testcases := []struct {
+		name      string
+		body      string
+		want      map[string]interface{}
+		shouldErr bool
+		err       string
+	}{
+		{
+			name: "evaluate token",
+			body: `{` + rules + `, "token": "` + usr.Token + `", "method": "get", "path": "/public/index.html"}`,
+			want: map[string]interface{}{
+				"allowed": true,
+				"verdict": "allowed by rule",
+				"rule":    "rule1",
+				"rules":   2,
+			},
+		},
+		{
+			name: "evaluate claims",
+			body: `{` + rules + `, "claims": {"sub": "jdoe", "roles": ["authp/user"]}, "method": "POST", "path": "/public/index.html"}`,
+			want: map[string]interface{}{
+				"allowed": false,
+				"verdict": "denied by default",
+				"rule":    "",
+				"rules":   2,
+			},
+		},
+		{
+			name: "evaluate portal policy",
+			body: `{"portal": "myportal", "claims": {"sub": "jdoe", "roles": ["authp/user"]}, "method": "GET", "path": "/"}`,
+			want: map[string]interface{}{
+				"allowed": true,
+				"verdict": "allowed by rule",
+				"rule":    "rule0",
+				"rules":   1,
+			},
+		},
+		{
+			name: "evaluate gatekeeper policy",
+			body: `{"gatekeeper": "apiaclgatekeeper", "claims": {"sub": "jdoe", "roles": ["authp/user"]}, "method": "GET", "path": "/"}`,
+			want: map[string]interface{}{
+				"allowed": false,
+				"verdict": "denied by default",
+				"rule":    "",
+				"rules":   1,
+			},
+		},
+		{
+			name:      "evaluate unknown gatekeeper policy",
+			body:      `{"gatekeeper": "foobar", "claims": {"sub": "jdoe"}}`,
+			shouldErr: true,
+			err:       "not found",
+		},
+		{
+			name:      "evaluate rules and portal policy",
+			body:      `{` + rules + `, "portal": "myportal", "claims": {"sub": "jdoe"}}`,
+			shouldErr: true,
+			err:       "mutually exclusive",
+		},
+		{
+			name:      "evaluate invalid token",
+			body:      `{` + rules + `, "token": "foobar"}`,
+			shouldErr: true,
+			err:       "Bad Request",
+		},
+		{
+			name:      "evaluate without claims",
+			body:      `{` + rules + `}`,
+			shouldErr: true,
+			err:       "token or claims not found",
+		},
+		{
+			name:      "evaluate without rules",
+			body:      `{"claims": {"sub": "jdoe"}}`,
+			shouldErr: true,
+			err:       "access list rules not found",
+		},
+		{
+			name:      "evaluate invalid rules",
+			body:      `{"rules": [{"conditions": ["match foo"], "action": "allow"}], "claims": {"sub": "jdoe"}}`,
+			shouldErr: true,
+			err:       "invalid rule syntax",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/acl/test", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			rr := requests.NewRequest()
+			if err := portal.handleAPIACL(context.Background(), w, r, rr, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp := make(map[string]interface{})
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed decoding response: %v", err)
+			}
+			if tc.shouldErr {
+				if w.Code != 400 {
+					t.Fatalf("expected status code 400, got %d: %v", w.Code, resp)
+				}
+				if msg, _ := resp["message"].(string); !strings.Contains(msg, tc.err) {
+					t.Fatalf("expected error %q, got %q", tc.err, msg)
+				}
+				return
+			}
+			explanation, _ := resp["explanation"].(map[string]interface{})
+			allowed, _ := explanation["allowed"].(bool)
+			verdict, _ := explanation["verdict"].(string)
+			rule, _ := explanation["rule"].(string)
+			evaluated, _ := explanation["rules"].([]interface{})
+			got := map[string]interface{}{
+				"allowed": allowed,
+				"verdict": verdict,
+				"rule":    rule,
+				"rules":   len(evaluated),
+			}
+			tests.EvalObjects(t, "explanation", tc.want, got)
+		})
+	}
+}
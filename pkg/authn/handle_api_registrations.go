@@ -0,0 +1,119 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"net/http"
+	"strings"
+)
+
+// apiRegistrationRequest is the body of the requests to the registrations
+// API.
+type apiRegistrationRequest struct {
+	Action string   `json:"action,omitempty"`
+	IDs    []string `json:"ids,omitempty"`
+	Note   string   `json:"note,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+func decodeAPIRegistrationRequest(w http.ResponseWriter, r *http.Request) (*apiRegistrationRequest, error) {
+	req := &apiRegistrationRequest{}
+	if r.ContentLength == 0 {
+		return req, nil
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 65536)
+	respDecoder := json.NewDecoder(r.Body)
+	respDecoder.DisallowUnknownFields()
+	if err := respDecoder.Decode(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func getRegistrationVerdict(action string) (string, error) {
+	switch action {
+	case "approve":
+		return "approved", nil
+	case "decline":
+		return "declined", nil
+	}
+	return "", fmt.Errorf("unsupported registration action %q", action)
+}
+
+// handleAPIRegistrations handles the requests to list, approve, or decline
+// pending registrations, i.e.
+//
+//	GET /api/registrations
+//	POST /api/registrations with {"action": "approve", "ids": [...]}
+//	POST /api/registrations/<registration_id>/approve
+//	POST /api/registrations/<registration_id>/decline
+func (p *Portal) handleAPIRegistrations(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
+	if p.registrar == nil {
+		return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+	}
+
+	s, err := getEndpoint(r.URL.Path, "/api/registrations")
+	if err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+	}
+	arr := strings.Split(strings.Trim(s, "/"), "/")
+
+	resp := make(map[string]interface{})
+	rr.Response.Code = http.StatusOK
+
+	switch {
+	case arr[0] == "" && r.Method == http.MethodGet:
+		entries, err := p.getPendingRegistrations()
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+		}
+		resp["registrations"] = entries
+		return p.writeAPIResponse(w, rr, resp)
+	case arr[0] == "" && r.Method == http.MethodPost:
+		req, err := decodeAPIRegistrationRequest(w, r)
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		verdict, err := getRegistrationVerdict(req.Action)
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		if len(req.IDs) < 1 {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, "registration ids not found")
+		}
+		resp["results"] = p.reviewRegistrations(r, rr, usr, req.IDs, verdict, strings.TrimSpace(req.Note), strings.TrimSpace(req.Reason))
+		return p.writeAPIResponse(w, rr, resp)
+	case len(arr) == 2 && arr[0] != "" && r.Method == http.MethodPost:
+		verdict, err := getRegistrationVerdict(arr[1])
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		req, err := decodeAPIRegistrationRequest(w, r)
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		if err := p.reviewRegistration(r, rr, usr, arr[0], verdict, strings.TrimSpace(req.Note), strings.TrimSpace(req.Reason)); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		resp["results"] = []*registrationReviewResult{{ID: arr[0], Verdict: verdict}}
+		return p.writeAPIResponse(w, rr, resp)
+	}
+	return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+}
@@ -0,0 +1,411 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiUserRequest is the body of the requests to the users API.
+type apiUserRequest struct {
+	Username     string   `json:"username,omitempty"`
+	Password     string   `json:"password,omitempty"`
+	PasswordHash string   `json:"password_hash,omitempty"`
+	Email        string   `json:"email,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Roles        []string `json:"roles,omitempty"`
+	Comment      string   `json:"comment,omitempty"`
+}
+
+// apiUsersEndpoint is the parsed path of the users API request, e.g.
+// /api/users/<user_id>/<resource>/<resource_id>.
+type apiUsersEndpoint struct {
+	userID     string
+	resource   string
+	resourceID string
+}
+
+func parseAPIUsersEndpoint(p string) (*apiUsersEndpoint, error) {
+	s, err := getEndpoint(p, "/api/users")
+	if err != nil {
+		return nil, err
+	}
+	s = strings.Trim(s, "/")
+	ep := &apiUsersEndpoint{}
+	if s == "" {
+		return ep, nil
+	}
+	arr := strings.Split(s, "/")
+	if len(arr) > 3 {
+		return nil, fmt.Errorf("malformed users endpoint")
+	}
+	for _, v := range arr {
+		if v == "" {
+			return nil, fmt.Errorf("malformed users endpoint")
+		}
+	}
+	ep.userID = arr[0]
+	if len(arr) > 1 {
+		ep.resource = arr[1]
+	}
+	if len(arr) > 2 {
+		ep.resourceID = arr[2]
+	}
+	return ep, nil
+}
+
+// getLocalBackendByRealm returns the local backend for the provided realm.
+// When the realm is empty, the first local backend is being returned.
+func (p *Portal) getLocalBackendByRealm(realm string) *backends.Backend {
+	for _, backend := range p.backends {
+		if backend.GetMethod() != "local" {
+			continue
+		}
+		if realm == "" || backend.GetRealm() == realm {
+			return backend
+		}
+	}
+	return nil
+}
+
+func decodeAPIUserRequest(w http.ResponseWriter, r *http.Request) (*apiUserRequest, error) {
+	req := &apiUserRequest{}
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	respDecoder := json.NewDecoder(r.Body)
+	respDecoder.DisallowUnknownFields()
+	if err := respDecoder.Decode(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func getSanitizedAPIKeys(bundle *identity.APIKeyBundle) []*identity.APIKey {
+	keys := []*identity.APIKey{}
+	for _, k := range bundle.Get() {
+		keys = append(keys, &identity.APIKey{
+			ID:        k.ID,
+			Usage:     k.Usage,
+			Comment:   k.Comment,
+			Expired:   k.Expired,
+			ExpiredAt: k.ExpiredAt,
+			CreatedAt: k.CreatedAt,
+		})
+	}
+	return keys
+}
+
+func getSanitizedMfaTokens(bundle *identity.MfaTokenBundle) []*identity.MfaToken {
+	tokens := []*identity.MfaToken{}
+	for _, t := range bundle.Get() {
+		tokens = append(tokens, &identity.MfaToken{
+			ID:        t.ID,
+			Type:      t.Type,
+			Algorithm: t.Algorithm,
+			Comment:   t.Comment,
+			Period:    t.Period,
+			Digits:    t.Digits,
+			Expired:   t.Expired,
+			ExpiredAt: t.ExpiredAt,
+			CreatedAt: t.CreatedAt,
+			Device:    t.Device,
+		})
+	}
+	return tokens
+}
+
+func (p *Portal) handleAPIUsers(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
+	ep, err := parseAPIUsersEndpoint(r.URL.Path)
+	if err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+	}
+
+	backend := p.getLocalBackendByRealm(r.URL.Query().Get("realm"))
+	if backend == nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, "local backend not found")
+	}
+
+	resp := make(map[string]interface{})
+
+	if ep.userID == "" {
+		switch r.Method {
+		case http.MethodGet:
+			if err := p.handleAPIListUsers(r, backend, resp); err != nil {
+				return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+			}
+			rr.Response.Code = http.StatusOK
+		case http.MethodPost:
+			req, err := decodeAPIUserRequest(w, r)
+			if err != nil {
+				return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+			}
+			ar := &requests.Request{
+				User: requests.User{
+					Username:     req.Username,
+					Password:     req.Password,
+					PasswordHash: req.PasswordHash,
+					Email:        req.Email,
+					FullName:     req.Name,
+					Roles:        req.Roles,
+				},
+			}
+			if err := backend.Request(operator.AddUser, ar); err != nil {
+				return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+			}
+			ar.Query.ID = req.Username
+			if err := backend.Request(operator.LookupUser, ar); err != nil {
+				return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+			}
+			resp["user"] = ar.Response.Payload.(*identity.User).GetMetadata()
+			rr.Response.Code = http.StatusCreated
+			ev := audit.NewEvent(audit.AdminAction, audit.OutcomeSuccess).WithDetail("action", "add_user")
+			ev.Username = req.Username
+			ev.Email = req.Email
+			p.logAuditEvent(r, rr, usr, ev)
+			p.logger.Info(
+				"user added via api",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.String("username", req.Username),
+				zap.String("email", req.Email),
+				zap.String("admin", usr.Claims.Email),
+			)
+		default:
+			return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		}
+		return p.writeAPIResponse(w, rr, resp)
+	}
+
+	// Resolve the user targeted by the request.
+	ar := &requests.Request{
+		Query: requests.Query{
+			ID: ep.userID,
+		},
+	}
+	if err := backend.Request(operator.LookupUser, ar); err != nil {
+		return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	}
+	targetUser := ar.Response.Payload.(*identity.User)
+	ar.Response.Payload = nil
+	// The looked up user is a copy. It is looked up again once changed.
+	getUpdatedUserMetadata := func() (*identity.UserMetadata, error) {
+		lr := &requests.Request{
+			Query: requests.Query{
+				ID: targetUser.ID,
+			},
+		}
+		if err := backend.Request(operator.LookupUser, lr); err != nil {
+			return nil, err
+		}
+		return lr.Response.Payload.(*identity.User).GetMetadata(), nil
+	}
+	isSelf := strings.EqualFold(ar.User.Email, usr.Claims.Email) || strings.EqualFold(ar.User.Username, usr.Claims.Subject)
+
+	rr.Response.Code = http.StatusOK
+	var ev *audit.Event
+	switch {
+	case ep.resource == "" && r.Method == http.MethodGet:
+		resp["user"] = targetUser.GetMetadata()
+	case ep.resource == "" && r.Method == http.MethodDelete:
+		if isSelf {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, "admin user cannot delete itself")
+		}
+		if err := backend.Request(operator.DeleteUser, ar); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		if err := p.revokeSubjectTokens(r, rr, ar.User.Username, "user deleted"); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+		}
+		resp["deleted"] = true
+		ev = audit.NewEvent(audit.AdminAction, audit.OutcomeSuccess).WithDetail("action", "delete_user")
+	case ep.resource == "disable" && r.Method == http.MethodPost:
+		if isSelf {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, "admin user cannot disable itself")
+		}
+		if err := backend.Request(operator.DisableUser, ar); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		ev = audit.NewEvent(audit.AdminAction, audit.OutcomeSuccess).WithDetail("action", "disable_user")
+		if err := p.revokeSubjectTokens(r, rr, ar.User.Username, "user disabled"); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+		}
+		metadata, err := getUpdatedUserMetadata()
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+		}
+		resp["user"] = metadata
+	case ep.resource == "enable" && r.Method == http.MethodPost:
+		if err := backend.Request(operator.EnableUser, ar); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		ev = audit.NewEvent(audit.AdminAction, audit.OutcomeSuccess).WithDetail("action", "enable_user")
+		metadata, err := getUpdatedUserMetadata()
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+		}
+		resp["user"] = metadata
+	case ep.resource == "unlock" && r.Method == http.MethodPost:
+		if err := backend.Request(operator.UnlockUser, ar); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		ev = audit.NewEvent(audit.AdminAction, audit.OutcomeSuccess).WithDetail("action", "unlock_user")
+		metadata, err := getUpdatedUserMetadata()
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+		}
+		resp["user"] = metadata
+	case ep.resource == "password" && r.Method == http.MethodPost:
+		req, err := decodeAPIUserRequest(w, r)
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		ar.User.Password = req.Password
+		ar.Flags.PasswordRecovery = true
+		if err := backend.Request(operator.ChangePassword, ar); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		ev = audit.NewEvent(audit.PasswordReset, audit.OutcomeSuccess)
+		if err := p.revokeSubjectTokens(r, rr, ar.User.Username, "password reset"); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+		}
+		metadata, err := getUpdatedUserMetadata()
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+		}
+		resp["user"] = metadata
+	case ep.resource == "roles" && (r.Method == http.MethodPut || r.Method == http.MethodPost):
+		req, err := decodeAPIUserRequest(w, r)
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		ar.User.Roles = req.Roles
+		if err := backend.Request(operator.UpdateUserRoles, ar); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		ev = audit.NewEvent(audit.AdminAction, audit.OutcomeSuccess).WithDetail("action", "update_roles").WithDetail("roles", ar.User.Roles)
+		metadata, err := getUpdatedUserMetadata()
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+		}
+		resp["user"] = metadata
+	case ep.resource == "tokens" && ep.resourceID == "" && r.Method == http.MethodDelete:
+		if err := p.revokeSubjectTokens(r, rr, ar.User.Username, "revoked by admin"); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+		}
+		resp["revoked"] = true
+	case ep.resource == "apikeys" && ep.resourceID == "" && r.Method == http.MethodGet:
+		ar.Key.Usage = "api"
+		if err := backend.Request(operator.GetAPIKeys, ar); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		resp["apikeys"] = getSanitizedAPIKeys(ar.Response.Payload.(*identity.APIKeyBundle))
+	case ep.resource == "apikeys" && ep.resourceID == "" && r.Method == http.MethodPost:
+		req, err := decodeAPIUserRequest(w, r)
+		if err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		ar.Key.Usage = "api"
+		ar.Key.Comment = strings.TrimSpace(req.Comment)
+		if err := backend.Request(operator.AddAPIKey, ar); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		resp["api_key"] = ar.Response.Payload.(string)
+		rr.Response.Code = http.StatusCreated
+		ev = audit.NewEvent(audit.APIKeyCreated, audit.OutcomeSuccess).WithDetail("comment", ar.Key.Comment)
+	case ep.resource == "apikeys" && ep.resourceID != "" && r.Method == http.MethodDelete:
+		ar.Key.ID = ep.resourceID
+		if err := backend.Request(operator.DeleteAPIKey, ar); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		ev = audit.NewEvent(audit.APIKeyDeleted, audit.OutcomeSuccess).WithDetail("key_id", ep.resourceID)
+		resp["deleted"] = true
+	case ep.resource == "mfa" && ep.resourceID == "" && r.Method == http.MethodGet:
+		if err := backend.Request(operator.GetMfaTokens, ar); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		resp["mfa_tokens"] = getSanitizedMfaTokens(ar.Response.Payload.(*identity.MfaTokenBundle))
+	case ep.resource == "mfa" && ep.resourceID != "" && r.Method == http.MethodDelete:
+		ar.MfaToken.ID = ep.resourceID
+		if err := backend.Request(operator.DeleteMfaToken, ar); err != nil {
+			return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+		}
+		ev = audit.NewEvent(audit.MfaTokenRemoved, audit.OutcomeSuccess).WithDetail("token_id", ep.resourceID)
+		resp["deleted"] = true
+	default:
+		return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+	}
+
+	if r.Method != http.MethodGet {
+		p.logger.Info(
+			"user modified via api",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("method", r.Method),
+			zap.String("url_path", r.URL.Path),
+			zap.String("username", ar.User.Username),
+			zap.String("admin", usr.Claims.Email),
+		)
+	}
+	if ev != nil {
+		ev.Username = ar.User.Username
+		ev.Email = ar.User.Email
+		p.logAuditEvent(r, rr, usr, ev)
+	}
+	return p.writeAPIResponse(w, rr, resp)
+}
+
+func (p *Portal) handleAPIListUsers(r *http.Request, backend *backends.Backend, resp map[string]interface{}) error {
+	ar := &requests.Request{}
+	for k, v := range map[string]*int{"offset": &ar.Query.Offset, "limit": &ar.Query.Limit} {
+		s := r.URL.Query().Get(k)
+		if s == "" {
+			continue
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("malformed %s: %v", k, err)
+		}
+		*v = i
+	}
+	if err := backend.Request(operator.ListUsers, ar); err != nil {
+		return err
+	}
+	bundle := ar.Response.Payload.(*identity.UserMetadataBundle)
+	resp["users"] = bundle.Get()
+	resp["total"] = bundle.Total()
+	resp["offset"] = ar.Query.Offset
+	resp["limit"] = ar.Query.Limit
+	return nil
+}
+
+func (p *Portal) writeAPIResponse(w http.ResponseWriter, rr *requests.Request, resp map[string]interface{}) error {
+	resp["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(rr.Response.Code)
+	w.Write(respBytes)
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"fmt"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"testing"
+)
+
+func TestParseAPIUsersEndpoint(t *testing.T) {
+	testcases := []struct {
+		name      string
+		path      string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "users collection",
+			path: "/auth/api/users",
+			want: map[string]interface{}{
+				"user_id":     "",
+				"resource":    "",
+				"resource_id": "",
+			},
+		},
+		{
+			name: "specific user",
+			path: "/auth/api/users/jsmith@gmail.com/",
+			want: map[string]interface{}{
+				"user_id":     "jsmith@gmail.com",
+				"resource":    "",
+				"resource_id": "",
+			},
+		},
+		{
+			name: "specific api key of a user",
+			path: "/auth/api/users/jsmith/apikeys/foobar",
+			want: map[string]interface{}{
+				"user_id":     "jsmith",
+				"resource":    "apikeys",
+				"resource_id": "foobar",
+			},
+		},
+		{
+			name:      "too many path elements",
+			path:      "/auth/api/users/jsmith/apikeys/foo/bar",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed users endpoint"),
+		},
+		{
+			name:      "empty path element",
+			path:      "/auth/api/users/jsmith//foo",
+			shouldErr: true,
+			err:       fmt.Errorf("malformed users endpoint"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			ep, err := parseAPIUsersEndpoint(tc.path)
+			if tests.EvalErrWithLog(t, err, "parse", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := map[string]interface{}{
+				"user_id":     ep.userID,
+				"resource":    ep.resource,
+				"resource_id": ep.resourceID,
+			}
+			tests.EvalObjectsWithLog(t, "endpoint", tc.want, got, msgs)
+		})
+	}
+}
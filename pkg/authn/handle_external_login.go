@@ -71,6 +71,7 @@ func (p *Portal) handleHTTPExternalLogin(ctx context.Context, w http.ResponseWri
 			zap.String("request_id", rr.ID),
 			zap.Error(err),
 		)
+		p.recordLogin(authRealm, false)
 		return p.handleHTTPError(ctx, w, r, rr, http.StatusUnauthorized)
 	}
 	switch rr.Response.Code {
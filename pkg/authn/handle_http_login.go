@@ -17,8 +17,10 @@ package authn
 import (
 	"context"
 	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
 	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"github.com/greenpau/go-authcrunch/pkg/user"
 	"github.com/greenpau/go-authcrunch/pkg/util"
@@ -87,6 +89,12 @@ func (p *Portal) handleHTTPLoginRequest(ctx context.Context, w http.ResponseWrit
 
 	// Identify the backend associated with the user and determine challenges.
 	if err := p.identifyUserRequest(rr, identity); err != nil {
+		ev := audit.NewEvent(audit.LoginFailure, audit.OutcomeFailure)
+		ev.Username = identity["username"]
+		ev.Realm = identity["realm"]
+		ev.Reason = err.Error()
+		p.logAuditEvent(r, rr, nil, ev)
+		p.recordLogin(identity["realm"], false)
 		rr.Response.Code = http.StatusBadRequest
 		return p.handleHTTPErrorWithLog(ctx, w, r, rr, rr.Response.Code, err.Error())
 	}
@@ -108,7 +116,7 @@ func (p *Portal) handleHTTPLoginRequest(ctx context.Context, w http.ResponseWrit
 	if _, exists := m["origin"]; !exists {
 		m["origin"] = rr.Upstream.Realm
 	}
-	m["iss"] = util.GetIssuerURL(r)
+	m["iss"] = p.getIssuer(r)
 	m["addr"] = addrutil.GetSourceAddress(r)
 
 	combineGroupRoles(m)
@@ -239,19 +247,40 @@ func (p *Portal) authenticateLoginRequest(ctx context.Context, w http.ResponseWr
 
 	if err := backend.Request(operator.IdentifyUser, rr); err != nil {
 		rr.Response.Code = http.StatusUnauthorized
+		p.logLoginFailure(r, rr, err)
 		return err
 	}
 
-	if len(rr.User.Challenges) != 1 {
+	// The expired password is reported only after the password is verified.
+	var passwordExpired bool
+	var challenges []string
+	for _, challenge := range rr.User.Challenges {
+		if challenge == "password_change" {
+			passwordExpired = true
+			continue
+		}
+		challenges = append(challenges, challenge)
+	}
+	if len(challenges) != 1 {
+		rr.Response.Code = http.StatusBadRequest
 		return fmt.Errorf("detected too many auth challenges")
 	}
-	if rr.User.Challenges[0] != "password" {
+	if challenges[0] != "password" {
+		rr.Response.Code = http.StatusBadRequest
 		return fmt.Errorf("detected unsupported auth challenges")
 	}
 	if err := backend.Request(operator.Authenticate, rr); err != nil {
-		rr.Response.Code = http.StatusUnauthorized
+		if rr.Response.Code != http.StatusLocked && rr.Response.Code != http.StatusForbidden {
+			rr.Response.Code = http.StatusUnauthorized
+		}
+		p.logLoginFailure(r, rr, err)
 		return err
 	}
+	if passwordExpired {
+		rr.Response.Code = http.StatusForbidden
+		p.logLoginFailure(r, rr, errors.ErrPasswordExpired)
+		return errors.ErrPasswordExpired
+	}
 	rr.Response.Code = http.StatusOK
 	return nil
 }
@@ -293,7 +322,7 @@ func (p *Portal) authorizeLoginRequest(ctx context.Context, w http.ResponseWrite
 	if _, exists := m["origin"]; !exists {
 		m["origin"] = rr.Upstream.Realm
 	}
-	m["iss"] = util.GetIssuerURL(r)
+	m["iss"] = p.getIssuer(r)
 	m["addr"] = addrutil.GetSourceAddress(r)
 
 	// Perform user claim transformation if necessary.
@@ -352,8 +381,9 @@ func (p *Portal) authorizeLoginRequest(ctx context.Context, w http.ResponseWrite
 func (p *Portal) grantAccess(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) {
 	var redirectLocation string
 
+	issuedAt := time.Now().UTC()
 	usr.SetExpiresAtClaim(time.Now().Add(time.Duration(p.keystore.GetTokenLifetime(nil, nil)) * time.Second).UTC().Unix())
-	usr.SetIssuedAtClaim(time.Now().UTC().Unix())
+	usr.SetIssuedAtClaim(issuedAt.Unix())
 	usr.SetNotBeforeClaim(time.Now().Add(time.Duration(60) * time.Second * -1).UTC().Unix())
 
 	if err := p.keystore.SignToken(nil, nil, usr); err != nil {
@@ -366,12 +396,21 @@ func (p *Portal) grantAccess(ctx context.Context, w http.ResponseWriter, r *http
 		rr.Response.Code = http.StatusInternalServerError
 		return
 	}
+	p.exemptUserToken(rr, usr, issuedAt)
 
 	h := addrutil.GetSourceHost(r)
 
 	rr.Response.Authenticated = true
 	usr.Authorized = true
 	p.sessions.Add(rr.Upstream.SessionID, usr)
+
+	ev := audit.NewEvent(audit.LoginSuccess, audit.OutcomeSuccess)
+	ev.Username = usr.Claims.Subject
+	ev.Email = usr.Claims.Email
+	ev.Realm = usr.Authenticator.Realm
+	ev.WithDetail("method", usr.Authenticator.Method)
+	p.logAuditEvent(r, rr, usr, ev)
+	p.recordLogin(usr.Authenticator.Realm, true)
 	w.Header().Set("Authorization", "Bearer "+usr.Token)
 	w.Header().Set("Set-Cookie", p.cookie.GetCookie(h, usr.TokenName, usr.Token))
 
@@ -407,6 +446,18 @@ func (p *Portal) grantAccess(ctx context.Context, w http.ResponseWriter, r *http
 	return
 }
 
+// logLoginFailure records failed authentication attempt in the audit log.
+func (p *Portal) logLoginFailure(r *http.Request, rr *requests.Request, err error) {
+	ev := audit.NewEvent(audit.LoginFailure, audit.OutcomeFailure)
+	ev.Reason = err.Error()
+	ev.WithDetail("method", rr.Upstream.Method)
+	if rr.Response.Code == http.StatusLocked {
+		ev.WithDetail("locked", true)
+	}
+	p.logAuditEvent(r, rr, nil, ev)
+	p.recordLogin(rr.Upstream.Realm, false)
+}
+
 func combineGroupRoles(m map[string]interface{}) {
 	var roles []string
 	roleMap := make(map[string]interface{})
@@ -525,3 +576,12 @@ func (p *Portal) transformUser(ctx context.Context, rr *requests.Request, m map[
 	)
 	return nil
 }
+
+// getIssuer returns the issuer of the tokens issued by the portal. When the
+// issuer is not configured, it is derived from the request.
+func (p *Portal) getIssuer(r *http.Request) string {
+	if p.issuer != "" {
+		return p.issuer
+	}
+	return util.GetIssuerURL(r)
+}
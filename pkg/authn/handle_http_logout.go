@@ -17,6 +17,7 @@ package authn
 import (
 	"context"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
 	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
 	"net/http"
 	"net/url"
@@ -28,8 +29,9 @@ func (p *Portal) deleteAuthCookies(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (p *Portal) handleHTTPLogout(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+func (p *Portal) handleHTTPLogout(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, usr *user.User) error {
 	p.disableClientCache(w)
+	p.revokeUserToken(r, rr, usr, "logout")
 	p.injectRedirectURL(ctx, w, r, rr)
 	h := addrutil.GetSourceHost(r)
 	for tokenName := range p.validator.GetAuthCookies() {
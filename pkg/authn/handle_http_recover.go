@@ -16,12 +16,332 @@ package authn
 
 import (
 	"context"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/validators"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
 	"net/http"
+	"strings"
+	"time"
 )
 
+type recoverRequest struct {
+	view       string
+	message    string
+	recoveryID string
+	username   string
+}
+
 func (p *Portal) handleHTTPRecover(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
 	p.disableClientCache(w)
-	// TODO(greenpau): implement
-	return p.handleHTTPError(ctx, w, r, rr, http.StatusNotImplemented)
+	if rr.Response.Authenticated {
+		// Authenticated users change their passwords via settings.
+		return p.handleHTTPRedirect(ctx, w, r, rr, "/portal")
+	}
+
+	if p.recoveries == nil {
+		return p.handleHTTPError(ctx, w, r, rr, http.StatusServiceUnavailable)
+	}
+
+	if strings.Contains(r.URL.Path, "/recover/") {
+		if r.Method != "POST" {
+			// Handle password reset page.
+			return p.handleHTTPRecoverReset(ctx, w, r, rr)
+		}
+		// Handle password reset request.
+		return p.handleHTTPRecoverResetRequest(ctx, w, r, rr)
+	}
+
+	if r.Method != "POST" {
+		// Handle password recovery landing page.
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, &recoverRequest{view: "recover"})
+	}
+	// Handle password recovery request.
+	return p.handleHTTPRecoverRequest(ctx, w, r, rr)
+}
+
+func (p *Portal) handleHTTPRecoverScreenWithMessage(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, rec *recoverRequest) error {
+	resp := p.ui.GetArgs()
+	resp.BaseURL(rr.Upstream.BasePath)
+	resp.Title = "Password Recovery"
+	resp.Data["view"] = rec.view
+
+	switch rec.view {
+	case "recover":
+		var realms []map[string]string
+		for _, backend := range p.backends {
+			if backend.GetMethod() != "local" {
+				continue
+			}
+			realms = append(realms, map[string]string{
+				"realm": backend.GetRealm(),
+				"label": strings.ToTitle(backend.GetRealm()),
+			})
+		}
+		resp.Data["realms"] = realms
+	case "reset":
+		resp.Data["recovery_id"] = rec.recoveryID
+		resp.Data["username"] = rec.username
+	case "fail":
+		resp.Data["message"] = rec.message
+	}
+
+	if rec.message != "" && rec.view != "fail" {
+		resp.Message = rec.message
+	}
+
+	content, err := p.ui.Render("recover", resp)
+	if err != nil {
+		return p.handleHTTPRenderError(ctx, w, r, rr, err)
+	}
+	return p.handleHTTPRenderHTML(ctx, w, http.StatusOK, content.Bytes())
+}
+
+func (p *Portal) handleHTTPRecoverRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	var maxBytesLimit int64 = 1000
+	var minBytesLimit int64 = 10
+	var violations []string
+
+	if r.ContentLength > maxBytesLimit || r.ContentLength < minBytesLimit {
+		violations = append(violations, "payload size")
+	}
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		violations = append(violations, "content type")
+	}
+	if len(violations) > 0 {
+		p.logger.Warn(
+			"Password recovery request is non compliant",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Int64("min_size", minBytesLimit),
+			zap.Int64("max_size", maxBytesLimit),
+			zap.String("content_type", r.Header.Get("Content-Type")),
+			zap.Int64("size", r.ContentLength),
+			zap.Strings("violations", violations),
+		)
+		rec := &recoverRequest{view: "recover", message: "Password recovery request is non compliant"}
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		p.logger.Warn(
+			"failed parsing submitted password recovery form",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+			zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
+			zap.String("error", err.Error()),
+		)
+		rec := &recoverRequest{view: "recover", message: "Failed processing the password recovery form"}
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+
+	userMail := strings.TrimSpace(r.PostFormValue("email"))
+	userRealm := strings.TrimSpace(r.PostFormValue("realm"))
+
+	if err := validators.ValidateUserInput("email", userMail, make(map[string]interface{})); err != nil {
+		rec := &recoverRequest{view: "recover", message: "Failed processing the password recovery form due " + err.Error()}
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+
+	backend := p.getBackendByRealm(userRealm)
+	if backend == nil || backend.GetMethod() != "local" {
+		rec := &recoverRequest{view: "recover", message: "Failed processing the password recovery form due to invalid realm"}
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+
+	// The response does not reveal whether the account exists.
+	rec := &recoverRequest{view: "recover_sent"}
+
+	req := &requests.Request{
+		ID: rr.ID,
+		User: requests.User{
+			Username: userMail,
+		},
+		Flags: requests.Flags{
+			PasswordRecovery: true,
+		},
+	}
+	if err := backend.Request(operator.IdentifyUser, req); err != nil || req.Response.Code != http.StatusOK {
+		p.logger.Warn(
+			"password recovery requested for unknown user or unconfirmed email address",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("email", userMail),
+			zap.String("realm", userRealm),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+			zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
+		)
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+
+	recoveryID := util.GetRandomStringFromRange(64, 96)
+	cachedEntry := map[string]string{
+		"username": req.User.Username,
+		"email":    userMail,
+		"realm":    backend.GetRealm(),
+	}
+	if err := p.recoveries.Add(recoveryID, cachedEntry); err != nil {
+		p.logger.Warn(
+			"failed adding a record to recovery cache",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, &recoverRequest{view: "fail", message: "Internal password recovery error"})
+	}
+
+	// The link is built from the configured base URL, because the host
+	// and forwarding headers of the request are supplied by the client.
+	recoveryURL := strings.TrimSuffix(p.config.BaseURL, "/") + "/recover"
+
+	recData := map[string]string{
+		"provider_name": p.config.UserRegistrationConfig.EmailProvider,
+		"provider_type": "email",
+		"template":      "password_recovery",
+		"session_id":    rr.Upstream.SessionID,
+		"request_id":    rr.ID,
+		"recovery_id":   recoveryID,
+		"recovery_url":  recoveryURL,
+		"username":      req.User.Username,
+		"email":         userMail,
+	}
+	recData["src_ip"] = addrutil.GetSourceAddress(r)
+	recData["src_conn_ip"] = addrutil.GetSourceConnAddress(r)
+	recData["timestamp"] = time.Now().UTC().Format(time.UnixDate)
+	if err := p.notify(recData); err != nil {
+		p.logger.Warn(
+			"Failed to send notification",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("recovery_type", "password_recovery"),
+			zap.Error(err),
+		)
+		p.recoveries.Delete(recoveryID)
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, &recoverRequest{view: "fail", message: "Internal password recovery messaging error"})
+	}
+
+	p.logger.Info("Password recovery requested",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", req.User.Username),
+		zap.String("email", userMail),
+		zap.String("realm", backend.GetRealm()),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
+	)
+	return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+}
+
+func (p *Portal) handleHTTPRecoverReset(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	rec := &recoverRequest{view: "fail"}
+	recoveryID, err := getEndpointKeyID(r.URL.Path, "/recover/")
+	if err != nil {
+		rec.message = "Malformed password recovery request"
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+	entry, err := p.recoveries.Get(recoveryID)
+	if err != nil {
+		rec.message = "Password recovery link is invalid or expired"
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+	rec.view = "reset"
+	rec.recoveryID = recoveryID
+	rec.username = entry["username"]
+	return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+}
+
+func (p *Portal) handleHTTPRecoverResetRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	rec := &recoverRequest{view: "fail"}
+	recoveryID, err := getEndpointKeyID(r.URL.Path, "/recover/")
+	if err != nil {
+		rec.message = "Malformed password recovery request"
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+	entry, err := p.recoveries.Get(recoveryID)
+	if err != nil {
+		rec.message = "Password recovery link is invalid or expired"
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+
+	backend := p.getBackendByRealm(entry["realm"])
+	if backend == nil {
+		rec.message = "Password recovery realm not found"
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+
+	rec.recoveryID = recoveryID
+	rec.username = entry["username"]
+	rec.view = "reset"
+
+	if err := validateRecoveryPasswordForm(r, rr); err != nil {
+		rec.message = err.Error()
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+
+	// The recovery link is single-use. It is removed from the cache prior
+	// to the password change, so that concurrent submissions of the same
+	// link cannot both succeed.
+	takenEntry, err := p.recoveries.Take(recoveryID)
+	if err != nil {
+		rec.view = "fail"
+		rec.message = "Password recovery link is invalid or expired"
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+	entry = takenEntry.GetUser()
+
+	req := &requests.Request{
+		ID: rr.ID,
+		User: requests.User{
+			Username: entry["username"],
+			Email:    entry["email"],
+			Password: rr.User.Password,
+		},
+		Flags: requests.Flags{
+			PasswordRecovery: true,
+		},
+	}
+
+	err = backend.Request(operator.ChangePassword, req)
+	ev := audit.NewEventWithError(audit.PasswordReset, err)
+	ev.Username = entry["username"]
+	ev.Email = entry["email"]
+	ev.Realm = entry["realm"]
+	ev.WithDetail("recovery", true)
+	p.logAuditEvent(r, rr, nil, ev)
+	if err != nil {
+		p.logger.Warn(
+			"failed password recovery",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("username", entry["username"]),
+			zap.String("realm", entry["realm"]),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+			zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
+			zap.Error(err),
+		)
+		// The password was not changed, e.g. it does not comply with the
+		// password policy. The link is being made usable again until its
+		// original expiration.
+		if err := p.recoveries.Restore(takenEntry); err != nil {
+			rec.view = "fail"
+			rec.message = "Failed changing the password, please request a new password recovery link"
+			return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+		}
+		rec.message = "Failed changing the password, please make sure it complies with the password policy"
+		return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, rec)
+	}
+
+	p.logger.Info("Successful password recovery",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", entry["username"]),
+		zap.String("realm", entry["realm"]),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
+	)
+	return p.handleHTTPRecoverScreenWithMessage(ctx, w, r, rr, &recoverRequest{view: "reset_done"})
 }
@@ -17,6 +17,7 @@ package authn
 import (
 	"context"
 	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
 	"github.com/greenpau/go-authcrunch/pkg/identity"
 	"github.com/greenpau/go-authcrunch/pkg/identity/qr"
@@ -130,6 +131,15 @@ func (p *Portal) handleHTTPSandbox(ctx context.Context, w http.ResponseWriter, r
 	rr.User.Email = usr.Claims.Email
 
 	data, err := p.nextSandboxCheckpoint(r, rr, usr, sandboxPartition)
+	// Persist the progress through the checkpoints, e.g. failed attempts.
+	if updateErr := p.sandboxes.Update(sandboxID, usr); updateErr != nil {
+		p.logger.Warn(
+			"failed to update cached sandbox entry",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(updateErr),
+		)
+	}
 	if err != nil {
 		p.logger.Warn(
 			"user authorization checkpoint failed",
@@ -137,6 +147,15 @@ func (p *Portal) handleHTTPSandbox(ctx context.Context, w http.ResponseWriter, r
 			zap.String("request_id", rr.ID),
 			zap.Error(err),
 		)
+		ev := audit.NewEvent(audit.LoginFailure, audit.OutcomeFailure)
+		ev.Realm = usr.Authenticator.Realm
+		ev.Reason = err.Error()
+		ev.WithDetail("method", usr.Authenticator.Method)
+		if title, ok := data["title"].(string); ok {
+			ev.WithDetail("checkpoint", title)
+		}
+		p.logAuditEvent(r, rr, nil, ev)
+		p.recordLogin(usr.Authenticator.Realm, false)
 		data["error"] = err.Error()
 	} else {
 		p.logger.Debug(
@@ -209,7 +228,7 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 			continue
 		}
 		switch checkpoint.Type {
-		case "password", "mfa":
+		case "password", "password_change", "mfa":
 			verifiedCount++
 		}
 	}
@@ -267,6 +286,8 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 				}
 				rr.Flags.Enabled = true
 				if err := backend.Request(operator.Authenticate, rr); err != nil {
+					locked := rr.Response.Code == http.StatusLocked
+					unconfirmed := rr.Response.Code == http.StatusForbidden
 					rr.Response.Code = http.StatusUnauthorized
 					checkpoint.FailedAttempts++
 					m["title"] = "Authentication Failed"
@@ -280,7 +301,17 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 						zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
 						zap.String("checkpoint_name", checkpoint.Name),
 						zap.String("checkpoint_type", checkpoint.Type),
+						zap.Bool("locked", locked),
+						zap.Bool("unconfirmed", unconfirmed),
 					)
+					if locked {
+						m["title"] = "Account Locked"
+						return m, fmt.Errorf("Your account is temporarily locked due to too many failed login attempts. Please retry later")
+					}
+					if unconfirmed {
+						m["title"] = "Email Not Confirmed"
+						return m, fmt.Errorf("Your email address is not confirmed. Please follow the verification link sent to your email address")
+					}
 					return m, fmt.Errorf("Password authentication failed. Please retry")
 				}
 				p.logger.Info(
@@ -297,6 +328,51 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 				m["view"] = "redirect"
 				return m, nil
 			}
+		case "password_change":
+			if r.Method != "POST" {
+				m["title"] = "Password Expired"
+				m["view"] = "password_change"
+				m["action"] = "auth"
+				return m, nil
+			}
+			if err := validatePasswordChangeForm(r, rr); err != nil {
+				checkpoint.FailedAttempts++
+				rr.Response.Code = http.StatusBadRequest
+				m["title"] = "Password Change Failed"
+				m["view"] = "error"
+				return m, err
+			}
+			err := backend.Request(operator.ChangePassword, rr)
+			p.logAuditEvent(r, rr, usr, audit.NewEventWithError(audit.PasswordChanged, err).WithDetail("expired", true))
+			if err != nil {
+				checkpoint.FailedAttempts++
+				rr.Response.Code = http.StatusBadRequest
+				m["title"] = "Password Change Failed"
+				m["view"] = "error"
+				p.logger.Warn(
+					"expired password change failed",
+					zap.String("session_id", rr.Upstream.SessionID),
+					zap.String("request_id", rr.ID),
+					zap.Int("checkpoint_id", checkpoint.ID),
+					zap.String("checkpoint_name", checkpoint.Name),
+					zap.String("checkpoint_type", checkpoint.Type),
+					zap.Error(err),
+				)
+				return m, err
+			}
+			p.logger.Info(
+				"user authorization checkpoint passed",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.Int("checkpoint_id", checkpoint.ID),
+				zap.String("checkpoint_name", checkpoint.Name),
+				zap.String("checkpoint_type", checkpoint.Type),
+			)
+			checkpoint.Passed = true
+			checkpoint.FailedAttempts = 0
+			verifiedCount++
+			m["view"] = "redirect"
+			return m, nil
 		case "mfa":
 			if err := backend.Request(operator.GetMfaTokens, rr); err != nil {
 				checkpoint.FailedAttempts++
@@ -352,6 +428,7 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 					tokenValidated = true
 					break
 				}
+				p.recordMfa(usr.Authenticator.Realm, "totp", tokenValidated)
 				if tokenValidated {
 					// If validated successfully, continue.
 					p.logger.Info(
@@ -386,10 +463,12 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 					}
 					rr.WebAuthn.Challenge = usr.Authenticator.TempChallenge
 					if err := backend.Request(operator.Authenticate, rr); err != nil {
+						p.recordMfa(usr.Authenticator.Realm, "u2f", false)
 						m["view"] = "error"
 						checkpoint.FailedAttempts++
 						return m, fmt.Errorf("Token verification failed. Please retry")
 					}
+					p.recordMfa(usr.Authenticator.Realm, "u2f", true)
 					checkpoint.Passed = true
 					checkpoint.FailedAttempts = 0
 					verifiedCount++
@@ -433,7 +512,9 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 						checkpoint.FailedAttempts++
 						return m, err
 					}
-					if err := backend.Request(operator.AddMfaToken, rr); err != nil {
+					err := backend.Request(operator.AddMfaToken, rr)
+					p.logAuditEvent(r, rr, usr, audit.NewEventWithError(audit.MfaTokenAdded, err).WithDetail("token_type", rr.MfaToken.Type))
+					if err != nil {
 						m["view"] = "error"
 						checkpoint.FailedAttempts++
 						return m, err
@@ -474,7 +555,9 @@ func (p *Portal) nextSandboxCheckpoint(r *http.Request, rr *requests.Request, us
 						checkpoint.FailedAttempts++
 						return m, err
 					}
-					if err := backend.Request(operator.AddMfaToken, rr); err != nil {
+					err := backend.Request(operator.AddMfaToken, rr)
+					p.logAuditEvent(r, rr, usr, audit.NewEventWithError(audit.MfaTokenAdded, err).WithDetail("token_type", rr.MfaToken.Type))
+					if err != nil {
 						m["view"] = "error"
 						checkpoint.FailedAttempts++
 						return m, err
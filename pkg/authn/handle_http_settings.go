@@ -94,6 +94,9 @@ func (p *Portal) handleHTTPSettings(ctx context.Context, w http.ResponseWriter,
 	rr.User.Username = usr.Claims.Subject
 	rr.User.Email = usr.Claims.Email
 
+	isRegistrationAdmin := p.registrar != nil && usr.HasRole("authp/admin")
+	resp.Data["registrations_enabled"] = isRegistrationAdmin
+
 	switch {
 	case strings.HasPrefix(endpoint, "/password"):
 		if err := p.handleHTTPPasswordSettings(ctx, r, rr, usr, backend, resp.Data); err != nil {
@@ -111,6 +114,17 @@ func (p *Portal) handleHTTPSettings(ctx context.Context, w http.ResponseWriter,
 		if err := p.handleHTTPGPGKeysSettings(ctx, r, rr, usr, backend, resp.Data); err != nil {
 			return p.handleHTTPError(ctx, w, r, rr, http.StatusBadRequest)
 		}
+	case strings.HasPrefix(endpoint, "/emails"):
+		if err := p.handleHTTPEmailSettings(ctx, r, rr, usr, backend, resp.Data); err != nil {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusBadRequest)
+		}
+	case strings.HasPrefix(endpoint, "/registrations"):
+		if !isRegistrationAdmin {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusForbidden)
+		}
+		if err := p.handleHTTPRegistrationsSettings(ctx, r, rr, usr, resp.Data); err != nil {
+			return p.handleHTTPError(ctx, w, r, rr, http.StatusBadRequest)
+		}
 	case strings.HasPrefix(endpoint, "/mfa/barcode/"):
 		return p.handleHTTPMfaBarcode(ctx, w, r, endpoint)
 	case strings.HasPrefix(endpoint, "/mfa"):
@@ -17,6 +17,7 @@ package authn
 import (
 	"context"
 	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
 	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
 	"github.com/greenpau/go-authcrunch/pkg/identity"
@@ -47,7 +48,9 @@ func (p *Portal) handleHTTPAPIKeysSettings(
 			break
 		}
 		rr.Key.Usage = "api"
-		if err = backend.Request(operator.AddAPIKey, rr); err != nil {
+		err = backend.Request(operator.AddAPIKey, rr)
+		p.logAuditEvent(r, rr, usr, audit.NewEventWithError(audit.APIKeyCreated, err).WithDetail("comment", rr.Key.Comment))
+		if err != nil {
 			attachFailStatus(data, fmt.Sprintf("%v", err))
 			break
 		}
@@ -64,7 +67,9 @@ func (p *Portal) handleHTTPAPIKeysSettings(
 			break
 		}
 		rr.Key.ID = keyID
-		if err = backend.Request(operator.DeleteAPIKey, rr); err != nil {
+		err = backend.Request(operator.DeleteAPIKey, rr)
+		p.logAuditEvent(r, rr, usr, audit.NewEventWithError(audit.APIKeyDeleted, err).WithDetail("key_id", keyID))
+		if err != nil {
 			attachFailStatus(data, fmt.Sprintf("failed deleting key id %s: %v", keyID, err))
 			break
 		}
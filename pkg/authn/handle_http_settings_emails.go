@@ -0,0 +1,113 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func (p *Portal) handleHTTPEmailSettings(
+	ctx context.Context, r *http.Request, rr *requests.Request,
+	usr *user.User, backend *backends.Backend, data map[string]interface{},
+) error {
+	var action string
+	var status bool
+	entrypoint := "emails"
+	data["view"] = entrypoint
+	endpoint, err := getEndpoint(r.URL.Path, "/"+entrypoint)
+	if err != nil {
+		return err
+	}
+	data["verification_enabled"] = p.verifySecret != nil
+	switch {
+	case strings.HasPrefix(endpoint, "/add") && r.Method == "POST":
+		// Add email address.
+		action = "add"
+		status = true
+		if err := validateEmailInputForm(r, rr); err != nil {
+			attachFailStatus(data, fmt.Sprintf("Bad Request: %v", err))
+			break
+		}
+		if err = backend.Request(operator.AddEmailAddress, rr); err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		if p.verifySecret == nil {
+			attachSuccessStatus(data, "Email address has been added")
+			break
+		}
+		if err := p.sendEmailVerification(r, rr, backend.GetRealm(), rr.Query.ID, rr.User.Username, rr.User.NewEmail); err != nil {
+			attachFailStatus(data, fmt.Sprintf("Email address has been added, but the verification email was not sent: %v", err))
+			break
+		}
+		attachSuccessStatus(data, "Email address has been added, please follow the link sent to the address to confirm it")
+	case strings.HasPrefix(endpoint, "/add"):
+		action = "add"
+	case strings.HasPrefix(endpoint, "/verify"):
+		// Resend the verification link for a particular email address.
+		action = "verify"
+		status = true
+		s, err := getEndpointKeyID(endpoint, "/verify/")
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		email, err := url.PathUnescape(s)
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		if err = backend.Request(operator.GetUser, rr); err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		user := rr.Response.Payload.(*identity.User)
+		addr := user.GetEmailAddress(email)
+		if addr == nil {
+			attachFailStatus(data, fmt.Sprintf("email address %s not found", email))
+			break
+		}
+		if addr.Confirmed {
+			attachSuccessStatus(data, fmt.Sprintf("Email address %s is already confirmed", addr.Address))
+			break
+		}
+		if err := p.sendEmailVerification(r, rr, backend.GetRealm(), user.ID, user.Username, addr.Address); err != nil {
+			attachFailStatus(data, fmt.Sprintf("failed sending verification email to %s: %v", addr.Address, err))
+			break
+		}
+		attachSuccessStatus(data, fmt.Sprintf("Verification email has been sent to %s", addr.Address))
+	default:
+		// List email addresses.
+		if err = backend.Request(operator.GetUser, rr); err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		user := rr.Response.Payload.(*identity.User)
+		if len(user.EmailAddresses) > 0 {
+			data[entrypoint] = user.EmailAddresses
+		}
+	}
+	attachView(data, entrypoint, action, status)
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
 	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
 	"github.com/greenpau/go-authcrunch/pkg/identity"
@@ -69,7 +70,9 @@ func (p *Portal) handleHTTPMfaSettings(
 			attachFailStatus(data, fmt.Sprintf("Bad Request: %s", err))
 			break
 		}
-		if err = backend.Request(operator.AddMfaToken, rr); err != nil {
+		err = backend.Request(operator.AddMfaToken, rr)
+		p.logAuditEvent(r, rr, usr, audit.NewEventWithError(audit.MfaTokenAdded, err).WithDetail("token_type", rr.MfaToken.Type))
+		if err != nil {
 			attachFailStatus(data, fmt.Sprintf("%v", err))
 			break
 		}
@@ -96,7 +99,9 @@ func (p *Portal) handleHTTPMfaSettings(
 			attachFailStatus(data, fmt.Sprintf("Bad Request: %s", err))
 			break
 		}
-		if err = backend.Request(operator.AddMfaToken, rr); err != nil {
+		err = backend.Request(operator.AddMfaToken, rr)
+		p.logAuditEvent(r, rr, usr, audit.NewEventWithError(audit.MfaTokenAdded, err).WithDetail("token_type", rr.MfaToken.Type))
+		if err != nil {
 			attachFailStatus(data, fmt.Sprintf("%v", err))
 			break
 		}
@@ -282,7 +287,9 @@ func (p *Portal) handleHTTPMfaSettings(
 			break
 		}
 		rr.MfaToken.ID = tokenID
-		if err = backend.Request(operator.DeleteMfaToken, rr); err != nil {
+		err = backend.Request(operator.DeleteMfaToken, rr)
+		p.logAuditEvent(r, rr, usr, audit.NewEventWithError(audit.MfaTokenRemoved, err).WithDetail("token_id", tokenID))
+		if err != nil {
 			attachFailStatus(data, fmt.Sprintf("failed deleting token id %s: %v", tokenID, err))
 			break
 		}
@@ -17,6 +17,7 @@ package authn
 import (
 	"context"
 	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
 	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
 	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
@@ -44,7 +45,9 @@ func (p *Portal) handleHTTPPasswordSettings(
 			attachFailStatus(data, "Bad Request")
 			break
 		}
-		if err = backend.Request(operator.ChangePassword, rr); err != nil {
+		err = backend.Request(operator.ChangePassword, rr)
+		p.logAuditEvent(r, rr, usr, audit.NewEventWithError(audit.PasswordChanged, err))
+		if err != nil {
 			attachFailStatus(data, fmt.Sprintf("%v", err))
 			break
 		}
@@ -0,0 +1,74 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"net/http"
+	"strings"
+)
+
+func (p *Portal) handleHTTPRegistrationsSettings(
+	ctx context.Context, r *http.Request, rr *requests.Request,
+	usr *user.User, data map[string]interface{},
+) error {
+	var action string
+	var status bool
+	entrypoint := "registrations"
+	data["view"] = entrypoint
+	endpoint, err := getEndpoint(r.URL.Path, "/"+entrypoint)
+	if err != nil {
+		return err
+	}
+	switch {
+	case strings.HasPrefix(endpoint, "/review") && r.Method == "POST":
+		// Approve or decline the selected registrations.
+		action = "review"
+		status = true
+		ids, verdict, note, reason, err := validateRegistrationReviewForm(r)
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("Bad Request: %v", err))
+			break
+		}
+		results := p.reviewRegistrations(r, rr, usr, ids, verdict, note, reason)
+		data["review_results"] = results
+		var failed int
+		for _, result := range results {
+			if result.Error != "" {
+				failed++
+			}
+		}
+		if failed > 0 {
+			attachFailStatus(data, fmt.Sprintf("%d of %d registration(s) failed review", failed, len(results)))
+			break
+		}
+		attachSuccessStatus(data, fmt.Sprintf("%d registration(s) %s", len(results), verdict))
+	default:
+		// List pending registrations.
+		entries, err := p.getPendingRegistrations()
+		if err != nil {
+			attachFailStatus(data, fmt.Sprintf("%v", err))
+			break
+		}
+		if len(entries) > 0 {
+			data[entrypoint] = entries
+		}
+	}
+	attachView(data, entrypoint, action, status)
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+func (p *Portal) handleHTTPVerifyEmail(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	p.disableClientCache(w)
+	if p.verifySecret == nil {
+		return p.handleHTTPGeneric(ctx, w, r, rr, http.StatusServiceUnavailable, "Email verification is disabled")
+	}
+
+	token, err := getEndpointKeyID(r.URL.Path, "/verify/")
+	if err != nil {
+		return p.handleHTTPGeneric(ctx, w, r, rr, http.StatusBadRequest, "Malformed email verification request")
+	}
+
+	claims, err := parseEmailVerificationToken(p.verifySecret, token, time.Now())
+	if err != nil {
+		p.logger.Warn(
+			"invalid email verification token",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+			zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
+			zap.Error(err),
+		)
+		return p.handleHTTPGeneric(ctx, w, r, rr, http.StatusBadRequest, "Email verification link is invalid or expired")
+	}
+
+	backend := p.getBackendByRealm(claims.Realm)
+	if backend == nil || backend.GetMethod() != "local" {
+		return p.handleHTTPGeneric(ctx, w, r, rr, http.StatusBadRequest, "Email verification realm not found")
+	}
+
+	req := &requests.Request{
+		ID: rr.ID,
+		Query: requests.Query{
+			ID: claims.UserID,
+		},
+		User: requests.User{
+			Email: claims.Email,
+		},
+	}
+	if err := backend.Request(operator.ConfirmEmailAddress, req); err != nil {
+		p.logger.Warn(
+			"failed email verification",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("email", claims.Email),
+			zap.String("realm", claims.Realm),
+			zap.String("src_ip", addrutil.GetSourceAddress(r)),
+			zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
+			zap.Error(err),
+		)
+		return p.handleHTTPGeneric(ctx, w, r, rr, http.StatusBadRequest, "Email verification link is invalid or expired")
+	}
+
+	p.logger.Info("Successful email verification",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", req.User.Username),
+		zap.String("email", claims.Email),
+		zap.String("realm", claims.Realm),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
+	)
+	return p.handleHTTPGeneric(ctx, w, r, rr, http.StatusOK, "Email address has been confirmed")
+}
@@ -17,8 +17,19 @@ package authn
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"github.com/greenpau/go-authcrunch/pkg/util"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"github.com/satori/go.uuid"
+	"go.uber.org/zap"
 	"net/http"
+	"time"
 )
 
 // AuthRequest is authentication request.
@@ -30,8 +41,15 @@ type AuthRequest struct {
 
 // AuthResponse is the response to authentication request.
 type AuthResponse struct {
-	Token     string `json:"token,omitempty" xml:"token,omitempty" yaml:"token,omitempty"`
-	TokenName string `json:"token_name,omitempty" xml:"token_name,omitempty" yaml:"token_name,omitempty"`
+	Token        string `json:"token,omitempty" xml:"token,omitempty" yaml:"token,omitempty"`
+	TokenName    string `json:"token_name,omitempty" xml:"token_name,omitempty" yaml:"token_name,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty" xml:"refresh_token,omitempty" yaml:"refresh_token,omitempty"`
+}
+
+// RefreshRequest is the request to exchange a refresh token for a new
+// access token.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty" xml:"refresh_token,omitempty" yaml:"refresh_token,omitempty"`
 }
 
 func (p *Portal) handleJSONLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
@@ -63,12 +81,157 @@ func (p *Portal) handleJSONLogin(ctx context.Context, w http.ResponseWriter, r *
 	if err != nil {
 		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
 	}
+	refreshToken := util.GetRandomStringFromRange(64, 96)
+	if err := p.refreshTokens.Add(refreshToken, uuid.NewV4().String(), usr, p.keystore.GetRefreshTokenLifetime()); err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
 	resp := &AuthResponse{
-		TokenName: usr.TokenName,
-		Token:     usr.Token,
+		TokenName:    usr.TokenName,
+		Token:        usr.Token,
+		RefreshToken: refreshToken,
 	}
 	respBytes, _ := json.Marshal(resp)
 	w.WriteHeader(rr.Response.Code)
 	w.Write(respBytes)
 	return nil
 }
+
+// handleJSONRefresh exchanges a refresh token for a new access token and a
+// new refresh token. The presented refresh token can no longer be used.
+func (p *Portal) handleJSONRefresh(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	refreshRequest := &RefreshRequest{}
+	if r.Method != "POST" {
+		return p.handleJSONError(ctx, w, http.StatusUnauthorized, "Authentication Required")
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	respDecoder := json.NewDecoder(r.Body)
+	respDecoder.DisallowUnknownFields()
+	if err := respDecoder.Decode(refreshRequest); err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusBadRequest, err.Error())
+	}
+
+	refreshToken := util.GetRandomStringFromRange(64, 96)
+	parentUser, err := p.refreshTokens.Rotate(refreshRequest.RefreshToken, refreshToken, p.keystore.GetRefreshTokenLifetime())
+	if err != nil {
+		if err == errors.ErrRefreshTokenReused {
+			// The reuse of a refresh token indicates its theft. The family
+			// of the refresh token has been invalidated.
+			ev := audit.NewEvent(audit.TokenRevoked, audit.OutcomeSuccess).WithDetail("scope", "refresh_token_family")
+			ev.Reason = err.Error()
+			if parentUser != nil && parentUser.Claims != nil {
+				ev.Username = parentUser.Claims.Subject
+				ev.Email = parentUser.Claims.Email
+				ev.Realm = parentUser.Authenticator.Realm
+			}
+			p.logAuditEvent(r, rr, nil, ev)
+		}
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusUnauthorized, err.Error())
+	}
+
+	// The tokens of the family are no longer valid once the access token
+	// issued along with the refresh token has been revoked, e.g. on logout.
+	if p.validator.GetRevocationStore().IsRevoked(parentUser.Claims.ID, parentUser.Claims.Subject, parentUser.Claims.IssuedAt) {
+		p.refreshTokens.DeleteFamily(refreshToken)
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusUnauthorized, "refresh token revoked")
+	}
+
+	m, err := p.getRefreshedUserClaims(ctx, rr, parentUser)
+	if err != nil {
+		p.refreshTokens.DeleteFamily(refreshToken)
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusUnauthorized, err.Error())
+	}
+	m["jti"] = util.GetRandomStringFromRange(36, 46)
+	m["exp"] = time.Now().Add(time.Duration(p.keystore.GetTokenLifetime(nil, nil)) * time.Second).UTC().Unix()
+	issuedAt := time.Now().UTC()
+	m["iat"] = issuedAt.Unix()
+	m["nbf"] = time.Now().Add(time.Duration(60)*time.Second*-1).UTC().Unix() * 1000
+	m["addr"] = addrutil.GetSourceAddress(r)
+	usr, err := user.NewUser(m)
+	if err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+	if err := p.keystore.SignToken(nil, nil, usr); err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+	p.exemptUserToken(rr, usr, issuedAt)
+	usr.Authenticator = parentUser.Authenticator
+	usr.Authorized = true
+
+	// The next refresh token of the family belongs to the new access token.
+	if err := p.refreshTokens.Update(refreshToken, usr); err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+	p.sessions.Add(usr.Claims.ID, usr)
+
+	p.logger.Info(
+		"Successful token refresh",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("jti", usr.Claims.ID),
+		zap.String("sub", usr.Claims.Subject),
+	)
+
+	resp := &AuthResponse{
+		TokenName:    usr.TokenName,
+		Token:        usr.Token,
+		RefreshToken: refreshToken,
+	}
+	respBytes, _ := json.Marshal(resp)
+	w.WriteHeader(http.StatusOK)
+	w.Write(respBytes)
+	return nil
+}
+
+// getRefreshedUserClaims returns the claims of the access token issued in
+// exchange for a refresh token. The users of local backends are being looked
+// up again, so that the refresh fails for the deleted or disabled users, and
+// the access token carries the current roles. The claims of the users of
+// the other backends are carried over from the previous access token.
+func (p *Portal) getRefreshedUserClaims(ctx context.Context, rr *requests.Request, parentUser *user.User) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	backend := p.getBackendByRealm(parentUser.Authenticator.Realm)
+	if backend == nil {
+		return nil, fmt.Errorf("no matching realm found")
+	}
+	if backend.GetMethod() != "local" {
+		for k, v := range parentUser.AsMap() {
+			m[k] = v
+		}
+		return m, nil
+	}
+
+	req := &requests.Request{
+		ID: rr.ID,
+		User: requests.User{
+			Username: parentUser.Claims.Subject,
+			Email:    parentUser.Claims.Email,
+		},
+	}
+	if err := backend.Request(operator.GetUser, req); err != nil {
+		return nil, err
+	}
+	identifiedUser, ok := req.Response.Payload.(*identity.User)
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	if identifiedUser.Disabled {
+		return nil, errors.ErrUserDisabled
+	}
+
+	m["sub"] = identifiedUser.Username
+	m["email"] = identifiedUser.GetMailClaim()
+	if name := identifiedUser.GetNameClaim(); name != "" {
+		m["name"] = name
+	}
+	if roles := identifiedUser.GetRolesClaim(); len(roles) > 0 {
+		m["roles"] = roles
+	}
+	m["origin"] = parentUser.Claims.Origin
+	m["iss"] = parentUser.Claims.Issuer
+	rr.Upstream.Realm = backend.GetRealm()
+	if err := p.transformUser(ctx, rr, m); err != nil {
+		return nil, err
+	}
+	injectPortalRoles(m)
+	return m, nil
+}
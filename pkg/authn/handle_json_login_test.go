@@ -0,0 +1,152 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends/local"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetRefreshedUserClaims(t *testing.T) {
+	ctx := context.Background()
+	db, err := testutils.CreateTestDatabase("TestGetRefreshedUserClaims")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	cfg := &PortalConfig{
+		Name: "myportal",
+		BackendConfigs: []backends.Config{
+			{
+				Local: &local.Config{
+					Name:   "local_backend",
+					Method: "local",
+					Realm:  "refresh",
+					Path:   db.GetPath(),
+				},
+			},
+		},
+	}
+	portal, err := NewPortal(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parentUser := &user.User{
+		Claims: &user.Claims{
+			ID:      "abcd",
+			Subject: tests.TestUser1,
+			Email:   tests.TestEmail1,
+			Roles:   []string{"revoked/role"},
+			Origin:  "refresh",
+		},
+		Authenticator: user.Authenticator{Realm: "refresh", Method: "local"},
+	}
+
+	m, err := portal.getRefreshedUserClaims(ctx, requests.NewRequest(), parentUser)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests.EvalObjects(t, "claims", map[string]interface{}{
+		"sub":   tests.TestUser1,
+		"email": tests.TestEmail1,
+	}, map[string]interface{}{
+		"sub":   m["sub"],
+		"email": m["email"],
+	})
+	for _, role := range m["roles"].([]string) {
+		if role == "revoked/role" {
+			t.Fatalf("refreshed claims carry over stale role: %v", m["roles"])
+		}
+	}
+
+	req := &requests.Request{
+		User: requests.User{
+			Username: tests.TestUser1,
+			Email:    tests.TestEmail1,
+		},
+	}
+	if err := portal.getBackendByRealm("refresh").Request(operator.DisableUser, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = portal.getRefreshedUserClaims(ctx, requests.NewRequest(), parentUser)
+	tests.EvalErr(t, err, "disabled user", true, errors.ErrUserDisabled)
+}
+
+func TestAuthenticateLoginRequestPasswordExpired(t *testing.T) {
+	ctx := context.Background()
+	db, err := testutils.CreateTestDatabase("TestAuthenticateLoginRequestPasswordExpired")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	db.Policy.Password.MaxAgeDays = 90
+	for _, usr := range db.Users {
+		usr.GetPassword().CreatedAt = time.Now().UTC().AddDate(0, 0, -90)
+	}
+	if err := db.Save(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &PortalConfig{
+		Name: "myportal",
+		BackendConfigs: []backends.Config{
+			{
+				Local: &local.Config{
+					Name:   "local_backend",
+					Method: "local",
+					Realm:  "pwdexpiry",
+					Path:   db.GetPath(),
+				},
+			},
+		},
+	}
+	portal, err := NewPortal(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer portal.Close()
+
+	login := func(password string) (int, error) {
+		r := httptest.NewRequest(http.MethodPost, "/api/login", nil)
+		w := httptest.NewRecorder()
+		rr := requests.NewRequest()
+		err := portal.authenticateLoginRequest(ctx, w, r, rr, map[string]string{
+			"username": tests.TestUser1,
+			"password": password,
+			"realm":    "pwdexpiry",
+		})
+		return rr.Response.Code, err
+	}
+
+	code, err := login("foobar")
+	tests.EvalObjects(t, "invalid password code", http.StatusUnauthorized, code)
+	if err == nil {
+		t.Fatalf("expected authentication failure with invalid password")
+	}
+
+	code, err = login(tests.TestPwd1)
+	tests.EvalObjects(t, "expired password code", http.StatusForbidden, code)
+	tests.EvalErr(t, err, "expired password", true, errors.ErrPasswordExpired)
+}
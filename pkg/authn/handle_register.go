@@ -16,6 +16,9 @@ package authn
 
 import (
 	"context"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/registration"
 	"github.com/greenpau/go-authcrunch/pkg/authn/validators"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"github.com/greenpau/go-authcrunch/pkg/util"
@@ -114,7 +117,7 @@ func (p *Portal) handleHTTPRegisterScreenWithMessage(ctx context.Context, w http
 	case "ack":
 		resp.Title = "Registration"
 		resp.Data["registration_id"] = reg.registrationID
-	case "acked":
+	case "acked", "approved":
 		resp.Title = "Registration"
 	}
 
@@ -235,6 +238,11 @@ func (p *Portal) handleHTTPRegisterRequest(ctx context.Context, w http.ResponseW
 				if err := validators.ValidateUserInput(k, userMail, emailOpts); err != nil {
 					validUserRegistration = false
 					message = "Failed processing the registration form due " + err.Error()
+					break
+				}
+				if rule := p.config.UserRegistrationConfig.MatchDomainRule(userMail); rule != nil && !rule.IsApproved() {
+					validUserRegistration = false
+					message = "Failed processing the registration form due to the email domain not being permitted"
 				}
 			}
 		}
@@ -406,6 +414,19 @@ func (p *Portal) handleHTTPRegisterAckRequest(ctx context.Context, w http.Respon
 		return p.handleHTTPRegisterScreenWithMessage(ctx, w, r, rr, reg)
 	}
 
+	if rule := p.config.UserRegistrationConfig.MatchDomainRule(req.User.Email); rule != nil {
+		if !rule.IsApproved() {
+			ev := audit.NewEvent(audit.RegistrationDeclined, audit.OutcomeSuccess).WithDetail("domain_rule", true)
+			ev.Username = req.User.Username
+			ev.Email = req.User.Email
+			ev.Actor = "domain_rule"
+			p.logAuditEvent(r, rr, nil, ev)
+			reg.message = "Registration from the email domain is not permitted"
+			return p.handleHTTPRegisterScreenWithMessage(ctx, w, r, rr, reg)
+		}
+		return p.handleHTTPRegisterApproval(ctx, w, r, rr, req, rule)
+	}
+
 	if err := p.registrar.AddUser(req); err != nil {
 		p.logger.Warn(
 			"registration request backend erred",
@@ -417,6 +438,17 @@ func (p *Portal) handleHTTPRegisterAckRequest(ctx context.Context, w http.Respon
 		return p.handleHTTPRegisterScreenWithMessage(ctx, w, r, rr, reg)
 	}
 
+	// The registrant proved the ownership of the email address by following
+	// the link in the registration confirmation email.
+	if err := p.registrar.ConfirmEmailAddress(&requests.Request{User: req.User}); err != nil {
+		p.logger.Warn(
+			"failed confirming registrant email address",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+	}
+
 	// Send a notification to admins.
 	regData := map[string]string{
 		"provider_name":   p.config.UserRegistrationConfig.EmailProvider,
@@ -449,6 +481,100 @@ func (p *Portal) handleHTTPRegisterAckRequest(ctx context.Context, w http.Respon
 	return p.handleHTTPRegisterScreenWithMessage(ctx, w, r, rr, reg)
 }
 
+// handleHTTPRegisterApproval adds the registrant to the local backend right
+// away, without the approval by portal administrators.
+func (p *Portal) handleHTTPRegisterApproval(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request, req *requests.Request, rule *registration.DomainRule) error {
+	reg := &registerRequest{
+		view: "ackfail",
+	}
+
+	backend := p.getLocalBackendByRealm(p.config.UserRegistrationConfig.Realm)
+	if backend == nil {
+		p.logger.Warn(
+			"registration realm not found",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("realm", p.config.UserRegistrationConfig.Realm),
+		)
+		reg.message = "Registration realm not found"
+		return p.handleHTTPRegisterScreenWithMessage(ctx, w, r, rr, reg)
+	}
+
+	req.User.Roles = rule.GetRoles()
+	if err := backend.Request(operator.AddUser, req); err != nil {
+		p.logger.Warn(
+			"registration approval backend erred",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("realm", backend.GetRealm()),
+			zap.Error(err),
+		)
+		reg.message = "Registration session is no longer valid"
+		return p.handleHTTPRegisterScreenWithMessage(ctx, w, r, rr, reg)
+	}
+
+	// The registrant proved the ownership of the email address by following
+	// the link in the registration confirmation email.
+	confirmReq := &requests.Request{
+		ID: rr.ID,
+		User: requests.User{
+			Username: req.User.Username,
+			Email:    req.User.Email,
+		},
+	}
+	if err := backend.Request(operator.ConfirmEmailAddress, confirmReq); err != nil {
+		p.logger.Warn(
+			"failed confirming registrant email address",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.Error(err),
+		)
+	}
+
+	p.logger.Info("Registration approved by domain rule",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("username", req.User.Username),
+		zap.String("email", req.User.Email),
+		zap.String("realm", backend.GetRealm()),
+		zap.Strings("roles", req.User.Roles),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
+	)
+
+	ev := audit.NewEvent(audit.RegistrationApproved, audit.OutcomeSuccess).WithDetail("roles", req.User.Roles)
+	ev.Username = req.User.Username
+	ev.Email = req.User.Email
+	ev.Realm = backend.GetRealm()
+	ev.Actor = "domain_rule"
+	p.logAuditEvent(r, rr, nil, ev)
+
+	// Send a notification to the registrant.
+	regData := map[string]string{
+		"provider_name": p.config.UserRegistrationConfig.EmailProvider,
+		"provider_type": "email",
+		"template":      "registration_verdict",
+		"session_id":    rr.Upstream.SessionID,
+		"request_id":    rr.ID,
+		"username":      req.User.Username,
+		"email":         req.User.Email,
+		"verdict":       "approved",
+	}
+	regData["timestamp"] = time.Now().UTC().Format(time.UnixDate)
+	if err := p.notify(regData); err != nil {
+		p.logger.Warn(
+			"Failed to send notification",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("registration_type", "registration_verdict"),
+			zap.Error(err),
+		)
+	}
+
+	reg.view = "approved"
+	return p.handleHTTPRegisterScreenWithMessage(ctx, w, r, rr, reg)
+}
+
 func getCurrentURL(r *http.Request, suffix string) string {
 	h := r.Header.Get("X-Forwarded-Host")
 	if h == "" {
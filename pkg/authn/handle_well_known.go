@@ -0,0 +1,87 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"net/http"
+	"strings"
+)
+
+// OpenIDConfiguration is the OpenID Connect discovery document of the
+// portal. See https://openid.net/specs/openid-connect-discovery-1_0.html.
+type OpenIDConfiguration struct {
+	Issuer                           string   `json:"issuer,omitempty" xml:"issuer,omitempty" yaml:"issuer,omitempty"`
+	UserinfoEndpoint                 string   `json:"userinfo_endpoint,omitempty" xml:"userinfo_endpoint,omitempty" yaml:"userinfo_endpoint,omitempty"`
+	EndSessionEndpoint               string   `json:"end_session_endpoint,omitempty" xml:"end_session_endpoint,omitempty" yaml:"end_session_endpoint,omitempty"`
+	JwksURI                          string   `json:"jwks_uri,omitempty" xml:"jwks_uri,omitempty" yaml:"jwks_uri,omitempty"`
+	SubjectTypesSupported            []string `json:"subject_types_supported,omitempty" xml:"subject_types_supported,omitempty" yaml:"subject_types_supported,omitempty"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported,omitempty" xml:"id_token_signing_alg_values_supported,omitempty" yaml:"id_token_signing_alg_values_supported,omitempty"`
+	ClaimsSupported                  []string `json:"claims_supported,omitempty" xml:"claims_supported,omitempty" yaml:"claims_supported,omitempty"`
+}
+
+// handleWellKnown serves the documents consumers use to discover the portal
+// and to fetch the keys verifying the tokens issued by the portal.
+func (p *Portal) handleWellKnown(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "GET" {
+		return p.handleJSONError(ctx, w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+	var resp interface{}
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/.well-known/jwks.json"):
+		resp = p.keystore.GetJWKS()
+	case strings.HasSuffix(r.URL.Path, "/.well-known/openid-configuration"):
+		// The discovery document is served only when the base url of the
+		// portal is configured, rather than built from the request headers.
+		if p.config.BaseURL == "" {
+			return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		}
+		resp = p.getOpenIDConfiguration()
+	default:
+		return p.handleJSONError(ctx, w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	}
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusInternalServerError, err.Error())
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(respBytes)
+	return nil
+}
+
+func (p *Portal) getOpenIDConfiguration() *OpenIDConfiguration {
+	// The issuer matches the iss claim of the tokens issued by the portal,
+	// when configured. The endpoints are relative to the base url.
+	portalURL := strings.TrimSuffix(p.config.BaseURL, "/")
+	issuer := p.issuer
+	if issuer == "" {
+		issuer = portalURL
+	}
+	return &OpenIDConfiguration{
+		Issuer:                           issuer,
+		UserinfoEndpoint:                 portalURL + "/whoami",
+		EndSessionEndpoint:               portalURL + "/logout",
+		JwksURI:                          portalURL + "/.well-known/jwks.json",
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: p.keystore.GetSigningMethods(),
+		ClaimsSupported: []string{
+			"aud", "email", "exp", "iat", "iss", "jti", "name",
+			"nbf", "origin", "roles", "sub",
+		},
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends/local"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/kms"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandleWellKnown(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestHandleWellKnown")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	cfg := &PortalConfig{
+		Name: "myportal",
+		BackendConfigs: []backends.Config{
+			{
+				Local: &local.Config{
+					Name:   "local_backend",
+					Method: "local",
+					Realm:  "wellknown",
+					Path:   db.GetPath(),
+				},
+			},
+		},
+	}
+	portal, err := NewPortal(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuerCfg := &PortalConfig{
+		Name:           "issuerportal",
+		BaseURL:        "https://auth.example.com/auth/",
+		Issuer:         "https://auth.example.com",
+		BackendConfigs: cfg.BackendConfigs,
+	}
+	issuerPortal, err := NewPortal(issuerCfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseURLCfg := &PortalConfig{
+		Name:           "baseurlportal",
+		BaseURL:        "https://auth.example.com/auth/",
+		BackendConfigs: cfg.BackendConfigs,
+	}
+	baseURLPortal, err := NewPortal(baseURLCfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name   string
+		portal *Portal
+		method string
+		path   string
+		want   map[string]interface{}
+	}{
+		{
+			name:   "get jwks",
+			method: "GET",
+			path:   "/auth/.well-known/jwks.json",
+			want: map[string]interface{}{
+				"code": http.StatusOK,
+				"body": map[string]interface{}{
+					"key_count": 1,
+					"kty":       "EC",
+					"crv":       "P-521",
+					"alg":       "ES512",
+				},
+			},
+		},
+		{
+			name:   "get openid configuration without base url",
+			method: "GET",
+			path:   "/auth/.well-known/openid-configuration",
+			want: map[string]interface{}{
+				"code": http.StatusNotFound,
+			},
+		},
+		{
+			name:   "get openid configuration with base url",
+			portal: baseURLPortal,
+			method: "GET",
+			path:   "/auth/.well-known/openid-configuration",
+			want: map[string]interface{}{
+				"code": http.StatusOK,
+				"body": map[string]interface{}{
+					"issuer":                                "https://auth.example.com/auth",
+					"jwks_uri":                              "https://auth.example.com/auth/.well-known/jwks.json",
+					"id_token_signing_alg_values_supported": []interface{}{"ES512"},
+				},
+			},
+		},
+		{
+			name:   "get openid configuration with configured issuer",
+			portal: issuerPortal,
+			method: "GET",
+			path:   "/auth/.well-known/openid-configuration",
+			want: map[string]interface{}{
+				"code": http.StatusOK,
+				"body": map[string]interface{}{
+					"issuer":                                "https://auth.example.com",
+					"jwks_uri":                              "https://auth.example.com/auth/.well-known/jwks.json",
+					"id_token_signing_alg_values_supported": []interface{}{"ES512"},
+				},
+			},
+		},
+		{
+			name:   "post jwks",
+			method: "POST",
+			path:   "/auth/.well-known/jwks.json",
+			want: map[string]interface{}{
+				"code": http.StatusMethodNotAllowed,
+			},
+		},
+		{
+			name:   "get unknown document",
+			method: "GET",
+			path:   "/auth/.well-known/foobar",
+			want: map[string]interface{}{
+				"code": http.StatusNotFound,
+			},
+		},
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			r := httptest.NewRequest(tc.method, "https://localhost"+tc.path, nil)
+			w := httptest.NewRecorder()
+			if tc.portal == nil {
+				tc.portal = portal
+			}
+			if err := tc.portal.ServeHTTP(context.Background(), w, r, requests.NewRequest()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := map[string]interface{}{
+				"code": w.Code,
+			}
+			if w.Code == http.StatusOK {
+				m := make(map[string]interface{})
+				if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+					t.Fatalf("failed parsing response: %v", err)
+				}
+				body := make(map[string]interface{})
+				if keys, exists := m["keys"]; exists {
+					body["key_count"] = len(keys.([]interface{}))
+					for _, k := range []string{"kty", "crv", "alg"} {
+						body[k] = keys.([]interface{})[0].(map[string]interface{})[k]
+					}
+				}
+				for _, k := range []string{"issuer", "jwks_uri", "id_token_signing_alg_values_supported"} {
+					if v, exists := m[k]; exists {
+						body[k] = v
+					}
+				}
+				got["body"] = body
+			}
+			tests.EvalObjectsWithLog(t, "response", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestHandleWellKnownWithKeyRotation(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestHandleWellKnownWithKeyRotation")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	keyDir, err := tests.TempDir("TestHandleWellKnownWithKeyRotationKeys")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	cfg := &PortalConfig{
+		Name: "myportal",
+		BackendConfigs: []backends.Config{
+			{
+				Local: &local.Config{
+					Name:   "local_backend",
+					Method: "local",
+					Realm:  "wellknownrotation",
+					Path:   db.GetPath(),
+				},
+			},
+		},
+		CryptoKeyStoreConfig: map[string]interface{}{
+			"key_rotation_interval":  float64(86400),
+			"key_rotation_algorithm": "EdDSA",
+			"key_rotation_directory": keyDir,
+		},
+	}
+	portal, err := NewPortal(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer portal.keystore.StopKeyRotation()
+
+	r := httptest.NewRequest("GET", "https://localhost/auth/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	if err := portal.ServeHTTP(context.Background(), w, r, requests.NewRequest()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := make(map[string][]map[string]interface{})
+	if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+		t.Fatalf("failed parsing response: %v", err)
+	}
+	if len(m["keys"]) != 1 {
+		t.Fatalf("unexpected key count: %v", m["keys"])
+	}
+	kid := m["keys"][0]["kid"].(string)
+	tests.EvalObjects(t, "crv", "Ed25519", m["keys"][0]["crv"])
+	if _, err := os.Stat(filepath.Join(keyDir, kid+".pem")); err != nil {
+		t.Fatalf("rotated key %q was not persisted: %v", kid, err)
+	}
+}
+
+func TestPortalKeyRotationSharedKeys(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestPortalKeyRotationSharedKeys")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	cfg := &PortalConfig{
+		Name: "myportal",
+		BackendConfigs: []backends.Config{
+			{
+				Local: &local.Config{
+					Name:   "local_backend",
+					Method: "local",
+					Realm:  "sharedrotation",
+					Path:   db.GetPath(),
+				},
+			},
+		},
+		CryptoKeyStoreConfig: map[string]interface{}{
+			"key_rotation_interval": float64(86400),
+		},
+	}
+	portal, err := NewPortal(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The keystore without configured keys, e.g. the one of a gatekeeper,
+	// verifies the tokens signed by the rotated keys.
+	ks := kms.NewCryptoKeyStore()
+	if err := ks.AutoGenerate("default", "ES512"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.AddRotatedKeys(); err != nil {
+		t.Fatal(err)
+	}
+	usr := testutils.NewTestUser()
+	if err := portal.keystore.SignToken(nil, nil, usr); err != nil {
+		t.Fatal(err)
+	}
+	ar := requests.NewAuthorizationRequest()
+	ar.Token.Name = "access_token"
+	ar.Token.Payload = usr.Token
+	if _, err := ks.ParseToken(ar); err != nil {
+		t.Fatalf("unexpected parse error for token signed by rotated key: %v", err)
+	}
+
+	// The closed portal stops the rotation and no longer shares its keys.
+	portal.Close()
+	_, err = ks.ParseToken(ar)
+	tests.EvalErr(t, err, "closed portal", true, errors.ErrCryptoKeyStoreParseTokenFailed)
+}
+
+func TestPortalKeyRotationWithConfiguredKeys(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestPortalKeyRotationWithConfiguredKeys")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	keyConfigs, err := kms.ParseCryptoKeyConfigs("crypto key sign-verify foobar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &PortalConfig{
+		Name: "myportal",
+		BackendConfigs: []backends.Config{
+			{
+				Local: &local.Config{
+					Name:   "local_backend",
+					Method: "local",
+					Realm:  "configuredrotation",
+					Path:   db.GetPath(),
+				},
+			},
+		},
+		CryptoKeyConfigs: keyConfigs,
+		CryptoKeyStoreConfig: map[string]interface{}{
+			"key_rotation_interval": float64(86400),
+		},
+	}
+	_, err = NewPortal(cfg, logutil.NewLogger())
+	tests.EvalErr(t, err, "rotation with configured keys", true, errors.ErrCryptoKeyStoreConfig.WithArgs("myportal", errors.ErrCryptoKeyStoreKeyRotationWithKeys))
+}
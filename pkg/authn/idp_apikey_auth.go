@@ -26,7 +26,7 @@ import (
 )
 
 // APIKeyAuth performs API key authentication.
-func (p *Portal) APIKeyAuth(r *idp.ProviderRequest) error {
+func (p *Portal) APIKeyAuth(r *idp.ProviderRequest) (err error) {
 	if r.Realm == "" {
 		r.Realm = "local"
 	}
@@ -36,6 +36,9 @@ func (p *Portal) APIKeyAuth(r *idp.ProviderRequest) error {
 	rr.Response.Authenticated = false
 	rr.Key.Payload = r.Secret
 	rr.Upstream.Realm = r.Realm
+	defer func() {
+		p.logProviderAuditEvent(r, rr, "apikey", err)
+	}()
 
 	backend := p.getBackendByRealm(r.Realm)
 	if backend == nil {
@@ -87,7 +90,10 @@ func (p *Portal) APIKeyAuth(r *idp.ProviderRequest) error {
 	if _, exists := m["origin"]; !exists {
 		m["origin"] = r.Realm
 	}
-	m["iss"] = "authp"
+	m["iss"] = p.issuer
+	if p.issuer == "" {
+		m["iss"] = defaultPortalIssuer
+	}
 	m["addr"] = r.Address
 
 	// Perform user claim transformation if necessary.
@@ -28,7 +28,7 @@ import (
 )
 
 // BasicAuth performs API key authentication.
-func (p *Portal) BasicAuth(r *idp.ProviderRequest) error {
+func (p *Portal) BasicAuth(r *idp.ProviderRequest) (err error) {
 	if r.Realm == "" {
 		r.Realm = "local"
 	}
@@ -37,6 +37,9 @@ func (p *Portal) BasicAuth(r *idp.ProviderRequest) error {
 	rr.Logger = p.logger
 	rr.Response.Authenticated = false
 	rr.Upstream.Realm = r.Realm
+	defer func() {
+		p.logProviderAuditEvent(r, rr, "basicauth", err)
+	}()
 
 	arr, err := base64.StdEncoding.DecodeString(r.Secret)
 	if err != nil {
@@ -139,7 +142,10 @@ func (p *Portal) BasicAuth(r *idp.ProviderRequest) error {
 	if _, exists := m["origin"]; !exists {
 		m["origin"] = r.Realm
 	}
-	m["iss"] = "authp"
+	m["iss"] = p.issuer
+	if p.issuer == "" {
+		m["iss"] = defaultPortalIssuer
+	}
 	m["addr"] = r.Address
 
 	// Perform user claim transformation if necessary.
@@ -0,0 +1,119 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"net/http"
+	"sort"
+)
+
+var (
+	loginCounter = metrics.NewCounterVec(
+		"authcrunch_portal_logins_total",
+		"The number of login attempts, by outcome.",
+		"portal", "realm", "backend", "outcome",
+	)
+	mfaCounter = metrics.NewCounterVec(
+		"authcrunch_portal_mfa_verifications_total",
+		"The number of multi-factor authentication attempts, by outcome.",
+		"portal", "realm", "token_type", "outcome",
+	)
+)
+
+func init() {
+	metrics.DefaultRegistry.Register("portal_logins", loginCounter)
+	metrics.DefaultRegistry.Register("portal_mfa", mfaCounter)
+}
+
+// recordLogin counts login attempt in metrics. The realms not served by the
+// portal are counted as unknown, because the realm comes from user input.
+func (p *Portal) recordLogin(realm string, success bool) {
+	backendName := "unknown"
+	if backend := p.getBackendByRealm(realm); backend != nil {
+		backendName = backend.GetName()
+	} else {
+		realm = "unknown"
+	}
+	loginCounter.Inc(p.config.Name, realm, backendName, getOutcome(success))
+}
+
+// recordMfa counts multi-factor authentication attempt in metrics.
+func (p *Portal) recordMfa(realm, tokenType string, success bool) {
+	mfaCounter.Inc(p.config.Name, realm, tokenType, getOutcome(success))
+}
+
+func getOutcome(success bool) string {
+	if success {
+		return "success"
+	}
+	return "failure"
+}
+
+// registerMetrics adds the collector reporting the state of the portal to
+// the metrics registry. The collector is keyed by the id of the portal,
+// i.e. the portal replacing another one does not remove it.
+func (p *Portal) registerMetrics() {
+	metrics.DefaultRegistry.Register(p.getMetricsKey(), metrics.CollectorFunc(p.collectMetrics))
+}
+
+// unregisterMetrics removes the collector of the portal from the metrics
+// registry.
+func (p *Portal) unregisterMetrics() {
+	metrics.DefaultRegistry.Unregister(p.getMetricsKey())
+}
+
+func (p *Portal) getMetricsKey() string {
+	return "portal/" + p.config.Name + "/" + p.id
+}
+
+// collectMetrics returns the number of entries in the caches of the portal.
+func (p *Portal) collectMetrics() []*metrics.Metric {
+	sizes := map[string]int{
+		"sessions":       p.sessions.Size(),
+		"sandboxes":      p.sandboxes.Size(),
+		"refresh_tokens": p.refreshTokens.Size(),
+	}
+	if p.registrations != nil {
+		sizes["registrations"] = p.registrations.Size()
+	}
+	if p.recoveries != nil {
+		sizes["recoveries"] = p.recoveries.Size()
+	}
+	var names []string
+	for name := range sizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var m []*metrics.Metric
+	for _, name := range names {
+		m = append(m, metrics.NewGauge(
+			"authcrunch_portal_cache_entries",
+			"The number of entries in the caches of the portal.",
+			float64(sizes[name]),
+			&metrics.Label{Name: "portal", Value: p.config.Name},
+			&metrics.Label{Name: "cache", Value: name},
+		))
+	}
+	return m
+}
+
+// handleMetrics serves the metrics in Prometheus text exposition format.
+func (p *Portal) handleMetrics(ctx context.Context, w http.ResponseWriter, r *http.Request, rr *requests.Request) error {
+	p.config.MetricsConfig.Serve(w, r, metrics.DefaultRegistry)
+	return nil
+}
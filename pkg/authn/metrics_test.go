@@ -0,0 +1,95 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends/local"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPortalMetrics(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestPortalMetrics")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	cfg := &PortalConfig{
+		Name: "metricsportal",
+		BackendConfigs: []backends.Config{
+			{
+				Local: &local.Config{
+					Name:   "local_backend",
+					Method: "local",
+					Realm:  "metricsrealm",
+					Path:   db.GetPath(),
+				},
+			},
+		},
+		MetricsConfig: &metrics.Config{Path: "/auth/metrics", AllowUnauthenticated: true},
+	}
+	portal, err := NewPortal(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	portal.recordLogin("metricsrealm", true)
+	portal.recordLogin("metricsrealm", false)
+	portal.recordLogin("foobar", false)
+	portal.recordMfa("metricsrealm", "totp", true)
+
+	r := httptest.NewRequest("GET", "/auth/metrics", nil)
+	w := httptest.NewRecorder()
+	rr := requests.NewRequest()
+	if err := portal.ServeHTTP(context.Background(), w, r, rr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != 200 {
+		t.Fatalf("expected status code 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		`authcrunch_portal_logins_total{portal="metricsportal",realm="metricsrealm",backend="local_backend",outcome="success"} 1`,
+		`authcrunch_portal_logins_total{portal="metricsportal",realm="metricsrealm",backend="local_backend",outcome="failure"} 1`,
+		`authcrunch_portal_logins_total{portal="metricsportal",realm="unknown",backend="unknown",outcome="failure"} 1`,
+		`authcrunch_portal_mfa_verifications_total{portal="metricsportal",realm="metricsrealm",token_type="totp",outcome="success"} 1`,
+		`authcrunch_portal_cache_entries{portal="metricsportal",cache="sessions"} 0`,
+		`# TYPE authcrunch_backend_request_duration_seconds histogram`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("line %q not found in output:\n%s", line, body)
+		}
+	}
+	// The collector of the closed portal is removed.
+	portal.Close()
+	for _, m := range metrics.DefaultRegistry.Gather() {
+		if m.Name != "authcrunch_portal_cache_entries" {
+			continue
+		}
+		for _, sample := range m.Samples {
+			for _, label := range sample.Labels {
+				if label.Name == "portal" && label.Value == "metricsportal" {
+					t.Fatalf("cache metrics of closed portal remain registered")
+				}
+			}
+		}
+	}
+}
@@ -68,6 +68,16 @@ func (p *Portal) notify(data map[string]string) error {
 		requiredFields = []string{
 			"username", "email", "verdict",
 		}
+	case "password_recovery":
+		requiredFields = []string{
+			"recovery_id", "username", "email", "recovery_url",
+			"src_ip", "src_conn_ip",
+		}
+	case "email_verification":
+		requiredFields = []string{
+			"verification_token", "username", "email", "verification_url",
+			"src_ip", "src_conn_ip",
+		}
 	default:
 		return errors.ErrNotifyRequestTemplateUnsupported.WithArgs(tmplName)
 	}
@@ -79,7 +89,7 @@ func (p *Portal) notify(data map[string]string) error {
 	}
 
 	switch tmplName {
-	case "registration_confirmation", "registration_verdict":
+	case "registration_confirmation", "registration_verdict", "password_recovery", "email_verification":
 		rcpts = append(rcpts, data["email"])
 	case "registration_ready":
 
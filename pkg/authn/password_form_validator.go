@@ -62,3 +62,20 @@ func validateSandboxPasswordForm(r *http.Request, rr *requests.Request) error {
 	rr.User.Password = r.PostFormValue("secret")
 	return nil
 }
+
+func validateRecoveryPasswordForm(r *http.Request, rr *requests.Request) error {
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return fmt.Errorf("Unsupported content type")
+	}
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("Failed parsing submitted form")
+	}
+	if r.PostFormValue("secret") == "" {
+		return fmt.Errorf("New password is empty")
+	}
+	if r.PostFormValue("secret") != r.PostFormValue("secret_confirm") {
+		return fmt.Errorf("New password mismatch")
+	}
+	rr.User.Password = r.PostFormValue("secret")
+	return nil
+}
@@ -16,7 +16,9 @@ package authn
 
 import (
 	"context"
+	"crypto/rand"
 	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/audit"
 	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
 	"github.com/greenpau/go-authcrunch/pkg/authn/cache"
 	"github.com/greenpau/go-authcrunch/pkg/authn/cookie"
@@ -32,6 +34,7 @@ import (
 
 	"github.com/satori/go.uuid"
 	"go.uber.org/zap"
+	"net/url"
 	"path"
 	"strings"
 	"time"
@@ -40,14 +43,19 @@ import (
 const (
 	defaultPortalACLCondition = "match roles authp/admin authp/user authp/guest superuser superadmin"
 	defaultPortalACLAction    = "allow stop"
+	// The issuer of the tokens issued for basic and api key authentication
+	// when the issuer is not configured.
+	defaultPortalIssuer = "authp"
 )
 
 // Portal is an authentication portal.
 type Portal struct {
 	id            string
 	config        *PortalConfig
+	issuer        string
 	registrar     *identity.Database
 	validator     *validator.TokenValidator
+	accessList    *acl.AccessList
 	keystore      *kms.CryptoKeyStore
 	backends      []*backends.Backend
 	cookie        *cookie.Factory
@@ -57,6 +65,11 @@ type Portal struct {
 	sessions      *cache.SessionCache
 	sandboxes     *cache.SandboxCache
 	registrations *cache.RegistrationCache
+	recoveries    *cache.RecoveryCache
+	refreshTokens *cache.RefreshTokenCache
+	verifySecret  []byte
+	cacheStore    cache.Store
+	audit         *audit.Logger
 	loginOptions  map[string]interface{}
 	logger        *zap.Logger
 }
@@ -78,6 +91,7 @@ func NewPortal(cfg *PortalConfig, logger *zap.Logger) (*Portal, error) {
 		logger: logger,
 	}
 	if err := p.configure(); err != nil {
+		p.Close()
 		return nil, err
 	}
 	return p, nil
@@ -88,6 +102,18 @@ func (p *Portal) Register() error {
 	return portalRegistry.RegisterPortal(p.config.Name, p)
 }
 
+// Close releases the resources held by the Portal, i.e. it stops the key
+// rotation, removes the metrics collector, and flushes and closes the audit
+// log. The Portal is closed when it is replaced in or removed from
+// PortalRegistry.
+func (p *Portal) Close() error {
+	if p.keystore != nil {
+		p.keystore.StopKeyRotation()
+	}
+	p.unregisterMetrics()
+	return p.audit.Close()
+}
+
 func (p *Portal) configure() error {
 	if err := p.configureEssentials(); err != nil {
 		return err
@@ -95,18 +121,28 @@ func (p *Portal) configure() error {
 	if err := p.configureCryptoKeyStore(); err != nil {
 		return err
 	}
+	if err := p.configureAuditLog(); err != nil {
+		return err
+	}
 	if err := p.configureBackends(); err != nil {
 		return err
 	}
 	if err := p.configureUserRegistration(); err != nil {
 		return err
 	}
+	if err := p.configurePasswordRecovery(); err != nil {
+		return err
+	}
+	if err := p.configureEmailVerification(); err != nil {
+		return err
+	}
 	if err := p.configureUserInterface(); err != nil {
 		return err
 	}
 	if err := p.configureUserTransformer(); err != nil {
 		return err
 	}
+	p.registerMetrics()
 	return nil
 }
 
@@ -121,6 +157,25 @@ func (p *Portal) configureEssentials() error {
 	p.sessions.Run()
 	p.sandboxes = cache.NewSandboxCache()
 	p.sandboxes.Run()
+	if p.config.CacheStore != nil {
+		store, err := cache.NewStore(p.config.CacheStore)
+		if err != nil {
+			return errors.ErrCacheStoreConfig.WithArgs(p.config.Name, err)
+		}
+		p.cacheStore = store
+		p.sessions.SetStore(store)
+		p.sandboxes.SetStore(store)
+	}
+	p.refreshTokens = cache.NewRefreshTokenCache()
+	p.refreshTokens.Run()
+	if p.cacheStore != nil {
+		p.refreshTokens.SetStore(p.cacheStore)
+	}
+
+	// When configured, the issuer is taken from the configuration, rather
+	// than from the request headers, so that the tokens and the discovery
+	// document agree on it regardless of the endpoint serving them.
+	p.issuer = strings.TrimSuffix(p.config.Issuer, "/")
 
 	p.logger.Debug(
 		"Configuring cookie parameters",
@@ -135,6 +190,24 @@ func (p *Portal) configureEssentials() error {
 	return nil
 }
 
+func (p *Portal) configureAuditLog() error {
+	if p.config.AuditConfig == nil {
+		return nil
+	}
+	p.logger.Debug(
+		"Configuring audit log",
+		zap.String("portal_name", p.config.Name),
+		zap.String("portal_id", p.id),
+		zap.Int("sink_count", len(p.config.AuditConfig.Sinks)),
+	)
+	logger, err := audit.NewLogger(p.config.AuditConfig, p.logger)
+	if err != nil {
+		return errors.ErrAuditLogConfig.WithArgs(p.config.Name, err)
+	}
+	p.audit = logger
+	return nil
+}
+
 func (p *Portal) configureCryptoKeyStore() error {
 	if len(p.config.AccessListConfigs) == 0 {
 		p.config.AccessListConfigs = []*acl.RuleConfiguration{
@@ -167,6 +240,7 @@ func (p *Portal) configureCryptoKeyStore() error {
 	if err := accessList.AddRules(ctx, p.config.AccessListConfigs); err != nil {
 		return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, err)
 	}
+	p.accessList = accessList
 
 	p.keystore = kms.NewCryptoKeyStore()
 	p.keystore.SetLogger(p.logger)
@@ -180,11 +254,18 @@ func (p *Portal) configureCryptoKeyStore() error {
 		}
 	}
 
-	if len(p.config.CryptoKeyConfigs) == 0 {
+	switch {
+	case len(p.config.CryptoKeyConfigs) > 0 && p.keystore.KeyRotationEnabled():
+		return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, errors.ErrCryptoKeyStoreKeyRotationWithKeys)
+	case p.keystore.KeyRotationEnabled():
+		if err := p.keystore.RotateKeys(); err != nil {
+			return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, err)
+		}
+	case len(p.config.CryptoKeyConfigs) == 0:
 		if err := p.keystore.AutoGenerate("default", "ES512"); err != nil {
 			return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, err)
 		}
-	} else {
+	default:
 		if err := p.keystore.AddKeysWithConfigs(p.config.CryptoKeyConfigs); err != nil {
 			return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, err)
 		}
@@ -199,6 +280,23 @@ func (p *Portal) configureCryptoKeyStore() error {
 		return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, err)
 	}
 
+	if p.keystore.KeyRotationEnabled() {
+		// Keep the keys of token validator in sync with rotated keys.
+		p.keystore.OnKeyRotation(func(keys []*kms.CryptoKey) {
+			if err := p.validator.UpdateKeys(ctx, keys); err != nil {
+				p.logger.Error(
+					"Failed updating validator keys after key rotation",
+					zap.String("portal_name", p.config.Name),
+					zap.String("portal_id", p.id),
+					zap.Error(err),
+				)
+			}
+		})
+		if err := p.keystore.StartKeyRotation(); err != nil {
+			return errors.ErrCryptoKeyStoreConfig.WithArgs(p.config.Name, err)
+		}
+	}
+
 	p.logger.Debug(
 		"Configured validator ACL",
 		zap.String("portal_name", p.config.Name),
@@ -358,6 +456,20 @@ func (p *Portal) configureUserRegistration() error {
 		return errors.ErrUserRegistrationConfig.WithArgs(p.config.Name, "admin email address(es) not found")
 	}
 
+	if err := p.config.UserRegistrationConfig.Validate(); err != nil {
+		return errors.ErrUserRegistrationConfig.WithArgs(p.config.Name, err)
+	}
+
+	for _, rule := range p.config.UserRegistrationConfig.DomainRules {
+		if !rule.IsApproved() {
+			continue
+		}
+		if backend := p.getLocalBackendByRealm(p.config.UserRegistrationConfig.Realm); backend == nil {
+			return errors.ErrUserRegistrationConfig.WithArgs(p.config.Name, errors.ErrRegistrationRealmNotFound.WithArgs(p.config.UserRegistrationConfig.Realm))
+		}
+		break
+	}
+
 	p.logger.Debug(
 		"Configuring user registration",
 		zap.String("portal_name", p.config.Name),
@@ -380,6 +492,9 @@ func (p *Portal) configureUserRegistration() error {
 	if p.registrations == nil {
 		p.registrations = cache.NewRegistrationCache()
 		p.registrations.Run()
+		if p.cacheStore != nil {
+			p.registrations.SetStore(p.cacheStore)
+		}
 	}
 
 	p.logger.Debug(
@@ -388,6 +503,99 @@ func (p *Portal) configureUserRegistration() error {
 		zap.String("portal_id", p.id),
 		zap.String("dropbox", p.config.UserRegistrationConfig.Dropbox),
 		zap.Strings("admin_emails", p.config.UserRegistrationConfig.AdminEmails),
+		zap.Int("domain_rule_count", len(p.config.UserRegistrationConfig.DomainRules)),
+	)
+	return nil
+}
+
+func (p *Portal) configurePasswordRecovery() error {
+	if p.config.UI == nil || !p.config.UI.PasswordRecoveryEnabled {
+		return nil
+	}
+
+	// The recovery emails are being sent via the email provider
+	// configured for the user registration.
+	if p.config.UserRegistrationConfig == nil || p.config.UserRegistrationConfig.EmailProvider == "" {
+		return errors.ErrPasswordRecoveryConfig.WithArgs(p.config.Name, "email provider not found")
+	}
+
+	var localBackendFound bool
+	for _, backend := range p.backends {
+		if backend.GetMethod() == "local" {
+			localBackendFound = true
+			break
+		}
+	}
+	if !localBackendFound {
+		return errors.ErrPasswordRecoveryConfig.WithArgs(p.config.Name, "local backend not found")
+	}
+
+	// The recovery links are built from the base URL, rather than from
+	// the request headers, which are controlled by the client.
+	if p.config.BaseURL == "" {
+		return errors.ErrPasswordRecoveryConfig.WithArgs(p.config.Name, "base url not found")
+	}
+	if u, err := url.Parse(p.config.BaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.ErrPasswordRecoveryConfig.WithArgs(p.config.Name, "base url is malformed")
+	}
+
+	if p.recoveries == nil {
+		p.recoveries = cache.NewRecoveryCache()
+		p.recoveries.Run()
+		if p.cacheStore != nil {
+			p.recoveries.SetStore(p.cacheStore)
+		}
+	}
+
+	p.logger.Debug(
+		"Configured password recovery",
+		zap.String("portal_name", p.config.Name),
+		zap.String("portal_id", p.id),
+		zap.String("email_provider", p.config.UserRegistrationConfig.EmailProvider),
+		zap.String("base_url", p.config.BaseURL),
+	)
+	return nil
+}
+
+func (p *Portal) configureEmailVerification() error {
+	// The verification emails are being sent via the email provider
+	// configured for the user registration.
+	if p.config.UserRegistrationConfig == nil || p.config.UserRegistrationConfig.EmailProvider == "" {
+		return nil
+	}
+
+	// The verification links are built from the base URL, rather than from
+	// the request headers, which are controlled by the client.
+	if p.config.BaseURL == "" {
+		if p.config.UserRegistrationConfig.EmailVerificationSecret != "" {
+			return errors.ErrEmailVerificationConfig.WithArgs(p.config.Name, "base url not found")
+		}
+		p.logger.Warn(
+			"Email verification disabled, base url not found",
+			zap.String("portal_name", p.config.Name),
+			zap.String("portal_id", p.id),
+		)
+		return nil
+	}
+	if u, err := url.Parse(p.config.BaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.ErrEmailVerificationConfig.WithArgs(p.config.Name, "base url is malformed")
+	}
+
+	if p.config.UserRegistrationConfig.EmailVerificationSecret != "" {
+		p.verifySecret = []byte(p.config.UserRegistrationConfig.EmailVerificationSecret)
+	} else {
+		p.verifySecret = make([]byte, 32)
+		if _, err := rand.Read(p.verifySecret); err != nil {
+			return errors.ErrEmailVerificationConfig.WithArgs(p.config.Name, err)
+		}
+	}
+
+	p.logger.Debug(
+		"Configured email verification",
+		zap.String("portal_name", p.config.Name),
+		zap.String("portal_id", p.id),
+		zap.String("email_provider", p.config.UserRegistrationConfig.EmailProvider),
+		zap.String("base_url", p.config.BaseURL),
 	)
 	return nil
 }
@@ -0,0 +1,98 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registration
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"strings"
+)
+
+const (
+	// ApproveAction is the domain rule action approving registrations.
+	ApproveAction = "approve"
+	// DeclineAction is the domain rule action declining registrations.
+	DeclineAction = "decline"
+)
+
+// DomainRule approves or declines the registrations of the users with
+// the email addresses in the listed domains.
+type DomainRule struct {
+	// The list of email domains. The domain prefixed with "*." matches
+	// its subdomains.
+	Domains []string `json:"domains,omitempty" xml:"domains,omitempty" yaml:"domains,omitempty"`
+	// The action, either approve or decline.
+	Action string `json:"action,omitempty" xml:"action,omitempty" yaml:"action,omitempty"`
+	// The roles assigned to approved registrants. When empty, the
+	// registrants get authp/user role.
+	Roles []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+}
+
+// Validate validates and normalizes the domain rule.
+func (r *DomainRule) Validate() error {
+	if r == nil {
+		return errors.ErrRegistrationDomainRuleNil
+	}
+	if len(r.Domains) < 1 {
+		return errors.ErrRegistrationDomainRuleDomainsEmpty
+	}
+	for i, domain := range r.Domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		s := strings.TrimPrefix(domain, "*.")
+		if s == "" || strings.ContainsAny(s, "@*/ ") || strings.HasPrefix(s, ".") || strings.HasSuffix(s, ".") {
+			return errors.ErrRegistrationDomainRuleDomainInvalid.WithArgs(r.Domains[i])
+		}
+		r.Domains[i] = domain
+	}
+	switch r.Action {
+	case ApproveAction:
+	case DeclineAction:
+		if len(r.Roles) > 0 {
+			return errors.ErrRegistrationDomainRuleRolesUnsupported
+		}
+	default:
+		return errors.ErrRegistrationDomainRuleActionUnsupported.WithArgs(r.Action)
+	}
+	return nil
+}
+
+// Match returns true when the provided email domain matches the rule.
+func (r *DomainRule) Match(domain string) bool {
+	domain = strings.ToLower(domain)
+	for _, s := range r.Domains {
+		if strings.HasPrefix(s, "*.") {
+			if strings.HasSuffix(domain, s[1:]) {
+				return true
+			}
+			continue
+		}
+		if domain == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsApproved returns true when the rule approves registrations.
+func (r *DomainRule) IsApproved() bool {
+	return r.Action == ApproveAction
+}
+
+// GetRoles returns the roles assigned to approved registrants.
+func (r *DomainRule) GetRoles() []string {
+	if len(r.Roles) > 0 {
+		return r.Roles
+	}
+	return []string{"authp/user"}
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registration
+
+import (
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"testing"
+)
+
+func TestDomainRules(t *testing.T) {
+	testcases := []struct {
+		name      string
+		rules     []*DomainRule
+		want      map[string]string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "match domain rules",
+			rules: []*DomainRule{
+				{Domains: []string{"contoso.com", "*.contoso.com"}, Action: "approve", Roles: []string{"authp/admin"}},
+				{Domains: []string{"Example.COM"}, Action: "decline"},
+			},
+			want: map[string]string{
+				"jsmith@contoso.com":       "approve",
+				"jsmith@hr.contoso.com":    "approve",
+				"jsmith@notcontoso.com":    "",
+				"jsmith@example.com":       "decline",
+				"jsmith@EXAMPLE.com":       "decline",
+				"jsmith@mail.example.com":  "",
+				"jsmith":                   "",
+				"jsmith@contoso.com.local": "",
+			},
+		},
+		{
+			name: "domain rule without domains",
+			rules: []*DomainRule{
+				{Action: "approve"},
+			},
+			shouldErr: true,
+			err:       errors.ErrRegistrationDomainRuleDomainsEmpty,
+		},
+		{
+			name: "domain rule with invalid domain",
+			rules: []*DomainRule{
+				{Domains: []string{"jsmith@contoso.com"}, Action: "approve"},
+			},
+			shouldErr: true,
+			err:       errors.ErrRegistrationDomainRuleDomainInvalid.WithArgs("jsmith@contoso.com"),
+		},
+		{
+			name: "domain rule with unsupported action",
+			rules: []*DomainRule{
+				{Domains: []string{"contoso.com"}, Action: "allow"},
+			},
+			shouldErr: true,
+			err:       errors.ErrRegistrationDomainRuleActionUnsupported.WithArgs("allow"),
+		},
+		{
+			name: "declining domain rule with roles",
+			rules: []*DomainRule{
+				{Domains: []string{"contoso.com"}, Action: "decline", Roles: []string{"authp/user"}},
+			},
+			shouldErr: true,
+			err:       errors.ErrRegistrationDomainRuleRolesUnsupported,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &Config{DomainRules: tc.rules}
+			err := cfg.Validate()
+			if tests.EvalErrWithLog(t, err, "domain rules", tc.shouldErr, tc.err, []string{}) {
+				return
+			}
+			got := make(map[string]string)
+			for email := range tc.want {
+				got[email] = ""
+				if rule := cfg.MatchDomainRule(email); rule != nil {
+					got[email] = rule.Action
+				}
+			}
+			tests.EvalObjectsWithLog(t, "actions", tc.want, got, []string{})
+		})
+	}
+}
@@ -14,6 +14,10 @@
 
 package registration
 
+import (
+	"strings"
+)
+
 // Config represents a common set of configuration settings for user registration
 type Config struct {
 	// The switch determining whether the registration is enabled/disabled.
@@ -38,4 +42,41 @@ type Config struct {
 	EmailProvider string `json:"email_provider,omitempty" xml:"email_provider,omitempty" yaml:"email_provider,omitempty"`
 	// The email address(es) of portal administrators.
 	AdminEmails []string `json:"admin_emails,omitempty" xml:"admin_emails,omitempty" yaml:"admin_emails,omitempty"`
+	// The secret signing email verification links. When empty, the secret
+	// is random and the links become invalid upon restart.
+	EmailVerificationSecret string `json:"email_verification_secret,omitempty" xml:"email_verification_secret,omitempty" yaml:"email_verification_secret,omitempty"`
+	// The rules approving or declining registrations automatically based on
+	// the domain of the registrant email address. The first matching rule
+	// applies. The registrations not matching any rule require the approval
+	// by portal administrators.
+	DomainRules []*DomainRule `json:"domain_rules,omitempty" xml:"domain_rules,omitempty" yaml:"domain_rules,omitempty"`
+	// The realm of the local backend receiving automatically approved
+	// registrants. When empty, the first local backend is being used.
+	Realm string `json:"realm,omitempty" xml:"realm,omitempty" yaml:"realm,omitempty"`
+}
+
+// Validate validates user registration configuration.
+func (cfg *Config) Validate() error {
+	for _, rule := range cfg.DomainRules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MatchDomainRule returns the first domain rule matching the domain of the
+// provided email address. It returns nil when no rule matches.
+func (cfg *Config) MatchDomainRule(email string) *DomainRule {
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return nil
+	}
+	domain := strings.ToLower(email[i+1:])
+	for _, rule := range cfg.DomainRules {
+		if rule.Match(domain) {
+			return rule
+		}
+	}
+	return nil
 }
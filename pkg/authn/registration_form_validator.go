@@ -0,0 +1,49 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func validateRegistrationReviewForm(r *http.Request) ([]string, string, string, string, error) {
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return nil, "", "", "", fmt.Errorf("Unsupported content type")
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, "", "", "", fmt.Errorf("Failed parsing submitted form")
+	}
+	var verdict string
+	switch r.PostFormValue("action") {
+	case "approve":
+		verdict = "approved"
+	case "decline":
+		verdict = "declined"
+	default:
+		return nil, "", "", "", fmt.Errorf("Unsupported review action")
+	}
+	ids := r.PostForm["registration_id"]
+	if len(ids) < 1 {
+		return nil, "", "", "", fmt.Errorf("No registrations selected")
+	}
+	note := strings.TrimSpace(r.PostFormValue("note"))
+	reason := strings.TrimSpace(r.PostFormValue("reason"))
+	if len(note) > 1024 || len(reason) > 1024 {
+		return nil, "", "", "", fmt.Errorf("Note or reason is too long")
+	}
+	return ids, verdict, note, reason, nil
+}
@@ -0,0 +1,235 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/audit"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/identity"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	addrutil "github.com/greenpau/go-authcrunch/pkg/util/addr"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// pendingRegistration is the registration awaiting the review by portal
+// administrators.
+type pendingRegistration struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// registrationReviewResult is the outcome of the review of a registration.
+type registrationReviewResult struct {
+	ID      string `json:"id"`
+	Verdict string `json:"verdict,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// getPendingRegistrations returns the registrations awaiting review in the
+// registration dropbox.
+func (p *Portal) getPendingRegistrations() ([]*pendingRegistration, error) {
+	if p.registrar == nil {
+		return nil, errors.ErrRegistrationDisabled
+	}
+	req := &requests.Request{}
+	if err := p.registrar.GetRegistrations(req); err != nil {
+		return nil, err
+	}
+	entries := []*pendingRegistration{}
+	for _, u := range req.Response.Payload.([]*identity.User) {
+		entries = append(entries, &pendingRegistration{
+			ID:        u.Registration.ID,
+			Username:  u.Username,
+			Email:     u.GetMailClaim(),
+			Name:      u.GetNameClaim(),
+			CreatedAt: u.Registration.CreatedAt,
+		})
+	}
+	return entries, nil
+}
+
+// reviewRegistrations approves or declines the registrations with the
+// provided ids. The errors are reported per registration.
+func (p *Portal) reviewRegistrations(r *http.Request, rr *requests.Request, admin *user.User, ids []string, verdict, note, reason string) []*registrationReviewResult {
+	results := []*registrationReviewResult{}
+	for _, id := range ids {
+		result := &registrationReviewResult{ID: id}
+		if err := p.reviewRegistration(r, rr, admin, id, verdict, note, reason); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Verdict = verdict
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// reviewRegistration approves or declines a pending registration in the
+// registration dropbox. The approved registrant is added to the local
+// backend. The registrant receives the verdict, including the reason, by
+// email.
+func (p *Portal) reviewRegistration(r *http.Request, rr *requests.Request, admin *user.User, id, verdict, note, reason string) error {
+	if p.registrar == nil {
+		return errors.ErrRegistrationDisabled
+	}
+
+	req := &requests.Request{
+		ID: rr.ID,
+		Query: requests.Query{
+			ID: id,
+		},
+		Registration: requests.Registration{
+			Note:   note,
+			Reason: reason,
+		},
+	}
+
+	switch verdict {
+	case "approved":
+		pending, err := p.getPendingRegistration(id)
+		if err != nil {
+			return err
+		}
+		backend := p.getLocalBackendByRealm(p.config.UserRegistrationConfig.Realm)
+		if backend == nil {
+			return errors.ErrRegistrationRealmNotFound.WithArgs(p.config.UserRegistrationConfig.Realm)
+		}
+		// The dropbox holds the hash of the registrant password.
+		var passwordHash string
+		if password := pending.GetPassword(); password != nil {
+			passwordHash = password.Encode()
+		}
+		addReq := &requests.Request{
+			ID: rr.ID,
+			User: requests.User{
+				Username:     pending.Username,
+				Email:        pending.GetMailClaim(),
+				PasswordHash: passwordHash,
+				FullName:     pending.GetNameClaim(),
+				Roles:        pending.GetRolesClaim(),
+			},
+		}
+		// The registration is approved prior to adding the registrant, so
+		// that concurrent approvals of the same registration cannot both
+		// add the registrant. The approval is reverted when adding fails.
+		if err := p.registrar.ApproveRegistration(req); err != nil {
+			return err
+		}
+		if err := backend.Request(operator.AddUser, addReq); err != nil {
+			if reopenErr := p.registrar.ReopenRegistration(req); reopenErr != nil {
+				p.logger.Warn(
+					"failed reopening registration",
+					zap.String("session_id", rr.Upstream.SessionID),
+					zap.String("request_id", rr.ID),
+					zap.String("registration_id", id),
+					zap.Error(reopenErr),
+				)
+			}
+			return err
+		}
+		// The registrant confirmed the email address prior to the review.
+		confirmReq := &requests.Request{
+			ID: rr.ID,
+			User: requests.User{
+				Username: addReq.User.Username,
+				Email:    addReq.User.Email,
+			},
+		}
+		if err := backend.Request(operator.ConfirmEmailAddress, confirmReq); err != nil {
+			p.logger.Warn(
+				"failed confirming registrant email address",
+				zap.String("session_id", rr.Upstream.SessionID),
+				zap.String("request_id", rr.ID),
+				zap.String("registration_id", id),
+				zap.Error(err),
+			)
+		}
+	case "declined":
+		if err := p.registrar.DeclineRegistration(req); err != nil {
+			return err
+		}
+	default:
+		return errors.ErrRegistrationVerdictUnsupported.WithArgs(verdict)
+	}
+
+	p.logger.Info(
+		"Registration reviewed",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("registration_id", id),
+		zap.String("verdict", verdict),
+		zap.String("username", req.User.Username),
+		zap.String("email", req.User.Email),
+		zap.String("admin", admin.Claims.Email),
+		zap.String("src_ip", addrutil.GetSourceAddress(r)),
+		zap.String("src_conn_ip", addrutil.GetSourceConnAddress(r)),
+	)
+
+	ev := audit.NewEvent(audit.RegistrationApproved, audit.OutcomeSuccess).WithDetail("registration_id", id)
+	if verdict == "declined" {
+		ev.Type = audit.RegistrationDeclined
+	}
+	ev.Username = req.User.Username
+	ev.Email = req.User.Email
+	ev.Realm = p.config.UserRegistrationConfig.Realm
+	ev.Reason = reason
+	p.logAuditEvent(r, rr, admin, ev)
+
+	regData := map[string]string{
+		"provider_name": p.config.UserRegistrationConfig.EmailProvider,
+		"provider_type": "email",
+		"template":      "registration_verdict",
+		"session_id":    rr.Upstream.SessionID,
+		"request_id":    rr.ID,
+		"username":      req.User.Username,
+		"email":         req.User.Email,
+		"verdict":       verdict,
+		"reason":        reason,
+	}
+	regData["timestamp"] = time.Now().UTC().Format(time.UnixDate)
+	if err := p.notify(regData); err != nil {
+		p.logger.Warn(
+			"Failed to send notification",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("registration_id", id),
+			zap.String("registration_type", "registration_verdict"),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// getPendingRegistration returns the registrant of the pending registration
+// with the provided id.
+func (p *Portal) getPendingRegistration(id string) (*identity.User, error) {
+	req := &requests.Request{}
+	if err := p.registrar.GetRegistrations(req); err != nil {
+		return nil, err
+	}
+	for _, u := range req.Response.Payload.([]*identity.User) {
+		if u.Registration.ID == id {
+			return u, nil
+		}
+	}
+	return nil, errors.ErrRegistrationNotFound.WithArgs(id)
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends"
+	"github.com/greenpau/go-authcrunch/pkg/authn/backends/local"
+	"github.com/greenpau/go-authcrunch/pkg/authn/enums/operator"
+	"github.com/greenpau/go-authcrunch/pkg/authn/registration"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestReviewRegistration(t *testing.T) {
+	db, err := testutils.CreateTestDatabase("TestReviewRegistration")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	cfg := &PortalConfig{
+		Name: "myportal",
+		BackendConfigs: []backends.Config{
+			{
+				Local: &local.Config{
+					Name:   "local_backend",
+					Method: "local",
+					Realm:  "registrations",
+					Path:   db.GetPath(),
+				},
+			},
+		},
+		UserRegistrationConfig: &registration.Config{
+			Dropbox:       filepath.Join(filepath.Dir(db.GetPath()), "registrations.json"),
+			EmailProvider: "localhost-smtp-server",
+			AdminEmails:   []string{"admin@localhost"},
+		},
+	}
+	portal, err := NewPortal(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	password := tests.NewRandomString(16)
+	for id, username := range map[string]string{"registration1": "mjordan", "registration2": "mwhite"} {
+		if err := portal.registrar.AddUser(&requests.Request{
+			User: requests.User{
+				Username: username,
+				Password: password,
+				Email:    username + "@example.com",
+				Roles:    []string{"authp/user"},
+			},
+			Query: requests.Query{
+				ID: id,
+			},
+		}); err != nil {
+			t.Fatalf("failed adding registrant: %v", err)
+		}
+	}
+
+	r := httptest.NewRequest("POST", "/settings/registrations/review", nil)
+	rr := requests.NewRequest()
+	admin := &user.User{Claims: &user.Claims{Email: "admin@localhost"}}
+
+	results := portal.reviewRegistrations(r, rr, admin, []string{"registration1", "registration2", "registration3"}, "approved", "", "")
+	tests.EvalObjects(t, "results", []*registrationReviewResult{
+		{ID: "registration1", Verdict: "approved"},
+		{ID: "registration2", Verdict: "approved"},
+		{ID: "registration3", Error: `registration "registration3" not found`},
+	}, results)
+
+	entries, err := portal.getPendingRegistrations()
+	if err != nil {
+		t.Fatalf("failed getting pending registrations: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no pending registrations, got: %v", entries)
+	}
+
+	// The approved registrant authenticates with the registration password.
+	backend := portal.getLocalBackendByRealm("registrations")
+	ar := &requests.Request{
+		User: requests.User{
+			Username: "mjordan@example.com",
+			Password: password,
+		},
+	}
+	if err := backend.Request(operator.Authenticate, ar); err != nil {
+		t.Fatalf("expected approved registrant to authenticate: %v", err)
+	}
+
+	if err := portal.reviewRegistration(r, rr, admin, "registration1", "declined", "", ""); err == nil {
+		t.Fatalf("expected failure declining reviewed registration")
+	}
+
+	// The approval of a registrant who cannot be added is reverted.
+	if err := portal.registrar.AddUser(&requests.Request{
+		User: requests.User{
+			Username: tests.TestUser1,
+			Password: password,
+			Email:    "registrant@example.com",
+			Roles:    []string{"authp/user"},
+		},
+		Query: requests.Query{
+			ID: "registration3",
+		},
+	}); err != nil {
+		t.Fatalf("failed adding registrant: %v", err)
+	}
+	if err := portal.reviewRegistration(r, rr, admin, "registration3", "approved", "", ""); err == nil {
+		t.Fatalf("expected failure approving registrant with existing username")
+	}
+	entries, err = portal.getPendingRegistrations()
+	if err != nil {
+		t.Fatalf("failed getting pending registrations: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != "registration3" {
+		t.Fatalf("expected pending registration3, got: %v", entries)
+	}
+}
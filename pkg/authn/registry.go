@@ -48,17 +48,29 @@ func (r *PortalRegistry) LookupPortal(s string) (*Portal, error) {
 	return nil, errors.ErrPortalRegistryEntryNotFound.WithArgs(s)
 }
 
-// RegisterPortal registers Portal with the PortalRegistry.
+// RegisterPortal registers Portal with the PortalRegistry. The replaced
+// Portal, if any, is closed.
 func (r *PortalRegistry) RegisterPortal(s string, p *Portal) error {
+	existingPortal, err := r.registerPortal(s, p)
+	if err != nil {
+		return err
+	}
+	if existingPortal != nil {
+		existingPortal.Close()
+	}
+	return nil
+}
+
+func (r *PortalRegistry) registerPortal(s string, p *Portal) (*Portal, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	existingPortal, exists := r.portals[s]
 	if !exists {
 		r.portals[s] = p
-		return nil
+		return nil, nil
 	}
 	if existingPortal.id == p.id {
-		return errors.ErrPortalRegistryEntryExists.WithArgs(s)
+		return nil, errors.ErrPortalRegistryEntryExists.WithArgs(s)
 	}
 	r.portals[s] = p
 
@@ -70,17 +82,20 @@ func (r *PortalRegistry) RegisterPortal(s string, p *Portal) error {
 		a.portal = p
 	}
 
-	return nil
+	return existingPortal, nil
 }
 
-// UnregisterPortal unregisters Portal from the PortalRegistry.
+// UnregisterPortal unregisters Portal from the PortalRegistry and closes it.
 func (r *PortalRegistry) UnregisterPortal(s string) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-	if _, exists := r.portals[s]; !exists {
+	p, exists := r.portals[s]
+	if !exists {
+		r.mu.Unlock()
 		return
 	}
 	delete(r.portals, s)
+	r.mu.Unlock()
+	p.Close()
 }
 
 // RegisterAuthenticator registers Authenticator with the PortalRegistry.
@@ -61,7 +61,11 @@ func (p *Portal) handleAPI(ctx context.Context, w http.ResponseWriter, r *http.R
 	case strings.Contains(r.URL.Path, "/api/teams"):
 		return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
 	case strings.Contains(r.URL.Path, "/api/users"):
-		return p.handleJSONError(ctx, w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+		return p.handleAPIUsers(ctx, w, r, rr, usr)
+	case strings.Contains(r.URL.Path, "/api/registrations"):
+		return p.handleAPIRegistrations(ctx, w, r, rr, usr)
+	case strings.Contains(r.URL.Path, "/api/acl"):
+		return p.handleAPIACL(ctx, w, r, rr, usr)
 	}
 
 	return p.handleJSONError(ctx, w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
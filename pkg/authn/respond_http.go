@@ -37,10 +37,11 @@ func (p *Portal) handleHTTP(ctx context.Context, w http.ResponseWriter, r *http.
 	case strings.HasSuffix(r.URL.Path, "/portal"):
 		return p.handleHTTPPortal(ctx, w, r, rr, usr)
 	case strings.HasSuffix(r.URL.Path, "/logout"):
-		return p.handleHTTPLogout(ctx, w, r, rr)
-	case strings.HasSuffix(r.URL.Path, "/recover"), strings.HasSuffix(r.URL.Path, "/forgot"):
-		// TODO(greenpau): implement password recovery.
+		return p.handleHTTPLogout(ctx, w, r, rr, usr)
+	case strings.HasSuffix(r.URL.Path, "/recover"), strings.HasSuffix(r.URL.Path, "/forgot"), strings.Contains(r.URL.Path, "/recover/"):
 		return p.handleHTTPRecover(ctx, w, r, rr)
+	case strings.Contains(r.URL.Path, "/verify/"):
+		return p.handleHTTPVerifyEmail(ctx, w, r, rr)
 	case strings.Contains(r.URL.Path, "/settings"):
 		return p.handleHTTPSettings(ctx, w, r, rr, usr)
 	case strings.HasSuffix(r.URL.Path, "/register"), strings.Contains(r.URL.Path, "/register/"):
@@ -295,9 +296,11 @@ func extractBasePath(ctx context.Context, r *http.Request, rr *requests.Request)
 		extractBaseURLPath(ctx, r, rr, "/logout")
 	case strings.Contains(r.URL.Path, "/sandbox/"):
 		extractBaseURLPath(ctx, r, rr, "/sandbox/")
+	case strings.Contains(r.URL.Path, "/verify/"):
+		extractBaseURLPath(ctx, r, rr, "/verify/")
 	case strings.Contains(r.URL.Path, "/settings"):
 		extractBaseURLPath(ctx, r, rr, "/settings")
-	case strings.HasSuffix(r.URL.Path, "/recover"), strings.HasSuffix(r.URL.Path, "/forgot"):
+	case strings.HasSuffix(r.URL.Path, "/recover"), strings.HasSuffix(r.URL.Path, "/forgot"), strings.Contains(r.URL.Path, "/recover/"):
 		extractBaseURLPath(ctx, r, rr, "/recover,/forgot")
 	case strings.HasSuffix(r.URL.Path, "/register"):
 		extractBaseURLPath(ctx, r, rr, "/register")
@@ -78,6 +78,12 @@ func (p *Portal) handleJSON(ctx context.Context, w http.ResponseWriter, r *http.
 		zap.String("source_address", addrutil.GetSourceAddress(r)),
 	)
 
+	// The refresh requests are not authorized with access tokens, because
+	// the access tokens are expected to expire.
+	if strings.HasSuffix(r.URL.Path, "/refresh") {
+		return p.handleJSONRefresh(ctx, w, r, rr)
+	}
+
 	usr, err := p.authorizeRequest(ctx, w, r, rr)
 	if err != nil {
 		return p.handleJSONErrorWithLog(ctx, w, r, rr, http.StatusUnauthorized, err.Error())
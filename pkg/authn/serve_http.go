@@ -35,6 +35,12 @@ func (p *Portal) ServeHTTP(ctx context.Context, w http.ResponseWriter, r *http.R
 		rr.Response.Title = p.config.UI.Title
 	}
 	rr.Response.RedirectTokenName = p.cookie.Referer
+	if p.config.MetricsConfig != nil && p.config.MetricsConfig.Match(r) {
+		return p.handleMetrics(ctx, w, r, rr)
+	}
+	if strings.Contains(r.URL.Path, "/.well-known/") {
+		return p.handleWellKnown(ctx, w, r, rr)
+	}
 	if strings.Contains(r.URL.Path, "/api/") {
 		return p.handleAPI(ctx, w, r, rr)
 	}
@@ -0,0 +1,109 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/audit"
+	"github.com/greenpau/go-authcrunch/pkg/authz/revocation"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"github.com/greenpau/go-authcrunch/pkg/user"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// revokeUserToken revokes the token of the user, i.e. the token presented
+// with the request, and removes the associated session and refresh tokens.
+func (p *Portal) revokeUserToken(r *http.Request, rr *requests.Request, usr *user.User, reason string) {
+	if usr == nil || usr.Claims == nil || usr.Claims.ID == "" {
+		return
+	}
+	e := revocation.NewTokenEntry(usr.Claims.ID, usr.Claims.ExpiresAt)
+	e.Reason = reason
+	if err := p.validator.RevokeToken(e); err != nil {
+		p.logger.Warn(
+			"failed revoking token",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("jti", usr.Claims.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	p.sessions.Delete(usr.Claims.ID)
+	p.refreshTokens.DeleteByAccessTokenID(usr.Claims.ID)
+	p.logger.Debug(
+		"revoked token",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("jti", usr.Claims.ID),
+		zap.String("reason", reason),
+	)
+	ev := audit.NewEvent(audit.TokenRevoked, audit.OutcomeSuccess).WithDetail("jti", usr.Claims.ID)
+	if reason == "logout" {
+		ev.Type = audit.Logout
+	}
+	ev.Username = usr.Claims.Subject
+	ev.Email = usr.Claims.Email
+	ev.Reason = reason
+	p.logAuditEvent(r, rr, usr, ev)
+}
+
+// exemptUserToken exempts the token issued to the user at the provided time
+// from the revocation of the tokens of the user, when the token has been
+// issued right after the revocation, in the same second.
+func (p *Portal) exemptUserToken(rr *requests.Request, usr *user.User, issuedAt time.Time) {
+	if usr == nil || usr.Claims == nil || usr.Claims.ID == "" {
+		return
+	}
+	if err := p.validator.GetRevocationStore().Exempt(usr.Claims.ID, usr.Claims.Subject, issuedAt); err != nil {
+		p.logger.Warn(
+			"failed exempting token from revocation",
+			zap.String("session_id", rr.Upstream.SessionID),
+			zap.String("request_id", rr.ID),
+			zap.String("jti", usr.Claims.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// revokeSubjectTokens revokes all the tokens issued to the subject so far,
+// including the refresh tokens. The revocation is kept for the lifetime of
+// the refresh tokens, because an access token obtained with a refresh token
+// carries the issued at timestamp of the refresh.
+func (p *Portal) revokeSubjectTokens(r *http.Request, rr *requests.Request, subject, reason string) error {
+	lifetime := p.keystore.GetRefreshTokenLifetime()
+	if tokenLifetime := p.keystore.GetTokenLifetime(nil, nil); tokenLifetime > lifetime {
+		lifetime = tokenLifetime
+	}
+	e := revocation.NewSubjectEntry(subject, time.Now().UTC(), lifetime)
+	e.Reason = reason
+	if err := p.validator.RevokeToken(e); err != nil {
+		return err
+	}
+	p.refreshTokens.DeleteSubject(subject)
+	p.logger.Info(
+		"revoked tokens",
+		zap.String("session_id", rr.Upstream.SessionID),
+		zap.String("request_id", rr.ID),
+		zap.String("sub", subject),
+		zap.String("reason", reason),
+	)
+	ev := audit.NewEvent(audit.TokenRevoked, audit.OutcomeSuccess).WithDetail("scope", "subject")
+	ev.Username = subject
+	ev.Reason = reason
+	p.logAuditEvent(r, rr, nil, ev)
+	return nil
+}
@@ -66,6 +66,84 @@ func TestFactory(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "add role to contractor until expiration date",
+			user: map[string]interface{}{
+				"email": "contractor@localhost",
+				"roles": "guest",
+			},
+			keys: []string{
+				"roles",
+			},
+			configs: []*Config{
+				{
+					Matchers: []string{
+						"exact match email contractor@localhost",
+						"match date before 2000-01-01",
+					},
+					Actions: []string{
+						"add role authp/expired",
+					},
+				},
+				{
+					Matchers: []string{
+						"exact match email contractor@localhost",
+						"match date after 2000-01-01",
+					},
+					Actions: []string{
+						"add role authp/contractor",
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"roles": []string{
+					"guest",
+					"authp/contractor",
+				},
+			},
+		},
+		{
+			name: "add role based on custom and nested claims",
+			user: map[string]interface{}{
+				"email":           "jsmith@localhost",
+				"roles":           "guest",
+				"department":      "engineering",
+				"clearance_level": float64(4),
+				"groups": []interface{}{
+					map[string]interface{}{"displayName": "Platform Team"},
+					map[string]interface{}{"displayName": "On-Call"},
+				},
+			},
+			keys: []string{
+				"roles",
+			},
+			configs: []*Config{
+				{
+					Matchers: []string{
+						"exact match claim.department engineering",
+						"match claim.clearance_level gt 3",
+						"prefix match claim.groups.displayName Platform",
+					},
+					Actions: []string{
+						"add role authp/platform",
+					},
+				},
+				{
+					Matchers: []string{
+						"match claim.clearance_level between 5 9",
+					},
+					Actions: []string{
+						"add role authp/admin",
+					},
+				},
+			},
+			want: map[string]interface{}{
+				"roles": []string{
+					"guest",
+					"authp/platform",
+				},
+			},
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
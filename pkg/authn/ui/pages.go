@@ -418,6 +418,11 @@ var PageTemplates = map[string]string{
                 credentials right away.
               </p>
               {{ end }}
+
+              {{ if eq .Data.view "approved" }}
+              <p style="margin-bottom: 1em">Thank you for confirming your registration and validating your email address!</p>
+              <p>Your registration has been approved. You may now login with your credentials.</p>
+              {{ end }}
             </div>
             <div class="card-action right-align">
               {{ if eq .Data.view "register" }}
@@ -482,6 +487,162 @@ var PageTemplates = map[string]string{
     </script>
     {{ end }}
   </body>
+</html>`,
+	"basic/recover": `<!doctype html>
+<html lang="en">
+  <head>
+    <title>{{ .Title }}</title>
+    <!-- Required meta tags -->
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+    <meta name="description" content="Authentication Portal">
+    <meta name="author" content="Paul Greenberg github.com/greenpau">
+    <link rel="shortcut icon" href="{{ pathjoin .ActionEndpoint "/assets/images/favicon.png" }}" type="image/png">
+    <link rel="icon" href="{{ pathjoin .ActionEndpoint "/assets/images/favicon.png" }}" type="image/png">
+
+		<!-- Matrialize CSS -->
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/materialize-css/css/materialize.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/google-webfonts/roboto.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/google-webfonts/montserrat.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/line-awesome/line-awesome.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/styles.css" }}" />
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/register.css" }}" />
+    {{ if eq .Data.ui_options.custom_css_required "yes" }}
+    <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/custom.css" }}" />
+    {{ end }}
+  </head>
+  <body class="app-body">
+    <div class="container">
+      <div class="row">
+        <div class="col s12 m12 l6 offset-l3 recovery-container">
+          {{ if eq .Data.view "recover" }}
+          <form action="{{ pathjoin .ActionEndpoint "/recover" }}" method="POST">
+          {{ end }}
+          {{ if eq .Data.view "reset" }}
+          <form action="{{ pathjoin .ActionEndpoint "/recover" .Data.recovery_id }}" method="POST">
+          {{ end }}
+          <div class="card card-large app-card">
+            <div class="card-content">
+              <span class="card-title center-align">
+                <div class="section app-header">
+                  {{ if .LogoURL }}
+                  <img class="d-block mx-auto mb-2" src="{{ .LogoURL }}" alt="{{ .LogoDescription }}" width="72" height="72">
+                  {{ end }}
+                  <h4>{{ .Title }}</h4>
+                </div>
+              </span>
+              {{ if eq .Data.view "recover" }}
+              <p style="margin-bottom: 1em">Please provide the email address associated with your account.
+                If the account exists, you will receive an email with a password reset link.</p>
+              <div class="input-field">
+                <input id="email" name="email" type="email" class="validate"
+                  autocorrect="off" autocapitalize="off" spellcheck="false"
+                  autocomplete="off"
+                  required />
+                <label for="email">Email Address</label>
+              </div>
+              {{ if gt (len .Data.realms) 1 }}
+              <div class="input-field">
+                <select id="realm" name="realm" class="browser-default">
+                {{ range .Data.realms }}
+                  <option value="{{ .realm }}">{{ .label }}</option>
+                {{ end }}
+                </select>
+              </div>
+              {{ else }}
+              {{ range .Data.realms }}
+              <input type="hidden" id="realm" name="realm" value="{{ .realm }}" />
+              {{ end }}
+              {{ end }}
+              {{ end }}
+
+              {{ if eq .Data.view "recover_sent" }}
+              <p style="margin-bottom: 1em">Thank you! If the account exists, you will receive an email with a password reset link.</p>
+              <p style="margin-bottom: 1em">Here are a few things to keep in mind:</p>
+              <ol style="margin-right: 3em">
+                <li>The link is valid for a limited time and can be used only once.</li>
+                <li>If you still don't see it, please email support so we can help you.</li>
+              </ol>
+              {{ end }}
+
+              {{ if eq .Data.view "reset" }}
+              <p style="margin-bottom: 1em">Please provide a new password for <code>{{ .Data.username }}</code>.</p>
+              <div class="input-field">
+                <input id="secret" name="secret" type="password" class="validate"
+                  autocorrect="off" autocapitalize="off" spellcheck="false"
+                  autocomplete="new-password"
+                  required />
+                <label for="secret">New Password</label>
+              </div>
+              <div class="input-field">
+                <input id="secret_confirm" name="secret_confirm" type="password" class="validate"
+                  autocorrect="off" autocapitalize="off" spellcheck="false"
+                  autocomplete="new-password"
+                  required />
+                <label for="secret_confirm">Confirm New Password</label>
+              </div>
+              {{ end }}
+
+              {{ if eq .Data.view "reset_done" }}
+              <p style="margin-bottom: 1em">Your password has been changed. You may now login with your new password.</p>
+              {{ end }}
+
+              {{ if eq .Data.view "fail" }}
+              <p>Unfortunately, things did not go as expected. {{ .Data.message }}.</p>
+              {{ end }}
+            </div>
+            <div class="card-action right-align">
+              {{ if or (eq .Data.view "recover") (eq .Data.view "reset") }}
+              <button type="reset" name="reset" class="btn waves-effect waves-light navbtn active navbtn-last red lighten-1 app-btn">
+                <i class="las la-redo-alt app-btn-icon"></i>
+                <span class="app-btn-text">Clear</span>
+              </button>
+
+              <a href="{{ .ActionEndpoint }}" class="navbtn-last">
+                <button type="button" class="waves-effect waves-light btn navbtn active navbtn-last app-btn">
+                  <i class="las la-undo left app-btn-icon"></i>
+                  <span class="app-btn-text">Back</span>
+                </button>
+              </a>
+              <button type="submit" name="submit" class="waves-effect waves-light btn navbtn active navbtn-last app-btn">
+                <i class="las la-chevron-circle-right app-btn-icon"></i>
+                <span class="app-btn-text">Submit</span>
+              </button>
+              {{ else }}
+              <a href="{{ .ActionEndpoint }}" class="navbtn-last">
+                <button type="button" class="btn waves-effect waves-light navbtn active navbtn-last app-btn">
+                  <i class="las la-home left app-btn-icon"></i>
+                  <span class="app-btn-text">Portal</span>
+                </button>
+              </a>
+              {{ end }}
+            </div>
+          </div>
+          {{ if or (eq .Data.view "recover") (eq .Data.view "reset") }}
+          </form>
+          {{ end }}
+        </div>
+      </div>
+    </div>
+
+    <!-- Optional JavaScript -->
+    <script src="{{ pathjoin .ActionEndpoint "/assets/materialize-css/js/materialize.js" }}"></script>
+    {{ if eq .Data.ui_options.custom_js_required "yes" }}
+    <script src="{{ pathjoin .ActionEndpoint "/assets/js/custom.js" }}"></script>
+    {{ end }}
+    {{ if .Message }}
+    <script>
+    var toastHTML = '<span>{{ .Message }}</span><button class="btn-flat toast-action" onclick="M.Toast.dismissAll();">Close</button>';
+    toastElement = M.toast({
+      html: toastHTML,
+      classes: 'toast-error',
+      displayLength: 10000
+    });
+    const appContainer = document.querySelector('.recovery-container')
+    appContainer.prepend(toastElement.el)
+    </script>
+    {{ end }}
+  </body>
 </html>`,
 	"basic/generic": `<!doctype html>
 <html lang="en">
@@ -611,12 +772,16 @@ var PageTemplates = map[string]string{
         <div class="col s12 l3">
           <div class="collection">
             <a href="{{ pathjoin .ActionEndpoint "/settings/" }}" class="collection-item{{ if eq .Data.view "general" }} active{{ end }}">General</a>
+            <a href="{{ pathjoin .ActionEndpoint "/settings/emails" }}" class="collection-item{{ if eq .Data.view "emails" }} active{{ end }}">Email Addresses</a>
             <a href="{{ pathjoin .ActionEndpoint "/settings/sshkeys" }}" class="collection-item{{ if eq .Data.view "sshkeys" }} active{{ end }}">SSH Keys</a>
             <a href="{{ pathjoin .ActionEndpoint "/settings/gpgkeys" }}" class="collection-item{{ if eq .Data.view "gpgkeys" }} active{{ end }}">GPG Keys</a>
             <a href="{{ pathjoin .ActionEndpoint "/settings/apikeys" }}" class="collection-item{{ if eq .Data.view "apikeys" }} active{{ end }}">API Keys</a>
             <a href="{{ pathjoin .ActionEndpoint "/settings/mfa" }}" class="collection-item{{ if eq .Data.view "mfa" }} active{{ end }}">MFA</a>
             <a href="{{ pathjoin .ActionEndpoint "/settings/password" }}" class="collection-item{{ if eq .Data.view "password" }} active{{ end }}">Password</a>
             <a href="{{ pathjoin .ActionEndpoint "/settings/connected" }}" class="collection-item{{ if eq .Data.view "connected" }} active{{ end }}">Connected Accounts</a>
+            {{ if .Data.registrations_enabled }}
+            <a href="{{ pathjoin .ActionEndpoint "/settings/registrations" }}" class="collection-item{{ if eq .Data.view "registrations" }} active{{ end }}">Registrations</a>
+            {{ end }}
             <a href="{{ pathjoin .ActionEndpoint "/portal" }}" class="hide-on-med-and-up collection-item">Portal</a>
             <a href="{{ pathjoin .ActionEndpoint "/logout" }}" class="hide-on-med-and-up collection-item">Logout</a>
           </div>
@@ -642,6 +807,151 @@ var PageTemplates = map[string]string{
             </div>
           </div>
           {{ end }}
+          {{ if eq .Data.view "emails" }}
+          <div class="row right">
+            <div class="col s12 right">
+              <a href="{{ pathjoin .ActionEndpoint "/settings/emails/add" }}">
+                <button type="button" class="btn waves-effect waves-light navbtn active app-btn">
+                  <i class="las la-envelope left app-btn-icon"></i>
+                  <span class="app-btn-text">Add Email Address</span>
+                </button>
+              </a>
+            </div>
+          </div>
+          <div class="row">
+            <div class="col s12">
+            {{ if .Data.emails }}
+              {{range .Data.emails}}
+              <div class="card">
+                <div class="card-content">
+                  <span class="card-title">{{ .Address }}</span>
+                  <p>
+                    {{ if .Confirmed }}
+                    <b>Status</b>: Confirmed<br/>
+                    {{ if not .ConfirmedAt.IsZero }}<b>Confirmed At</b>: {{ .ConfirmedAt }}<br/>{{ end }}
+                    {{ else }}
+                    <b>Status</b>: Not Confirmed<br/>
+                    {{ end }}
+                    {{ if .Domain }}<b>Domain</b>: {{ .Domain }}{{ end }}
+                  </p>
+                </div>
+                {{ if and (not .Confirmed) $.Data.verification_enabled }}
+                <div class="card-action">
+                  <a href="{{ pathjoin $.ActionEndpoint "/settings/emails/verify" .Address }}">Verify</a>
+                </div>
+                {{ end }}
+              </div>
+              {{ end }}
+            {{ else }}
+              <p>No email addresses found</p>
+            {{ end }}
+            </div>
+          </div>
+          {{ end }}
+          {{ if eq .Data.view "emails-add" }}
+            <form action="{{ pathjoin .ActionEndpoint "/settings/emails/add" }}" method="POST">
+              <div class="row">
+                <div class="col s12">
+                  <h1>Add Email Address</h1>
+                  <p>Please provide your email address.{{ if .Data.verification_enabled }} A verification link will be sent to the address.{{ end }}</p>
+                  <div class="input-field">
+                    <input placeholder="Email Address" name="email" id="email" type="email" autocorrect="off" autocapitalize="off" autocomplete="off" class="validate" required>
+                  </div>
+                  <div class="right">
+                    <button type="submit" name="submit" class="btn waves-effect waves-light navbtn active navbtn-last app-btn">
+                      <i class="las la-plus-circle left app-btn-icon"></i>
+                      <span class="app-btn-text">Add Email Address</span>
+                    </button>
+                  </div>
+                </div>
+              </div>
+            </form>
+          {{ end }}
+          {{ if or (eq .Data.view "emails-add-status") (eq .Data.view "emails-verify-status") }}
+          <div class="row">
+            <div class="col s12">
+              <h1>Email Address</h1>
+            {{ if eq .Data.status "SUCCESS" }}
+              <p>{{ .Data.status_reason }}</p>
+            {{ else }}
+              <p>Reason: {{ .Data.status_reason }} </p>
+            {{ end }}
+              <a href="{{ pathjoin .ActionEndpoint "/settings/emails" }}">
+                <button type="button" class="btn waves-effect waves-light navbtn active">
+                  <i class="las la-undo-alt left app-btn-icon"></i>
+                  <span class="app-btn-text">Go Back</span>
+                </button>
+              </a>
+            </div>
+          </div>
+          {{ end }}
+          {{ if eq .Data.view "registrations" }}
+          <div class="row">
+            <div class="col s12">
+            {{ if .Data.registrations }}
+            <form action="{{ pathjoin .ActionEndpoint "/settings/registrations/review" }}" method="POST">
+              {{range .Data.registrations}}
+              <div class="card">
+                <div class="card-content">
+                  <label>
+                    <input type="checkbox" name="registration_id" value="{{ .ID }}" />
+                    <span class="card-title">{{ .Username }}</span>
+                  </label>
+                  <p>
+                    <b>Email</b>: {{ .Email }}<br/>
+                    {{ if .Name }}<b>Name</b>: {{ .Name }}<br/>{{ end }}
+                    <b>Registration ID</b>: {{ .ID }}<br/>
+                    <b>Created At</b>: {{ .CreatedAt }}
+                  </p>
+                </div>
+              </div>
+              {{ end }}
+              <div class="input-field">
+                <textarea id="note" name="note" class="materialize-textarea" maxlength="1024"></textarea>
+                <label for="note">Note (not shared with the registrants)</label>
+              </div>
+              <div class="input-field">
+                <input id="reason" name="reason" type="text" maxlength="1024" autocomplete="off">
+                <label for="reason">Reason (sent to the registrants)</label>
+              </div>
+              <div class="right">
+                <button type="submit" name="action" value="decline" class="btn waves-effect waves-light navbtn active red lighten-1 app-btn">
+                  <i class="las la-user-times left app-btn-icon"></i>
+                  <span class="app-btn-text">Decline Selected</span>
+                </button>
+                <button type="submit" name="action" value="approve" class="btn waves-effect waves-light navbtn active navbtn-last app-btn">
+                  <i class="las la-user-check left app-btn-icon"></i>
+                  <span class="app-btn-text">Approve Selected</span>
+                </button>
+              </div>
+            </form>
+            {{ else }}
+              <p>No pending registrations found</p>
+            {{ end }}
+            </div>
+          </div>
+          {{ end }}
+          {{ if eq .Data.view "registrations-review-status" }}
+          <div class="row">
+            <div class="col s12">
+              <h1>Registrations</h1>
+              <p>{{.Data.status }}: {{ .Data.status_reason }}</p>
+              {{ if .Data.review_results }}
+              <ul class="collection">
+                {{range .Data.review_results}}
+                <li class="collection-item">{{ .ID }}: {{ if .Error }}{{ .Error }}{{ else }}{{ .Verdict }}{{ end }}</li>
+                {{ end }}
+              </ul>
+              {{ end }}
+              <a href="{{ pathjoin .ActionEndpoint "/settings/registrations" }}">
+                <button type="button" class="btn waves-effect waves-light navbtn active">
+                  <i class="las la-undo-alt left app-btn-icon"></i>
+                  <span class="app-btn-text">Go Back</span>
+                </button>
+              </a>
+            </div>
+          </div>
+          {{ end }}
           {{ if eq .Data.view "sshkeys" }}
           <div class="row right">
             <div class="col s12 right">
@@ -1485,7 +1795,7 @@ function u2f_token_authenticate(formID, btnID) {
     {{ if or (eq .Data.view "mfa_app_auth") (eq .Data.view "mfa_app_register") }}
     <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/mfa_app.css" }}" />
     {{ end }}
-    {{ if or (eq .Data.view "password_auth") (eq .Data.view "password_recovery") }}
+    {{ if or (eq .Data.view "password_auth") (eq .Data.view "password_recovery") (eq .Data.view "password_change") }}
     <link rel="stylesheet" href="{{ pathjoin .ActionEndpoint "/assets/css/password.css" }}" />
     {{ end }}
   </head>
@@ -1635,6 +1945,70 @@ function u2f_token_authenticate(formID, btnID) {
               </ul>
             </div>
           </div>
+          {{ else if eq .Data.view "password_change" }}
+          <div class="row">
+            <p>Your password has expired. Please provide your current password and choose a new one.</p>
+            <form class="password-auth-form"
+                  action="{{ pathjoin .ActionEndpoint "sandbox" .Data.id "password-change" }}"
+                  method="POST"
+                  autocomplete="off"
+                  >
+              <div class="password-auth-ctrl row app-input-row valign-wrapper">
+                <div class="input-field app-input-field">
+                  <i class="las la-key"></i>
+                  <input id="secret1" name="secret1" type="password" class="validate"
+                       placeholder="Current Password"
+                       autocorrect="off" autocapitalize="off" autocomplete="off"
+                       required />
+                </div>
+              </div>
+              <div class="password-auth-ctrl row app-input-row valign-wrapper">
+                <div class="input-field app-input-field">
+                  <i class="las la-lock"></i>
+                  <input id="secret2" name="secret2" type="password" class="validate"
+                       placeholder="New Password"
+                       autocorrect="off" autocapitalize="off" autocomplete="off"
+                       required />
+                </div>
+              </div>
+              <div class="password-auth-ctrl row app-input-row valign-wrapper">
+                <div class="input-field app-input-field">
+                  <i class="las la-lock"></i>
+                  <input id="secret3" name="secret3" type="password" class="validate"
+                       placeholder="Confirm New Password"
+                       autocorrect="off" autocapitalize="off" autocomplete="off"
+                       required />
+                </div>
+              </div>
+              <input id="sandbox_id" name="sandbox_id" type="hidden" value="{{ .Data.id }}" />
+              <div class="password-auth-btn">
+                <button type="reset" name="reset" class="btn waves-effect waves-light navbtn active navbtn-last red lighten-1">
+                  <i class="las la-redo-alt left app-btn-icon"></i>
+                </button>
+                <button type="submit" name="submit" class="btn waves-effect waves-light navbtn active navbtn-last">
+                  <i class="las la-check-square left app-btn-icon"></i>
+                  <span class="app-btn-text">Change Password</span>
+                </button>
+              </div>
+            </form>
+            <div class="password-auth-help-menu">
+              <p>Having issues?</p>
+              <ul>
+                <li>
+                  <i class="las la-question"></i>
+                  <a href="{{ pathjoin .ActionEndpoint "help" }}">
+                    Contact support
+                  </a>
+                </li>
+                <li>
+                  <i class="las la-home"></i>
+                  <a href="{{ pathjoin .ActionEndpoint "sandbox" .Data.id "terminate" }}">
+                    Login page
+                  </a>
+                </li>
+              </ul>
+            </div>
+          </div>
           {{ else if eq .Data.view "mfa_app_auth" }}
           <div class="row">
             <form class="mfa-app-auth-form"
@@ -39,6 +39,11 @@ var (
 
 // Authenticate authorizes HTTP requests.
 func (g *Gatekeeper) Authenticate(w http.ResponseWriter, r *http.Request, ar *requests.AuthorizationRequest) error {
+	// Serve metrics, if the endpoint is enabled.
+	if g.config.MetricsConfig != nil && g.config.MetricsConfig.Match(r) {
+		return g.handleMetrics(w, r, ar)
+	}
+
 	// Perform authorization bypass checks
 	if g.bypassEnabled && bypass.Match(r, g.config.BypassConfigs) {
 		ar.Response.Authorized = false
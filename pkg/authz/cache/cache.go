@@ -15,6 +15,7 @@
 package cache
 
 import (
+	"github.com/greenpau/go-authcrunch/pkg/authz/revocation"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/user"
 	"sync"
@@ -101,3 +102,18 @@ func (c *TokenCache) Get(token string) *user.User {
 	}
 	return usr
 }
+
+// DeleteRevoked removes the cached tokens revoked by the provided
+// revocation store. It returns the number of the removed tokens.
+func (c *TokenCache) DeleteRevoked(s revocation.Store) int {
+	var count int
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, usr := range c.Entries {
+		if s.IsRevoked(usr.Claims.ID, usr.Claims.Subject, usr.Claims.IssuedAt) {
+			delete(c.Entries, k)
+			count++
+		}
+	}
+	return count
+}
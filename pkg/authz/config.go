@@ -20,6 +20,7 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/authz/injector"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/kms"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
 	"github.com/greenpau/go-authcrunch/pkg/shared/idp"
 	cfgutil "github.com/greenpau/go-authcrunch/pkg/util/cfg"
 	"strings"
@@ -41,6 +42,7 @@ type PolicyConfig struct {
 	// The list of mappings between header names and field names.
 	HeaderInjectionConfigs []*injector.Config       `json:"header_injection_configs,omitempty" xml:"header_injection_configs,omitempty" yaml:"header_injection_configs,omitempty"`
 	AccessListRules        []*acl.RuleConfiguration `json:"access_list_rules,omitempty" xml:"access_list_rules,omitempty" yaml:"access_list_rules,omitempty"`
+	NetworkLists           map[string][]string      `json:"network_lists,omitempty" xml:"network_lists,omitempty" yaml:"network_lists,omitempty"`
 	CryptoKeyConfigs       []*kms.CryptoKeyConfig   `json:"crypto_key_configs,omitempty" xml:"crypto_key_configs,omitempty" yaml:"crypto_key_configs,omitempty"`
 	// CryptoKeyStoreConfig hold the default configuration for the keys, e.g. token name and lifetime.
 	CryptoKeyStoreConfig   map[string]interface{}      `json:"crypto_key_store_config,omitempty" xml:"crypto_key_store_config,omitempty" yaml:"crypto_key_store_config,omitempty"`
@@ -61,6 +63,9 @@ type PolicyConfig struct {
 	PassClaimsWithHeaders bool `json:"pass_claims_with_headers,omitempty" xml:"pass_claims_with_headers,omitempty" yaml:"pass_claims_with_headers,omitempty"`
 	// Validate the login hint which can be passed to the auth provider
 	LoginHintValidators []string `json:"login_hint_validators,omitempty" xml:"login_hint_validators,omitempty" yaml:"login_hint_validators,omitempty"`
+	// The configuration of the endpoint serving metrics. When it is not
+	// set, the endpoint is disabled.
+	MetricsConfig *metrics.Config `json:"metrics_config,omitempty" xml:"metrics_config,omitempty" yaml:"metrics_config,omitempty"`
 	// Holds raw crypto configuration.
 	cryptoRawConfigs []string
 	// Holds raw identity provider configuration.
@@ -181,6 +186,13 @@ func (cfg *PolicyConfig) Validate() error {
 		cfg.PassClaimsWithHeaders = true
 	}
 
+	// Validate metrics endpoint config.
+	if cfg.MetricsConfig != nil {
+		if err := cfg.MetricsConfig.Validate(); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(cfg.Name, err)
+		}
+	}
+
 	cfg.validated = true
 	return nil
 }
@@ -24,6 +24,7 @@ import (
 
 	"github.com/satori/go.uuid"
 	"go.uber.org/zap"
+	"sort"
 	"strings"
 )
 
@@ -68,6 +69,16 @@ func (g *Gatekeeper) Register() error {
 	return gatekeeperRegistry.RegisterGatekeeper(g.config.Name, g)
 }
 
+// LookupAccessList returns the access list of the Gatekeeper registered
+// with GatekeeperRegistry under the provided name.
+func LookupAccessList(name string) (*acl.AccessList, error) {
+	g, err := gatekeeperRegistry.LookupGatekeeper(name)
+	if err != nil {
+		return nil, err
+	}
+	return g.accessList, nil
+}
+
 func (g *Gatekeeper) configure() error {
 	ctx := context.Background()
 
@@ -113,6 +124,10 @@ func (g *Gatekeeper) configure() error {
 		if err := ks.AutoGenerate("default", "ES512"); err != nil {
 			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
 		}
+		// Accept the tokens signed by the portals rotating their keys.
+		if err := ks.AddRotatedKeys(); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
+		}
 	} else {
 		if err := ks.AddKeysWithConfigs(g.config.CryptoKeyConfigs); err != nil {
 			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
@@ -128,9 +143,20 @@ func (g *Gatekeeper) configure() error {
 	}
 	accessList := acl.NewAccessList()
 	accessList.SetLogger(g.logger)
+	var networkListNames []string
+	for name := range g.config.NetworkLists {
+		networkListNames = append(networkListNames, name)
+	}
+	sort.Strings(networkListNames)
+	for _, name := range networkListNames {
+		if err := accessList.AddNetworkList(name, g.config.NetworkLists[name]); err != nil {
+			return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
+		}
+	}
 	if err := accessList.AddRules(ctx, g.config.AccessListRules); err != nil {
 		return errors.ErrInvalidConfiguration.WithArgs(g.config.Name, err)
 	}
+	g.accessList = accessList
 
 	// Add identity provider to the token validator.
 	if g.config.IdentityProviderConfig != nil {
@@ -161,5 +187,7 @@ func (g *Gatekeeper) configure() error {
 		zap.Any("access_list_rules", g.config.AccessListRules),
 		zap.String("forbidden_path", g.config.ForbiddenURL),
 	)
+
+	g.registerMetrics()
 	return nil
 }
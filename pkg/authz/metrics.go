@@ -0,0 +1,67 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"net/http"
+	"strconv"
+)
+
+// registerMetrics adds the collector reporting the counters of the access
+// list rules to the metrics registry.
+func (g *Gatekeeper) registerMetrics() {
+	metrics.DefaultRegistry.Register("gatekeeper/"+g.config.Name, metrics.CollectorFunc(g.collectMetrics))
+}
+
+// collectMetrics returns the number of times the rules with counter action
+// matched and did not match the requests.
+func (g *Gatekeeper) collectMetrics() []*metrics.Metric {
+	if g.accessList == nil {
+		return nil
+	}
+	matches := &metrics.Metric{
+		Name: "authcrunch_acl_rule_matches_total",
+		Help: "The number of times the conditions of an access list rule with counter action matched.",
+		Type: metrics.TypeCounter,
+	}
+	misses := &metrics.Metric{
+		Name: "authcrunch_acl_rule_misses_total",
+		Help: "The number of times the conditions of an access list rule with counter action did not match.",
+		Type: metrics.TypeCounter,
+	}
+	for _, c := range g.accessList.GetCounters() {
+		labels := []*metrics.Label{
+			{Name: "policy", Value: g.config.Name},
+			{Name: "rule", Value: c.Tag},
+			{Name: "index", Value: strconv.Itoa(c.Index)},
+		}
+		matches.Samples = append(matches.Samples, &metrics.Sample{Labels: labels, Value: float64(c.Matches)})
+		misses.Samples = append(misses.Samples, &metrics.Sample{Labels: labels, Value: float64(c.Misses)})
+	}
+	return []*metrics.Metric{matches, misses}
+}
+
+// handleMetrics serves the metrics in Prometheus text exposition format.
+// The returned error signals the caller that the response has been written
+// and the request must not reach the upstream.
+func (g *Gatekeeper) handleMetrics(w http.ResponseWriter, r *http.Request, ar *requests.AuthorizationRequest) error {
+	g.config.MetricsConfig.Serve(w, r, metrics.DefaultRegistry)
+	ar.Response.Authorized = false
+	ar.Response.Error = errors.ErrMetricsResponseWritten
+	return ar.Response.Error
+}
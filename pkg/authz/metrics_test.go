@@ -0,0 +1,128 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/internal/testutils"
+	"github.com/greenpau/go-authcrunch/pkg/acl"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	logutil "github.com/greenpau/go-authcrunch/pkg/util/log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGatekeeperMetrics(t *testing.T) {
+	cfg := &PolicyConfig{
+		Name:        "metricsgatekeeper",
+		AuthURLPath: "/auth",
+		AccessListRules: []*acl.RuleConfiguration{
+			{
+				Conditions: []string{"match roles authp/admin"},
+				Action:     "allow stop counter tag admins",
+			},
+		},
+		MetricsConfig:    &metrics.Config{Token: "foobar"},
+		cryptoRawConfigs: []string{"key verify " + testutils.GetSharedKey()},
+	}
+	gatekeeper, err := NewGatekeeper(cfg, logutil.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	gatekeeper.accessList.Allow(ctx, map[string]interface{}{"roles": []string{"authp/admin"}})
+	gatekeeper.accessList.Allow(ctx, map[string]interface{}{"roles": []string{"authp/user"}})
+
+	var testcases = []struct {
+		name      string
+		path      string
+		token     string
+		want      map[string]interface{}
+		lines     []string
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:  "serve metrics",
+			path:  "/metrics",
+			token: "foobar",
+			want: map[string]interface{}{
+				"status_code": 200,
+			},
+			lines: []string{
+				`authcrunch_acl_rule_matches_total{policy="metricsgatekeeper",rule="admins",index="0"} 1`,
+				`authcrunch_acl_rule_misses_total{policy="metricsgatekeeper",rule="admins",index="0"} 1`,
+				`# TYPE authcrunch_token_validation_failures_total counter`,
+			},
+			shouldErr: true,
+			err:       errors.ErrMetricsResponseWritten,
+		},
+		{
+			name: "reject metrics request without token",
+			path: "/metrics",
+			want: map[string]interface{}{
+				"status_code": 401,
+			},
+			shouldErr: true,
+			err:       errors.ErrMetricsResponseWritten,
+		},
+		{
+			name: "redirect unauthenticated request to upstream metrics",
+			path: "/app/metrics",
+			want: map[string]interface{}{
+				"status_code": 302,
+			},
+			shouldErr: true,
+			err:       errors.ErrNoTokenFound,
+		},
+		{
+			name: "redirect unauthenticated request",
+			path: "/app",
+			want: map[string]interface{}{
+				"status_code": 302,
+			},
+			shouldErr: true,
+			err:       errors.ErrNoTokenFound,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			w := httptest.NewRecorder()
+			ar := requests.NewAuthorizationRequest()
+			err := gatekeeper.Authenticate(w, req, ar)
+			body := w.Body.String()
+			got := map[string]interface{}{
+				"status_code": w.Code,
+			}
+			tests.EvalObjects(t, "response", tc.want, got)
+			for _, line := range tc.lines {
+				if !strings.Contains(body, line+"\n") {
+					t.Errorf("line %q not found in output:\n%s", line, body)
+				}
+			}
+			tests.EvalErr(t, err, nil, tc.shouldErr, tc.err)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revocation
+
+import (
+	"encoding/json"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileStore is a token revocation store persisting its entries to a JSON
+// file, so that the revocations survive restarts.
+type FileStore struct {
+	mu    sync.Mutex
+	path  string
+	store *MemoryStore
+}
+
+// NewFileStore returns an instance of FileStore. The existing unexpired
+// entries are being loaded from the file at the provided path.
+func NewFileStore(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, errors.ErrRevocationConfigPathEmpty
+	}
+	s := &FileStore{
+		path:  path,
+		store: NewMemoryStore(),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) load() error {
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.ErrRevocationStoreLoad.WithArgs(s.path, err)
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return errors.ErrRevocationStoreLoad.WithArgs(s.path, err)
+	}
+	now := time.Now()
+	for _, e := range entries {
+		if err := e.Validate(); err != nil {
+			return errors.ErrRevocationStoreLoad.WithArgs(s.path, err)
+		}
+		if e.Expired(now) {
+			continue
+		}
+		s.store.add(e)
+	}
+	return nil
+}
+
+func (s *FileStore) commit() error {
+	entries := s.store.GetEntries()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return errors.ErrRevocationStoreCommit.WithArgs(s.path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return errors.ErrRevocationStoreCommit.WithArgs(s.path, err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, b, 0600); err != nil {
+		return errors.ErrRevocationStoreCommit.WithArgs(s.path, err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return errors.ErrRevocationStoreCommit.WithArgs(s.path, err)
+	}
+	return nil
+}
+
+// Add adds a revocation entry to FileStore and persists the store.
+func (s *FileStore) Add(e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.store.Add(e); err != nil {
+		return err
+	}
+	return s.commit()
+}
+
+// IsRevoked returns true when the token has been revoked.
+func (s *FileStore) IsRevoked(id, subject string, issuedAt int64) bool {
+	return s.store.IsRevoked(id, subject, issuedAt)
+}
+
+// Exempt exempts the token from the revocation of its subject, when the
+// token has been issued after the revocation, and persists the store.
+func (s *FileStore) Exempt(id, subject string, issuedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.store.exempt(id, subject, issuedAt) {
+		return nil
+	}
+	return s.commit()
+}
+
+// GetEntries returns the unexpired entries of FileStore.
+func (s *FileStore) GetEntries() []*Entry {
+	return s.store.GetEntries()
+}
+
+// Close stops the removal of the expired entries of FileStore.
+func (s *FileStore) Close() error {
+	return s.store.Close()
+}
+
+// GetPath returns the path to the file backing FileStore.
+func (s *FileStore) GetPath() string {
+	return s.path
+}
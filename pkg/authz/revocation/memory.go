@@ -0,0 +1,185 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revocation
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory token revocation store. The entries are
+// being removed once the tokens they revoke expire.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	tokens   map[string]*Entry
+	subjects map[string]*Entry
+	// exit channel stops the removal of the expired entries.
+	exit   chan bool
+	closed bool
+}
+
+// NewMemoryStore returns an instance of MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		tokens:   make(map[string]*Entry),
+		subjects: make(map[string]*Entry),
+		exit:     make(chan bool),
+	}
+	go manageMemoryStore(defaultCleanupInterval, s)
+	return s
+}
+
+func manageMemoryStore(i int, s *MemoryStore) {
+	intervals := time.NewTicker(time.Second * time.Duration(i))
+	defer intervals.Stop()
+	for {
+		select {
+		case <-intervals.C:
+			s.expire(time.Now())
+		case <-s.exit:
+			return
+		}
+	}
+}
+
+// Close stops the removal of the expired entries of MemoryStore.
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.exit)
+	}
+	return nil
+}
+
+func (s *MemoryStore) expire(t time.Time) int {
+	var count int
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, e := range s.tokens {
+		if e.Expired(t) {
+			delete(s.tokens, k)
+			count++
+		}
+	}
+	for k, e := range s.subjects {
+		if e.Expired(t) {
+			delete(s.subjects, k)
+			count++
+		}
+	}
+	return count
+}
+
+// Add adds a revocation entry to MemoryStore.
+func (s *MemoryStore) Add(e *Entry) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(e)
+	return nil
+}
+
+func (s *MemoryStore) add(e *Entry) {
+	if e.ID != "" {
+		s.tokens[e.ID] = e
+		return
+	}
+	prev, exists := s.subjects[e.Subject]
+	if !exists {
+		s.subjects[e.Subject] = e.copy()
+		return
+	}
+	// Keep the most restrictive revocation of a subject. The tokens exempt
+	// from the previous revocation are subject to the later one.
+	if e.IssuedBefore > prev.IssuedBefore || (e.IssuedBefore == prev.IssuedBefore && e.CreatedAt.After(prev.CreatedAt)) {
+		prev.IssuedBefore = e.IssuedBefore
+		prev.Reason = e.Reason
+		prev.CreatedAt = e.CreatedAt
+		prev.Exempt = e.Exempt
+	}
+	if e.ExpiresAt > prev.ExpiresAt {
+		prev.ExpiresAt = e.ExpiresAt
+	}
+}
+
+// Exempt exempts the token from the revocation of its subject, when the
+// token has been issued after the revocation, in the same second.
+func (s *MemoryStore) Exempt(id, subject string, issuedAt time.Time) error {
+	s.exempt(id, subject, issuedAt)
+	return nil
+}
+
+// exempt returns true when the token has been exempt.
+func (s *MemoryStore) exempt(id, subject string, issuedAt time.Time) bool {
+	if id == "" || subject == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, exists := s.subjects[subject]
+	if !exists || issuedAt.Unix() >= e.IssuedBefore || !issuedAt.After(e.CreatedAt) || e.isExempt(id) {
+		return false
+	}
+	e.Exempt = append(e.Exempt, id)
+	return true
+}
+
+// IsRevoked returns true when the token has been revoked, either by its
+// token id, or by its subject and issued at timestamp. The tokens without
+// issued at timestamp are considered revoked when their subject is revoked.
+// The tokens issued in the same second as the revocation of their subject
+// remain valid only when exempt, e.g. the ones issued by a login right after
+// the revocation.
+func (s *MemoryStore) IsRevoked(id, subject string, issuedAt int64) bool {
+	now := time.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if id != "" {
+		if e, exists := s.tokens[id]; exists && !e.Expired(now) {
+			return true
+		}
+	}
+	if subject != "" {
+		if e, exists := s.subjects[subject]; exists && !e.Expired(now) {
+			if (issuedAt == 0 || issuedAt < e.IssuedBefore) && !e.isExempt(id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetEntries returns the copies of the unexpired entries of MemoryStore.
+func (s *MemoryStore) GetEntries() []*Entry {
+	now := time.Now()
+	entries := []*Entry{}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, e := range s.tokens {
+		if !e.Expired(now) {
+			entries = append(entries, e.copy())
+		}
+	}
+	for _, e := range s.subjects {
+		if !e.Expired(now) {
+			entries = append(entries, e.copy())
+		}
+	}
+	return entries
+}
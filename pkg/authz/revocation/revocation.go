@@ -0,0 +1,192 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package revocation provides the stores for revoked tokens.
+package revocation
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultEntryLifetime is the lifetime of an entry without explicit
+	// expiration, i.e. the maximum lifetime of a token.
+	defaultEntryLifetime = 86400
+	// defaultCleanupInterval is the interval at which the expired entries
+	// are being removed from a store.
+	defaultCleanupInterval = 60
+)
+
+var (
+	defaultStore   Store
+	defaultStoreMu sync.RWMutex
+)
+
+// Store is the interface of a token revocation store.
+type Store interface {
+	// Add adds a revocation entry to the store.
+	Add(*Entry) error
+	// IsRevoked returns true when the token with the provided token id,
+	// subject, and issued at timestamp has been revoked.
+	IsRevoked(id, subject string, issuedAt int64) bool
+	// Exempt exempts the token with the provided token id, issued to the
+	// subject at the provided time, from the revocation of the subject
+	// when the token has been issued after the revocation.
+	Exempt(id, subject string, issuedAt time.Time) error
+	// GetEntries returns the unexpired entries of the store.
+	GetEntries() []*Entry
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// Entry is a revocation entry. It revokes either a single token, identified
+// by its token id (jti), or all the tokens of a subject (sub) issued before
+// a specific time. Since the issued at timestamps of the tokens have whole
+// seconds, the tokens issued in the second of the revocation are revoked,
+// except for the ones in Exempt, i.e. issued right after the revocation.
+type Entry struct {
+	ID           string    `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Subject      string    `json:"subject,omitempty" xml:"subject,omitempty" yaml:"subject,omitempty"`
+	IssuedBefore int64     `json:"issued_before,omitempty" xml:"issued_before,omitempty" yaml:"issued_before,omitempty"`
+	ExpiresAt    int64     `json:"expires_at,omitempty" xml:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	Reason       string    `json:"reason,omitempty" xml:"reason,omitempty" yaml:"reason,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty" xml:"created_at,omitempty" yaml:"created_at,omitempty"`
+	Exempt       []string  `json:"exempt,omitempty" xml:"exempt,omitempty" yaml:"exempt,omitempty"`
+}
+
+// Config is the configuration of a token revocation store.
+type Config struct {
+	// Path is the path to the file where the revocation entries are being
+	// persisted. When empty, the entries are held in memory only.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// NewTokenEntry returns an Entry revoking a single token.
+func NewTokenEntry(id string, expiresAt int64) *Entry {
+	return &Entry{
+		ID:        id,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// NewSubjectEntry returns an Entry revoking the tokens of a subject issued
+// up to the provided time, including the second of the time. The lifetime
+// is the maximum lifetime of the tokens, in seconds.
+func NewSubjectEntry(subject string, issuedBefore time.Time, lifetime int) *Entry {
+	return &Entry{
+		Subject:      subject,
+		IssuedBefore: issuedBefore.Unix() + 1,
+		ExpiresAt:    issuedBefore.Add(time.Duration(lifetime) * time.Second).Unix(),
+		CreatedAt:    issuedBefore.UTC(),
+	}
+}
+
+// Validate validates Entry and sets defaults.
+func (e *Entry) Validate() error {
+	if e == nil {
+		return errors.ErrRevocationEntryNil
+	}
+	if e.ID == "" && (e.Subject == "" || e.IssuedBefore == 0) {
+		return errors.ErrRevocationEntryIncomplete
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	if e.ExpiresAt == 0 {
+		e.ExpiresAt = e.CreatedAt.Add(time.Duration(defaultEntryLifetime) * time.Second).Unix()
+	}
+	return nil
+}
+
+func (e *Entry) copy() *Entry {
+	c := *e
+	if e.Exempt != nil {
+		c.Exempt = append([]string{}, e.Exempt...)
+	}
+	return &c
+}
+
+// isExempt returns true when the token with the provided token id is exempt
+// from the revocation.
+func (e *Entry) isExempt(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, exemptID := range e.Exempt {
+		if exemptID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired returns true when the tokens revoked by the entry are expired,
+// i.e. the entry is no longer needed.
+func (e *Entry) Expired(t time.Time) bool {
+	return e.ExpiresAt < t.Unix()
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg.Path == "" {
+		return errors.ErrRevocationConfigPathEmpty
+	}
+	return nil
+}
+
+// NewStore returns an instance of Store based on the provided Config. When
+// the Config is nil, the store is held in memory.
+func NewStore(cfg *Config) (Store, error) {
+	if cfg == nil {
+		return NewMemoryStore(), nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return NewFileStore(cfg.Path)
+}
+
+// GetDefaultStore returns the store shared by token validators. Unless set
+// with SetDefaultStore, the store is held in memory.
+func GetDefaultStore() Store {
+	defaultStoreMu.RLock()
+	s := defaultStore
+	defaultStoreMu.RUnlock()
+	if s != nil {
+		return s
+	}
+	defaultStoreMu.Lock()
+	defer defaultStoreMu.Unlock()
+	if defaultStore == nil {
+		defaultStore = NewMemoryStore()
+	}
+	return defaultStore
+}
+
+// SetDefaultStore replaces the store shared by token validators. The
+// replaced store is being closed.
+func SetDefaultStore(s Store) error {
+	if s == nil {
+		return errors.ErrRevocationStoreNil
+	}
+	defaultStoreMu.Lock()
+	defer defaultStoreMu.Unlock()
+	if defaultStore != nil && defaultStore != s {
+		defaultStore.Close()
+	}
+	defaultStore = s
+	return nil
+}
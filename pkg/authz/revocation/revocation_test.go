@@ -0,0 +1,236 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revocation
+
+import (
+	"fmt"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Now()
+	// The revocations in the same second as the issued tokens start at
+	// a whole second.
+	rev := time.Unix(now.Unix(), 0)
+	testcases := []struct {
+		name      string
+		entries   []*Entry
+		exemptAt  time.Time
+		id        string
+		subject   string
+		issuedAt  int64
+		want      bool
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:    "revoked token id",
+			entries: []*Entry{NewTokenEntry("foo", now.Add(time.Hour).Unix())},
+			id:      "foo",
+			subject: "jsmith",
+			want:    true,
+		},
+		{
+			name:    "token id not revoked",
+			entries: []*Entry{NewTokenEntry("foo", now.Add(time.Hour).Unix())},
+			id:      "bar",
+			subject: "jsmith",
+		},
+		{
+			name:    "expired token id revocation",
+			entries: []*Entry{NewTokenEntry("foo", now.Add(-time.Hour).Unix())},
+			id:      "foo",
+			subject: "jsmith",
+		},
+		{
+			name:     "token issued before subject revocation",
+			entries:  []*Entry{NewSubjectEntry("jsmith", now, 900)},
+			id:       "foo",
+			subject:  "jsmith",
+			issuedAt: now.Add(-time.Minute).Unix(),
+			want:     true,
+		},
+		{
+			name:     "token issued after subject revocation",
+			entries:  []*Entry{NewSubjectEntry("jsmith", now, 900)},
+			id:       "foo",
+			subject:  "jsmith",
+			issuedAt: now.Add(time.Minute).Unix(),
+		},
+		{
+			name:     "token issued in the same second as subject revocation",
+			entries:  []*Entry{NewSubjectEntry("jsmith", now, 900)},
+			id:       "foo",
+			subject:  "jsmith",
+			issuedAt: now.Unix(),
+			want:     true,
+		},
+		{
+			name:     "token exempt after subject revocation in the same second",
+			entries:  []*Entry{NewSubjectEntry("jsmith", rev, 900)},
+			exemptAt: rev.Add(time.Millisecond),
+			id:       "foo",
+			subject:  "jsmith",
+			issuedAt: rev.Unix(),
+		},
+		{
+			name:     "token exempt before subject revocation in the same second",
+			entries:  []*Entry{NewSubjectEntry("jsmith", rev.Add(500*time.Millisecond), 900)},
+			exemptAt: rev.Add(time.Millisecond),
+			id:       "foo",
+			subject:  "jsmith",
+			issuedAt: rev.Unix(),
+			want:     true,
+		},
+		{
+			name:     "token exempt after subject revocation in the next second",
+			entries:  []*Entry{NewSubjectEntry("jsmith", rev, 900)},
+			exemptAt: rev.Add(time.Second),
+			id:       "foo",
+			subject:  "jsmith",
+			issuedAt: rev.Unix(),
+			want:     true,
+		},
+		{
+			name:    "token without issued at timestamp of revoked subject",
+			entries: []*Entry{NewSubjectEntry("jsmith", now, 900)},
+			id:      "foo",
+			subject: "jsmith",
+			want:    true,
+		},
+		{
+			name: "subject revocation extended by subsequent entry",
+			entries: []*Entry{
+				NewSubjectEntry("jsmith", now.Add(-time.Hour), 900),
+				NewSubjectEntry("jsmith", now, 900),
+			},
+			id:       "foo",
+			subject:  "jsmith",
+			issuedAt: now.Add(-time.Minute).Unix(),
+			want:     true,
+		},
+		{
+			name:      "entry without token id and subject",
+			entries:   []*Entry{{}},
+			shouldErr: true,
+			err:       errors.ErrRevocationEntryIncomplete,
+		},
+		{
+			name:      "nil entry",
+			entries:   []*Entry{nil},
+			shouldErr: true,
+			err:       errors.ErrRevocationEntryNil,
+		},
+	}
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			tmpDir, err := tests.TempDir("TestRevocationStore")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			fileStore, err := NewFileStore(filepath.Join(tmpDir, fmt.Sprintf("revocations_%d.json", i)))
+			if err != nil {
+				t.Fatalf("failed to create file store: %v", err)
+			}
+			memoryStore := NewMemoryStore()
+			defer memoryStore.Close()
+			defer fileStore.Close()
+			for _, store := range []Store{memoryStore, fileStore} {
+				for _, entry := range tc.entries {
+					err = store.Add(entry)
+					if err != nil {
+						break
+					}
+				}
+				if tests.EvalErrWithLog(t, err, "add", tc.shouldErr, tc.err, msgs) {
+					return
+				}
+				if !tc.exemptAt.IsZero() {
+					if err := store.Exempt(tc.id, tc.subject, tc.exemptAt); err != nil {
+						t.Fatalf("unexpected error exempting token: %v", err)
+					}
+				}
+				got := store.IsRevoked(tc.id, tc.subject, tc.issuedAt)
+				tests.EvalObjectsWithLog(t, fmt.Sprintf("%T revoked", store), tc.want, got, msgs)
+			}
+
+			// The file store must survive restarts.
+			restoredStore, err := NewFileStore(fileStore.GetPath())
+			if err != nil {
+				t.Fatalf("failed to load file store: %v", err)
+			}
+			defer restoredStore.Close()
+			got := restoredStore.IsRevoked(tc.id, tc.subject, tc.issuedAt)
+			tests.EvalObjectsWithLog(t, "restored store revoked", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestMemoryStoreExpire(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	defer store.Close()
+	store.Add(NewTokenEntry("foo", now.Add(-time.Minute).Unix()))
+	store.Add(NewTokenEntry("bar", now.Add(time.Minute).Unix()))
+	store.Add(NewSubjectEntry("jsmith", now.Add(-time.Hour), 900))
+	if n := store.expire(now); n != 2 {
+		t.Fatalf("expected 2 expired entries, got %d", n)
+	}
+	if n := len(store.GetEntries()); n != 1 {
+		t.Fatalf("expected 1 remaining entry, got %d", n)
+	}
+}
+
+func TestMemoryStoreClose(t *testing.T) {
+	store := NewMemoryStore()
+	for i := 0; i < 2; i++ {
+		if err := store.Close(); err != nil {
+			t.Fatalf("unexpected error closing store: %v", err)
+		}
+	}
+	select {
+	case <-store.exit:
+	default:
+		t.Fatalf("store management remains active after close")
+	}
+}
+
+func TestDefaultStore(t *testing.T) {
+	s := GetDefaultStore()
+	if s == nil {
+		t.Fatalf("expected default store")
+	}
+	if GetDefaultStore() != s {
+		t.Fatalf("expected the same default store")
+	}
+	replacement := NewMemoryStore()
+	if err := SetDefaultStore(replacement); err != nil {
+		t.Fatalf("unexpected error setting default store: %v", err)
+	}
+	defer replacement.Close()
+	if GetDefaultStore() != replacement {
+		t.Fatalf("expected the replacement default store")
+	}
+	select {
+	case <-s.(*MemoryStore).exit:
+	default:
+		t.Fatalf("replaced default store remains active")
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/metrics"
+)
+
+var validationFailures = metrics.NewCounterVec(
+	"authcrunch_token_validation_failures_total",
+	"The number of requests failing token validation, by reason.",
+	"reason",
+)
+
+func init() {
+	metrics.DefaultRegistry.Register("validator", validationFailures)
+}
+
+// getFailureReason returns the reason of the validation failure reported
+// in metrics. The errors with arguments are reported by the standard error
+// they wrap.
+func getFailureReason(err error) string {
+	switch unwrapStandardError(err) {
+	case errors.ErrNoTokenFound:
+		return "no_token"
+	case errors.ErrBasicAuthFailed:
+		return "basic_auth_failed"
+	case errors.ErrAPIKeyAuthFailed:
+		return "api_key_auth_failed"
+	case errors.ErrCryptoKeyStoreParseTokenExpired:
+		return "expired"
+	case errors.ErrCryptoKeyStoreParseTokenFailed:
+		return "invalid"
+	case errors.ErrCryptoKeyStoreTokenData:
+		return "bad_data"
+	case errors.ErrRevocationTokenRevoked:
+		return "revoked"
+	case errors.ErrAccessNotAllowed, errors.ErrAccessNotAllowedByPathACL:
+		return "access_denied"
+	case errors.ErrSourceAddressNotFound, errors.ErrSourceAddressMismatch:
+		return "source_address"
+	}
+	return "other"
+}
+
+// unwrapStandardError returns the standard error in the chain of the
+// wrapped errors, or nil when there is none.
+func unwrapStandardError(err error) error {
+	for err != nil {
+		if _, ok := err.(errors.StandardError); ok {
+			return err
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return nil
+		}
+		err = u.Unwrap()
+	}
+	return nil
+}
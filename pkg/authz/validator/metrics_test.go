@@ -0,0 +1,58 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestGetFailureReason(t *testing.T) {
+	testcases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "standard error",
+			err:  errors.ErrNoTokenFound,
+			want: "no_token",
+		},
+		{
+			name: "standard error with arguments",
+			err:  errors.ErrSourceAddressMismatch.WithArgs("10.0.0.1", "10.0.0.2"),
+			want: "source_address",
+		},
+		{
+			name: "wrapped standard error",
+			err:  fmt.Errorf("authorization failed: %w", errors.ErrCryptoKeyStoreParseTokenExpired),
+			want: "expired",
+		},
+		{
+			name: "unknown error",
+			err:  fmt.Errorf("foobar"),
+			want: "other",
+		},
+	}
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test %d, name: %s", i, tc.name)}
+			tests.EvalObjectsWithLog(t, "reason", tc.want, getFailureReason(tc.err), msgs)
+		})
+	}
+}
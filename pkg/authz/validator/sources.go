@@ -151,6 +151,12 @@ func (v *TokenValidator) parseCookies(ctx context.Context, r *http.Request, ar *
 // Authorize authorizes HTTP requests based on the presence and the content of
 // the tokens in the requests.
 func (v *TokenValidator) Authorize(ctx context.Context, r *http.Request, ar *requests.AuthorizationRequest) (usr *user.User, err error) {
+	defer func() {
+		if err != nil {
+			validationFailures.Inc(getFailureReason(err))
+		}
+	}()
+
 	// var token, tokenName, tokenSource string
 	// var found bool
 	for _, sourceName := range v.tokenSources {
@@ -189,6 +195,11 @@ func (v *TokenValidator) Authorize(ctx context.Context, r *http.Request, ar *req
 		}
 	}
 
+	if v.GetRevocationStore().IsRevoked(usr.Claims.ID, usr.Claims.Subject, usr.Claims.IssuedAt) {
+		v.cache.Delete(ar.Token.Payload)
+		return nil, errors.ErrRevocationTokenRevoked
+	}
+
 	if err := v.guardian.authorize(ctx, r, usr); err != nil {
 		ar.Response.User = make(map[string]interface{})
 		if usr.Claims.ID != "" {
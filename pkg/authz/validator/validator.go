@@ -22,6 +22,7 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/acl"
 	"github.com/greenpau/go-authcrunch/pkg/authz/cache"
 	"github.com/greenpau/go-authcrunch/pkg/authz/options"
+	"github.com/greenpau/go-authcrunch/pkg/authz/revocation"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/kms"
 	"github.com/greenpau/go-authcrunch/pkg/shared/idp"
@@ -80,6 +81,7 @@ type TokenValidator struct {
 	apiKeyAuthEnabled bool
 	customAuthEnabled bool
 	idpConfig         *idp.IdentityProviderConfig
+	revocations       revocation.Store
 }
 
 // NewTokenValidator returns an instance of TokenValidator
@@ -391,11 +393,67 @@ func (v *TokenValidator) addKeys(ctx context.Context, keys []*kms.CryptoKey) err
 	return nil
 }
 
+// UpdateKeys replaces the token verification keys of TokenValidator, e.g.
+// after the signing keys were rotated. The names of the tokens accepted by
+// TokenValidator remain unchanged.
+func (v *TokenValidator) UpdateKeys(ctx context.Context, keys []*kms.CryptoKey) error {
+	var verifyKeys []*kms.CryptoKey
+	if len(keys) == 0 {
+		return errors.ErrValidatorCryptoKeyStoreNoKeys
+	}
+	for _, k := range keys {
+		if !k.Verify.Token.Capable {
+			continue
+		}
+		if k.Verify.Token.Name == "" {
+			continue
+		}
+		if k.Verify.Token.MaxLifetime == 0 {
+			continue
+		}
+		verifyKeys = append(verifyKeys, k)
+	}
+	if len(verifyKeys) == 0 {
+		return errors.ErrValidatorCryptoKeyStoreNoVerifyKeys
+	}
+	return v.keystore.ReplaceKeys(verifyKeys)
+}
+
 // CacheUser adds a user to token validator cache.
 func (v *TokenValidator) CacheUser(usr *user.User) error {
 	return v.cache.Add(usr)
 }
 
+// SetRevocationStore sets the token revocation store of TokenValidator.
+// By default, TokenValidator uses the store returned by
+// revocation.GetDefaultStore.
+func (v *TokenValidator) SetRevocationStore(s revocation.Store) error {
+	if s == nil {
+		return errors.ErrRevocationStoreNil
+	}
+	v.revocations = s
+	return nil
+}
+
+// GetRevocationStore returns the token revocation store of TokenValidator.
+func (v *TokenValidator) GetRevocationStore() revocation.Store {
+	if v.revocations != nil {
+		return v.revocations
+	}
+	return revocation.GetDefaultStore()
+}
+
+// RevokeToken adds the revocation entry to the token revocation store and
+// removes the revoked tokens from the token validator cache.
+func (v *TokenValidator) RevokeToken(e *revocation.Entry) error {
+	s := v.GetRevocationStore()
+	if err := s.Add(e); err != nil {
+		return err
+	}
+	v.cache.DeleteRevoked(s)
+	return nil
+}
+
 // RegisterIdentityProvider registers an identity provider with TokenValidator.
 func (v *TokenValidator) RegisterIdentityProvider(cfg *idp.IdentityProviderConfig) error {
 	if cfg == nil {
@@ -16,6 +16,7 @@ package validator
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -26,6 +27,7 @@ import (
 	"github.com/greenpau/go-authcrunch/internal/testutils"
 	"github.com/greenpau/go-authcrunch/pkg/acl"
 	"github.com/greenpau/go-authcrunch/pkg/authz/options"
+	"github.com/greenpau/go-authcrunch/pkg/authz/revocation"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/kms"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
@@ -318,6 +320,7 @@ func TestAuthorize(t *testing.T) {
 		sourceAddress               string
 		enableBearer                bool
 		cacheUser                   bool
+		revokeToken                 bool
 		validateAccessListPathClaim bool
 		validateSourceAddress       bool
 		validateMethodPath          bool
@@ -856,6 +859,15 @@ func TestAuthorize(t *testing.T) {
 			path:      "/app/page3/allowed",
 			cacheUser: true,
 		},
+		{
+			name:        "revoked cached user",
+			claims:      viewer2,
+			config:      defaultRolesAllowACL,
+			method:      "GET",
+			path:        "/app/page3/allowed",
+			cacheUser:   true,
+			revokeToken: true,
+		},
 		{
 			name:                  "token ip address and client ip address match but not roles",
 			claims:                viewer2,
@@ -969,6 +981,7 @@ func TestAuthorize(t *testing.T) {
 			signingKey := keys[0]
 
 			validator := NewTokenValidator()
+			validator.SetRevocationStore(revocation.NewMemoryStore())
 
 			if !tc.optionsDisabled {
 				opts = options.NewTokenValidatorOptions()
@@ -1072,6 +1085,17 @@ func TestAuthorize(t *testing.T) {
 						return
 					}
 				}
+
+				if tc.revokeToken {
+					if err := validator.RevokeToken(revocation.NewSubjectEntry(usr.Claims.Subject, time.Now().Add(time.Second), 900)); err != nil {
+						t.Fatalf("failed revoking token: %v", err)
+					}
+					if validator.cache.Get(usr.Token) != nil {
+						t.Fatalf("revoked token remains in cache")
+					}
+					_, err = validator.Authorize(ctx, r, ar)
+					tests.EvalErrWithLog(t, err, "revoked auth", true, errors.ErrRevocationTokenRevoked, msgs)
+				}
 			}
 
 			req, err := http.NewRequest(tc.method, tc.path, nil)
@@ -1096,6 +1120,127 @@ func TestAuthorize(t *testing.T) {
 	}
 }
 
+func TestAuthorizeEdDSA(t *testing.T) {
+	ctx := context.Background()
+	ks := kms.NewCryptoKeyStore()
+	if err := ks.AutoGenerate("validator-eddsa", "EdDSA"); err != nil {
+		t.Fatal(err)
+	}
+	accessList := testutils.NewTestGuestAccessList()
+	validator := NewTokenValidator()
+	validator.SetRevocationStore(revocation.NewMemoryStore())
+	if err := validator.Configure(ctx, ks.GetKeys(), accessList, options.NewTokenValidatorOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	usr := testutils.NewTestUser()
+	if err := ks.SignToken(nil, "EdDSA", usr); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", "/app/page", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("access_token=%s", usr.Token))
+	ar := requests.NewAuthorizationRequest()
+	got, err := validator.Authorize(ctx, req, ar)
+	if err != nil {
+		t.Fatalf("unexpected authorization error: %v", err)
+	}
+	tests.EvalObjects(t, "subject", usr.Claims.Subject, got.Claims.Subject)
+}
+
+func TestAuthorizeRemoteJWKS(t *testing.T) {
+	ctx := context.Background()
+	issuer := kms.NewCryptoKeyStore()
+	if err := issuer.AutoGenerate("remote-portal", "ES512"); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(issuer.GetJWKS())
+	}))
+	defer srv.Close()
+	// The remote key set is fetched with the default transport, which must
+	// trust the certificate of the test server.
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = srv.Client().Transport
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	configs, err := kms.ParseCryptoKeyConfigs("crypto key verify from jwks " + srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := kms.NewCryptoKeyStore()
+	if err := ks.AddKeysWithConfigs(configs); err != nil {
+		t.Fatal(err)
+	}
+	accessList := testutils.NewTestGuestAccessList()
+	validator := NewTokenValidator()
+	if err := validator.Configure(ctx, ks.GetVerifyKeys(), accessList, options.NewTokenValidatorOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	usr := testutils.NewTestUser()
+	if err := issuer.SignToken(nil, nil, usr); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", "/app/page", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("access_token=%s", usr.Token))
+	ar := requests.NewAuthorizationRequest()
+	got, err := validator.Authorize(ctx, req, ar)
+	if err != nil {
+		t.Fatalf("unexpected authorization error: %v", err)
+	}
+	tests.EvalObjects(t, "subject", usr.Claims.Subject, got.Claims.Subject)
+}
+
+func TestUpdateKeys(t *testing.T) {
+	ctx := context.Background()
+	var keystores []*kms.CryptoKeyStore
+	for _, tag := range []string{"validator-update-1", "validator-update-2"} {
+		ks := kms.NewCryptoKeyStore()
+		if err := ks.AutoGenerate(tag, "ES512"); err != nil {
+			t.Fatal(err)
+		}
+		keystores = append(keystores, ks)
+	}
+	validator := NewTokenValidator()
+	validator.SetRevocationStore(revocation.NewMemoryStore())
+	if err := validator.Configure(ctx, keystores[0].GetKeys(), testutils.NewTestGuestAccessList(), options.NewTokenValidatorOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if err := validator.UpdateKeys(ctx, nil); err == nil {
+		t.Fatalf("expected error when updating with no keys")
+	}
+	if err := validator.UpdateKeys(ctx, keystores[1].GetVerifyKeys()); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, ks := range keystores {
+		usr := testutils.NewTestUser()
+		if err := ks.SignToken(nil, nil, usr); err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("GET", "/app/page", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("access_token=%s", usr.Token))
+		_, err = validator.Authorize(ctx, req, requests.NewAuthorizationRequest())
+		switch {
+		case i == 0 && err == nil:
+			t.Fatalf("expected authorization error for token signed by replaced key")
+		case i == 1 && err != nil:
+			t.Fatalf("unexpected authorization error: %v", err)
+		}
+	}
+}
+
 func TestAddKeys(t *testing.T) {
 	testcases := []struct {
 		name                 string
@@ -16,16 +16,24 @@ package errors
 
 // ACL Errors
 const (
-	ErrAccessListRuleConfig                     StandardError = "acl rule configuration error: %v: %v"
-	ErrAccessListRuleConditionConfig            StandardError = "acl rule condition configuration error: %v: %v"
-	ErrAccessListNoRules                        StandardError = "acl has no rules"
-	ErrACLRuleConditionSyntaxMatchNotFound      StandardError = "invalid condition syntax, matcher not found: %v"
-	ErrACLRuleConditionSyntaxMatchFieldNotFound StandardError = "invalid condition syntax, matcher field not found: %v"
-	ErrACLRuleConditionSyntaxMatchValueNotFound StandardError = "invalid condition syntax, matcher values not found: %v"
-	ErrACLRuleConditionSyntaxCondDataType       StandardError = "invalid condition syntax, matcher condition data type unsupported: %v"
-	ErrACLRuleConditionSyntaxUnsupported        StandardError = "invalid condition syntax, failed creating rule condition: %v"
-	ErrACLRuleConditionSyntaxStrategyNotFound   StandardError = "invalid condition syntax, matcher strategy not found: %v"
-	ErrACLRuleConditionSyntaxReservedWordUsage  StandardError = "invalid condition syntax, found reserved keyword %q: %v"
+	ErrAccessListRuleConfig                      StandardError = "acl rule configuration error: %v: %v"
+	ErrAccessListRuleConditionConfig             StandardError = "acl rule condition configuration error: %v: %v"
+	ErrAccessListNoRules                         StandardError = "acl has no rules"
+	ErrACLRuleConditionSyntaxMatchNotFound       StandardError = "invalid condition syntax, matcher not found: %v"
+	ErrACLRuleConditionSyntaxMatchFieldNotFound  StandardError = "invalid condition syntax, matcher field not found: %v"
+	ErrACLRuleConditionSyntaxMatchValueNotFound  StandardError = "invalid condition syntax, matcher values not found: %v"
+	ErrACLRuleConditionSyntaxCondDataType        StandardError = "invalid condition syntax, matcher condition data type unsupported: %v"
+	ErrACLRuleConditionSyntaxUnsupported         StandardError = "invalid condition syntax, failed creating rule condition: %v"
+	ErrACLRuleConditionSyntaxStrategyNotFound    StandardError = "invalid condition syntax, matcher strategy not found: %v"
+	ErrACLRuleConditionSyntaxReservedWordUsage   StandardError = "invalid condition syntax, found reserved keyword %q: %v"
+	ErrACLRuleConditionSyntaxOperatorUnsupported StandardError = "invalid condition syntax, %s operator %q unsupported: %v"
+	ErrACLRuleConditionSyntaxValueInvalid        StandardError = "invalid condition syntax, %s value %q is invalid: %v"
+	ErrACLRuleConditionSyntaxTimezoneInvalid     StandardError = "invalid condition syntax, timezone %q is invalid: %v"
+
+	ErrACLNetworkListNameEmpty StandardError = "acl network list name is empty"
+	ErrACLNetworkListEmpty     StandardError = "acl network list %q has no entries"
+	ErrACLNetworkListInvalid   StandardError = "acl network list %q entry %q is invalid"
+	ErrACLNetworkListNotFound  StandardError = "acl network list %q not found: %v"
 
 	ErrACLRuleSyntaxExtractCondToken   StandardError = "invalid rule syntax, failed to extract condition tokens: %v"
 	ErrACLRuleSyntaxDuplicateField     StandardError = "invalid rule syntax, duplicate field: %s"
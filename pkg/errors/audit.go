@@ -0,0 +1,36 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Audit Errors
+const (
+	ErrAuditConfigNil             StandardError = "audit: config is nil"
+	ErrAuditSinkConfigNil         StandardError = "audit: sink config is nil"
+	ErrAuditSinkKindUnsupported   StandardError = "audit: sink kind %q is not supported"
+	ErrAuditSinkPathEmpty         StandardError = "audit: file sink path is empty"
+	ErrAuditSinkURLEmpty          StandardError = "audit: webhook sink url is empty"
+	ErrAuditSinkURLInvalid        StandardError = "audit: webhook sink url %q is invalid: %v"
+	ErrAuditSinkNetworkInvalid    StandardError = "audit: syslog sink network %q is invalid"
+	ErrAuditSinkFacilityInvalid   StandardError = "audit: syslog sink facility %q is invalid"
+	ErrAuditSinkSyslogUnsupported StandardError = "audit: syslog sink is not supported on this platform"
+	ErrAuditSinkOpen              StandardError = "audit: failed opening %s sink: %v"
+	ErrAuditSinkWrite             StandardError = "audit: failed writing to %s sink: %v"
+	ErrAuditSinkRotate            StandardError = "audit: failed rotating %q: %v"
+	ErrAuditSinkQueueFull         StandardError = "audit: %s sink queue is full"
+	ErrAuditSinkClosed            StandardError = "audit: %s sink is closed"
+	ErrAuditEventTypeUnsupported  StandardError = "audit: event type %q is not supported"
+	ErrAuditEventEncode           StandardError = "audit: failed encoding event: %v"
+	ErrAuditWebhookResponse       StandardError = "audit: webhook %q responded with status code %d"
+)
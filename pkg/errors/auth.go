@@ -21,9 +21,18 @@ const (
 	ErrUserInterfaceBuiltinTemplateAddFailed StandardError = "user interface validation for %s portal failed for built-in template %s in %s theme: %v"
 	ErrUserInterfaceCustomTemplateAddFailed  StandardError = "user interface validation for %s portal failed for custom template %s in %s: %v"
 
-	ErrUserRegistrationConfig StandardError = "user registration configuration for %q instance failed: %v"
-	ErrCryptoKeyStoreConfig   StandardError = "crypto key store configuration for %q instance failed: %v"
-	ErrGeneric                StandardError = "%s: %v"
+	ErrUserRegistrationConfig  StandardError = "user registration configuration for %q instance failed: %v"
+	ErrPasswordRecoveryConfig  StandardError = "password recovery configuration for %q instance failed: %v"
+	ErrEmailVerificationConfig StandardError = "email verification configuration for %q instance failed: %v"
+	ErrCryptoKeyStoreConfig    StandardError = "crypto key store configuration for %q instance failed: %v"
+	ErrCacheStoreConfig        StandardError = "cache store configuration for %q instance failed: %v"
+	ErrAuditLogConfig          StandardError = "audit log configuration for %q instance failed: %v"
+	ErrGeneric                 StandardError = "%s: %v"
 
 	ErrAuthorizationFailed StandardError = "user authorization failed: %s, reason: %v"
+
+	ErrEmailVerificationDisabled       StandardError = "email verification is disabled"
+	ErrEmailVerificationTokenMalformed StandardError = "email verification token is malformed"
+	ErrEmailVerificationTokenSignature StandardError = "email verification token signature is invalid"
+	ErrEmailVerificationTokenExpired   StandardError = "email verification token is expired"
 )
@@ -18,4 +18,24 @@ package errors
 const (
 	ErrCacheEmptyToken StandardError = "cache: user token is empty"
 	ErrCacheNilUser    StandardError = "cache: user is nil"
+
+	ErrRefreshTokenCacheUnavailable StandardError = "refresh token cache is not available"
+	ErrRefreshTokenInvalid          StandardError = "refresh token is invalid: %v"
+	ErrRefreshTokenNotFound         StandardError = "refresh token not found"
+	ErrRefreshTokenExpired          StandardError = "refresh token expired"
+	ErrRefreshTokenReused           StandardError = "refresh token reuse detected"
+
+	ErrCacheStoreConfigNil            StandardError = "cache store: config is nil"
+	ErrCacheStoreKindUnsupported      StandardError = "cache store: kind %q is not supported"
+	ErrCacheStorePathEmpty            StandardError = "cache store: file path is empty"
+	ErrCacheStoreAddressEmpty         StandardError = "cache store: address is empty"
+	ErrCacheStoreLoad                 StandardError = "cache store: failed loading %q: %v"
+	ErrCacheStoreCommit               StandardError = "cache store: failed committing %q: %v"
+	ErrCacheStoreConnect              StandardError = "cache store: failed connecting to %q: %v"
+	ErrCacheStoreCommand              StandardError = "cache store: %s command failed: %v"
+	ErrCacheStoreUnexpectedResponse   StandardError = "cache store: unexpected response %q"
+	ErrCacheStoreEntryNotFound        StandardError = "cache store: entry not found"
+	ErrCacheStoreEntryEncode          StandardError = "cache store: failed encoding entry: %v"
+	ErrCacheStoreEntryDecode          StandardError = "cache store: failed decoding entry: %v"
+	ErrCacheStoreEntryLifetimeInvalid StandardError = "cache store: entry lifetime %d is invalid"
 )
@@ -30,4 +30,5 @@ const (
 	ErrPortalConfigCredentialsNil                       StandardError = "portal config credentials is nil"
 	ErrPortalConfigCredentialsNotFound                  StandardError = "portal config credential %q not found"
 	ErrPortalConfigAdminEmailNotFound                   StandardError = "portal config registration admin email not found"
+	ErrPortalConfigIssuerMalformed                      StandardError = "portal config issuer %q is malformed"
 )
@@ -27,6 +27,13 @@ const (
 	ErrDatabaseOperation    StandardError = "database operation failed: %v"
 	ErrDatabaseInvalidUser  StandardError = "username and email point to a different identity in the database"
 	ErrDatabaseUserNotFound StandardError = "user not found"
+
+	ErrDatabaseStoreRevision  StandardError = "database revision %d is out of date"
+	ErrDatabaseBackup         StandardError = "failed backing up database %q: %v"
+	ErrMigrateDatabase        StandardError = "failed migrating database from %q to %q: %v"
+	ErrMigrateDatabaseExists  StandardError = "destination database already exists"
+	ErrMigrateDatabaseEmpty   StandardError = "source database not found"
+	ErrSQLStoreDriverNotFound StandardError = "sql driver %q is not registered"
 	// ErrDatabaseInvalidUserPassword StandardError = "invalid password"
 	ErrAuthFailed StandardError = "user authentication failed: %v"
 
@@ -44,22 +51,48 @@ const (
 
 	ErrUserPolicyCompliance     StandardError = "username policy compliance check failed"
 	ErrPasswordPolicyCompliance StandardError = "user password policy compliance check failed"
+	ErrPasswordReused           StandardError = "new password matches one of the previously used passwords"
+	ErrPasswordChangeBlocked    StandardError = "password change is not permitted by policy"
+	ErrPasswordMinAge           StandardError = "password cannot be changed until %s"
+	ErrPasswordExpired          StandardError = "password expired and must be changed"
+
+	ErrRegistrationNotFound StandardError = "registration %q not found"
+	ErrRegistrationReviewed StandardError = "registration %q has already been reviewed"
+	ErrReviewRegistration   StandardError = "failed reviewing registration %q: %v"
 
 	ErrAddUser    StandardError = "failed adding user %q: %v"
 	ErrDeleteUser StandardError = "failed deleting user %q: %v"
 	ErrGetUsers   StandardError = "failed retrieving users: %v"
 	ErrGetUser    StandardError = "failed retrieving user %q: %v"
 
+	ErrDisableUser     StandardError = "failed disabling user %q: %v"
+	ErrEnableUser      StandardError = "failed enabling user %q: %v"
+	ErrUpdateUserRoles StandardError = "failed updating roles of user %q: %v"
+	ErrUserDisabled    StandardError = "user is disabled"
+	ErrUnlockUser      StandardError = "failed unlocking user %q: %v"
+	ErrUserLockedOut   StandardError = "user is locked out until %s"
+
 	ErrPasswordEmpty                StandardError = "empty password"
 	ErrPasswordEmptyAlgorithm       StandardError = "empty password hash algorithm"
 	ErrPasswordGenerate             StandardError = "password generation error: %v"
 	ErrPasswordUnsupportedAlgorithm StandardError = "unsupported password hash algorithm: %v"
+	ErrPasswordHashUnsupported      StandardError = "unsupported password hash format"
+	ErrPasswordHashMalformed        StandardError = "malformed password hash: %v"
+	ErrPasswordHashLimitExceeded    StandardError = "%s password hash %s exceeds the limit of %d"
+
+	ErrImportFormatUnsupported StandardError = "unsupported import file format: %s"
+	ErrImportEntryMalformed    StandardError = "malformed import entry on line %d: %v"
 
 	ErrUserIDInvalidLength StandardError = "invalid user id length: %d"
 	ErrUsernameEmpty       StandardError = "username is empty"
 
-	ErrEmailAddressInvalid StandardError = "invalid email address"
-	ErrRoleEmpty           StandardError = "role name is empty"
+	ErrEmailAddressInvalid      StandardError = "invalid email address"
+	ErrEmailAddressNotFound     StandardError = "email address not found"
+	ErrEmailAddressNotConfirmed StandardError = "email address %s is not confirmed"
+	ErrAddEmailAddress          StandardError = "failed adding email address %q: %v"
+	ErrConfirmEmailAddress      StandardError = "failed confirming email address %q: %v"
+
+	ErrRoleEmpty StandardError = "role name is empty"
 
 	ErrParseNameFailed StandardError = "failed to parse name: %s"
 
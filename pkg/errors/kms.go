@@ -31,6 +31,14 @@ const (
 	ErrCryptoKeyConfigNoConfigFound             StandardError = "no key configs found"
 	ErrCryptoKeyConfigKeyInvalid                StandardError = "key config %d is invalid: %v"
 
+	// JWKS
+	ErrCryptoKeyJWKNotVerifyKey   StandardError = "kms: key %q is not a verification key"
+	ErrCryptoKeyJWKUnsupportedKey StandardError = "kms: key %q of type %T cannot be published"
+	ErrCryptoKeyJWKInvalid        StandardError = "kms: jwk %q is invalid: %v"
+	ErrJwksKeySourceFetch         StandardError = "kms: failed fetching jwks from %q: %v"
+	ErrJwksKeySourceNoKeys        StandardError = "kms: jwks from %q has no usable keys"
+	ErrJwksKeySourceKeyNotFound   StandardError = "kms: key %q not found in jwks from %q"
+
 	// KeyManager
 	ErrKeyManagerAddKeyNil                  StandardError = "kms: failed adding nil key to key manager"
 	ErrKeyManagerCryptoKeyConfigInvalidType StandardError = "kms: failed key manager with invalid token config type: %T"
@@ -46,6 +54,11 @@ const (
 	ErrCryptoKeyStoreAutoGenerateNotAvailable StandardError = "auto-generate not available when keystore is not empty"
 	ErrCryptoKeyStoreAutoGenerateFailed       StandardError = "failed to auto-generate keystore keypair: %v"
 	ErrCryptoKeyStoreAutoGenerateAlgo         StandardError = "auto-generate does not support %q algorithm"
+	ErrCryptoKeyStoreRefreshTokenLifetime     StandardError = "keystore: refresh token lifetime %v is not a number"
+	ErrCryptoKeyStoreKeyRotationInterval      StandardError = "keystore: key rotation interval %v is not a number"
+	ErrCryptoKeyStoreKeyRotationDisabled      StandardError = "keystore: key rotation is not configured"
+	ErrCryptoKeyStoreKeyRotationFailed        StandardError = "keystore: key rotation failed: %v"
+	ErrCryptoKeyStoreKeyRotationWithKeys      StandardError = "keystore: key rotation is not supported with configured keys"
 	// Signing
 	ErrUnsupportedSigningMethod StandardError = "kms: grantor does not support %s token signing method"
 )
@@ -0,0 +1,27 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// User Registration Errors
+const (
+	ErrRegistrationDomainRuleNil               StandardError = "registration domain rule is nil"
+	ErrRegistrationDomainRuleDomainsEmpty      StandardError = "registration domain rule has no domains"
+	ErrRegistrationDomainRuleDomainInvalid     StandardError = "registration domain rule domain %q is invalid"
+	ErrRegistrationDomainRuleActionUnsupported StandardError = "registration domain rule action %q is unsupported"
+	ErrRegistrationDomainRuleRolesUnsupported  StandardError = "registration domain rule declining registrations does not support roles"
+	ErrRegistrationRealmNotFound               StandardError = "local backend for registration realm %q not found"
+	ErrRegistrationDisabled                    StandardError = "user registration is disabled"
+	ErrRegistrationVerdictUnsupported          StandardError = "registration verdict %q is unsupported"
+)
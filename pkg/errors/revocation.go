@@ -0,0 +1,26 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+// Token Revocation Errors
+const (
+	ErrRevocationEntryNil        StandardError = "revocation: entry is nil"
+	ErrRevocationEntryIncomplete StandardError = "revocation: entry must have either token id or subject with issued before timestamp"
+	ErrRevocationStoreNil        StandardError = "revocation: store is nil"
+	ErrRevocationConfigPathEmpty StandardError = "revocation: store path is empty"
+	ErrRevocationStoreLoad       StandardError = "revocation: failed loading %q: %v"
+	ErrRevocationStoreCommit     StandardError = "revocation: failed committing %q: %v"
+	ErrRevocationTokenRevoked    StandardError = "token has been revoked"
+)
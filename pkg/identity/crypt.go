@@ -0,0 +1,469 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"hash"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cryptAlphabet          = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	shaCryptDefaultRounds  = 5000
+	shaCryptMinRounds      = 1000
+	shaCryptMaxRounds      = 999999999
+	shaCryptMaxSaltLength  = 16
+	md5CryptMaxSaltLength  = 8
+	md5CryptIterationCount = 1000
+)
+
+var (
+	// The order of the bytes of the digest in crypt(3) encoding.
+	sha256CryptOrder = [][]int{
+		{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+		{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29},
+		{-1, 31, 30},
+	}
+	sha512CryptOrder = [][]int{
+		{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+		{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+		{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+		{15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+		{62, 20, 41}, {-1, -1, 63},
+	}
+	md5CryptOrder = [][]int{
+		{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5},
+		{-1, -1, 11},
+	}
+
+	// The legacy algorithms map the scheme prefixes of the hashes to the
+	// algorithm names.
+	legacyHashPrefixes = []struct {
+		prefix    string
+		algorithm string
+	}{
+		{"$2a$", "bcrypt"},
+		{"$2b$", "bcrypt"},
+		{"$2y$", "bcrypt"},
+		{"$apr1$", "apr1"},
+		{"$1$", "md5-crypt"},
+		{"$5$", "sha256-crypt"},
+		{"$6$", "sha512-crypt"},
+		{"{SHA}", "sha"},
+		{"{SSHA}", "ssha"},
+		{"{SSHA256}", "ssha256"},
+		{"{SSHA512}", "ssha512"},
+	}
+)
+
+// NewPasswordFromHash returns an instance of Password holding the provided
+// pre-hashed password, e.g. the one found in htpasswd, shadow, or LDIF files.
+// The supported formats are bcrypt, argon2id and scrypt in PHC string format,
+// Apache MD5 (apr1), crypt(3) MD5, SHA-256 and SHA-512, and LDAP SHA, SSHA,
+// SSHA256, and SSHA512. The LDAP {CRYPT} scheme prefix is accepted with any
+// of the crypt(3) formats.
+func NewPasswordFromHash(s string) (*Password, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errors.ErrPasswordEmpty
+	}
+	if len(s) > 7 && strings.EqualFold(s[:7], "{CRYPT}") {
+		s = s[7:]
+	}
+	if strings.HasPrefix(s, "$argon2id$") || strings.HasPrefix(s, "$scrypt$") {
+		return parsePHCHash(s)
+	}
+	for _, entry := range legacyHashPrefixes {
+		if !strings.HasPrefix(strings.ToUpper(s), strings.ToUpper(entry.prefix)) {
+			continue
+		}
+		if strings.HasPrefix(entry.prefix, "{") {
+			// Normalize the case of LDAP scheme prefix.
+			s = entry.prefix + s[len(entry.prefix):]
+		}
+		p := &Password{
+			Purpose:   "generic",
+			Algorithm: entry.algorithm,
+			Hash:      s,
+			CreatedAt: time.Now().UTC(),
+		}
+		if p.Algorithm == "bcrypt" {
+			cost, err := bcrypt.Cost([]byte(s))
+			if err != nil {
+				return nil, errors.ErrPasswordHashMalformed.WithArgs(err)
+			}
+			p.Cost = cost
+			return p, nil
+		}
+		if _, ok := p.matchLegacy(""); !ok {
+			return nil, errors.ErrPasswordHashMalformed.WithArgs(p.Algorithm)
+		}
+		return p, nil
+	}
+	return nil, errors.ErrPasswordHashUnsupported
+}
+
+// Encode returns the hash of the Password in the form accepted by
+// NewPasswordFromHash, i.e. PHC string format for argon2id and scrypt.
+func (p *Password) Encode() string {
+	switch p.Algorithm {
+	case "argon2id":
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism, p.Salt, p.Hash)
+	case "scrypt":
+		return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", bits.Len(uint(p.Cost))-1, p.BlockSize, p.Parallelism, p.Salt, p.Hash)
+	}
+	return p.Hash
+}
+
+// parsePHCHash returns an instance of Password holding argon2id or scrypt
+// hash in PHC string format, e.g. $argon2id$v=19$m=65536,t=3,p=4$salt$hash
+// or $scrypt$ln=15,r=8,p=1$salt$hash.
+func parsePHCHash(s string) (*Password, error) {
+	arr := strings.Split(s, "$")
+	p := &Password{
+		Purpose:   "generic",
+		Algorithm: arr[1],
+		CreatedAt: time.Now().UTC(),
+	}
+	if p.Algorithm == "argon2id" {
+		if len(arr) != 6 || arr[2] != fmt.Sprintf("v=%d", argon2.Version) {
+			return nil, errors.ErrPasswordHashMalformed.WithArgs(p.Algorithm)
+		}
+		arr = append(arr[:2], arr[3:]...)
+	}
+	if len(arr) != 5 {
+		return nil, errors.ErrPasswordHashMalformed.WithArgs(p.Algorithm)
+	}
+	params := make(map[string]int)
+	for _, kv := range strings.Split(arr[2], ",") {
+		kvs := strings.SplitN(kv, "=", 2)
+		if len(kvs) != 2 {
+			return nil, errors.ErrPasswordHashMalformed.WithArgs(p.Algorithm)
+		}
+		n, err := strconv.Atoi(kvs[1])
+		if err != nil || n < 1 {
+			return nil, errors.ErrPasswordHashMalformed.WithArgs(p.Algorithm)
+		}
+		params[kvs[0]] = n
+	}
+	switch p.Algorithm {
+	case "argon2id":
+		p.Memory, p.Iterations, p.Parallelism = params["m"], params["t"], params["p"]
+		if p.Memory < 1 || p.Iterations < 1 || p.Parallelism < 1 || p.Parallelism > 255 {
+			return nil, errors.ErrPasswordHashMalformed.WithArgs(p.Algorithm)
+		}
+	case "scrypt":
+		if params["ln"] < 1 || params["ln"] > 30 || params["r"] < 1 || params["p"] < 1 {
+			return nil, errors.ErrPasswordHashMalformed.WithArgs(p.Algorithm)
+		}
+		p.Cost, p.BlockSize, p.Parallelism = 1<<uint(params["ln"]), params["r"], params["p"]
+	}
+	if _, err := base64.RawStdEncoding.DecodeString(arr[3]); err != nil {
+		return nil, errors.ErrPasswordHashMalformed.WithArgs(p.Algorithm)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(arr[4])
+	if err != nil || len(key) < 1 {
+		return nil, errors.ErrPasswordHashMalformed.WithArgs(p.Algorithm)
+	}
+	p.Salt, p.Hash, p.KeyLength = arr[3], arr[4], len(key)
+	if err := p.checkLimits(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// isLegacy returns true when the Password holds a hash that was imported
+// and could only be verified, but not produced.
+func (p *Password) isLegacy() bool {
+	switch p.Algorithm {
+	case "bcrypt", "argon2id", "scrypt", "":
+		return false
+	}
+	return true
+}
+
+// matchLegacy verifies the provided password against the legacy hash. The
+// second return value is false when the hash is malformed.
+func (p *Password) matchLegacy(s string) (bool, bool) {
+	var computed string
+	switch p.Algorithm {
+	case "apr1":
+		salt, _, ok := parseCryptHash(p.Hash, "$apr1$")
+		if !ok {
+			return false, false
+		}
+		computed = md5Crypt([]byte(s), []byte(salt), "$apr1$")
+	case "md5-crypt":
+		salt, _, ok := parseCryptHash(p.Hash, "$1$")
+		if !ok {
+			return false, false
+		}
+		computed = md5Crypt([]byte(s), []byte(salt), "$1$")
+	case "sha256-crypt", "sha512-crypt":
+		magic, fn, order := "$5$", sha256.New, sha256CryptOrder
+		if p.Algorithm == "sha512-crypt" {
+			magic, fn, order = "$6$", sha512.New, sha512CryptOrder
+		}
+		salt, rounds, ok := parseCryptHash(p.Hash, magic)
+		if !ok {
+			return false, false
+		}
+		computed = shaCrypt(fn, order, []byte(s), []byte(salt), rounds, magic)
+	case "sha", "ssha", "ssha256", "ssha512":
+		return matchSaltedSHA(p.Algorithm, p.Hash, s)
+	default:
+		return false, false
+	}
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(p.Hash)) == 1, true
+}
+
+// parseCryptHash returns the salt and the number of rounds of a crypt(3)
+// hash, i.e. $magic$[rounds=N$]salt$digest. The rounds are zero when
+// not specified.
+func parseCryptHash(s, magic string) (string, int, bool) {
+	if !strings.HasPrefix(s, magic) {
+		return "", 0, false
+	}
+	arr := strings.Split(s[len(magic):], "$")
+	var rounds int
+	if len(arr) == 3 && strings.HasPrefix(arr[0], "rounds=") {
+		n, err := strconv.Atoi(strings.TrimPrefix(arr[0], "rounds="))
+		if err != nil || n < 1 {
+			return "", 0, false
+		}
+		rounds = n
+		arr = arr[1:]
+	}
+	if len(arr) != 2 || arr[1] == "" {
+		return "", 0, false
+	}
+	return arr[0], rounds, true
+}
+
+func matchSaltedSHA(algorithm, s, password string) (bool, bool) {
+	var fn func() hash.Hash
+	var prefix string
+	switch algorithm {
+	case "sha":
+		fn, prefix = sha1.New, "{SHA}"
+	case "ssha":
+		fn, prefix = sha1.New, "{SSHA}"
+	case "ssha256":
+		fn, prefix = sha256.New, "{SSHA256}"
+	case "ssha512":
+		fn, prefix = sha512.New, "{SSHA512}"
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, prefix))
+	if err != nil {
+		return false, false
+	}
+	h := fn()
+	size := h.Size()
+	if len(b) < size || (algorithm == "sha" && len(b) != size) {
+		return false, false
+	}
+	digest, salt := b[:size], b[size:]
+	h.Write([]byte(password))
+	h.Write(salt)
+	return subtle.ConstantTimeCompare(h.Sum(nil), digest) == 1, true
+}
+
+// md5Crypt implements the MD5-based crypt(3) algorithm and its Apache
+// variant with the $apr1$ magic.
+func md5Crypt(password, salt []byte, magic string) string {
+	if len(salt) > md5CryptMaxSaltLength {
+		salt = salt[:md5CryptMaxSaltLength]
+	}
+
+	alt := md5.New()
+	alt.Write(password)
+	alt.Write(salt)
+	alt.Write(password)
+	altSum := alt.Sum(nil)
+
+	d := md5.New()
+	d.Write(password)
+	d.Write([]byte(magic))
+	d.Write(salt)
+	for i := len(password); i > 0; i -= 16 {
+		if i > 16 {
+			d.Write(altSum)
+		} else {
+			d.Write(altSum[:i])
+		}
+	}
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			d.Write([]byte{0})
+		} else {
+			d.Write(password[:1])
+		}
+	}
+	sum := d.Sum(nil)
+
+	for i := 0; i < md5CryptIterationCount; i++ {
+		c := md5.New()
+		if i&1 != 0 {
+			c.Write(password)
+		} else {
+			c.Write(sum)
+		}
+		if i%3 != 0 {
+			c.Write(salt)
+		}
+		if i%7 != 0 {
+			c.Write(password)
+		}
+		if i&1 != 0 {
+			c.Write(sum)
+		} else {
+			c.Write(password)
+		}
+		sum = c.Sum(nil)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(magic)
+	sb.Write(salt)
+	sb.WriteString("$")
+	sb.WriteString(encodeCrypt(sum, md5CryptOrder))
+	return sb.String()
+}
+
+// shaCrypt implements the SHA-256 and SHA-512 based crypt(3) algorithms.
+func shaCrypt(fn func() hash.Hash, order [][]int, password, salt []byte, rounds int, magic string) string {
+	customRounds := rounds > 0
+	switch {
+	case !customRounds:
+		rounds = shaCryptDefaultRounds
+	case rounds < shaCryptMinRounds:
+		rounds = shaCryptMinRounds
+	case rounds > shaCryptMaxRounds:
+		rounds = shaCryptMaxRounds
+	}
+	if len(salt) > shaCryptMaxSaltLength {
+		salt = salt[:shaCryptMaxSaltLength]
+	}
+
+	b := fn()
+	b.Write(password)
+	b.Write(salt)
+	b.Write(password)
+	bSum := b.Sum(nil)
+	size := len(bSum)
+
+	a := fn()
+	a.Write(password)
+	a.Write(salt)
+	i := len(password)
+	for ; i > size; i -= size {
+		a.Write(bSum)
+	}
+	a.Write(bSum[:i])
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write(bSum)
+		} else {
+			a.Write(password)
+		}
+	}
+	aSum := a.Sum(nil)
+
+	dp := fn()
+	for i := 0; i < len(password); i++ {
+		dp.Write(password)
+	}
+	p := repeatBytes(dp.Sum(nil), len(password))
+
+	ds := fn()
+	for i := 0; i < 16+int(aSum[0]); i++ {
+		ds.Write(salt)
+	}
+	s := repeatBytes(ds.Sum(nil), len(salt))
+
+	sum := aSum
+	for i := 0; i < rounds; i++ {
+		c := fn()
+		if i&1 != 0 {
+			c.Write(p)
+		} else {
+			c.Write(sum)
+		}
+		if i%3 != 0 {
+			c.Write(s)
+		}
+		if i%7 != 0 {
+			c.Write(p)
+		}
+		if i&1 != 0 {
+			c.Write(sum)
+		} else {
+			c.Write(p)
+		}
+		sum = c.Sum(nil)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(magic)
+	if customRounds {
+		sb.WriteString("rounds=" + strconv.Itoa(rounds) + "$")
+	}
+	sb.Write(salt)
+	sb.WriteString("$")
+	sb.WriteString(encodeCrypt(sum, order))
+	return sb.String()
+}
+
+func repeatBytes(b []byte, n int) []byte {
+	return bytes.Repeat(b, n/len(b)+1)[:n]
+}
+
+// encodeCrypt encodes the digest with crypt(3) base64 alphabet. Each entry
+// of the order holds the indexes of three bytes forming 24-bit group. The
+// negative indexes denote the absent bytes in the last group.
+func encodeCrypt(b []byte, order [][]int) string {
+	var sb strings.Builder
+	for _, group := range order {
+		var w uint
+		var n int
+		for _, i := range group {
+			w <<= 8
+			if i < 0 {
+				continue
+			}
+			w |= uint(b[i])
+			n++
+		}
+		for j := 0; j <= n; j++ {
+			sb.WriteByte(cryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,294 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewPasswordFromHash(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("foobar"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testcases := []struct {
+		name      string
+		input     string
+		password  string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:     "htpasswd bcrypt hash",
+			input:    strings.Replace(string(bcryptHash), "$2a$", "$2y$", 1),
+			password: "foobar",
+			want: map[string]interface{}{
+				"algorithm":      "bcrypt",
+				"password_match": true,
+				"needs_rehash":   false,
+			},
+		},
+		{
+			name:     "htpasswd apr1 hash",
+			input:    "$apr1$abcdefgh$bpWUFITn1N2a204vyP4n//",
+			password: "foobar",
+			want: map[string]interface{}{
+				"algorithm":      "apr1",
+				"password_match": true,
+				"needs_rehash":   true,
+			},
+		},
+		{
+			name:     "htpasswd apr1 hash with invalid password",
+			input:    "$apr1$abcdefgh$bpWUFITn1N2a204vyP4n//",
+			password: "foobar2",
+			want: map[string]interface{}{
+				"algorithm":      "apr1",
+				"password_match": false,
+				"needs_rehash":   true,
+			},
+		},
+		{
+			name:     "htpasswd sha hash",
+			input:    "{SHA}iEPX+SQWIR3p67lj/0zigSWTKHg=",
+			password: "foobar",
+			want: map[string]interface{}{
+				"algorithm":      "sha",
+				"password_match": true,
+				"needs_rehash":   true,
+			},
+		},
+		{
+			name:     "crypt md5 hash",
+			input:    "$1$abcdefgh$XKLM7NXX5Exa9ZWJkF9li1",
+			password: "foobar",
+			want: map[string]interface{}{
+				"algorithm":      "md5-crypt",
+				"password_match": true,
+				"needs_rehash":   true,
+			},
+		},
+		{
+			name:     "crypt sha256 hash",
+			input:    "$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5",
+			password: "Hello world!",
+			want: map[string]interface{}{
+				"algorithm":      "sha256-crypt",
+				"password_match": true,
+				"needs_rehash":   true,
+			},
+		},
+		{
+			name:     "crypt sha256 hash with rounds",
+			input:    "$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA",
+			password: "Hello world!",
+			want: map[string]interface{}{
+				"algorithm":      "sha256-crypt",
+				"password_match": true,
+				"needs_rehash":   true,
+			},
+		},
+		{
+			name:     "crypt sha512 hash",
+			input:    "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+			password: "Hello world!",
+			want: map[string]interface{}{
+				"algorithm":      "sha512-crypt",
+				"password_match": true,
+				"needs_rehash":   true,
+			},
+		},
+		{
+			name:     "ldap crypt sha512 hash with rounds",
+			input:    "{CRYPT}$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.",
+			password: "Hello world!",
+			want: map[string]interface{}{
+				"algorithm":      "sha512-crypt",
+				"password_match": true,
+				"needs_rehash":   true,
+			},
+		},
+		{
+			name:     "ldap ssha hash",
+			input:    "{SSHA}t7yhsDXhE3SS2kqKKdvMfMRv1WthYmNk",
+			password: "foobar",
+			want: map[string]interface{}{
+				"algorithm":      "ssha",
+				"password_match": true,
+				"needs_rehash":   true,
+			},
+		},
+		{
+			name:     "ldap ssha256 hash",
+			input:    "{ssha256}IVM9h0G9BcpzQ2Guy7pmtYAp0LLB9N7YitQv9MEcVY1hYmNk",
+			password: "foobar",
+			want: map[string]interface{}{
+				"algorithm":      "ssha256",
+				"password_match": true,
+				"needs_rehash":   true,
+			},
+		},
+		{
+			name:     "ldap ssha512 hash",
+			input:    "{SSHA512}i6ET9xrTFEJkCKl+ShfucGGlW0VZVoYiNNFilX0wn+NQIHLT5Fl3O88L9XocGg58rt2w1mRZY/HCQphCkn0BomFiY2Q=",
+			password: "foobar",
+			want: map[string]interface{}{
+				"algorithm":      "ssha512",
+				"password_match": true,
+				"needs_rehash":   true,
+			},
+		},
+		{
+			name:      "malformed crypt sha512 hash",
+			input:     "$6$saltstring",
+			shouldErr: true,
+			err:       errors.ErrPasswordHashMalformed.WithArgs("sha512-crypt"),
+		},
+		{
+			name:      "malformed ldap ssha hash",
+			input:     "{SSHA}foo",
+			shouldErr: true,
+			err:       errors.ErrPasswordHashMalformed.WithArgs("ssha"),
+		},
+		{
+			name:      "malformed argon2id hash",
+			input:     "$argon2id$v=19$m=0,t=1,p=1$c2FsdA$aGFzaA",
+			shouldErr: true,
+			err:       errors.ErrPasswordHashMalformed.WithArgs("argon2id"),
+		},
+		{
+			name:      "argon2id hash with unsupported version",
+			input:     "$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$aGFzaA",
+			shouldErr: true,
+			err:       errors.ErrPasswordHashMalformed.WithArgs("argon2id"),
+		},
+		{
+			name:      "argon2id hash with memory above limit",
+			input:     "$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdA$aGFzaA",
+			shouldErr: true,
+			err:       errors.ErrPasswordHashLimitExceeded.WithArgs("argon2id", "memory", 262144),
+		},
+		{
+			name:      "argon2id hash with iterations above limit",
+			input:     "$argon2id$v=19$m=1024,t=1000000000,p=1$c2FsdA$aGFzaA",
+			shouldErr: true,
+			err:       errors.ErrPasswordHashLimitExceeded.WithArgs("argon2id", "iterations", 16),
+		},
+		{
+			name:      "scrypt hash with cost above limit",
+			input:     "$scrypt$ln=30,r=1,p=1$c2FsdA$aGFzaA",
+			shouldErr: true,
+			err:       errors.ErrPasswordHashLimitExceeded.WithArgs("scrypt", "cost", 1048576),
+		},
+		{
+			name:      "scrypt hash with memory above limit",
+			input:     "$scrypt$ln=20,r=8,p=1$c2FsdA$aGFzaA",
+			shouldErr: true,
+			err:       errors.ErrPasswordHashLimitExceeded.WithArgs("scrypt", "memory", 268435456),
+		},
+		{
+			name:      "scrypt hash with parallelism above limit",
+			input:     "$scrypt$ln=4,r=8,p=1000000$c2FsdA$aGFzaA",
+			shouldErr: true,
+			err:       errors.ErrPasswordHashLimitExceeded.WithArgs("scrypt", "parallelism", 16),
+		},
+		{
+			name:      "malformed scrypt hash",
+			input:     "$scrypt$ln=4,r=8$c2FsdA",
+			shouldErr: true,
+			err:       errors.ErrPasswordHashMalformed.WithArgs("scrypt"),
+		},
+		{
+			name:      "unsupported hash",
+			input:     "{MD5}foobar",
+			shouldErr: true,
+			err:       errors.ErrPasswordHashUnsupported,
+		},
+		{
+			name:      "empty hash",
+			input:     " ",
+			shouldErr: true,
+			err:       errors.ErrPasswordEmpty,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			entry, err := NewPasswordFromHash(tc.input)
+			if tests.EvalErrWithLog(t, err, "new password from hash", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			got := make(map[string]interface{})
+			got["algorithm"] = entry.Algorithm
+			got["password_match"] = entry.Match(tc.password)
+			got["needs_rehash"] = entry.needsRehash(&PasswordHashPolicy{})
+			tests.EvalObjectsWithLog(t, "eval", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestPasswordEncode(t *testing.T) {
+	testcases := []struct {
+		name   string
+		algo   string
+		params map[string]interface{}
+	}{
+		{
+			name:   "bcrypt",
+			algo:   "bcrypt",
+			params: map[string]interface{}{"cost": bcrypt.MinCost},
+		},
+		{
+			name:   "argon2id",
+			algo:   "argon2id",
+			params: map[string]interface{}{"iterations": 1, "memory": 1024, "parallelism": 2},
+		},
+		{
+			name:   "scrypt",
+			algo:   "scrypt",
+			params: map[string]interface{}{"cost": 1024, "block_size": 8, "parallelism": 1},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := NewPasswordWithOptions("foobar", "generic", tc.algo, tc.params)
+			if err != nil {
+				t.Fatalf("failed creating password: %v", err)
+			}
+			entry, err := NewPasswordFromHash(p.Encode())
+			if err != nil {
+				t.Fatalf("failed parsing encoded password %q: %v", p.Encode(), err)
+			}
+			got := map[string]interface{}{
+				"encoded":        entry.Encode(),
+				"password_match": entry.Match("foobar"),
+				"mismatch":       entry.Match("foobar2"),
+			}
+			want := map[string]interface{}{
+				"encoded":        p.Encode(),
+				"password_match": true,
+				"mismatch":       false,
+			}
+			tests.EvalObjects(t, "eval", want, got)
+		})
+	}
+}
@@ -15,20 +15,26 @@
 package identity
 
 import (
-	"encoding/json"
 	"fmt"
-	"github.com/greenpau/go-authcrunch/internal/utils"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"github.com/greenpau/versioned"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
+// backupInterval is the minimum interval between the backups taken on
+// commit, i.e. the frequent commits, e.g. the ones recording failed
+// authentication attempts, do not rotate the older backups out.
+const backupInterval = 15 * time.Minute
+
+// revisionCheckInterval is the minimum interval between the reads of the
+// revision of the shared storage on the read lock, i.e. the reads do not
+// query the storage each time. The write lock always reads the revision.
+const revisionCheckInterval = time.Second
+
 var (
 	app           *versioned.PackageManager
 	appVersion    string
@@ -70,8 +76,11 @@ func init() {
 
 // Policy represents database usage policy.
 type Policy struct {
-	Password PasswordPolicy `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
-	User     UserPolicy     `json:"user,omitempty" xml:"user,omitempty" yaml:"user,omitempty"`
+	Password PasswordPolicy     `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
+	User     UserPolicy         `json:"user,omitempty" xml:"user,omitempty" yaml:"user,omitempty"`
+	Lockout  LockoutPolicy      `json:"lockout,omitempty" xml:"lockout,omitempty" yaml:"lockout,omitempty"`
+	Hash     PasswordHashPolicy `json:"hash,omitempty" xml:"hash,omitempty" yaml:"hash,omitempty"`
+	Email    EmailPolicy        `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
 }
 
 // PasswordPolicy represents database password policy.
@@ -85,6 +94,64 @@ type PasswordPolicy struct {
 	RequireNonAlphaNumeric bool `json:"require_non_alpha_numeric" xml:"require_non_alpha_numeric" yaml:"require_non_alpha_numeric"`
 	BlockReuse             bool `json:"block_reuse" xml:"block_reuse" yaml:"block_reuse"`
 	BlockPasswordChange    bool `json:"block_password_change" xml:"block_password_change" yaml:"block_password_change"`
+	// MaxAgeDays is the number of days after which a password expires and
+	// must be changed at next login. Zero disables the expiry.
+	MaxAgeDays int `json:"max_age_days" xml:"max_age_days" yaml:"max_age_days"`
+	// MinAgeDays is the number of days a password must be in use before
+	// a user is allowed to change it.
+	MinAgeDays int `json:"min_age_days" xml:"min_age_days" yaml:"min_age_days"`
+}
+
+// PasswordHashPolicy represents database password hashing policy. The
+// algorithm is one of bcrypt, argon2id, or scrypt. The zero values of the
+// parameters fall back to the defaults of the algorithm. When the algorithm
+// is empty, the passwords are hashed with bcrypt and never rehashed.
+type PasswordHashPolicy struct {
+	Algorithm string `json:"algorithm" xml:"algorithm" yaml:"algorithm"`
+	// Cost is the bcrypt cost or the scrypt CPU/memory cost (N). The scrypt
+	// cost must be a power of two.
+	Cost int `json:"cost" xml:"cost" yaml:"cost"`
+	// Iterations is the argon2id number of passes over the memory.
+	Iterations int `json:"iterations" xml:"iterations" yaml:"iterations"`
+	// Memory is the argon2id memory size in KiB.
+	Memory int `json:"memory" xml:"memory" yaml:"memory"`
+	// Parallelism is the argon2id number of threads or the scrypt
+	// parallelization parameter (p).
+	Parallelism int `json:"parallelism" xml:"parallelism" yaml:"parallelism"`
+	// BlockSize is the scrypt block size parameter (r).
+	BlockSize  int `json:"block_size" xml:"block_size" yaml:"block_size"`
+	KeyLength  int `json:"key_length" xml:"key_length" yaml:"key_length"`
+	SaltLength int `json:"salt_length" xml:"salt_length" yaml:"salt_length"`
+}
+
+func (p *PasswordHashPolicy) validate() error {
+	if p.Algorithm == "" {
+		return nil
+	}
+	_, err := (&Password{Algorithm: p.Algorithm}).applyParams(p.getParams())
+	return err
+}
+
+func (p *PasswordHashPolicy) getParams() map[string]interface{} {
+	return map[string]interface{}{
+		"cost":        p.Cost,
+		"iterations":  p.Iterations,
+		"memory":      p.Memory,
+		"parallelism": p.Parallelism,
+		"block_size":  p.BlockSize,
+		"key_length":  p.KeyLength,
+		"salt_length": p.SaltLength,
+	}
+}
+
+// EmailPolicy represents database email address policy.
+type EmailPolicy struct {
+	// RequireConfirmedLogin refuses login to the users whose email address
+	// is not confirmed.
+	RequireConfirmedLogin bool `json:"require_confirmed_login" xml:"require_confirmed_login" yaml:"require_confirmed_login"`
+	// RequireConfirmedRecovery refuses password recovery via the email
+	// address that is not confirmed.
+	RequireConfirmedRecovery bool `json:"require_confirmed_recovery" xml:"require_confirmed_recovery" yaml:"require_confirmed_recovery"`
 }
 
 // UserPolicy represents database username policy
@@ -108,70 +175,86 @@ type Database struct {
 	refID           map[string]*User
 	refAPIKey       map[string]*User
 	path            string
+	store           Store
+	// backups is the number of the backups taken on commit.
+	backups    int
+	backedUpAt time.Time
+	// revisionCheckedAt is the time the reads last compared the revision
+	// of the shared storage with the revision of the database.
+	revisionMu            sync.Mutex
+	revisionCheckedAt     time.Time
+	revisionCheckInterval time.Duration
 }
 
-// NewDatabase return an instance of Database.
+// NewDatabase return an instance of Database. The database is stored in
+// JSON file, unless the location refers to SQL database. See NewStore.
 func NewDatabase(fp string) (*Database, error) {
+	store, err := NewStore(fp)
+	if err != nil {
+		return nil, errors.ErrNewDatabase.WithArgs(fp, err)
+	}
 	db := &Database{
-		mu:              &sync.RWMutex{},
-		path:            fp,
-		refUsername:     make(map[string]*User),
-		refID:           make(map[string]*User),
-		refEmailAddress: make(map[string]*User),
-		refAPIKey:       make(map[string]*User),
-	}
-	fileInfo, err := os.Stat(fp)
+		mu:                    &sync.RWMutex{},
+		path:                  getStorePath(store, fp),
+		store:                 store,
+		revisionCheckInterval: revisionCheckInterval,
+	}
+	if err := db.load(); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// load reads the database from the storage and indexes the users.
+func (db *Database) load() error {
+	exists, err := db.store.Load(db)
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return nil, errors.ErrNewDatabase.WithArgs(fp, err)
-		}
-		if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
-			return nil, errors.ErrNewDatabase.WithArgs(fp, err)
-		}
+		return errors.ErrNewDatabase.WithArgs(db.path, err)
+	}
+	if !exists {
 		db.Version = app.Version
 		db.enforceDefaultPolicy()
 		if err := db.commit(); err != nil {
-			return nil, errors.ErrNewDatabase.WithArgs(fp, err)
+			return errors.ErrNewDatabase.WithArgs(db.path, err)
 		}
 	} else {
-		if fileInfo.IsDir() {
-			return nil, errors.ErrNewDatabase.WithArgs(fp, "path points to a directory")
-		}
-		b, err := utils.ReadFileBytes(fp)
-		if err != nil {
-			return nil, errors.ErrNewDatabase.WithArgs(fp, err)
-		}
-		if err := json.Unmarshal(b, db); err != nil {
-			return nil, errors.ErrNewDatabase.WithArgs(fp, err)
+		if err := db.Policy.Hash.validate(); err != nil {
+			return errors.ErrNewDatabase.WithArgs(db.path, err)
 		}
 		if changed := db.enforceDefaultPolicy(); changed {
 			if err := db.commit(); err != nil {
-				return nil, errors.ErrNewDatabase.WithArgs(fp, err)
+				return errors.ErrNewDatabase.WithArgs(db.path, err)
 			}
 		}
 	}
-
-	// db.mu = &sync.RWMutex{}
-	// db.path = fp
 	db.Version = app.Version
+	return db.index()
+}
 
+// index builds the lookup tables of the users.
+func (db *Database) index() error {
+	db.refUsername = make(map[string]*User)
+	db.refID = make(map[string]*User)
+	db.refEmailAddress = make(map[string]*User)
+	db.refAPIKey = make(map[string]*User)
 	for _, user := range db.Users {
 		if err := user.Valid(); err != nil {
-			return nil, errors.ErrNewDatabaseInvalidUser.WithArgs(user, err)
+			return errors.ErrNewDatabaseInvalidUser.WithArgs(user, err)
 		}
 		username := strings.ToLower(user.Username)
 		if _, exists := db.refUsername[username]; exists {
-			return nil, errors.ErrNewDatabaseDuplicateUser.WithArgs(user.Username, user)
+			return errors.ErrNewDatabaseDuplicateUser.WithArgs(user.Username, user)
 		}
 		if _, exists := db.refID[user.ID]; exists {
-			return nil, errors.ErrNewDatabaseDuplicateUserID.WithArgs(user.ID, user)
+			return errors.ErrNewDatabaseDuplicateUserID.WithArgs(user.ID, user)
 		}
 		db.refUsername[username] = user
 		db.refID[user.ID] = user
 		for _, email := range user.EmailAddresses {
 			emailAddress := strings.ToLower(email.Address)
 			if _, exists := db.refEmailAddress[emailAddress]; exists {
-				return nil, errors.ErrNewDatabaseDuplicateEmail.WithArgs(emailAddress, user)
+				return errors.ErrNewDatabaseDuplicateEmail.WithArgs(emailAddress, user)
 			}
 			db.refEmailAddress[emailAddress] = user
 		}
@@ -182,12 +265,85 @@ func NewDatabase(fp string) (*Database, error) {
 		}
 		for _, apiKey := range user.APIKeys {
 			if _, exists := db.refAPIKey[apiKey.Prefix]; exists {
-				return nil, errors.ErrNewDatabaseDuplicateAPIKey.WithArgs(apiKey.Prefix, user)
+				return errors.ErrNewDatabaseDuplicateAPIKey.WithArgs(apiKey.Prefix, user)
 			}
 			db.refAPIKey[apiKey.Prefix] = user
 		}
 	}
-	return db, nil
+	return nil
+}
+
+// lock acquires the write lock of the database. When the storage is shared
+// with other instances, the database is reloaded if it changed.
+func (db *Database) lock() {
+	db.mu.Lock()
+	db.refresh()
+}
+
+// rlock acquires the read lock of the database. When the storage is shared
+// with other instances, the database is reloaded if it changed. The write
+// lock is acquired only for the reload. The changes made by the other
+// instances become visible to the reads within revisionCheckInterval.
+func (db *Database) rlock() {
+	db.mu.RLock()
+	if !db.isStale() {
+		return
+	}
+	db.mu.RUnlock()
+	db.mu.Lock()
+	db.refresh()
+	db.mu.Unlock()
+	db.mu.RLock()
+}
+
+// isStale returns true when the revision of the shared storage differs
+// from the revision of the database. The revision of the storage is read
+// only when the previous check is older than the check interval.
+func (db *Database) isStale() bool {
+	store, ok := db.store.(revisionStore)
+	if !ok {
+		return false
+	}
+	db.revisionMu.Lock()
+	now := time.Now()
+	if now.Sub(db.revisionCheckedAt) < db.revisionCheckInterval {
+		db.revisionMu.Unlock()
+		return false
+	}
+	db.revisionCheckedAt = now
+	db.revisionMu.Unlock()
+	revision, err := store.GetRevision()
+	if err != nil {
+		return false
+	}
+	return revision != db.Revision
+}
+
+// refresh reloads the database when the revision of the shared storage
+// differs from the revision of the database. On failure, the database
+// continues with the data it has.
+func (db *Database) refresh() {
+	store, ok := db.store.(revisionStore)
+	if !ok {
+		return
+	}
+	revision, err := store.GetRevision()
+	if err != nil || revision == db.Revision {
+		return
+	}
+	tmp := &Database{path: db.path, store: db.store}
+	if err := tmp.load(); err != nil {
+		return
+	}
+	db.Version = tmp.Version
+	db.Policy = tmp.Policy
+	db.Revision = tmp.Revision
+	db.LastModified = tmp.LastModified
+	db.Users = tmp.Users
+	db.refUsername = tmp.refUsername
+	db.refID = tmp.refID
+	db.refEmailAddress = tmp.refEmailAddress
+	db.refAPIKey = tmp.refAPIKey
 }
 
 func (db *Database) enforceDefaultPolicy() bool {
@@ -242,6 +398,31 @@ func (db *Database) checkPasswordPolicyCompliance(s string) error {
 	return nil
 }
 
+// checkPasswordChangeCompliance checks whether the password change complies
+// with the reuse, change restriction and minimum age policies. The password
+// resets, e.g. via password recovery or by an administrator, and the changes
+// of expired passwords are not subject to the change restrictions.
+func (db *Database) checkPasswordChangeCompliance(user *User, r *requests.Request, now time.Time) error {
+	policy := db.Policy.Password
+	if !r.Flags.PasswordRecovery && !user.IsPasswordExpired(policy.MaxAgeDays, now) {
+		if policy.BlockPasswordChange {
+			return errors.ErrPasswordChangeBlocked
+		}
+		if policy.MinAgeDays > 0 {
+			if p := user.GetPassword(); p != nil {
+				changeAfter := p.CreatedAt.AddDate(0, 0, policy.MinAgeDays)
+				if now.Before(changeAfter) {
+					return errors.ErrPasswordMinAge.WithArgs(changeAfter.Format(time.RFC3339))
+				}
+			}
+		}
+	}
+	if policy.BlockReuse && user.IsPasswordReused(r.User.Password) {
+		return errors.ErrPasswordReused
+	}
+	return nil
+}
+
 // GetPath returns the path  to Database.
 func (db *Database) GetPath() string {
 	return db.path
@@ -249,21 +430,40 @@ func (db *Database) GetPath() string {
 
 // AddUser adds user identity to the database.
 func (db *Database) AddUser(r *requests.Request) error {
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
 
-	if err := db.checkPolicyCompliance(r.User.Username, r.User.Password); err != nil {
-		return errors.ErrAddUser.WithArgs(r.User.Username, err)
+	var user *User
+	var err error
+	if r.User.PasswordHash != "" {
+		// The pre-hashed passwords are imported as is and get rehashed upon
+		// the first successful login.
+		if err := db.checkUserPolicyCompliance(r.User.Username); err != nil {
+			return errors.ErrAddUser.WithArgs(r.User.Username, err)
+		}
+		user, err = NewUserWithPasswordHash(
+			r.User.Username, r.User.PasswordHash,
+			r.User.Email, r.User.FullName,
+			r.User.Roles,
+		)
+	} else {
+		if err := db.checkPolicyCompliance(r.User.Username, r.User.Password); err != nil {
+			return errors.ErrAddUser.WithArgs(r.User.Username, err)
+		}
+		user, err = NewUserWithRoles(
+			r.User.Username, r.User.Password,
+			r.User.Email, r.User.FullName,
+			r.User.Roles,
+		)
 	}
-
-	user, err := NewUserWithRoles(
-		r.User.Username, r.User.Password,
-		r.User.Email, r.User.FullName,
-		r.User.Roles,
-	)
 	if err != nil {
 		return errors.ErrAddUser.WithArgs(r.User.Username, err)
 	}
+	if r.User.PasswordHash == "" && user.GetPassword().needsRehash(&db.Policy.Hash) {
+		if err := user.rehashPassword(r.User.Password, &db.Policy.Hash); err != nil {
+			return errors.ErrAddUser.WithArgs(r.User.Username, err)
+		}
+	}
 	for i := 0; i < 10; i++ {
 		id := NewID()
 		if _, exists := db.refID[id]; !exists {
@@ -297,7 +497,7 @@ func (db *Database) AddUser(r *requests.Request) error {
 	}
 	db.Users = append(db.Users, user)
 
-	if err := db.commit(); err != nil {
+	if err := db.commitUser(user); err != nil {
 		return errors.ErrAddUser.WithArgs(username, err)
 	}
 	return nil
@@ -305,7 +505,7 @@ func (db *Database) AddUser(r *requests.Request) error {
 
 // GetUsers return a list of user identities.
 func (db *Database) GetUsers(r *requests.Request) error {
-	db.mu.RLock()
+	db.rlock()
 	defer db.mu.RUnlock()
 	_, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -321,7 +521,7 @@ func (db *Database) GetUsers(r *requests.Request) error {
 
 // GetUser return an instance of User.
 func (db *Database) GetUser(r *requests.Request) error {
-	db.mu.RLock()
+	db.rlock()
 	defer db.mu.RUnlock()
 	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -333,59 +533,430 @@ func (db *Database) GetUser(r *requests.Request) error {
 
 // DeleteUser deletes a user by user id.
 func (db *Database) DeleteUser(r *requests.Request) error {
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
-	// user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
-	_, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
-		return errors.ErrDeleteUser.WithArgs(r.Query.ID, err)
+		return errors.ErrDeleteUser.WithArgs(r.User.Username, err)
+	}
+	users := []*User{}
+	for _, u := range db.Users {
+		if u.ID == user.ID {
+			continue
+		}
+		users = append(users, u)
+	}
+	db.Users = users
+	delete(db.refUsername, strings.ToLower(user.Username))
+	delete(db.refID, user.ID)
+	for _, email := range user.EmailAddresses {
+		delete(db.refEmailAddress, strings.ToLower(email.Address))
+	}
+	for _, apiKey := range user.APIKeys {
+		delete(db.refAPIKey, apiKey.Prefix)
+	}
+	if err := db.commitDeleteUser(user); err != nil {
+		return errors.ErrDeleteUser.WithArgs(r.User.Username, err)
+	}
+	return nil
+}
+
+// ListUsers returns the metadata of the users in the database. The
+// Offset and Limit of the request query control paging.
+func (db *Database) ListUsers(r *requests.Request) error {
+	db.rlock()
+	defer db.mu.RUnlock()
+	if r.Query.Offset < 0 || r.Query.Limit < 0 {
+		return errors.ErrGetUsers.WithArgs("invalid offset or limit")
+	}
+	bundle := NewUserMetadataBundle()
+	bundle.total = len(db.Users)
+	for i, user := range db.Users {
+		if i < r.Query.Offset {
+			continue
+		}
+		if r.Query.Limit > 0 && bundle.Size() >= r.Query.Limit {
+			break
+		}
+		bundle.Add(user.GetMetadata())
 	}
-	return errors.ErrDeleteUser.WithArgs(r.Query.ID, "user delete operation is not supported")
-	// TODO: how do we delete a user ???
+	r.Response.Payload = bundle
+	return nil
+}
 
-	// if err := user.DeletePublicKey(r); err != nil {
-	//	return err
-	//}
-	/*
-		if err := db.commit(); err != nil {
-			return errors.ErrDeleteUser.WithArgs(r.Query.ID, err)
+// LookupUser finds a user by the user id, username, or email address
+// provided in the ID of the request query. Upon success, the username and
+// email address of the found user are being added to the request.
+func (db *Database) LookupUser(r *requests.Request) error {
+	db.rlock()
+	defer db.mu.RUnlock()
+	user, err := db.getUserByID(r.Query.ID)
+	if err != nil {
+		user, err = db.getUser(r.Query.ID)
+		if err != nil {
+			return errors.ErrGetUser.WithArgs(r.Query.ID, err)
 		}
+	}
+	// The copy of the user is safe to read after the lock is released.
+	userCopy, err := user.clone()
+	if err != nil {
+		return errors.ErrGetUser.WithArgs(r.Query.ID, err)
+	}
+	r.User.Username = user.Username
+	r.User.Email = user.GetMailClaim()
+	r.Response.Payload = userCopy
+	return nil
+}
+
+// DisableUser disables a user.
+func (db *Database) DisableUser(r *requests.Request) error {
+	db.lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrDisableUser.WithArgs(r.User.Username, err)
+	}
+	user.Disable()
+	if err := db.commitUser(user); err != nil {
+		return errors.ErrDisableUser.WithArgs(r.User.Username, err)
+	}
+	return nil
+}
+
+// EnableUser enables previously disabled user.
+func (db *Database) EnableUser(r *requests.Request) error {
+	db.lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrEnableUser.WithArgs(r.User.Username, err)
+	}
+	user.Enable()
+	if err := db.commitUser(user); err != nil {
+		return errors.ErrEnableUser.WithArgs(r.User.Username, err)
+	}
+	return nil
+}
+
+// UnlockUser clears the lockout and failed authentication attempts of a
+// user.
+func (db *Database) UnlockUser(r *requests.Request) error {
+	db.lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrUnlockUser.WithArgs(r.User.Username, err)
+	}
+	if user.Lockout == nil {
 		return nil
-	*/
+	}
+	user.Lockout = nil
+	if err := db.commitUser(user); err != nil {
+		return errors.ErrUnlockUser.WithArgs(r.User.Username, err)
+	}
+	return nil
 }
 
-// AuthenticateUser adds user identity to the database.
-func (db *Database) AuthenticateUser(r *requests.Request) error {
-	db.mu.RLock()
+// UpdateUserRoles replaces the roles of a user.
+func (db *Database) UpdateUserRoles(r *requests.Request) error {
+	db.lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrUpdateUserRoles.WithArgs(r.User.Username, err)
+	}
+	if err := user.SetRoles(r.User.Roles); err != nil {
+		return errors.ErrUpdateUserRoles.WithArgs(r.User.Username, err)
+	}
+	if err := db.commitUser(user); err != nil {
+		return errors.ErrUpdateUserRoles.WithArgs(r.User.Username, err)
+	}
+	return nil
+}
+
+// AddEmailAddress adds an email address to a user. The address is not
+// confirmed.
+func (db *Database) AddEmailAddress(r *requests.Request) error {
+	db.lock()
+	defer db.mu.Unlock()
+	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
+	if err != nil {
+		return errors.ErrAddEmailAddress.WithArgs(r.User.NewEmail, err)
+	}
+	emailAddress := strings.ToLower(r.User.NewEmail)
+	if _, exists := db.refEmailAddress[emailAddress]; exists {
+		return errors.ErrAddEmailAddress.WithArgs(r.User.NewEmail, "email address already in use")
+	}
+	if err := user.AddEmailAddress(r.User.NewEmail); err != nil {
+		return errors.ErrAddEmailAddress.WithArgs(r.User.NewEmail, err)
+	}
+	db.refEmailAddress[emailAddress] = user
+	if err := db.commitUser(user); err != nil {
+		return errors.ErrAddEmailAddress.WithArgs(r.User.NewEmail, err)
+	}
+	r.Query.ID = user.ID
+	return nil
+}
+
+// ConfirmEmailAddress marks the email address of a user as confirmed. The
+// user is identified by the user id in the request query or, when absent,
+// by the username.
+func (db *Database) ConfirmEmailAddress(r *requests.Request) error {
+	db.lock()
+	defer db.mu.Unlock()
+	var user *User
+	var err error
+	if r.Query.ID != "" {
+		user, err = db.getUserByID(r.Query.ID)
+	} else {
+		user, err = db.getUserByUsername(r.User.Username)
+	}
+	if err != nil {
+		return errors.ErrConfirmEmailAddress.WithArgs(r.User.Email, err)
+	}
+	if user.IsEmailAddressConfirmed(r.User.Email) {
+		return nil
+	}
+	if err := user.ConfirmEmailAddress(r.User.Email); err != nil {
+		return errors.ErrConfirmEmailAddress.WithArgs(r.User.Email, err)
+	}
+	if err := db.commitUser(user); err != nil {
+		return errors.ErrConfirmEmailAddress.WithArgs(r.User.Email, err)
+	}
+	r.User.Username = user.Username
+	return nil
+}
+
+// GetRegistrations returns the users whose registrations are pending
+// review, the oldest first.
+func (db *Database) GetRegistrations(r *requests.Request) error {
+	db.rlock()
 	defer db.mu.RUnlock()
+	users := []*User{}
+	for _, user := range db.Users {
+		if user.Registration == nil || !user.Registration.IsPending() {
+			continue
+		}
+		users = append(users, user)
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		return users[i].Registration.CreatedAt.Before(users[j].Registration.CreatedAt)
+	})
+	r.Response.Payload = users
+	return nil
+}
+
+// ApproveRegistration approves the pending registration with the id in the
+// request query. The response payload is the registered user.
+func (db *Database) ApproveRegistration(r *requests.Request) error {
+	return db.reviewRegistration(r, true)
+}
+
+// DeclineRegistration declines the pending registration with the id in the
+// request query. The response payload is the registered user.
+func (db *Database) DeclineRegistration(r *requests.Request) error {
+	return db.reviewRegistration(r, false)
+}
+
+// ReopenRegistration returns the reviewed registration with the id in the
+// request query to pending review, e.g. when the approved registrant could
+// not be added to the realm of the registrations.
+func (db *Database) ReopenRegistration(r *requests.Request) error {
+	db.lock()
+	defer db.mu.Unlock()
+	user := db.getUserByRegistrationID(r.Query.ID)
+	if user == nil {
+		return errors.ErrRegistrationNotFound.WithArgs(r.Query.ID)
+	}
+	if user.Registration.IsPending() {
+		return nil
+	}
+	prev := *user.Registration
+	user.Registration.Reopen()
+	user.Revise()
+	if err := db.commitUser(user); err != nil {
+		*user.Registration = prev
+		return errors.ErrReviewRegistration.WithArgs(r.Query.ID, err)
+	}
+	return nil
+}
+
+func (db *Database) getUserByRegistrationID(id string) *User {
+	for _, u := range db.Users {
+		if u.Registration != nil && u.Registration.ID == id {
+			return u
+		}
+	}
+	return nil
+}
+
+func (db *Database) reviewRegistration(r *requests.Request, approved bool) error {
+	db.lock()
+	defer db.mu.Unlock()
+	user := db.getUserByRegistrationID(r.Query.ID)
+	if user == nil {
+		return errors.ErrRegistrationNotFound.WithArgs(r.Query.ID)
+	}
+	if !user.Registration.IsPending() {
+		return errors.ErrRegistrationReviewed.WithArgs(r.Query.ID)
+	}
+	prev := *user.Registration
+	if approved {
+		user.Registration.Approve()
+	} else {
+		user.Registration.Decline()
+	}
+	user.Registration.Note = r.Registration.Note
+	user.Registration.Reason = r.Registration.Reason
+	user.Revise()
+	if err := db.commitUser(user); err != nil {
+		*user.Registration = prev
+		return errors.ErrReviewRegistration.WithArgs(r.Query.ID, err)
+	}
+	r.User.Username = user.Username
+	r.User.Email = user.GetMailClaim()
+	r.Response.Payload = user
+	return nil
+}
+
+// AuthenticateUser adds user identity to the database. The credentials are
+// verified under the read lock. The write lock is acquired only to record
+// the outcome, i.e. failed attempt, lockout reset, or password rehash.
+func (db *Database) AuthenticateUser(r *requests.Request) error {
+	db.rlock()
 	user, err := db.getUser(r.User.Username)
 	if err != nil {
+		db.mu.RUnlock()
 		r.Response.Code = 400
 		// Calculate password hash as the means to prevent user discovery.
-		NewPassword(r.User.Password)
+		newPasswordWithPolicy(r.User.Password, &db.Policy.Hash)
 		return errors.ErrAuthFailed.WithArgs(err)
 	}
 
+	now := time.Now().UTC()
+	if db.Policy.Lockout.IsEnabled() && user.Lockout.IsLocked(now) {
+		db.mu.RUnlock()
+		r.Response.Code = 423
+		return errors.ErrAuthFailed.WithArgs(errors.ErrUserLockedOut.WithArgs(user.Lockout.EndTime.Format(time.RFC3339)))
+	}
+
+	var authErr error
 	switch {
 	case r.User.Password != "":
-		if err := user.VerifyPassword(r.User.Password); err != nil {
-			r.Response.Code = 400
-			return errors.ErrAuthFailed.WithArgs(err)
-		}
+		authErr = user.VerifyPassword(r.User.Password)
 	case r.WebAuthn.Request != "":
-		if err := user.VerifyWebAuthnRequest(r); err != nil {
-			r.Response.Code = 400
-			return errors.ErrAuthFailed.WithArgs(err)
-		}
+		authErr = user.VerifyWebAuthnRequest(r)
 	default:
+		db.mu.RUnlock()
 		r.Response.Code = 400
 		return errors.ErrAuthFailed.WithArgs("malformed auth request")
 	}
 
+	// The disabled account is reported only after the credentials are
+	// verified, so that the state of the account is not disclosed.
+	if authErr == nil && user.Disabled {
+		db.mu.RUnlock()
+		r.Response.Code = 400
+		return errors.ErrAuthFailed.WithArgs(errors.ErrUserDisabled)
+	}
+
+	if authErr == nil && db.Policy.Email.RequireConfirmedLogin {
+		email := r.User.Username
+		if !strings.Contains(email, "@") {
+			email = user.GetMailClaim()
+		}
+		if !user.IsEmailAddressConfirmed(email) {
+			db.mu.RUnlock()
+			r.Response.Code = 403
+			return errors.ErrAuthFailed.WithArgs(errors.ErrEmailAddressNotConfirmed.WithArgs(email))
+		}
+	}
+
+	// Upgrade the hash of the password to the configured algorithm. The
+	// hash is computed before the write lock is acquired.
+	var current string
+	var rehashed *Password
+	if authErr == nil && r.User.Password != "" && user.GetPassword().needsRehash(&db.Policy.Hash) {
+		current = user.GetPassword().Hash
+		rehashed, _ = newPasswordWithPolicy(r.User.Password, &db.Policy.Hash)
+	}
+
+	recordFailure := authErr != nil && db.Policy.Lockout.IsEnabled()
+	resetLockout := authErr == nil && user.Lockout != nil
+	db.mu.RUnlock()
+
+	if !recordFailure && !resetLockout && rehashed == nil {
+		if authErr != nil {
+			r.Response.Code = 400
+			return errors.ErrAuthFailed.WithArgs(authErr)
+		}
+		r.Response.Code = 200
+		return nil
+	}
+
+	// The user is looked up again, because the database might have been
+	// reloaded or changed after the read lock was released.
+	db.lock()
+	defer db.mu.Unlock()
+	user, err = db.getUser(r.User.Username)
+	if err != nil {
+		r.Response.Code = 400
+		return errors.ErrAuthFailed.WithArgs(err)
+	}
+
+	if authErr != nil {
+		r.Response.Code = 400
+		if db.addFailedAttempt(user, now) {
+			r.Response.Code = 423
+			return errors.ErrAuthFailed.WithArgs(errors.ErrUserLockedOut.WithArgs(user.Lockout.EndTime.Format(time.RFC3339)))
+		}
+		return errors.ErrAuthFailed.WithArgs(authErr)
+	}
+
+	var changed bool
+	if user.Lockout != nil {
+		user.Lockout = nil
+		changed = true
+	}
+	if rehashed != nil && user.setRehashedPassword(current, rehashed) {
+		changed = true
+	}
+
+	if changed {
+		if err := db.commitUser(user); err != nil {
+			r.Response.Code = 500
+			return errors.ErrAuthFailed.WithArgs(err)
+		}
+	}
+
 	r.Response.Code = 200
 	return nil
 }
 
+// addFailedAttempt records failed authentication attempt of a user when
+// the lockout policy is enabled. Returns true when the user got locked out.
+// The attempts engaging the lockout are written immediately. The other
+// attempts of a user are written at most once per lockoutCommitInterval.
+// The attempts not yet written are kept in memory.
+func (db *Database) addFailedAttempt(user *User, now time.Time) bool {
+	if !db.Policy.Lockout.IsEnabled() {
+		return false
+	}
+	if user.Lockout == nil {
+		user.Lockout = NewLockoutState()
+	}
+	locked := user.Lockout.AddFailedAttempt(&db.Policy.Lockout, now)
+	if !locked && now.Sub(user.Lockout.committedAt) < lockoutCommitInterval {
+		return false
+	}
+	// The failure to persist the attempt does not change the outcome of
+	// the authentication.
+	if err := db.commitUser(user); err == nil {
+		user.Lockout.committedAt = now
+	}
+	return locked
+}
+
 // getUser return User by either email address or username.
 func (db *Database) getUser(s string) (*User, error) {
 	if strings.Contains(s, "@") {
@@ -433,38 +1004,143 @@ func (db *Database) getUserByEmailAddress(s string) (*User, error) {
 
 // GetUserCount returns user count.
 func (db *Database) GetUserCount() int {
-	db.mu.RLock()
+	db.rlock()
 	defer db.mu.RUnlock()
 	return len(db.Users)
 }
 
 // Save saves the database.
 func (db *Database) Save() error {
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
 	return db.commit()
 }
 
-// Copy copies the database to another file.
+// Copy copies the database to another file. The copy is always stored in
+// JSON file.
 func (db *Database) Copy(fp string) error {
+	db.lock()
+	defer db.mu.Unlock()
+	return db.copy(fp)
+}
+
+func (db *Database) copy(fp string) error {
+	if err := writeJSONFile(fp, db); err != nil {
+		return errors.ErrDatabaseCommit.WithArgs(fp, err)
+	}
+	return nil
+}
+
+// Backup copies the database to the file with .1 suffix, after shifting
+// the existing backups, e.g. .1 to .2, and keeps at most the provided
+// number of backups. The backups are supported for the database stored in
+// JSON file.
+func (db *Database) Backup(count int) error {
+	db.lock()
+	defer db.mu.Unlock()
+	if _, ok := db.store.(*jsonStore); !ok {
+		return errors.ErrDatabaseBackup.WithArgs(db.path, "not supported by the storage")
+	}
+	if count < 1 {
+		return errors.ErrDatabaseBackup.WithArgs(db.path, "invalid backup count")
+	}
+	if err := rotateBackups(db.path, count); err != nil {
+		return errors.ErrDatabaseBackup.WithArgs(db.path, err)
+	}
+	return db.copy(db.path + ".1")
+}
+
+// SetBackupCount sets the number of the backups kept by the database. When
+// the count is greater than zero, a commit backs up the revision of the
+// database it replaces, unless a backup was taken within backupInterval.
+// The previous revision becomes .1 backup and the .1 backup becomes .2.
+// The backups are supported for the database stored in JSON file.
+func (db *Database) SetBackupCount(count int) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	path := db.path
-	db.path = fp
-	err := db.commit()
-	db.path = path
-	return err
+	if count < 0 {
+		return errors.ErrDatabaseBackup.WithArgs(db.path, "invalid backup count")
+	}
+	if _, ok := db.store.(*jsonStore); !ok && count > 0 {
+		return errors.ErrDatabaseBackup.WithArgs(db.path, "not supported by the storage")
+	}
+	db.backups = count
+	return nil
 }
 
-// commit writes the database contents to a file.
+// backup copies the revision of the database in the storage to the file
+// with .1 suffix, after shifting the existing backups. The backup is taken
+// at most once per backupInterval.
+func (db *Database) backup(now time.Time) error {
+	if db.backups < 1 || now.Sub(db.backedUpAt) < backupInterval {
+		return nil
+	}
+	prev := &Database{path: db.path, store: db.store}
+	if err := prev.load(); err != nil {
+		return errors.ErrDatabaseBackup.WithArgs(db.path, err)
+	}
+	if prev.Revision != db.Revision-1 {
+		// The storage holds the revision of another instance. The commit
+		// fails on the revision check.
+		return nil
+	}
+	if err := rotateBackups(db.path, db.backups); err != nil {
+		return errors.ErrDatabaseBackup.WithArgs(db.path, err)
+	}
+	if err := prev.copy(db.path + ".1"); err != nil {
+		return errors.ErrDatabaseBackup.WithArgs(db.path, err)
+	}
+	db.backedUpAt = now
+	return nil
+}
+
+// Close releases the resources associated with the storage of the database.
+func (db *Database) Close() error {
+	return db.store.Close()
+}
+
+// commit writes the database contents to the storage.
 func (db *Database) commit() error {
 	db.Revision++
 	db.LastModified = time.Now().UTC()
-	data, err := json.MarshalIndent(db, "", "  ")
-	if err != nil {
+	if err := db.backup(db.LastModified); err != nil {
+		db.Revision--
+		return err
+	}
+	if err := db.store.Save(db); err != nil {
+		db.Revision--
+		return errors.ErrDatabaseCommit.WithArgs(db.path, err)
+	}
+	return nil
+}
+
+// commitUser writes the database metadata and the contents of a user to
+// the storage.
+func (db *Database) commitUser(user *User) error {
+	db.Revision++
+	db.LastModified = time.Now().UTC()
+	if err := db.backup(db.LastModified); err != nil {
+		db.Revision--
+		return err
+	}
+	if err := db.store.SaveUser(db, user); err != nil {
+		db.Revision--
 		return errors.ErrDatabaseCommit.WithArgs(db.path, err)
 	}
-	if err := ioutil.WriteFile(db.path, []byte(data), 0600); err != nil {
+	return nil
+}
+
+// commitDeleteUser writes the database metadata and removes a user from
+// the storage.
+func (db *Database) commitDeleteUser(user *User) error {
+	db.Revision++
+	db.LastModified = time.Now().UTC()
+	if err := db.backup(db.LastModified); err != nil {
+		db.Revision--
+		return err
+	}
+	if err := db.store.DeleteUser(db, user); err != nil {
+		db.Revision--
 		return errors.ErrDatabaseCommit.WithArgs(db.path, err)
 	}
 	return nil
@@ -487,7 +1163,7 @@ func (db *Database) validateUserIdentity(username, email string) (*User, error)
 
 // AddPublicKey adds public key, e.g. GPG or SSH, for a user.
 func (db *Database) AddPublicKey(r *requests.Request) error {
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
 	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -496,7 +1172,7 @@ func (db *Database) AddPublicKey(r *requests.Request) error {
 	if err := user.AddPublicKey(r); err != nil {
 		return err
 	}
-	if err := db.commit(); err != nil {
+	if err := db.commitUser(user); err != nil {
 		return errors.ErrAddPublicKey.WithArgs(r.Key.Usage, err)
 	}
 	return nil
@@ -504,7 +1180,7 @@ func (db *Database) AddPublicKey(r *requests.Request) error {
 
 // GetPublicKeys returns a list of public keys associated with a user.
 func (db *Database) GetPublicKeys(r *requests.Request) error {
-	db.mu.RLock()
+	db.rlock()
 	defer db.mu.RUnlock()
 	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -526,7 +1202,7 @@ func (db *Database) GetPublicKeys(r *requests.Request) error {
 
 // DeletePublicKey deletes a public key associated with a user by key id.
 func (db *Database) DeletePublicKey(r *requests.Request) error {
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
 	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -535,7 +1211,7 @@ func (db *Database) DeletePublicKey(r *requests.Request) error {
 	if err := user.DeletePublicKey(r); err != nil {
 		return err
 	}
-	if err := db.commit(); err != nil {
+	if err := db.commitUser(user); err != nil {
 		return errors.ErrDeletePublicKey.WithArgs(r.Key.Usage, err)
 	}
 	return nil
@@ -543,7 +1219,7 @@ func (db *Database) DeletePublicKey(r *requests.Request) error {
 
 // AddAPIKey adds API key for a user.
 func (db *Database) AddAPIKey(r *requests.Request) error {
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
 	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -574,7 +1250,7 @@ func (db *Database) AddAPIKey(r *requests.Request) error {
 		break
 	}
 
-	if err := db.commit(); err != nil {
+	if err := db.commitUser(user); err != nil {
 		return errors.ErrAddAPIKey.WithArgs(r.Key.Usage, err)
 	}
 	return nil
@@ -582,7 +1258,7 @@ func (db *Database) AddAPIKey(r *requests.Request) error {
 
 // DeleteAPIKey deletes an API key associated with a user by key id.
 func (db *Database) DeleteAPIKey(r *requests.Request) error {
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
 	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -592,7 +1268,7 @@ func (db *Database) DeleteAPIKey(r *requests.Request) error {
 		return err
 	}
 	delete(db.refAPIKey, r.Key.Prefix)
-	if err := db.commit(); err != nil {
+	if err := db.commitUser(user); err != nil {
 		return errors.ErrDeleteAPIKey.WithArgs(r.Key.Usage, err)
 	}
 	return nil
@@ -600,7 +1276,7 @@ func (db *Database) DeleteAPIKey(r *requests.Request) error {
 
 // GetAPIKeys returns a list of API keys associated with a user.
 func (db *Database) GetAPIKeys(r *requests.Request) error {
-	db.mu.RLock()
+	db.rlock()
 	defer db.mu.RUnlock()
 	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -622,7 +1298,7 @@ func (db *Database) GetAPIKeys(r *requests.Request) error {
 
 // ChangeUserPassword change user password.
 func (db *Database) ChangeUserPassword(r *requests.Request) error {
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
 	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -631,11 +1307,20 @@ func (db *Database) ChangeUserPassword(r *requests.Request) error {
 	if err := db.checkPasswordPolicyCompliance(r.User.Password); err != nil {
 		return errors.ErrChangeUserPassword.WithArgs(err)
 	}
-	if err := user.ChangePassword(r, db.Policy.Password.KeepVersions); err != nil {
-		return err
+	if !r.Flags.PasswordRecovery {
+		if err := user.VerifyPassword(r.User.OldPassword); err != nil {
+			return errors.ErrChangeUserPassword.WithArgs(err)
+		}
 	}
-	// if db.Policy.Password.KeepVersions
-	if err := db.commit(); err != nil {
+	if err := db.checkPasswordChangeCompliance(user, r, time.Now().UTC()); err != nil {
+		return errors.ErrChangeUserPassword.WithArgs(err)
+	}
+	password, err := newPasswordWithPolicy(r.User.Password, &db.Policy.Hash)
+	if err != nil {
+		return errors.ErrChangeUserPassword.WithArgs(err)
+	}
+	user.addPassword(password, db.Policy.Password.KeepVersions)
+	if err := db.commitUser(user); err != nil {
 		return errors.ErrChangeUserPassword.WithArgs(err)
 	}
 	return nil
@@ -644,7 +1329,7 @@ func (db *Database) ChangeUserPassword(r *requests.Request) error {
 // IdentifyUser returns user identity and a list of challenges that should be
 // satisfied prior to successfully authenticating a user.
 func (db *Database) IdentifyUser(r *requests.Request) error {
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
 	user, err := db.getUser(r.User.Username)
 	if err != nil {
@@ -653,6 +1338,10 @@ func (db *Database) IdentifyUser(r *requests.Request) error {
 		r.User.Challenges = []string{"password"}
 		return nil
 	}
+	if r.Flags.PasswordRecovery && db.Policy.Email.RequireConfirmedRecovery && !user.IsEmailAddressConfirmed(r.User.Username) {
+		r.Response.Code = 403
+		return errors.ErrEmailAddressNotConfirmed.WithArgs(r.User.Username)
+	}
 	if r.Flags.Enabled {
 		user.GetFlags(r)
 	}
@@ -661,6 +1350,9 @@ func (db *Database) IdentifyUser(r *requests.Request) error {
 	r.User.FullName = user.GetNameClaim()
 	r.User.Roles = user.GetRolesClaim()
 	r.User.Challenges = user.GetChallenges()
+	if user.IsPasswordExpired(db.Policy.Password.MaxAgeDays, time.Now().UTC()) {
+		r.User.Challenges = append(r.User.Challenges, "password_change")
+	}
 	r.Response.Code = 200
 	return nil
 }
@@ -675,10 +1367,10 @@ func (db *Database) LookupAPIKey(r *requests.Request) error {
 		return errors.ErrLookupAPIKeyMalformedPayload
 	}
 	r.Key.Prefix = string(r.Key.Payload[:24])
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
 	user, exists := db.refAPIKey[r.Key.Prefix]
-	if !exists {
+	if !exists || user.Disabled {
 		return errors.ErrLookupAPIKeyFailed
 	}
 	if err := user.LookupAPIKey(r); err != nil {
@@ -692,7 +1384,7 @@ func (db *Database) LookupAPIKey(r *requests.Request) error {
 
 // AddMfaToken adds MFA token for a user.
 func (db *Database) AddMfaToken(r *requests.Request) error {
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
 	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -701,7 +1393,7 @@ func (db *Database) AddMfaToken(r *requests.Request) error {
 	if err := user.AddMfaToken(r); err != nil {
 		return err
 	}
-	if err := db.commit(); err != nil {
+	if err := db.commitUser(user); err != nil {
 		return errors.ErrAddMfaToken.WithArgs(err)
 	}
 	return nil
@@ -709,7 +1401,7 @@ func (db *Database) AddMfaToken(r *requests.Request) error {
 
 // GetMfaTokens returns a list of MFA tokens associated with a user.
 func (db *Database) GetMfaTokens(r *requests.Request) error {
-	db.mu.RLock()
+	db.rlock()
 	defer db.mu.RUnlock()
 	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -728,7 +1420,7 @@ func (db *Database) GetMfaTokens(r *requests.Request) error {
 
 // DeleteMfaToken deletes MFA token associated with a user by token id.
 func (db *Database) DeleteMfaToken(r *requests.Request) error {
-	db.mu.Lock()
+	db.lock()
 	defer db.mu.Unlock()
 	user, err := db.validateUserIdentity(r.User.Username, r.User.Email)
 	if err != nil {
@@ -737,7 +1429,7 @@ func (db *Database) DeleteMfaToken(r *requests.Request) error {
 	if err := user.DeleteMfaToken(r); err != nil {
 		return err
 	}
-	if err := db.commit(); err != nil {
+	if err := db.commitUser(user); err != nil {
 		return errors.ErrDeleteMfaToken.WithArgs(r.MfaToken.ID, err)
 	}
 	return nil
@@ -501,6 +501,39 @@ func TestDatabaseChangeUserPassword(t *testing.T) {
 				"password_count": 2,
 			},
 		},
+		{
+			name: "reset user1 password via password recovery",
+			req: &requests.Request{
+				User: requests.User{
+					Username: testUser1,
+					Email:    testEmail1,
+					Password: tests.NewRandomString(16),
+				},
+				Flags: requests.Flags{
+					PasswordRecovery: true,
+				},
+			},
+			want: map[string]interface{}{
+				"password_count": 3,
+			},
+		},
+		{
+			name: "reset user1 password via password recovery with non-compliant password",
+			req: &requests.Request{
+				User: requests.User{
+					Username: testUser1,
+					Email:    testEmail1,
+					Password: "foo",
+				},
+				Flags: requests.Flags{
+					PasswordRecovery: true,
+				},
+			},
+			shouldErr: true,
+			err: errors.ErrChangeUserPassword.WithArgs(
+				errors.ErrPasswordPolicyCompliance,
+			),
+		},
 		{
 			name: "change password of invalid user",
 			req: &requests.Request{
@@ -562,6 +595,226 @@ func TestDatabaseChangeUserPassword(t *testing.T) {
 	}
 }
 
+func TestDatabasePasswordPolicyEnforcement(t *testing.T) {
+	newPassword := tests.NewRandomString(16)
+	testcases := []struct {
+		name string
+		// The age of the current password of user1 in days.
+		passwordAge int
+		policy      PasswordPolicy
+		req         *requests.Request
+		want        map[string]interface{}
+		shouldErr   bool
+		err         error
+	}{
+		{
+			name:   "change password to current password with reuse blocked",
+			policy: PasswordPolicy{BlockReuse: true},
+			req: &requests.Request{
+				User: requests.User{
+					Username:    testUser1,
+					Email:       testEmail1,
+					OldPassword: testPwd1,
+					Password:    testPwd1,
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrChangeUserPassword.WithArgs(errors.ErrPasswordReused),
+		},
+		{
+			name:   "reset password to current password with reuse blocked",
+			policy: PasswordPolicy{BlockReuse: true},
+			req: &requests.Request{
+				User: requests.User{
+					Username: testUser1,
+					Email:    testEmail1,
+					Password: testPwd1,
+				},
+				Flags: requests.Flags{
+					PasswordRecovery: true,
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrChangeUserPassword.WithArgs(errors.ErrPasswordReused),
+		},
+		{
+			name:   "change password to current password with reuse allowed",
+			policy: PasswordPolicy{},
+			req: &requests.Request{
+				User: requests.User{
+					Username:    testUser1,
+					Email:       testEmail1,
+					OldPassword: testPwd1,
+					Password:    testPwd1,
+				},
+			},
+			want: map[string]interface{}{
+				"challenges": []string{"password"},
+			},
+		},
+		{
+			name:   "change password with password change blocked",
+			policy: PasswordPolicy{BlockPasswordChange: true},
+			req: &requests.Request{
+				User: requests.User{
+					Username:    testUser1,
+					Email:       testEmail1,
+					OldPassword: testPwd1,
+					Password:    newPassword,
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrChangeUserPassword.WithArgs(errors.ErrPasswordChangeBlocked),
+		},
+		{
+			name:   "reset password with password change blocked",
+			policy: PasswordPolicy{BlockPasswordChange: true},
+			req: &requests.Request{
+				User: requests.User{
+					Username: testUser1,
+					Email:    testEmail1,
+					Password: newPassword,
+				},
+				Flags: requests.Flags{
+					PasswordRecovery: true,
+				},
+			},
+			want: map[string]interface{}{
+				"challenges": []string{"password"},
+			},
+		},
+		{
+			name:        "change expired password with password change blocked",
+			passwordAge: 91,
+			policy:      PasswordPolicy{BlockPasswordChange: true, MaxAgeDays: 90},
+			req: &requests.Request{
+				User: requests.User{
+					Username:    testUser1,
+					Email:       testEmail1,
+					OldPassword: testPwd1,
+					Password:    newPassword,
+				},
+			},
+			want: map[string]interface{}{
+				"challenges": []string{"password"},
+			},
+		},
+		{
+			name:        "change password before min age",
+			passwordAge: 1,
+			policy:      PasswordPolicy{MinAgeDays: 2},
+			req: &requests.Request{
+				User: requests.User{
+					Username:    testUser1,
+					Email:       testEmail1,
+					OldPassword: testPwd1,
+					Password:    newPassword,
+				},
+			},
+			shouldErr: true,
+			// The error is completed with the time when the change is allowed.
+			err: errors.ErrPasswordMinAge,
+		},
+		{
+			name:        "change password after min age",
+			passwordAge: 3,
+			policy:      PasswordPolicy{MinAgeDays: 2},
+			req: &requests.Request{
+				User: requests.User{
+					Username:    testUser1,
+					Email:       testEmail1,
+					OldPassword: testPwd1,
+					Password:    newPassword,
+				},
+			},
+			want: map[string]interface{}{
+				"challenges": []string{"password"},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := createTestDatabase("TestDatabasePasswordPolicyEnforcement")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			msgs = append(msgs, fmt.Sprintf("database path: %s", db.path))
+
+			user, err := db.getUser(testUser1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			createdAt := time.Now().UTC().AddDate(0, 0, -tc.passwordAge)
+			user.GetPassword().CreatedAt = createdAt
+			db.Policy.Password.BlockReuse = tc.policy.BlockReuse
+			db.Policy.Password.BlockPasswordChange = tc.policy.BlockPasswordChange
+			db.Policy.Password.MaxAgeDays = tc.policy.MaxAgeDays
+			db.Policy.Password.MinAgeDays = tc.policy.MinAgeDays
+
+			if tc.err == errors.ErrPasswordMinAge {
+				changeAfter := createdAt.AddDate(0, 0, tc.policy.MinAgeDays).Format(time.RFC3339)
+				tc.err = errors.ErrChangeUserPassword.WithArgs(errors.ErrPasswordMinAge.WithArgs(changeAfter))
+			}
+
+			err = db.ChangeUserPassword(tc.req)
+			if tests.EvalErrWithLog(t, err, "change password", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+
+			req := &requests.Request{User: requests.User{Username: testUser1}}
+			if err := db.IdentifyUser(req); err != nil {
+				t.Fatalf("unexpected identification failure: %v", err)
+			}
+			got := make(map[string]interface{})
+			got["challenges"] = req.User.Challenges
+			tests.EvalObjectsWithLog(t, "user", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestDatabasePasswordExpiry(t *testing.T) {
+	db, err := createTestDatabase("TestDatabasePasswordExpiry")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	db.Policy.Password.MaxAgeDays = 90
+
+	identify := func() []string {
+		req := &requests.Request{User: requests.User{Username: testUser1}}
+		if err := db.IdentifyUser(req); err != nil {
+			t.Fatalf("unexpected identification failure: %v", err)
+		}
+		return req.User.Challenges
+	}
+
+	tests.EvalObjects(t, "fresh password challenges", []string{"password"}, identify())
+
+	user, err := db.getUser(testUser1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.GetPassword().CreatedAt = time.Now().UTC().AddDate(0, 0, -90)
+	tests.EvalObjects(t, "expired password challenges", []string{"password", "password_change"}, identify())
+
+	// The expired password remains valid for the authentication.
+	if err := db.AuthenticateUser(&requests.Request{User: requests.User{Username: testUser1, Password: testPwd1}}); err != nil {
+		t.Fatalf("expected authentication success, but got failure: %v", err)
+	}
+
+	if err := db.ChangeUserPassword(&requests.Request{
+		User: requests.User{
+			Username:    testUser1,
+			Email:       testEmail1,
+			OldPassword: testPwd1,
+			Password:    tests.NewRandomString(16),
+		},
+	}); err != nil {
+		t.Fatalf("unexpected password change failure: %v", err)
+	}
+	tests.EvalObjects(t, "changed password challenges", []string{"password"}, identify())
+}
+
 func TestDatabaseUserPublicKey(t *testing.T) {
 	var databasePath string
 	db, err := createTestDatabase("TestDatabaseUserPublicKey")
@@ -1185,6 +1438,7 @@ func TestDatabaseGetUsers(t *testing.T) {
 						Email:        "jsmith@gmail.com",
 						LastModified: ts,
 						Created:      ts,
+						Roles:        []string{"viewer", "editor", "admin"},
 					},
 					{
 						ID:           "000000000000000000000000000000000002",
@@ -1192,6 +1446,7 @@ func TestDatabaseGetUsers(t *testing.T) {
 						Email:        "bjones@gmail.com",
 						LastModified: ts,
 						Created:      ts,
+						Roles:        []string{"viewer"},
 					},
 				},
 			},
@@ -1223,6 +1478,235 @@ func TestDatabaseGetUsers(t *testing.T) {
 	}
 }
 
+func TestDatabaseManageUsers(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseManageUsers")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	testcases := []struct {
+		name      string
+		operation string
+		req       *requests.Request
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:      "list users with limit",
+			operation: "list",
+			req: &requests.Request{
+				Query: requests.Query{
+					Limit: 1,
+				},
+			},
+			want: map[string]interface{}{
+				"total":     2,
+				"usernames": []string{"jsmith"},
+			},
+		},
+		{
+			name:      "list users with offset",
+			operation: "list",
+			req: &requests.Request{
+				Query: requests.Query{
+					Offset: 1,
+				},
+			},
+			want: map[string]interface{}{
+				"total":     2,
+				"usernames": []string{"bjones"},
+			},
+		},
+		{
+			name:      "list users with negative offset",
+			operation: "list",
+			req: &requests.Request{
+				Query: requests.Query{
+					Offset: -1,
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrGetUsers.WithArgs("invalid offset or limit"),
+		},
+		{
+			name:      "lookup user by email address",
+			operation: "lookup",
+			req: &requests.Request{
+				Query: requests.Query{
+					ID: testEmail2,
+				},
+			},
+			want: map[string]interface{}{
+				"username": testUser2,
+				"email":    testEmail2,
+				"copy":     true,
+			},
+		},
+		{
+			name:      "lookup non-existing user",
+			operation: "lookup",
+			req: &requests.Request{
+				Query: requests.Query{
+					ID: "foobar",
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrGetUser.WithArgs("foobar", errors.ErrDatabaseUserNotFound),
+		},
+		{
+			name:      "update user roles",
+			operation: "roles",
+			req: &requests.Request{
+				User: requests.User{
+					Username: testUser2,
+					Email:    testEmail2,
+					Roles:    []string{"editor", "viewer", "editor"},
+				},
+			},
+			want: map[string]interface{}{
+				"roles": []string{"editor", "viewer"},
+			},
+		},
+		{
+			name:      "disable user",
+			operation: "disable",
+			req: &requests.Request{
+				User: requests.User{
+					Username: testUser2,
+					Email:    testEmail2,
+				},
+			},
+			want: map[string]interface{}{
+				"disabled": true,
+			},
+		},
+		{
+			name:      "authenticate disabled user",
+			operation: "authenticate",
+			req: &requests.Request{
+				User: requests.User{
+					Username: testUser2,
+					Password: testPwd2,
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrAuthFailed.WithArgs(errors.ErrUserDisabled),
+		},
+		{
+			name:      "authenticate disabled user with invalid password",
+			operation: "authenticate",
+			req: &requests.Request{
+				User: requests.User{
+					Username: testUser2,
+					Password: testPwd1,
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrAuthFailed.WithArgs(errors.ErrUserPasswordInvalid),
+		},
+		{
+			name:      "enable user",
+			operation: "enable",
+			req: &requests.Request{
+				User: requests.User{
+					Username: testUser2,
+					Email:    testEmail2,
+				},
+			},
+			want: map[string]interface{}{
+				"disabled": false,
+			},
+		},
+		{
+			name:      "delete user",
+			operation: "delete",
+			req: &requests.Request{
+				User: requests.User{
+					Username: testUser2,
+					Email:    testEmail2,
+				},
+			},
+			want: map[string]interface{}{
+				"user_count": 1,
+			},
+		},
+		{
+			name:      "delete previously deleted user",
+			operation: "delete",
+			req: &requests.Request{
+				User: requests.User{
+					Username: testUser2,
+					Email:    testEmail2,
+				},
+			},
+			shouldErr: true,
+			err:       errors.ErrDeleteUser.WithArgs(testUser2, errors.ErrDatabaseUserNotFound),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			msgs = append(msgs, fmt.Sprintf("database path: %s", db.path))
+			got := make(map[string]interface{})
+			switch tc.operation {
+			case "list":
+				err = db.ListUsers(tc.req)
+			case "lookup":
+				err = db.LookupUser(tc.req)
+			case "roles":
+				err = db.UpdateUserRoles(tc.req)
+			case "disable":
+				err = db.DisableUser(tc.req)
+			case "enable":
+				err = db.EnableUser(tc.req)
+			case "authenticate":
+				err = db.AuthenticateUser(tc.req)
+			case "delete":
+				err = db.DeleteUser(tc.req)
+			default:
+				t.Fatalf("unsupported operation: %s", tc.operation)
+			}
+			if tests.EvalErrWithLog(t, err, tc.operation, tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			switch tc.operation {
+			case "list":
+				bundle := tc.req.Response.Payload.(*UserMetadataBundle)
+				got["total"] = bundle.Total()
+				usernames := []string{}
+				for _, user := range bundle.Get() {
+					usernames = append(usernames, user.Username)
+				}
+				got["usernames"] = usernames
+			case "lookup":
+				got["username"] = tc.req.User.Username
+				got["email"] = tc.req.User.Email
+				user, err := db.getUser(tc.req.User.Username)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got["copy"] = tc.req.Response.Payload.(*User) != user
+			case "roles":
+				user, err := db.getUser(tc.req.User.Username)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got["roles"] = user.GetRolesClaim()
+			case "disable", "enable":
+				user, err := db.getUser(tc.req.User.Username)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got["disabled"] = user.Disabled
+			case "delete":
+				got["user_count"] = len(db.Users)
+			}
+			tests.EvalObjectsWithLog(t, "output", tc.want, got, msgs)
+		})
+	}
+}
+
 func TestDatabasePolicy(t *testing.T) {
 	var databasePath string
 	db, err := createTestDatabase("TestDatabasePolicy")
@@ -1282,3 +1766,429 @@ func TestDatabasePolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestDatabaseLockout(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseLockout")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	db.Policy.Lockout = LockoutPolicy{MaxFailures: 3, Window: 60, Duration: 300}
+
+	authenticate := func(password string) (int, error) {
+		r := &requests.Request{
+			User: requests.User{
+				Username: testUser1,
+				Password: password,
+			},
+		}
+		err := db.AuthenticateUser(r)
+		return r.Response.Code, err
+	}
+
+	for i := 1; i <= 3; i++ {
+		code, err := authenticate(testPwd2)
+		if err == nil {
+			t.Fatalf("attempt %d: expected authentication failure", i)
+		}
+		switch {
+		case i < 3 && code != 400:
+			t.Fatalf("attempt %d: unexpected response code: %d", i, code)
+		case i == 3 && code != 423:
+			t.Fatalf("attempt %d: expected lockout, got response code: %d", i, code)
+		}
+	}
+
+	// The valid password is rejected while the user is locked out.
+	if code, err := authenticate(testPwd1); err == nil || code != 423 {
+		t.Fatalf("expected locked out user to fail authentication, got code %d: %v", code, err)
+	}
+
+	// The lockout survives database reload.
+	reloaded, err := NewDatabase(db.path)
+	if err != nil {
+		t.Fatalf("failed to reload database: %v", err)
+	}
+	user, err := reloaded.getUser(testUser1)
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if !user.Lockout.IsLocked(time.Now().UTC()) {
+		t.Fatalf("expected persisted lockout, got: %+v", user.Lockout)
+	}
+	if !user.GetMetadata().Locked {
+		t.Fatalf("expected user metadata to indicate lockout")
+	}
+
+	if err := db.UnlockUser(&requests.Request{
+		User: requests.User{
+			Username: testUser1,
+			Email:    testEmail1,
+		},
+	}); err != nil {
+		t.Fatalf("failed to unlock user: %v", err)
+	}
+
+	if code, err := authenticate(testPwd1); err != nil || code != 200 {
+		t.Fatalf("expected unlocked user to authenticate, got code %d: %v", code, err)
+	}
+}
+
+func TestDatabaseLockoutCommitInterval(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseLockoutCommitInterval")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	db.Policy.Lockout = LockoutPolicy{MaxFailures: 5, Window: 60, Duration: 300}
+
+	authenticate := func() {
+		if err := db.AuthenticateUser(&requests.Request{User: requests.User{Username: testUser1, Password: testPwd2}}); err == nil {
+			t.Fatalf("expected authentication failure")
+		}
+	}
+	getPersistedAttempts := func() int {
+		reloaded, err := NewDatabase(db.path)
+		if err != nil {
+			t.Fatalf("failed to reload database: %v", err)
+		}
+		user, err := reloaded.getUser(testUser1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Lockout == nil {
+			return 0
+		}
+		return user.Lockout.FailedAttempts
+	}
+
+	// The first attempt is written, the following ones are kept in memory.
+	authenticate()
+	authenticate()
+	tests.EvalObjects(t, "persisted attempts", 1, getPersistedAttempts())
+
+	user, err := db.getUser(testUser1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests.EvalObjects(t, "attempts in memory", 2, user.Lockout.FailedAttempts)
+
+	// The attempts are written once the interval elapsed.
+	user.Lockout.committedAt = user.Lockout.committedAt.Add(-lockoutCommitInterval)
+	authenticate()
+	tests.EvalObjects(t, "persisted attempts after interval", 3, getPersistedAttempts())
+}
+
+func TestDatabasePasswordRehash(t *testing.T) {
+	db, err := createTestDatabase("TestDatabasePasswordRehash")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	user, err := db.getUser(testUser1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createdAt := user.GetPassword().CreatedAt
+	db.Policy.Hash = PasswordHashPolicy{Algorithm: "argon2id", Iterations: 1, Memory: 1024}
+
+	// The failed authentication does not upgrade the hash.
+	if err := db.AuthenticateUser(&requests.Request{User: requests.User{Username: testUser1, Password: testPwd2}}); err == nil {
+		t.Fatalf("expected authentication failure, but got success")
+	}
+	tests.EvalObjects(t, "algorithm after failed authentication", "bcrypt", user.GetPassword().Algorithm)
+
+	if err := db.AuthenticateUser(&requests.Request{User: requests.User{Username: testUser1, Password: testPwd1}}); err != nil {
+		t.Fatalf("expected authentication success, but got failure: %v", err)
+	}
+
+	reloaded, err := NewDatabase(db.path)
+	if err != nil {
+		t.Fatalf("failed to reload database: %v", err)
+	}
+	user, err = reloaded.getUser(testUser1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]interface{}{
+		"algorithm":      user.GetPassword().Algorithm,
+		"iterations":     user.GetPassword().Iterations,
+		"memory":         user.GetPassword().Memory,
+		"password_count": len(user.Passwords),
+		"created_at":     user.GetPassword().CreatedAt.Equal(createdAt),
+	}
+	want := map[string]interface{}{
+		"algorithm":      "argon2id",
+		"iterations":     1,
+		"memory":         1024,
+		"password_count": 1,
+		"created_at":     true,
+	}
+	tests.EvalObjects(t, "rehashed password", want, got)
+
+	if err := reloaded.AuthenticateUser(&requests.Request{User: requests.User{Username: testUser1, Password: testPwd1}}); err != nil {
+		t.Fatalf("expected authentication success with rehashed password, but got failure: %v", err)
+	}
+
+	reloaded.Policy.Hash.Algorithm = "foobar"
+	if err := reloaded.commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDatabase(db.path); err == nil {
+		t.Fatalf("expected failure loading database with unsupported hash algorithm")
+	}
+}
+
+func TestDatabaseImportUser(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseImportUser")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	// The password hash is "foobar" hashed with Apache MD5 algorithm.
+	if err := db.AddUser(&requests.Request{
+		User: requests.User{
+			Username:     "mjordan",
+			Email:        "mjordan@example.com",
+			PasswordHash: "$apr1$abcdefgh$bpWUFITn1N2a204vyP4n//",
+			Roles:        []string{"viewer"},
+		},
+	}); err != nil {
+		t.Fatalf("failed to import user: %v", err)
+	}
+
+	err = db.AddUser(&requests.Request{
+		User: requests.User{
+			Username:     "mwhite",
+			Email:        "mwhite@example.com",
+			PasswordHash: "{MD5}foobar",
+		},
+	})
+	tests.EvalErr(t, err, "import user with unsupported hash", true,
+		errors.ErrAddUser.WithArgs("mwhite", errors.ErrPasswordHashUnsupported),
+	)
+
+	if err := db.AuthenticateUser(&requests.Request{User: requests.User{Username: "mjordan", Password: "barfoo"}}); err == nil {
+		t.Fatalf("expected authentication failure, but got success")
+	}
+	if err := db.AuthenticateUser(&requests.Request{User: requests.User{Username: "mjordan", Password: "foobar"}}); err != nil {
+		t.Fatalf("expected authentication success, but got failure: %v", err)
+	}
+
+	reloaded, err := NewDatabase(db.path)
+	if err != nil {
+		t.Fatalf("failed to reload database: %v", err)
+	}
+	user, err := reloaded.getUser("mjordan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests.EvalObjects(t, "rehashed password algorithm", "bcrypt", user.GetPassword().Algorithm)
+	if err := reloaded.AuthenticateUser(&requests.Request{User: requests.User{Username: "mjordan", Password: "foobar"}}); err != nil {
+		t.Fatalf("expected authentication success with rehashed password, but got failure: %v", err)
+	}
+}
+
+func TestDatabaseEmailVerification(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseEmailVerification")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	db.Policy.Email = EmailPolicy{RequireConfirmedLogin: true, RequireConfirmedRecovery: true}
+
+	authenticate := func(username string) (int, error) {
+		r := &requests.Request{
+			User: requests.User{
+				Username: username,
+				Password: testPwd1,
+			},
+		}
+		err := db.AuthenticateUser(r)
+		return r.Response.Code, err
+	}
+
+	identify := func(email string) (int, error) {
+		r := &requests.Request{
+			User: requests.User{
+				Username: email,
+			},
+			Flags: requests.Flags{
+				PasswordRecovery: true,
+			},
+		}
+		err := db.IdentifyUser(r)
+		return r.Response.Code, err
+	}
+
+	// The login and recovery are refused for unconfirmed addresses.
+	for _, username := range []string{testUser1, testEmail1} {
+		if code, err := authenticate(username); err == nil || code != 403 {
+			t.Fatalf("expected unconfirmed user %s to fail authentication, got code %d: %v", username, code, err)
+		}
+	}
+	if code, err := identify(testEmail1); err == nil || code != 403 {
+		t.Fatalf("expected recovery to unconfirmed address to fail, got code %d: %v", code, err)
+	}
+
+	// Add a new address and confirm it by user id.
+	addReq := &requests.Request{
+		User: requests.User{
+			Username: testUser1,
+			Email:    testEmail1,
+			NewEmail: "jsmith@example.com",
+		},
+	}
+	if err := db.AddEmailAddress(addReq); err != nil {
+		t.Fatalf("failed adding email address: %v", err)
+	}
+	if addReq.Query.ID == "" {
+		t.Fatalf("expected user id in request query")
+	}
+
+	// The address in use by another user is rejected.
+	if err := db.AddEmailAddress(&requests.Request{
+		User: requests.User{
+			Username: testUser2,
+			Email:    testEmail2,
+			NewEmail: "JSmith@example.com",
+		},
+	}); err == nil {
+		t.Fatalf("expected failure adding an email address in use")
+	}
+
+	if code, err := identify("jsmith@example.com"); err == nil || code != 403 {
+		t.Fatalf("expected recovery to unconfirmed address to fail, got code %d: %v", code, err)
+	}
+	if err := db.ConfirmEmailAddress(&requests.Request{
+		Query: requests.Query{ID: addReq.Query.ID},
+		User:  requests.User{Email: "jsmith@example.com"},
+	}); err != nil {
+		t.Fatalf("failed confirming email address: %v", err)
+	}
+	if code, err := identify("jsmith@example.com"); err != nil || code != 200 {
+		t.Fatalf("expected recovery to confirmed address to succeed, got code %d: %v", code, err)
+	}
+	if code, err := authenticate("jsmith@example.com"); err != nil || code != 200 {
+		t.Fatalf("expected login with confirmed address to succeed, got code %d: %v", code, err)
+	}
+
+	// The primary address remains unconfirmed until confirmed by username.
+	if code, err := authenticate(testUser1); err == nil || code != 403 {
+		t.Fatalf("expected login with unconfirmed primary address to fail, got code %d: %v", code, err)
+	}
+	if err := db.ConfirmEmailAddress(&requests.Request{
+		User: requests.User{Username: testUser1, Email: testEmail1},
+	}); err != nil {
+		t.Fatalf("failed confirming email address: %v", err)
+	}
+	if code, err := authenticate(testUser1); err != nil || code != 200 {
+		t.Fatalf("expected login with confirmed address to succeed, got code %d: %v", code, err)
+	}
+
+	// Unknown addresses cannot be confirmed.
+	if err := db.ConfirmEmailAddress(&requests.Request{
+		User: requests.User{Username: testUser1, Email: "unknown@example.com"},
+	}); err == nil {
+		t.Fatalf("expected failure confirming unknown email address")
+	}
+
+	// The confirmation survives database reload.
+	reloaded, err := NewDatabase(db.path)
+	if err != nil {
+		t.Fatalf("failed to reload database: %v", err)
+	}
+	user, err := reloaded.getUser(testUser1)
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	addr := user.GetEmailAddress("jsmith@example.com")
+	if addr == nil || !addr.Confirmed || addr.ConfirmedAt.IsZero() {
+		t.Fatalf("expected persisted confirmation, got: %+v", addr)
+	}
+}
+
+func TestDatabaseReviewRegistration(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseReviewRegistration")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	for i, username := range []string{"mjordan", "mwhite", "pbrown"} {
+		if err := db.AddUser(&requests.Request{
+			User: requests.User{
+				Username: username,
+				Password: testPwd1,
+				Email:    username + "@example.com",
+				Roles:    []string{"authp/user"},
+			},
+			Query: requests.Query{
+				ID: fmt.Sprintf("registration%d", i),
+			},
+		}); err != nil {
+			t.Fatalf("failed adding registrant: %v", err)
+		}
+	}
+
+	getRegistrations := func() []string {
+		r := &requests.Request{}
+		if err := db.GetRegistrations(r); err != nil {
+			t.Fatalf("failed getting registrations: %v", err)
+		}
+		var ids []string
+		for _, u := range r.Response.Payload.([]*User) {
+			ids = append(ids, u.Registration.ID)
+		}
+		return ids
+	}
+
+	tests.EvalObjects(t, "pending registrations", []string{"registration0", "registration1", "registration2"}, getRegistrations())
+
+	r := &requests.Request{
+		Query:        requests.Query{ID: "registration0"},
+		Registration: requests.Registration{Note: "staff", Reason: "welcome"},
+	}
+	if err := db.ApproveRegistration(r); err != nil {
+		t.Fatalf("failed approving registration: %v", err)
+	}
+	registrant := r.Response.Payload.(*User)
+	if !registrant.Registration.Approved || registrant.Registration.Reason != "welcome" || registrant.Registration.Note != "staff" {
+		t.Fatalf("unexpected registration: %+v", registrant.Registration)
+	}
+	if r.User.Username != "mjordan" || r.User.Email != "mjordan@example.com" {
+		t.Fatalf("unexpected registrant: %+v", r.User)
+	}
+
+	if err := db.DeclineRegistration(&requests.Request{Query: requests.Query{ID: "registration1"}}); err != nil {
+		t.Fatalf("failed declining registration: %v", err)
+	}
+
+	tests.EvalObjects(t, "pending registrations", []string{"registration2"}, getRegistrations())
+
+	err = db.DeclineRegistration(&requests.Request{Query: requests.Query{ID: "registration0"}})
+	tests.EvalErr(t, err, "reviewed registration", true, errors.ErrRegistrationReviewed.WithArgs("registration0"))
+
+	err = db.ApproveRegistration(&requests.Request{Query: requests.Query{ID: "foobar"}})
+	tests.EvalErr(t, err, "unknown registration", true, errors.ErrRegistrationNotFound.WithArgs("foobar"))
+
+	// The reopened registration is pending review again.
+	if err := db.ReopenRegistration(&requests.Request{Query: requests.Query{ID: "registration0"}}); err != nil {
+		t.Fatalf("failed reopening registration: %v", err)
+	}
+	tests.EvalObjects(t, "pending registrations", []string{"registration0", "registration2"}, getRegistrations())
+	if registrant.Registration.Note != "" || registrant.Registration.Reason != "" {
+		t.Fatalf("unexpected reopened registration: %+v", registrant.Registration)
+	}
+	if err := db.ApproveRegistration(&requests.Request{Query: requests.Query{ID: "registration0"}}); err != nil {
+		t.Fatalf("failed approving reopened registration: %v", err)
+	}
+
+	// The verdict survives database reload.
+	reloaded, err := NewDatabase(db.path)
+	if err != nil {
+		t.Fatalf("failed to reload database: %v", err)
+	}
+	user, err := reloaded.getUser("mwhite")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if !user.Registration.Declined || user.Registration.DeclinedAt.IsZero() {
+		t.Fatalf("expected persisted verdict, got: %+v", user.Registration)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var emailRegex *regexp.Regexp
@@ -32,10 +33,11 @@ func init() {
 
 // EmailAddress is an instance of email address
 type EmailAddress struct {
-	Address   string `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
-	Confirmed bool   `json:"confirmed,omitempty" xml:"confirmed,omitempty" yaml:"confirmed,omitempty"`
-	Domain    string `json:"domain,omitempty" xml:"domain,omitempty" yaml:"domain,omitempty"`
-	isPrimary bool   `json:"is_primary,omitempty" xml:"is_primary,omitempty" yaml:"is_primary,omitempty"`
+	Address     string    `json:"address,omitempty" xml:"address,omitempty" yaml:"address,omitempty"`
+	Confirmed   bool      `json:"confirmed,omitempty" xml:"confirmed,omitempty" yaml:"confirmed,omitempty"`
+	ConfirmedAt time.Time `json:"confirmed_at,omitempty" xml:"confirmed_at,omitempty" yaml:"confirmed_at,omitempty"`
+	Domain      string    `json:"domain,omitempty" xml:"domain,omitempty" yaml:"domain,omitempty"`
+	isPrimary   bool      `json:"is_primary,omitempty" xml:"is_primary,omitempty" yaml:"is_primary,omitempty"`
 }
 
 // NewEmailAddress returns an instance of EmailAddress.
@@ -59,6 +61,12 @@ func (m *EmailAddress) Primary() bool {
 	return false
 }
 
+// Confirm marks the email address as confirmed by its owner.
+func (m *EmailAddress) Confirm() {
+	m.Confirmed = true
+	m.ConfirmedAt = time.Now().UTC()
+}
+
 // ToString returns string representation of an email address.
 func (m *EmailAddress) ToString() string {
 	return m.Address
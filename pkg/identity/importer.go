@@ -0,0 +1,158 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"strings"
+)
+
+// ImportEntry is a user identity with pre-hashed password parsed from
+// htpasswd, shadow, or LDIF file.
+type ImportEntry struct {
+	Username     string `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email        string `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	Name         string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	PasswordHash string `json:"password_hash,omitempty" xml:"password_hash,omitempty" yaml:"password_hash,omitempty"`
+}
+
+// ParseImportFile parses the content of htpasswd, shadow, or ldif file and
+// returns user identities with pre-hashed passwords.
+func ParseImportFile(format string, b []byte) ([]*ImportEntry, error) {
+	switch format {
+	case "htpasswd":
+		return parseHtpasswd(b)
+	case "shadow":
+		return parseShadow(b)
+	case "ldif":
+		return parseLDIF(b)
+	}
+	return nil, errors.ErrImportFormatUnsupported.WithArgs(format)
+}
+
+// parseHtpasswd parses Apache htpasswd file, i.e. username:hash lines.
+func parseHtpasswd(b []byte) ([]*ImportEntry, error) {
+	var entries []*ImportEntry
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		arr := strings.SplitN(line, ":", 2)
+		if len(arr) != 2 || arr[0] == "" || arr[1] == "" {
+			return nil, errors.ErrImportEntryMalformed.WithArgs(i+1, "expected username:hash")
+		}
+		entries = append(entries, &ImportEntry{
+			Username:     arr[0],
+			PasswordHash: arr[1],
+		})
+	}
+	return entries, nil
+}
+
+// parseShadow parses /etc/shadow-style file. The accounts without password
+// and the locked accounts are skipped.
+func parseShadow(b []byte) ([]*ImportEntry, error) {
+	var entries []*ImportEntry
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		arr := strings.Split(line, ":")
+		if len(arr) < 2 || arr[0] == "" {
+			return nil, errors.ErrImportEntryMalformed.WithArgs(i+1, "expected username:hash:...")
+		}
+		if arr[1] == "" || strings.HasPrefix(arr[1], "!") || strings.HasPrefix(arr[1], "*") {
+			continue
+		}
+		entries = append(entries, &ImportEntry{
+			Username:     arr[0],
+			PasswordHash: arr[1],
+		})
+	}
+	return entries, nil
+}
+
+// parseLDIF parses LDIF file. The uid, mail, cn, and userPassword attributes
+// of the records are mapped to the username, email, name, and password hash.
+// The records without uid or userPassword are skipped.
+func parseLDIF(b []byte) ([]*ImportEntry, error) {
+	var entries []*ImportEntry
+	var entry *ImportEntry
+	var lines []string
+	var lineNumbers []int
+
+	// Unfold the continuation lines, i.e. the ones starting with a space.
+	for i, line := range strings.Split(string(bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))), "\n") {
+		if strings.HasPrefix(line, " ") && len(lines) > 0 && lines[len(lines)-1] != "" {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+		lineNumbers = append(lineNumbers, i+1)
+	}
+	lines = append(lines, "")
+	lineNumbers = append(lineNumbers, len(lineNumbers)+1)
+
+	for i, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			if entry != nil && entry.Username != "" && entry.PasswordHash != "" {
+				entries = append(entries, entry)
+			}
+			entry = nil
+			continue
+		}
+		arr := strings.SplitN(line, ":", 2)
+		if len(arr) != 2 {
+			return nil, errors.ErrImportEntryMalformed.WithArgs(lineNumbers[i], "expected attribute: value")
+		}
+		k := strings.ToLower(arr[0])
+		v := arr[1]
+		if strings.HasPrefix(v, ":") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v[1:]))
+			if err != nil {
+				return nil, errors.ErrImportEntryMalformed.WithArgs(lineNumbers[i], err)
+			}
+			v = string(decoded)
+		}
+		v = strings.TrimSpace(v)
+		if entry == nil {
+			entry = &ImportEntry{}
+		}
+		switch k {
+		case "uid":
+			entry.Username = v
+		case "mail":
+			if entry.Email == "" {
+				entry.Email = v
+			}
+		case "cn":
+			if entry.Name == "" {
+				entry.Name = v
+			}
+		case "userpassword":
+			if entry.PasswordHash == "" {
+				entry.PasswordHash = v
+			}
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+)
+
+func TestParseImportFile(t *testing.T) {
+	testcases := []struct {
+		name      string
+		format    string
+		input     []string
+		want      []*ImportEntry
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "parse htpasswd file",
+			format: "htpasswd",
+			input: []string{
+				"# comment",
+				"jsmith:$apr1$abcdefgh$bpWUFITn1N2a204vyP4n//",
+				"",
+				"bjones:{SHA}iEPX+SQWIR3p67lj/0zigSWTKHg=",
+			},
+			want: []*ImportEntry{
+				{Username: "jsmith", PasswordHash: "$apr1$abcdefgh$bpWUFITn1N2a204vyP4n//"},
+				{Username: "bjones", PasswordHash: "{SHA}iEPX+SQWIR3p67lj/0zigSWTKHg="},
+			},
+		},
+		{
+			name:   "parse malformed htpasswd file",
+			format: "htpasswd",
+			input: []string{
+				"jsmith:$apr1$abcdefgh$bpWUFITn1N2a204vyP4n//",
+				"bjones",
+			},
+			shouldErr: true,
+			err:       errors.ErrImportEntryMalformed.WithArgs(2, "expected username:hash"),
+		},
+		{
+			name:   "parse shadow file",
+			format: "shadow",
+			input: []string{
+				"root:*:19000:0:99999:7:::",
+				"daemon:!:19000:0:99999:7:::",
+				"jsmith:$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1:19000:0:99999:7:::",
+				"bjones:!$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1:19000:0:99999:7:::",
+				"nobody::19000:0:99999:7:::",
+			},
+			want: []*ImportEntry{
+				{Username: "jsmith", PasswordHash: "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+			},
+		},
+		{
+			name:   "parse ldif file",
+			format: "ldif",
+			input: []string{
+				"version: 1",
+				"",
+				"# John Smith",
+				"dn: uid=jsmith,ou=people,dc=example,dc=com",
+				"objectClass: inetOrgPerson",
+				"uid: jsmith",
+				"cn: John Smith",
+				"mail: jsmith@example.com",
+				"userPassword: {SSHA}t7yhsDXhE3SS2kqKKdvMfMRv1WthYmNk",
+				"",
+				"dn: uid=bjones,ou=people,dc=example,dc=com",
+				"uid: bjones",
+				"mail: bjones@exam",
+				" ple.com",
+				"userPassword:: e1NIQX1pRVBYK1NRV0lSM3A2N2xqLzB6aWdTV1RLSGc9",
+				"",
+				"dn: ou=people,dc=example,dc=com",
+				"objectClass: organizationalUnit",
+			},
+			want: []*ImportEntry{
+				{Username: "jsmith", Name: "John Smith", Email: "jsmith@example.com", PasswordHash: "{SSHA}t7yhsDXhE3SS2kqKKdvMfMRv1WthYmNk"},
+				{Username: "bjones", Email: "bjones@example.com", PasswordHash: "{SHA}iEPX+SQWIR3p67lj/0zigSWTKHg="},
+			},
+		},
+		{
+			name:   "parse ldif file with malformed base64 value",
+			format: "ldif",
+			input: []string{
+				"dn: uid=jsmith,ou=people,dc=example,dc=com",
+				"uid: jsmith",
+				"userPassword:: foo",
+			},
+			shouldErr: true,
+			err:       errors.ErrImportEntryMalformed.WithArgs(3, "illegal base64 data at input byte 0"),
+		},
+		{
+			name:      "parse file with unsupported format",
+			format:    "foobar",
+			shouldErr: true,
+			err:       errors.ErrImportFormatUnsupported.WithArgs("foobar"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			entries, err := ParseImportFile(tc.format, []byte(strings.Join(tc.input, "\n")))
+			if tests.EvalErrWithLog(t, err, "import", tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "entries", tc.want, entries, msgs)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/internal/utils"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// jsonStore stores the database in a JSON file located at the path of
+// the database. The file is replaced atomically on every write and the
+// writers hold an advisory lock on the file with .lock suffix. Multiple
+// processes may share the file.
+type jsonStore struct {
+	// mu guards the cached file info and revision, because the revision is
+	// checked by the concurrent readers of the database.
+	mu       sync.Mutex
+	path     string
+	fileInfo os.FileInfo
+	revision uint64
+}
+
+func (s *jsonStore) Load(db *Database) (bool, error) {
+	fileInfo, err := os.Stat(db.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+		if err := os.MkdirAll(filepath.Dir(db.path), 0700); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	if fileInfo.IsDir() {
+		return false, fmt.Errorf("path points to a directory")
+	}
+	b, err := utils.ReadFileBytes(db.path)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(b, db); err != nil {
+		return false, err
+	}
+	s.update(db.path, fileInfo, db.Revision)
+	return true, nil
+}
+
+// Save writes the database to a file, provided the file holds the previous
+// revision of the database, i.e. no other process changed the file.
+func (s *jsonStore) Save(db *Database) error {
+	if fileInfo, err := os.Stat(db.path); err == nil && fileInfo.IsDir() {
+		return &os.PathError{Op: "open", Path: db.path, Err: syscall.EISDIR}
+	}
+	unlock, err := lockFile(db.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	revision, err := readJSONRevision(db.path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	case revision != db.Revision-1:
+		return errors.ErrDatabaseStoreRevision.WithArgs(db.Revision - 1)
+	}
+
+	if err := writeJSONFile(db.path, db); err != nil {
+		return err
+	}
+	if fileInfo, err := os.Stat(db.path); err == nil {
+		s.update(db.path, fileInfo, db.Revision)
+	}
+	return nil
+}
+
+// SaveUser writes the entire database, because JSON file could not be
+// updated partially.
+func (s *jsonStore) SaveUser(db *Database, user *User) error {
+	return s.Save(db)
+}
+
+// DeleteUser writes the entire database, because JSON file could not be
+// updated partially.
+func (s *jsonStore) DeleteUser(db *Database, user *User) error {
+	return s.Save(db)
+}
+
+// GetRevision returns the revision of the database in the file. The file
+// is read only when it got replaced, or its size or modification time
+// changed.
+func (s *jsonStore) GetRevision() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fileInfo, err := os.Stat(s.path)
+	if err != nil {
+		return 0, err
+	}
+	if s.fileInfo != nil && os.SameFile(fileInfo, s.fileInfo) &&
+		fileInfo.Size() == s.fileInfo.Size() && fileInfo.ModTime().Equal(s.fileInfo.ModTime()) {
+		return s.revision, nil
+	}
+	revision, err := readJSONRevision(s.path)
+	if err != nil {
+		return 0, err
+	}
+	s.fileInfo = fileInfo
+	s.revision = revision
+	return revision, nil
+}
+
+func (s *jsonStore) Close() error {
+	return nil
+}
+
+func (s *jsonStore) update(fp string, fileInfo os.FileInfo, revision uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = fp
+	s.fileInfo = fileInfo
+	s.revision = revision
+}
+
+func readJSONRevision(fp string) (uint64, error) {
+	b, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return 0, err
+	}
+	var m struct {
+		Revision uint64 `json:"revision"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return 0, err
+	}
+	return m.Revision, nil
+}
+
+// rotateBackups shifts the backups of the file, e.g. .1 to .2, and removes
+// the oldest backup, so that at most the provided number of backups remain
+// once the .1 backup is written.
+func rotateBackups(fp string, count int) error {
+	os.Remove(fmt.Sprintf("%s.%d", fp, count))
+	for i := count - 1; i > 0; i-- {
+		src := fmt.Sprintf("%s.%d", fp, i)
+		if err := os.Rename(src, fmt.Sprintf("%s.%d", fp, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJSONFile writes the database to a temporary file and then renames
+// it to the destination. A crash leaves either the old or the new file
+// intact.
+func writeJSONFile(fp string, db *Database) error {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(fp, data)
+}
+
+func writeFile(fp string, data []byte) error {
+	dir := filepath.Dir(fp)
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(fp)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), fp); err != nil {
+		return err
+	}
+	// Persist the rename. Some platforms do not support syncing
+	// directories, therefore the errors are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
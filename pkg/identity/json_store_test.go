@@ -0,0 +1,197 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+)
+
+func TestJSONStore(t *testing.T) {
+	db1, err := createTestDatabase("TestJSONStore")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	db2, err := NewDatabase(db1.path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	// The reads check the revision of the storage each time.
+	db1.revisionCheckInterval = 0
+	db2.revisionCheckInterval = 0
+
+	// The changes made by one instance are visible to the other.
+	if err := db1.AddUser(&requests.Request{
+		User: requests.User{
+			Username: "mjordan",
+			Password: tests.NewRandomString(12),
+			Email:    "mjordan@example.com",
+		},
+	}); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	tests.EvalObjects(t, "user count", 3, db2.GetUserCount())
+	if err := db2.DeleteUser(&requests.Request{User: requests.User{Username: "mjordan", Email: "mjordan@example.com"}}); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	tests.EvalObjects(t, "user count", 2, db1.GetUserCount())
+	tests.EvalObjects(t, "revision", db2.Revision, db1.Revision)
+
+	// The concurrent readers share the read lock and reload the changes.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				db2.GetUserCount()
+			}
+		}()
+	}
+	if err := db1.AddUser(&requests.Request{
+		User: requests.User{
+			Username: "mjordan",
+			Password: tests.NewRandomString(12),
+			Email:    "mjordan@example.com",
+		},
+	}); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	wg.Wait()
+	tests.EvalObjects(t, "user count", 3, db2.GetUserCount())
+
+	// The write based on outdated revision is rejected.
+	revision := db1.Revision
+	db1.Revision--
+	err = db1.commit()
+	tests.EvalErr(t, err, "commit outdated revision", true,
+		errors.ErrDatabaseCommit.WithArgs(db1.path, errors.ErrDatabaseStoreRevision.WithArgs(revision-1)),
+	)
+	db1.Revision = revision
+
+	// No temporary files remain after writes.
+	entries, err := os.ReadDir(filepath.Dir(db1.path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp") {
+			t.Fatalf("found temporary file %s", entry.Name())
+		}
+	}
+}
+
+func TestDatabaseBackup(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseBackup")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	err = db.Backup(0)
+	tests.EvalErr(t, err, "backup with invalid count", true,
+		errors.ErrDatabaseBackup.WithArgs(db.path, "invalid backup count"),
+	)
+	for i := 0; i < 3; i++ {
+		if err := db.Backup(2); err != nil {
+			t.Fatalf("failed to back up database: %v", err)
+		}
+	}
+	for _, suffix := range []string{".1", ".2"} {
+		backup, err := NewDatabase(db.path + suffix)
+		if err != nil {
+			t.Fatalf("failed to open backup %s: %v", suffix, err)
+		}
+		tests.EvalObjects(t, "backup user count", 2, backup.GetUserCount())
+	}
+	if _, err := os.Stat(db.path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected backup .3 to be absent")
+	}
+}
+
+func TestDatabaseBackupOnCommit(t *testing.T) {
+	db, err := createTestDatabase("TestDatabaseBackupOnCommit")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	err = db.SetBackupCount(-1)
+	tests.EvalErr(t, err, "set invalid backup count", true,
+		errors.ErrDatabaseBackup.WithArgs(db.path, "invalid backup count"),
+	)
+	if err := db.SetBackupCount(2); err != nil {
+		t.Fatalf("failed to set backup count: %v", err)
+	}
+	revision := db.Revision
+	for _, username := range []string{"mjordan", "lbird", "kbryant"} {
+		if err := db.AddUser(&requests.Request{
+			User: requests.User{
+				Username: username,
+				Password: tests.NewRandomString(12),
+				Email:    username + "@example.com",
+			},
+		}); err != nil {
+			t.Fatalf("failed to add user: %v", err)
+		}
+	}
+
+	// The backup is taken once within the backup interval, i.e. it holds
+	// the revision preceding the first commit.
+	backup, err := NewDatabase(db.path + ".1")
+	if err != nil {
+		t.Fatalf("failed to open backup: %v", err)
+	}
+	tests.EvalObjects(t, "backup revision", revision, backup.Revision)
+	if _, err := os.Stat(db.path + ".2"); !os.IsNotExist(err) {
+		t.Fatalf("expected backup .2 to be absent")
+	}
+
+	// The failed authentication attempts do not rotate the backups out.
+	db.Policy.Lockout = LockoutPolicy{MaxFailures: 3}
+	for i := 0; i < 3; i++ {
+		db.AuthenticateUser(&requests.Request{User: requests.User{Username: testUser1, Password: testPwd2}})
+	}
+	if _, err := os.Stat(db.path + ".2"); !os.IsNotExist(err) {
+		t.Fatalf("expected backup .2 to be absent")
+	}
+
+	// The backups hold the revisions preceding the commits made after the
+	// backup interval elapsed.
+	db.backedUpAt = db.backedUpAt.Add(-backupInterval)
+	revision = db.Revision
+	if err := db.DeleteUser(&requests.Request{User: requests.User{Username: "kbryant", Email: "kbryant@example.com"}}); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	for suffix, count := range map[string]int{".1": 5, ".2": 2} {
+		backup, err := NewDatabase(db.path + suffix)
+		if err != nil {
+			t.Fatalf("failed to open backup %s: %v", suffix, err)
+		}
+		tests.EvalObjects(t, "backup user count", count, backup.GetUserCount())
+	}
+	backup, err = NewDatabase(db.path + ".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests.EvalObjects(t, "backup revision after interval", revision, backup.Revision)
+	if _, err := os.Stat(db.path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected backup .3 to be absent")
+	}
+}
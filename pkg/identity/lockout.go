@@ -18,6 +18,13 @@ import (
 	"time"
 )
 
+const (
+	defaultLockoutWindow   = 900
+	defaultLockoutDuration = 900
+	// The minimum interval between the writes of the failed attempts.
+	lockoutCommitInterval = 10 * time.Second
+)
+
 // LockoutState indicates whether user identity is temporarily
 // disabled. If the identity is lockedout, when does the
 // lockout end.
@@ -25,9 +32,92 @@ type LockoutState struct {
 	Enabled   bool      `json:"enabled,omitempty" xml:"enabled,omitempty" yaml:"enabled,omitempty"`
 	StartTime time.Time `json:"start_time,omitempty" xml:"start_time,omitempty" yaml:"start_time,omitempty"`
 	EndTime   time.Time `json:"end_time,omitempty" xml:"end_time,omitempty" yaml:"end_time,omitempty"`
+	// FailedAttempts is the number of failed authentication attempts
+	// since FailedAttemptsSince.
+	FailedAttempts      int       `json:"failed_attempts,omitempty" xml:"failed_attempts,omitempty" yaml:"failed_attempts,omitempty"`
+	FailedAttemptsSince time.Time `json:"failed_attempts_since,omitempty" xml:"failed_attempts_since,omitempty" yaml:"failed_attempts_since,omitempty"`
+	// LockoutCount is the number of lockouts since the last successful
+	// authentication. It drives progressive backoff.
+	LockoutCount int `json:"lockout_count,omitempty" xml:"lockout_count,omitempty" yaml:"lockout_count,omitempty"`
+	// committedAt is the time the failed attempts were last written.
+	committedAt time.Time
+}
+
+// LockoutPolicy represents database account lockout policy. The lockout
+// is disabled when MaxFailures is zero. The durations are in seconds.
+type LockoutPolicy struct {
+	// MaxFailures is the number of failed authentication attempts within
+	// Window triggering the lockout.
+	MaxFailures int `json:"max_failures" xml:"max_failures" yaml:"max_failures"`
+	Window      int `json:"window" xml:"window" yaml:"window"`
+	Duration    int `json:"duration" xml:"duration" yaml:"duration"`
+	// ProgressiveBackoff doubles the lockout duration on each subsequent
+	// lockout, up to MaxDuration.
+	ProgressiveBackoff bool `json:"progressive_backoff" xml:"progressive_backoff" yaml:"progressive_backoff"`
+	MaxDuration        int  `json:"max_duration" xml:"max_duration" yaml:"max_duration"`
 }
 
 // NewLockoutState returns an instance of LockoutState.
 func NewLockoutState() *LockoutState {
 	return &LockoutState{}
 }
+
+// IsEnabled returns true when the lockout policy is enforced.
+func (p *LockoutPolicy) IsEnabled() bool {
+	return p.MaxFailures > 0
+}
+
+func (p *LockoutPolicy) getWindow() time.Duration {
+	if p.Window > 0 {
+		return time.Duration(p.Window) * time.Second
+	}
+	return time.Duration(defaultLockoutWindow) * time.Second
+}
+
+// getDuration returns the duration of the lockout following the provided
+// number of previous lockouts.
+func (p *LockoutPolicy) getDuration(count int) time.Duration {
+	duration := p.Duration
+	if duration < 1 {
+		duration = defaultLockoutDuration
+	}
+	if p.ProgressiveBackoff {
+		for i := 0; i < count; i++ {
+			duration *= 2
+			if p.MaxDuration > 0 && duration >= p.MaxDuration {
+				duration = p.MaxDuration
+				break
+			}
+		}
+	}
+	return time.Duration(duration) * time.Second
+}
+
+// IsLocked returns true when the lockout is in effect at the provided time.
+func (s *LockoutState) IsLocked(now time.Time) bool {
+	if s == nil || !s.Enabled {
+		return false
+	}
+	return now.Before(s.EndTime)
+}
+
+// AddFailedAttempt records failed authentication attempt and engages the
+// lockout when the number of failed attempts within the policy window
+// reaches the maximum. Returns true when the lockout was engaged.
+func (s *LockoutState) AddFailedAttempt(p *LockoutPolicy, now time.Time) bool {
+	if s.FailedAttempts == 0 || now.Sub(s.FailedAttemptsSince) > p.getWindow() {
+		s.FailedAttempts = 0
+		s.FailedAttemptsSince = now
+	}
+	s.FailedAttempts++
+	if s.FailedAttempts < p.MaxFailures {
+		return false
+	}
+	s.Enabled = true
+	s.StartTime = now
+	s.EndTime = now.Add(p.getDuration(s.LockoutCount))
+	s.LockoutCount++
+	s.FailedAttempts = 0
+	s.FailedAttemptsSince = time.Time{}
+	return true
+}
@@ -15,9 +15,92 @@
 package identity
 
 import (
+	"fmt"
+	"github.com/greenpau/go-authcrunch/internal/tests"
 	"testing"
+	"time"
 )
 
 func TestNewLockoutState(t *testing.T) {
 	NewLockoutState()
 }
+
+func TestLockoutState(t *testing.T) {
+	start := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		name     string
+		policy   *LockoutPolicy
+		attempts []time.Duration
+		want     map[string]interface{}
+	}{
+		{
+			name:     "lockout after max failures within window",
+			policy:   &LockoutPolicy{MaxFailures: 3, Window: 60, Duration: 300},
+			attempts: []time.Duration{0, 10 * time.Second, 20 * time.Second},
+			want: map[string]interface{}{
+				"locked":        []bool{false, false, true},
+				"end_time":      start.Add(20 * time.Second).Add(300 * time.Second),
+				"lockout_count": 1,
+			},
+		},
+		{
+			name:     "failures outside of window are not counted",
+			policy:   &LockoutPolicy{MaxFailures: 3, Window: 60, Duration: 300},
+			attempts: []time.Duration{0, 10 * time.Second, 90 * time.Second, 100 * time.Second},
+			want: map[string]interface{}{
+				"locked":        []bool{false, false, false, false},
+				"end_time":      time.Time{},
+				"lockout_count": 0,
+			},
+		},
+		{
+			name:   "progressive backoff up to max duration",
+			policy: &LockoutPolicy{MaxFailures: 1, Duration: 60, ProgressiveBackoff: true, MaxDuration: 200},
+			attempts: []time.Duration{
+				0,
+				60 * time.Second,
+				180 * time.Second,
+				380 * time.Second,
+			},
+			want: map[string]interface{}{
+				"locked":        []bool{true, true, true, true},
+				"end_time":      start.Add(380 * time.Second).Add(200 * time.Second),
+				"lockout_count": 4,
+			},
+		},
+		{
+			name:     "default window and duration",
+			policy:   &LockoutPolicy{MaxFailures: 2},
+			attempts: []time.Duration{0, 899 * time.Second},
+			want: map[string]interface{}{
+				"locked":        []bool{false, true},
+				"end_time":      start.Add(899 * time.Second).Add(900 * time.Second),
+				"lockout_count": 1,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			state := NewLockoutState()
+			var locked []bool
+			for _, d := range tc.attempts {
+				locked = append(locked, state.AddFailedAttempt(tc.policy, start.Add(d)))
+			}
+			got := map[string]interface{}{
+				"locked":        locked,
+				"end_time":      state.EndTime,
+				"lockout_count": state.LockoutCount,
+			}
+			tests.EvalObjectsWithLog(t, "state", tc.want, got, msgs)
+			if state.Enabled {
+				if !state.IsLocked(state.EndTime.Add(-time.Second)) {
+					t.Fatalf("expected lockout before end time")
+				}
+				if state.IsLocked(state.EndTime) {
+					t.Fatalf("unexpected lockout at end time")
+				}
+			}
+		})
+	}
+}
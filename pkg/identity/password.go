@@ -15,24 +15,60 @@
 package identity
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
 	"strings"
 	"time"
 )
 
+const (
+	defaultBcryptCost        = 10
+	defaultArgon2Iterations  = 2
+	defaultArgon2Memory      = 19456
+	defaultArgon2Parallelism = 1
+	defaultScryptCost        = 32768
+	defaultScryptBlockSize   = 8
+	defaultScryptParallelism = 1
+	defaultHashKeyLength     = 32
+	defaultHashSaltLength    = 16
+
+	// The upper limits of the argon2id and scrypt parameters. The hashes
+	// exceeding them, e.g. the imported ones, would exhaust the memory or
+	// the CPU at each login.
+	maxArgon2Iterations  = 16
+	maxArgon2Memory      = 262144
+	maxScryptCost        = 1048576
+	maxScryptBlockSize   = 32
+	maxScryptParallelism = 16
+	maxScryptMemory      = 268435456
+	maxHashKeyLength     = 128
+)
+
 // Password is a memorized secret, typically a string of characters,
 // used to confirm the identity of a user.
 type Password struct {
-	Purpose    string    `json:"purpose,omitempty" xml:"purpose,omitempty" yaml:"purpose,omitempty"`
-	Algorithm  string    `json:"algorithm,omitempty" xml:"algorithm,omitempty" yaml:"algorithm,omitempty"`
-	Hash       string    `json:"hash,omitempty" xml:"hash,omitempty" yaml:"hash,omitempty"`
-	Cost       int       `json:"cost,omitempty" xml:"cost,omitempty" yaml:"cost,omitempty"`
-	Expired    bool      `json:"expired,omitempty" xml:"expired,omitempty" yaml:"expired,omitempty"`
-	ExpiredAt  time.Time `json:"expired_at,omitempty" xml:"expired_at,omitempty" yaml:"expired_at,omitempty"`
-	CreatedAt  time.Time `json:"created_at,omitempty" xml:"created_at,omitempty" yaml:"created_at,omitempty"`
-	Disabled   bool      `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
-	DisabledAt time.Time `json:"disabled_at,omitempty" xml:"disabled_at,omitempty" yaml:"disabled_at,omitempty"`
+	Purpose   string `json:"purpose,omitempty" xml:"purpose,omitempty" yaml:"purpose,omitempty"`
+	Algorithm string `json:"algorithm,omitempty" xml:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	Hash      string `json:"hash,omitempty" xml:"hash,omitempty" yaml:"hash,omitempty"`
+	Cost      int    `json:"cost,omitempty" xml:"cost,omitempty" yaml:"cost,omitempty"`
+	// The parameters of argon2id and scrypt algorithms. For scrypt, the Cost
+	// holds the CPU/memory cost parameter (N).
+	Salt        string    `json:"salt,omitempty" xml:"salt,omitempty" yaml:"salt,omitempty"`
+	Iterations  int       `json:"iterations,omitempty" xml:"iterations,omitempty" yaml:"iterations,omitempty"`
+	Memory      int       `json:"memory,omitempty" xml:"memory,omitempty" yaml:"memory,omitempty"`
+	Parallelism int       `json:"parallelism,omitempty" xml:"parallelism,omitempty" yaml:"parallelism,omitempty"`
+	BlockSize   int       `json:"block_size,omitempty" xml:"block_size,omitempty" yaml:"block_size,omitempty"`
+	KeyLength   int       `json:"key_length,omitempty" xml:"key_length,omitempty" yaml:"key_length,omitempty"`
+	Expired     bool      `json:"expired,omitempty" xml:"expired,omitempty" yaml:"expired,omitempty"`
+	ExpiredAt   time.Time `json:"expired_at,omitempty" xml:"expired_at,omitempty" yaml:"expired_at,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty" xml:"created_at,omitempty" yaml:"created_at,omitempty"`
+	Disabled    bool      `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
+	DisabledAt  time.Time `json:"disabled_at,omitempty" xml:"disabled_at,omitempty" yaml:"disabled_at,omitempty"`
 }
 
 // NewPassword returns an instance of Password.
@@ -49,18 +85,21 @@ func NewPasswordWithOptions(s, purpose, algo string, params map[string]interface
 		CreatedAt: time.Now().UTC(),
 	}
 
-	if params != nil {
-		if v, exists := params["cost"]; exists {
-			p.Cost = v.(int)
-		}
-	}
-
-	if err := p.hash(s); err != nil {
+	if err := p.hash(s, params); err != nil {
 		return nil, err
 	}
 	return p, nil
 }
 
+// newPasswordWithPolicy returns an instance of Password hashed in accordance
+// with the provided hashing policy.
+func newPasswordWithPolicy(s string, policy *PasswordHashPolicy) (*Password, error) {
+	if policy == nil || policy.Algorithm == "" {
+		return NewPassword(s)
+	}
+	return NewPasswordWithOptions(s, "generic", policy.Algorithm, policy.getParams())
+}
+
 // Disable disables Password instance.
 func (p *Password) Disable() {
 	p.Expired = true
@@ -69,32 +108,214 @@ func (p *Password) Disable() {
 	p.DisabledAt = time.Now().UTC()
 }
 
-func (p *Password) hash(s string) error {
+func (p *Password) hash(s string, params map[string]interface{}) error {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		return errors.ErrPasswordEmpty
 	}
-	switch p.Algorithm {
-	case "bcrypt":
-		if p.Cost < 8 {
-			p.Cost = 10
-		}
+	saltLength, err := p.applyParams(params)
+	if err != nil {
+		return err
+	}
+	if p.Algorithm == "bcrypt" {
 		ph, err := bcrypt.GenerateFromPassword([]byte(s), p.Cost)
 		if err != nil {
 			return errors.ErrPasswordGenerate.WithArgs(err)
 		}
 		p.Hash = string(ph)
 		return nil
+	}
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return errors.ErrPasswordGenerate.WithArgs(err)
+	}
+	p.Salt = base64.RawStdEncoding.EncodeToString(salt)
+	key, err := p.deriveKey(s, salt)
+	if err != nil {
+		return errors.ErrPasswordGenerate.WithArgs(err)
+	}
+	p.Hash = base64.RawStdEncoding.EncodeToString(key)
+	return nil
+}
+
+// applyParams sets the hashing parameters of the Password, falling back to
+// the defaults of the algorithm. Returns the length of the salt.
+func (p *Password) applyParams(params map[string]interface{}) (int, error) {
+	saltLength := defaultHashSaltLength
+	for k, v := range params {
+		switch k {
+		case "cost":
+			p.Cost = v.(int)
+		case "iterations":
+			p.Iterations = v.(int)
+		case "memory":
+			p.Memory = v.(int)
+		case "parallelism":
+			p.Parallelism = v.(int)
+		case "block_size":
+			p.BlockSize = v.(int)
+		case "key_length":
+			p.KeyLength = v.(int)
+		case "salt_length":
+			if v.(int) > 0 {
+				saltLength = v.(int)
+			}
+		}
+	}
+
+	switch p.Algorithm {
+	case "bcrypt":
+		if p.Cost < 8 {
+			p.Cost = defaultBcryptCost
+		}
+		return 0, nil
+	case "argon2id":
+		if p.Iterations < 1 {
+			p.Iterations = defaultArgon2Iterations
+		}
+		if p.Memory < 1 {
+			p.Memory = defaultArgon2Memory
+		}
+		if p.Parallelism < 1 {
+			p.Parallelism = defaultArgon2Parallelism
+		}
+		if p.Parallelism > 255 {
+			return 0, errors.ErrPasswordGenerate.WithArgs("argon2id parallelism exceeds 255")
+		}
+	case "scrypt":
+		if p.Cost < 2 {
+			p.Cost = defaultScryptCost
+		}
+		if p.Cost&(p.Cost-1) != 0 {
+			return 0, errors.ErrPasswordGenerate.WithArgs("scrypt cost is not a power of two")
+		}
+		if p.BlockSize < 1 {
+			p.BlockSize = defaultScryptBlockSize
+		}
+		if p.Parallelism < 1 {
+			p.Parallelism = defaultScryptParallelism
+		}
 	case "":
-		return errors.ErrPasswordEmptyAlgorithm
+		return 0, errors.ErrPasswordEmptyAlgorithm
+	default:
+		return 0, errors.ErrPasswordUnsupportedAlgorithm.WithArgs(p.Algorithm)
+	}
+	if p.KeyLength < 1 {
+		p.KeyLength = defaultHashKeyLength
+	}
+	if err := p.checkLimits(); err != nil {
+		return 0, err
+	}
+	return saltLength, nil
+}
+
+// checkLimits returns an error when the argon2id or scrypt parameters of
+// the Password exceed the upper limits. The scrypt memory is 128 * N * r
+// bytes.
+func (p *Password) checkLimits() error {
+	switch p.Algorithm {
+	case "argon2id":
+		if p.Iterations > maxArgon2Iterations {
+			return errors.ErrPasswordHashLimitExceeded.WithArgs(p.Algorithm, "iterations", maxArgon2Iterations)
+		}
+		if p.Memory > maxArgon2Memory {
+			return errors.ErrPasswordHashLimitExceeded.WithArgs(p.Algorithm, "memory", maxArgon2Memory)
+		}
+	case "scrypt":
+		if p.Cost > maxScryptCost {
+			return errors.ErrPasswordHashLimitExceeded.WithArgs(p.Algorithm, "cost", maxScryptCost)
+		}
+		if p.BlockSize > maxScryptBlockSize {
+			return errors.ErrPasswordHashLimitExceeded.WithArgs(p.Algorithm, "block size", maxScryptBlockSize)
+		}
+		if p.Parallelism > maxScryptParallelism {
+			return errors.ErrPasswordHashLimitExceeded.WithArgs(p.Algorithm, "parallelism", maxScryptParallelism)
+		}
+		if 128*int64(p.Cost)*int64(p.BlockSize) > maxScryptMemory {
+			return errors.ErrPasswordHashLimitExceeded.WithArgs(p.Algorithm, "memory", maxScryptMemory)
+		}
+	default:
+		return nil
 	}
-	return errors.ErrPasswordUnsupportedAlgorithm.WithArgs(p.Algorithm)
+	if p.KeyLength > maxHashKeyLength {
+		return errors.ErrPasswordHashLimitExceeded.WithArgs(p.Algorithm, "key length", maxHashKeyLength)
+	}
+	return nil
+}
+
+// deriveKey derives a key from the provided password and salt using the
+// argon2id or scrypt parameters of the Password.
+func (p *Password) deriveKey(s string, salt []byte) ([]byte, error) {
+	switch p.Algorithm {
+	case "argon2id":
+		return argon2.IDKey([]byte(s), salt, uint32(p.Iterations), uint32(p.Memory), uint8(p.Parallelism), uint32(p.KeyLength)), nil
+	case "scrypt":
+		return scrypt.Key([]byte(s), salt, p.Cost, p.BlockSize, p.Parallelism, p.KeyLength)
+	}
+	return nil, errors.ErrPasswordUnsupportedAlgorithm.WithArgs(p.Algorithm)
 }
 
 // Match returns true when the provided password matches the user.
 func (p *Password) Match(s string) bool {
-	if err := bcrypt.CompareHashAndPassword([]byte(p.Hash), []byte(s)); err == nil {
+	switch p.Algorithm {
+	case "argon2id", "scrypt":
+		if err := p.checkLimits(); err != nil {
+			return false
+		}
+		// An empty key would match any password.
+		if p.KeyLength < 1 {
+			return false
+		}
+		salt, err := base64.RawStdEncoding.DecodeString(p.Salt)
+		if err != nil || len(salt) == 0 {
+			return false
+		}
+		hash, err := base64.RawStdEncoding.DecodeString(p.Hash)
+		if err != nil || len(hash) != p.KeyLength {
+			return false
+		}
+		key, err := p.deriveKey(s, salt)
+		if err != nil {
+			return false
+		}
+		return subtle.ConstantTimeCompare(key, hash) == 1
+	case "bcrypt", "":
+		if err := bcrypt.CompareHashAndPassword([]byte(p.Hash), []byte(s)); err == nil {
+			return true
+		}
+		return false
+	}
+	matched, _ := p.matchLegacy(s)
+	return matched
+}
+
+// needsRehash returns true when the Password was hashed with the algorithm
+// or the parameters different from the ones in the provided hashing policy,
+// or when the Password holds an imported legacy hash.
+func (p *Password) needsRehash(policy *PasswordHashPolicy) bool {
+	if p == nil {
+		return false
+	}
+	if p.isLegacy() {
+		return true
+	}
+	if policy == nil || policy.Algorithm == "" {
+		return false
+	}
+	expected := &Password{Algorithm: policy.Algorithm}
+	if _, err := expected.applyParams(policy.getParams()); err != nil {
+		return false
+	}
+	switch {
+	case p.Algorithm != expected.Algorithm:
 		return true
+	case p.Algorithm == "bcrypt":
+		return p.Cost != expected.Cost
 	}
-	return false
+	return p.Cost != expected.Cost ||
+		p.Iterations != expected.Iterations ||
+		p.Memory != expected.Memory ||
+		p.Parallelism != expected.Parallelism ||
+		p.BlockSize != expected.BlockSize ||
+		p.KeyLength != expected.KeyLength
 }
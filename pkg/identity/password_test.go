@@ -75,6 +75,79 @@ func TestNewPassword(t *testing.T) {
 			shouldErr: true,
 			err:       errors.ErrPasswordGenerate.WithArgs("crypto/bcrypt: cost 10000 is outside allowed range (4,31)"),
 		},
+		{
+			name:      "test password with argon2id algorithm",
+			purpose:   "generic",
+			algorithm: "argon2id",
+			params: map[string]interface{}{
+				"iterations":  1,
+				"memory":      1024,
+				"parallelism": 1,
+			},
+			input:    "foobar",
+			password: "foobar",
+			want: map[string]interface{}{
+				"purpose":        "generic",
+				"algorithm":      "argon2id",
+				"cost":           0,
+				"password_match": true,
+			},
+		},
+		{
+			name:      "test password with argon2id algorithm and mismatched password",
+			purpose:   "generic",
+			algorithm: "argon2id",
+			params: map[string]interface{}{
+				"iterations": 1,
+				"memory":     1024,
+			},
+			input:    "foobar",
+			password: "foobar2",
+			want: map[string]interface{}{
+				"purpose":        "generic",
+				"algorithm":      "argon2id",
+				"cost":           0,
+				"password_match": false,
+			},
+		},
+		{
+			name:      "test password with invalid argon2id params",
+			purpose:   "generic",
+			algorithm: "argon2id",
+			params: map[string]interface{}{
+				"parallelism": 1000,
+			},
+			input:     "foobar",
+			shouldErr: true,
+			err:       errors.ErrPasswordGenerate.WithArgs("argon2id parallelism exceeds 255"),
+		},
+		{
+			name:      "test password with scrypt algorithm",
+			purpose:   "generic",
+			algorithm: "scrypt",
+			params: map[string]interface{}{
+				"cost": 1024,
+			},
+			input:    "foobar",
+			password: "foobar",
+			want: map[string]interface{}{
+				"purpose":        "generic",
+				"algorithm":      "scrypt",
+				"cost":           1024,
+				"password_match": true,
+			},
+		},
+		{
+			name:      "test password with invalid scrypt params",
+			purpose:   "generic",
+			algorithm: "scrypt",
+			params: map[string]interface{}{
+				"cost": 1000,
+			},
+			input:     "foobar",
+			shouldErr: true,
+			err:       errors.ErrPasswordGenerate.WithArgs("scrypt cost is not a power of two"),
+		},
 		{
 			name:      "test password with empty hash algorithm",
 			input:     "foobar",
@@ -118,3 +191,171 @@ func TestNewPassword(t *testing.T) {
 		})
 	}
 }
+
+func TestPasswordMatchMalformedHash(t *testing.T) {
+	for _, algorithm := range []string{"argon2id", "scrypt"} {
+		testcases := []struct {
+			name   string
+			tamper func(*Password)
+		}{
+			{
+				name:   "empty hash",
+				tamper: func(p *Password) { p.Hash = "" },
+			},
+			{
+				name:   "empty salt",
+				tamper: func(p *Password) { p.Salt = "" },
+			},
+			{
+				name:   "zero key length",
+				tamper: func(p *Password) { p.Hash, p.KeyLength = "", 0 },
+			},
+			{
+				name:   "key length not matching hash",
+				tamper: func(p *Password) { p.KeyLength = 16 },
+			},
+		}
+		for _, tc := range testcases {
+			t.Run(algorithm+" with "+tc.name, func(t *testing.T) {
+				p, err := NewPasswordWithOptions(testPwd1, "generic", algorithm, nil)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !p.Match(testPwd1) {
+					t.Fatalf("expected password match prior to tampering")
+				}
+				tc.tamper(p)
+				if p.Match(testPwd1) || p.Match("") {
+					t.Fatalf("unexpected password match with %s", tc.name)
+				}
+			})
+		}
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	testcases := []struct {
+		name      string
+		algorithm string
+		params    map[string]interface{}
+		policy    *PasswordHashPolicy
+		want      bool
+	}{
+		{
+			name:      "bcrypt password without hashing policy",
+			algorithm: "bcrypt",
+			policy:    &PasswordHashPolicy{},
+			want:      false,
+		},
+		{
+			name:      "bcrypt password with default bcrypt policy",
+			algorithm: "bcrypt",
+			policy:    &PasswordHashPolicy{Algorithm: "bcrypt"},
+			want:      false,
+		},
+		{
+			name:      "bcrypt password with increased bcrypt cost",
+			algorithm: "bcrypt",
+			policy:    &PasswordHashPolicy{Algorithm: "bcrypt", Cost: 11},
+			want:      true,
+		},
+		{
+			name:      "bcrypt password with argon2id policy",
+			algorithm: "bcrypt",
+			policy:    &PasswordHashPolicy{Algorithm: "argon2id"},
+			want:      true,
+		},
+		{
+			name:      "argon2id password with matching policy",
+			algorithm: "argon2id",
+			params: map[string]interface{}{
+				"iterations": 1,
+				"memory":     1024,
+			},
+			policy: &PasswordHashPolicy{Algorithm: "argon2id", Iterations: 1, Memory: 1024},
+			want:   false,
+		},
+		{
+			name:      "argon2id password with increased memory",
+			algorithm: "argon2id",
+			params: map[string]interface{}{
+				"iterations": 1,
+				"memory":     1024,
+			},
+			policy: &PasswordHashPolicy{Algorithm: "argon2id", Iterations: 1, Memory: 2048},
+			want:   true,
+		},
+		{
+			name:      "scrypt password with argon2id policy",
+			algorithm: "scrypt",
+			params: map[string]interface{}{
+				"cost": 1024,
+			},
+			policy: &PasswordHashPolicy{Algorithm: "argon2id"},
+			want:   true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			entry, err := NewPasswordWithOptions("foobar", "generic", tc.algorithm, tc.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "needs rehash", tc.want, entry.needsRehash(tc.policy), msgs)
+		})
+	}
+}
+
+func TestPasswordHashPolicyValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		policy    *PasswordHashPolicy
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "test empty policy",
+			policy: &PasswordHashPolicy{},
+		},
+		{
+			name:   "test scrypt policy with default cost",
+			policy: &PasswordHashPolicy{Algorithm: "scrypt"},
+		},
+		{
+			name:   "test scrypt policy with power of two cost",
+			policy: &PasswordHashPolicy{Algorithm: "scrypt", Cost: 16384},
+		},
+		{
+			name:      "test scrypt policy with cost not being power of two",
+			policy:    &PasswordHashPolicy{Algorithm: "scrypt", Cost: 1000},
+			shouldErr: true,
+			err:       errors.ErrPasswordGenerate.WithArgs("scrypt cost is not a power of two"),
+		},
+		{
+			name:      "test argon2id policy with invalid parallelism",
+			policy:    &PasswordHashPolicy{Algorithm: "argon2id", Parallelism: 1000},
+			shouldErr: true,
+			err:       errors.ErrPasswordGenerate.WithArgs("argon2id parallelism exceeds 255"),
+		},
+		{
+			name:      "test argon2id policy with memory above limit",
+			policy:    &PasswordHashPolicy{Algorithm: "argon2id", Memory: 1048576},
+			shouldErr: true,
+			err:       errors.ErrPasswordHashLimitExceeded.WithArgs("argon2id", "memory", 262144),
+		},
+		{
+			name:      "test policy with unsupported algorithm",
+			policy:    &PasswordHashPolicy{Algorithm: "foobar"},
+			shouldErr: true,
+			err:       errors.ErrPasswordUnsupportedAlgorithm.WithArgs("foobar"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			err := tc.policy.validate()
+			tests.EvalErrWithLog(t, err, "validate", tc.shouldErr, tc.err, msgs)
+		})
+	}
+}
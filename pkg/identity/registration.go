@@ -29,6 +29,10 @@ type Registration struct {
 	Approved   bool      `json:"approved,omitempty" xml:"approved,omitempty" yaml:"approved,omitempty"`
 	DeclinedAt time.Time `json:"declined_at,omitempty" xml:"declined_at,omitempty" yaml:"declined_at,omitempty"`
 	Declined   bool      `json:"declined,omitempty" xml:"declined,omitempty" yaml:"declined,omitempty"`
+	// Note is the note of the administrator who reviewed the registration.
+	Note string `json:"note,omitempty" xml:"note,omitempty" yaml:"note,omitempty"`
+	// Reason is the reason of the verdict, shared with the registrant.
+	Reason string `json:"reason,omitempty" xml:"reason,omitempty" yaml:"reason,omitempty"`
 }
 
 // NewRegistration returns an instance of Registration.
@@ -51,3 +55,19 @@ func (r *Registration) Decline() {
 	r.Declined = true
 	r.DeclinedAt = time.Now().UTC()
 }
+
+// Reopen returns the Registration to pending review.
+func (r *Registration) Reopen() {
+	r.Approved = false
+	r.ApprovedAt = time.Time{}
+	r.Declined = false
+	r.DeclinedAt = time.Time{}
+	r.Note = ""
+	r.Reason = ""
+}
+
+// IsPending returns true when the Registration is neither approved, nor
+// declined.
+func (r *Registration) IsPending() bool {
+	return !r.Approved && !r.Declined
+}
@@ -0,0 +1,439 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sqliteStorePrefix = "sqlite3://"
+
+// sqlStoreSchema is the schema of SQL database. The statements are
+// compatible with both SQLite and PostgreSQL. The child collections of the
+// users are stored in the separate tables and the remaining user fields
+// are stored as JSON.
+var sqlStoreSchema = []string{
+	`CREATE TABLE IF NOT EXISTS authdb_metadata (
+		id INTEGER PRIMARY KEY,
+		version VARCHAR(64) NOT NULL,
+		revision BIGINT NOT NULL,
+		last_modified VARCHAR(64) NOT NULL,
+		policy TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS authdb_users (
+		id VARCHAR(64) PRIMARY KEY,
+		seq BIGINT NOT NULL,
+		username VARCHAR(255) NOT NULL UNIQUE,
+		data TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS authdb_passwords (
+		user_id VARCHAR(64) NOT NULL,
+		position INTEGER NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (user_id, position)
+	)`,
+	`CREATE TABLE IF NOT EXISTS authdb_api_keys (
+		user_id VARCHAR(64) NOT NULL,
+		position INTEGER NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (user_id, position)
+	)`,
+	`CREATE TABLE IF NOT EXISTS authdb_public_keys (
+		user_id VARCHAR(64) NOT NULL,
+		position INTEGER NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (user_id, position)
+	)`,
+	`CREATE TABLE IF NOT EXISTS authdb_mfa_tokens (
+		user_id VARCHAR(64) NOT NULL,
+		position INTEGER NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (user_id, position)
+	)`,
+}
+
+// sqlStoreCollections are the tables holding the child collections of
+// the users.
+var sqlStoreCollections = []string{
+	"authdb_passwords",
+	"authdb_api_keys",
+	"authdb_public_keys",
+	"authdb_mfa_tokens",
+}
+
+// sqlStore stores the database in SQL database. The store is safe to share
+// between multiple database instances, e.g. replicas.
+type sqlStore struct {
+	driver string
+	path   string
+	conn   *sql.DB
+}
+
+func newSQLStore(driver, dsn string) (*sqlStore, error) {
+	if !isSQLDriverRegistered(driver) {
+		return nil, errors.ErrSQLStoreDriverNotFound.WithArgs(driver)
+	}
+	s := &sqlStore{driver: driver}
+	switch driver {
+	case "sqlite3":
+		s.path = sqliteStorePrefix + dsn
+		fp := strings.SplitN(strings.TrimPrefix(dsn, "file:"), "?", 2)[0]
+		if fp != "" && fp != ":memory:" {
+			if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("malformed data source name")
+		}
+		s.path = u.Redacted()
+	}
+	conn, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == "sqlite3" {
+		// SQLite allows a single writer at a time.
+		conn.SetMaxOpenConns(1)
+	}
+	for _, stmt := range sqlStoreSchema {
+		if _, err := conn.Exec(stmt); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	s.conn = conn
+	return s, nil
+}
+
+func isSQLDriverRegistered(driver string) bool {
+	for _, name := range sql.Drivers() {
+		if name == driver {
+			return true
+		}
+	}
+	return false
+}
+
+// rebind converts the query placeholders to the format of the driver.
+func (s *sqlStore) rebind(query string) string {
+	if s.driver != "postgres" {
+		return query
+	}
+	var sb strings.Builder
+	var n int
+	for _, c := range query {
+		if c != '?' {
+			sb.WriteRune(c)
+			continue
+		}
+		n++
+		sb.WriteString("$" + strconv.Itoa(n))
+	}
+	return sb.String()
+}
+
+func (s *sqlStore) Load(db *Database) (bool, error) {
+	var lastModified, policy string
+	err := s.conn.QueryRow(
+		s.rebind("SELECT version, revision, last_modified, policy FROM authdb_metadata WHERE id = ?"), 1,
+	).Scan(&db.Version, &db.Revision, &lastModified, &policy)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if db.LastModified, err = time.Parse(time.RFC3339Nano, lastModified); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(policy), &db.Policy); err != nil {
+		return false, err
+	}
+
+	rows, err := s.conn.Query("SELECT data FROM authdb_users ORDER BY seq")
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	users := []*User{}
+	refID := make(map[string]*User)
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return false, err
+		}
+		user := &User{}
+		if err := json.Unmarshal([]byte(data), user); err != nil {
+			return false, err
+		}
+		users = append(users, user)
+		refID[user.ID] = user
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	for _, table := range sqlStoreCollections {
+		if err := s.loadCollection(table, refID); err != nil {
+			return false, err
+		}
+	}
+	db.Users = users
+	return true, nil
+}
+
+func (s *sqlStore) loadCollection(table string, refID map[string]*User) error {
+	rows, err := s.conn.Query("SELECT user_id, data FROM " + table + " ORDER BY user_id, position")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID, data string
+		if err := rows.Scan(&userID, &data); err != nil {
+			return err
+		}
+		user, exists := refID[userID]
+		if !exists {
+			continue
+		}
+		if err := addUserCollectionItem(user, table, []byte(data)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *sqlStore) Save(db *Database) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if err := s.saveMetadata(tx, db); err != nil {
+			return err
+		}
+		for _, table := range append(sqlStoreCollections, "authdb_users") {
+			if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+				return err
+			}
+		}
+		for i, user := range db.Users {
+			if err := s.insertUser(tx, user, int64(i+1)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) SaveUser(db *Database, user *User) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if err := s.saveMetadata(tx, db); err != nil {
+			return err
+		}
+		var seq int64
+		err := tx.QueryRow(s.rebind("SELECT seq FROM authdb_users WHERE id = ?"), user.ID).Scan(&seq)
+		switch {
+		case err == sql.ErrNoRows:
+			if err := tx.QueryRow("SELECT COALESCE(MAX(seq), 0) + 1 FROM authdb_users").Scan(&seq); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		}
+		if err := s.deleteUser(tx, user); err != nil {
+			return err
+		}
+		return s.insertUser(tx, user, seq)
+	})
+}
+
+func (s *sqlStore) DeleteUser(db *Database, user *User) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if err := s.saveMetadata(tx, db); err != nil {
+			return err
+		}
+		return s.deleteUser(tx, user)
+	})
+}
+
+// GetRevision returns the revision of the database in the storage.
+func (s *sqlStore) GetRevision() (uint64, error) {
+	var revision uint64
+	err := s.conn.QueryRow(s.rebind("SELECT revision FROM authdb_metadata WHERE id = ?"), 1).Scan(&revision)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return revision, err
+}
+
+func (s *sqlStore) Close() error {
+	return s.conn.Close()
+}
+
+func (s *sqlStore) withTx(f func(tx *sql.Tx) error) error {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveMetadata writes the metadata of the database. The metadata is
+// updated only when the storage holds the previous revision of the
+// database, i.e. no other instance changed the database in the meantime.
+func (s *sqlStore) saveMetadata(tx *sql.Tx, db *Database) error {
+	policy, err := json.Marshal(db.Policy)
+	if err != nil {
+		return err
+	}
+	lastModified := db.LastModified.UTC().Format(time.RFC3339Nano)
+	res, err := tx.Exec(
+		s.rebind("UPDATE authdb_metadata SET version = ?, revision = ?, last_modified = ?, policy = ? WHERE id = ? AND revision = ?"),
+		db.Version, int64(db.Revision), lastModified, string(policy), 1, int64(db.Revision-1),
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM authdb_metadata").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.ErrDatabaseStoreRevision.WithArgs(db.Revision - 1)
+	}
+	_, err = tx.Exec(
+		s.rebind("INSERT INTO authdb_metadata (id, version, revision, last_modified, policy) VALUES (?, ?, ?, ?, ?)"),
+		1, db.Version, int64(db.Revision), lastModified, string(policy),
+	)
+	return err
+}
+
+func (s *sqlStore) insertUser(tx *sql.Tx, user *User, seq int64) error {
+	// The child collections are stored in the separate tables.
+	u := *user
+	u.Passwords = nil
+	u.APIKeys = nil
+	u.PublicKeys = nil
+	u.MfaTokens = nil
+	data, err := json.Marshal(&u)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		s.rebind("INSERT INTO authdb_users (id, seq, username, data) VALUES (?, ?, ?, ?)"),
+		user.ID, seq, strings.ToLower(user.Username), string(data),
+	); err != nil {
+		return err
+	}
+	for _, table := range sqlStoreCollections {
+		for i, item := range getUserCollection(user, table) {
+			b, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(
+				s.rebind("INSERT INTO "+table+" (user_id, position, data) VALUES (?, ?, ?)"),
+				user.ID, i, string(b),
+			); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *sqlStore) deleteUser(tx *sql.Tx, user *User) error {
+	for _, table := range sqlStoreCollections {
+		if _, err := tx.Exec(s.rebind("DELETE FROM "+table+" WHERE user_id = ?"), user.ID); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(s.rebind("DELETE FROM authdb_users WHERE id = ?"), user.ID)
+	return err
+}
+
+// getUserCollection returns the items of the user collection stored in
+// the table.
+func getUserCollection(user *User, table string) []interface{} {
+	var items []interface{}
+	switch table {
+	case "authdb_passwords":
+		for _, item := range user.Passwords {
+			items = append(items, item)
+		}
+	case "authdb_api_keys":
+		for _, item := range user.APIKeys {
+			items = append(items, item)
+		}
+	case "authdb_public_keys":
+		for _, item := range user.PublicKeys {
+			items = append(items, item)
+		}
+	case "authdb_mfa_tokens":
+		for _, item := range user.MfaTokens {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// addUserCollectionItem adds the item stored in the table to the user
+// collection.
+func addUserCollectionItem(user *User, table string, data []byte) error {
+	switch table {
+	case "authdb_passwords":
+		item := &Password{}
+		if err := json.Unmarshal(data, item); err != nil {
+			return err
+		}
+		user.Passwords = append(user.Passwords, item)
+	case "authdb_api_keys":
+		item := &APIKey{}
+		if err := json.Unmarshal(data, item); err != nil {
+			return err
+		}
+		user.APIKeys = append(user.APIKeys, item)
+	case "authdb_public_keys":
+		item := &PublicKey{}
+		if err := json.Unmarshal(data, item); err != nil {
+			return err
+		}
+		user.PublicKeys = append(user.PublicKeys, item)
+	case "authdb_mfa_tokens":
+		item := &MfaToken{}
+		if err := json.Unmarshal(data, item); err != nil {
+			return err
+		}
+		user.MfaTokens = append(user.MfaTokens, item)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSQLStore(t *testing.T) {
+	db, err := createTestDatabase("TestSQLStore")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	user, err := db.getUser(testUser1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.MfaTokens = append(user.MfaTokens, &MfaToken{ID: "foo", Type: "totp", Secret: "bar", Period: 30, Digits: 6})
+	user.APIKeys = append(user.APIKeys, &APIKey{ID: "foo", Prefix: "bar", Usage: "api"})
+	if err := db.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	dsn := "sqlite3://" + filepath.Join(filepath.Dir(db.path), "user_db.sqlite")
+	if err := MigrateDatabase(db.path, dsn); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	err = MigrateDatabase(db.path, dsn)
+	tests.EvalErr(t, err, "migrate to existing database", true,
+		errors.ErrMigrateDatabase.WithArgs(db.path, dsn, errors.ErrMigrateDatabaseExists),
+	)
+
+	db1, err := NewDatabase(dsn)
+	if err != nil {
+		t.Fatalf("failed to open sql database: %v", err)
+	}
+	defer db1.Close()
+	tests.EvalObjects(t, "path", dsn, db1.GetPath())
+	tests.EvalObjects(t, "revision", db.Revision, db1.Revision)
+	tests.EvalObjects(t, "policy", db.Policy, db1.Policy)
+	tests.EvalObjects(t, "users", marshalTestUsers(t, db), marshalTestUsers(t, db1))
+
+	db2, err := NewDatabase(dsn)
+	if err != nil {
+		t.Fatalf("failed to open sql database: %v", err)
+	}
+	defer db2.Close()
+
+	// The reads check the revision of the storage each time.
+	db1.revisionCheckInterval = 0
+	db2.revisionCheckInterval = 0
+
+	// The changes made by one instance are visible to the other.
+	if err := db1.AddUser(&requests.Request{
+		User: requests.User{
+			Username: "mjordan",
+			Password: tests.NewRandomString(12),
+			Email:    "mjordan@example.com",
+			Roles:    []string{"viewer"},
+		},
+	}); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	tests.EvalObjects(t, "user count", 3, db2.GetUserCount())
+	if err := db2.AuthenticateUser(&requests.Request{User: requests.User{Username: testUser2, Password: testPwd2}}); err != nil {
+		t.Fatalf("expected authentication success, but got failure: %v", err)
+	}
+	if err := db2.DeleteUser(&requests.Request{User: requests.User{Username: testUser2, Email: testEmail2}}); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	tests.EvalObjects(t, "user count", 2, db1.GetUserCount())
+	tests.EvalObjects(t, "users", marshalTestUsers(t, db2), marshalTestUsers(t, db1))
+
+	db3, err := NewDatabase(dsn)
+	if err != nil {
+		t.Fatalf("failed to reopen sql database: %v", err)
+	}
+	defer db3.Close()
+	tests.EvalObjects(t, "users", marshalTestUsers(t, db1), marshalTestUsers(t, db3))
+	if _, err := db3.getUser(testUser2); err == nil {
+		t.Fatalf("expected deleted user to be absent")
+	}
+	if _, err := db3.getUser("mjordan"); err != nil {
+		t.Fatalf("expected added user to be present: %v", err)
+	}
+
+	// The reads within the revision check interval do not query the storage.
+	db3.revisionCheckInterval = time.Hour
+	tests.EvalObjects(t, "user count", 2, db3.GetUserCount())
+	if err := db1.DeleteUser(&requests.Request{User: requests.User{Username: "mjordan", Email: "mjordan@example.com"}}); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	tests.EvalObjects(t, "user count within check interval", 2, db3.GetUserCount())
+	db3.revisionCheckedAt = time.Time{}
+	tests.EvalObjects(t, "user count after check interval", 1, db3.GetUserCount())
+}
+
+func TestSQLStoreDriverNotFound(t *testing.T) {
+	_, err := NewStore("postgres://authdb@localhost/authdb")
+	tests.EvalErr(t, err, "postgres store without driver", true,
+		errors.ErrSQLStoreDriverNotFound.WithArgs("postgres"),
+	)
+}
+
+func marshalTestUsers(t *testing.T, db *Database) string {
+	b, err := json.Marshal(db.Users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
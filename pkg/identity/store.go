@@ -0,0 +1,111 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"strings"
+)
+
+// Store is the persistent storage of Database.
+type Store interface {
+	// Load reads the contents of the database from the storage. It returns
+	// false when the storage holds no database yet.
+	Load(db *Database) (bool, error)
+	// Save writes the entire database to the storage.
+	Save(db *Database) error
+	// SaveUser writes the database metadata and the provided user to the
+	// storage.
+	SaveUser(db *Database, user *User) error
+	// DeleteUser writes the database metadata and removes the provided user
+	// from the storage.
+	DeleteUser(db *Database, user *User) error
+	// Close releases the resources associated with the storage.
+	Close() error
+}
+
+// revisionStore is the storage shared between multiple database instances.
+// The instances compare the revision of the storage with their own revision
+// to detect the changes made by the other instances.
+type revisionStore interface {
+	GetRevision() (uint64, error)
+}
+
+// NewStore returns the storage for the provided location. The locations
+// starting with sqlite3://, postgres://, or postgresql:// refer to SQL
+// databases. Otherwise, the location is the path to JSON file.
+//
+// The package does not register SQL drivers. The application using SQL
+// databases imports the drivers registered as sqlite3 or postgres, e.g.
+// github.com/mattn/go-sqlite3, which requires cgo, or github.com/lib/pq.
+func NewStore(s string) (Store, error) {
+	switch {
+	case strings.HasPrefix(s, sqliteStorePrefix):
+		return newSQLStore("sqlite3", strings.TrimPrefix(s, sqliteStorePrefix))
+	case strings.HasPrefix(s, "postgres://"), strings.HasPrefix(s, "postgresql://"):
+		return newSQLStore("postgres", s)
+	}
+	return &jsonStore{}, nil
+}
+
+// getStorePath returns the location of the storage suitable for logging,
+// i.e. without credentials.
+func getStorePath(store Store, s string) string {
+	if sqlStore, ok := store.(*sqlStore); ok {
+		return sqlStore.path
+	}
+	return s
+}
+
+// MigrateDatabase copies the database from the source location to the
+// destination location, e.g. from JSON file to SQL database. The
+// destination must not hold a database.
+func MigrateDatabase(src, dst string) error {
+	srcStore, err := NewStore(src)
+	if err != nil {
+		return errors.ErrMigrateDatabase.WithArgs(src, dst, err)
+	}
+	defer srcStore.Close()
+	src = getStorePath(srcStore, src)
+	db := &Database{path: src, store: srcStore}
+	exists, err := srcStore.Load(db)
+	if err != nil {
+		return errors.ErrMigrateDatabase.WithArgs(src, dst, err)
+	}
+	if !exists {
+		return errors.ErrMigrateDatabase.WithArgs(src, dst, errors.ErrMigrateDatabaseEmpty)
+	}
+	if err := db.index(); err != nil {
+		return errors.ErrMigrateDatabase.WithArgs(src, dst, err)
+	}
+
+	dstStore, err := NewStore(dst)
+	if err != nil {
+		return errors.ErrMigrateDatabase.WithArgs(src, dst, err)
+	}
+	defer dstStore.Close()
+	dst = getStorePath(dstStore, dst)
+	if exists, err := dstStore.Load(&Database{path: dst}); err != nil || exists {
+		if err == nil {
+			err = errors.ErrMigrateDatabaseExists
+		}
+		return errors.ErrMigrateDatabase.WithArgs(src, dst, err)
+	}
+	db.path = dst
+	if err := dstStore.Save(db); err != nil {
+		return errors.ErrMigrateDatabase.WithArgs(src, dst, err)
+	}
+	return nil
+}
@@ -15,6 +15,7 @@
 package identity
 
 import (
+	"encoding/json"
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	"github.com/greenpau/go-authcrunch/pkg/requests"
 	"strings"
@@ -33,12 +34,17 @@ type UserMetadata struct {
 	LastModified time.Time `json:"last_modified,omitempty" xml:"last_modified,omitempty" yaml:"last_modified,omitempty"`
 	Revision     int       `json:"revision,omitempty" xml:"revision,omitempty" yaml:"revision,omitempty"`
 	Avatar       string    `json:"avatar,omitempty" xml:"avatar,omitempty" yaml:"avatar,omitempty"`
+	Roles        []string  `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	Disabled     bool      `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Locked       bool      `json:"locked,omitempty" xml:"locked,omitempty" yaml:"locked,omitempty"`
+	LockedUntil  time.Time `json:"locked_until,omitempty" xml:"locked_until,omitempty" yaml:"locked_until,omitempty"`
 }
 
 // UserMetadataBundle is a collection of public users.
 type UserMetadataBundle struct {
 	users []*UserMetadata
 	size  int
+	total int
 }
 
 // User is a user identity.
@@ -66,6 +72,8 @@ type User struct {
 	Revision       int             `json:"revision,omitempty" xml:"revision,omitempty" yaml:"revision,omitempty"`
 	Roles          []*Role         `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
 	Registration   *Registration   `json:"registration,omitempty" xml:"registration,omitempty" yaml:"registration,omitempty"`
+	Disabled       bool            `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
+	DisabledAt     time.Time       `json:"disabled_at,omitempty" xml:"disabled_at,omitempty" yaml:"disabled_at,omitempty"`
 }
 
 // NewUserMetadataBundle returns an instance of UserMetadataBundle.
@@ -91,6 +99,12 @@ func (b *UserMetadataBundle) Size() int {
 	return b.size
 }
 
+// Total returns the total number of users matching the query, regardless
+// of paging.
+func (b *UserMetadataBundle) Total() int {
+	return b.total
+}
+
 // NewUser returns an instance of User.
 func NewUser(s string) *User {
 	user := &User{
@@ -104,10 +118,26 @@ func NewUser(s string) *User {
 
 // NewUserWithRoles returns User with additional fields.
 func NewUserWithRoles(username, password, email, fullName string, roles []string) (*User, error) {
-	user := NewUser(username)
-	if err := user.AddPassword(password, 0); err != nil {
+	p, err := NewPassword(password)
+	if err != nil {
 		return nil, err
 	}
+	return newUserWithPassword(username, p, email, fullName, roles)
+}
+
+// NewUserWithPasswordHash returns User with additional fields and
+// the pre-hashed password, e.g. the one imported from htpasswd file.
+func NewUserWithPasswordHash(username, passwordHash, email, fullName string, roles []string) (*User, error) {
+	p, err := NewPasswordFromHash(passwordHash)
+	if err != nil {
+		return nil, err
+	}
+	return newUserWithPassword(username, p, email, fullName, roles)
+}
+
+func newUserWithPassword(username string, password *Password, email, fullName string, roles []string) (*User, error) {
+	user := NewUser(username)
+	user.addPassword(password, 0)
 	if err := user.AddEmailAddress(email); err != nil {
 		return nil, err
 	}
@@ -132,6 +162,20 @@ func NewUserWithRoles(username, password, email, fullName string, roles []string
 	return user, nil
 }
 
+// clone returns a deep copy of the user, which remains unchanged when the
+// user in the database changes.
+func (user *User) clone() (*User, error) {
+	b, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	u := &User{}
+	if err := json.Unmarshal(b, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
 // Valid returns true if a user conforms to a standard.
 func (user *User) Valid() error {
 	if len(user.ID) != 36 {
@@ -148,11 +192,18 @@ func (user *User) Valid() error {
 
 // AddPassword returns creates and adds password for a user identity.
 func (user *User) AddPassword(s string, keepVersions int) error {
-	var passwords []*Password
 	password, err := NewPassword(s)
 	if err != nil {
 		return err
 	}
+	user.addPassword(password, keepVersions)
+	return nil
+}
+
+// addPassword makes the provided password the current password of a user
+// identity and retains the previous passwords as disabled.
+func (user *User) addPassword(password *Password, keepVersions int) {
+	var passwords []*Password
 	if keepVersions < 1 {
 		keepVersions = 9
 	}
@@ -170,9 +221,75 @@ func (user *User) AddPassword(s string, keepVersions int) error {
 	}
 	user.Passwords = passwords
 	user.Revise()
+}
+
+// rehashPassword replaces the hash of the current password of a user identity
+// with the one produced by the provided hashing policy. The creation time of
+// the password remains unchanged.
+func (user *User) rehashPassword(s string, policy *PasswordHashPolicy) error {
+	current := user.GetPassword()
+	if current == nil {
+		return errors.ErrUserPasswordNotFound
+	}
+	password, err := newPasswordWithPolicy(s, policy)
+	if err != nil {
+		return err
+	}
+	user.setRehashedPassword(current.Hash, password)
+	return nil
+}
+
+// setRehashedPassword replaces the current password of a user identity with
+// the provided rehashed password, unless the current password no longer has
+// the provided hash, e.g. it was changed after the rehash. The creation time
+// of the password remains unchanged.
+func (user *User) setRehashedPassword(hash string, password *Password) bool {
+	current := user.GetPassword()
+	if current == nil || current.Hash != hash {
+		return false
+	}
+	password.Purpose = current.Purpose
+	password.CreatedAt = current.CreatedAt
+	*current = *password
+	user.Revise()
+	return true
+}
+
+// GetPassword returns the current password of a user identity.
+func (user *User) GetPassword() *Password {
+	for _, p := range user.Passwords {
+		if p.Disabled || p.Expired {
+			continue
+		}
+		return p
+	}
 	return nil
 }
 
+// IsPasswordExpired returns true when the current password of a user identity
+// is older than the provided number of days. Zero days disables the check.
+func (user *User) IsPasswordExpired(maxAgeDays int, now time.Time) bool {
+	if maxAgeDays < 1 {
+		return false
+	}
+	p := user.GetPassword()
+	if p == nil || p.CreatedAt.IsZero() {
+		return false
+	}
+	return !now.Before(p.CreatedAt.AddDate(0, 0, maxAgeDays))
+}
+
+// IsPasswordReused returns true when the provided password matches either
+// the current password or one of the retained previous passwords.
+func (user *User) IsPasswordReused(s string) bool {
+	for _, p := range user.Passwords {
+		if p.Match(s) {
+			return true
+		}
+	}
+	return false
+}
+
 // AddEmailAddress returns creates and adds password for a user identity.
 func (user *User) AddEmailAddress(s string) error {
 	email, err := NewEmailAddress(s)
@@ -195,6 +312,41 @@ func (user *User) AddEmailAddress(s string) error {
 	return nil
 }
 
+// GetEmailAddress returns the email address of a user matching the provided
+// address.
+func (user *User) GetEmailAddress(s string) *EmailAddress {
+	for _, email := range user.EmailAddresses {
+		if strings.EqualFold(email.Address, s) {
+			return email
+		}
+	}
+	return nil
+}
+
+// ConfirmEmailAddress marks the email address of a user as confirmed.
+func (user *User) ConfirmEmailAddress(s string) error {
+	email := user.GetEmailAddress(s)
+	if email == nil {
+		return errors.ErrEmailAddressNotFound
+	}
+	if email.Confirmed {
+		return nil
+	}
+	email.Confirm()
+	user.Revise()
+	return nil
+}
+
+// IsEmailAddressConfirmed checks whether the email address of a user is
+// confirmed.
+func (user *User) IsEmailAddressConfirmed(s string) bool {
+	email := user.GetEmailAddress(s)
+	if email == nil {
+		return false
+	}
+	return email.Confirmed
+}
+
 // HasEmailAddresses checks whether a user has email address.
 func (user *User) HasEmailAddresses() bool {
 	if len(user.EmailAddresses) == 0 {
@@ -508,10 +660,13 @@ func (user *User) GetFlags(r *requests.Request) {
 	}
 }
 
-// ChangePassword changes user password.
+// ChangePassword changes user password. The current password is not
+// verified when the request is a part of password recovery.
 func (user *User) ChangePassword(r *requests.Request, keepVersions int) error {
-	if err := user.VerifyPassword(r.User.OldPassword); err != nil {
-		return errors.ErrChangeUserPassword.WithArgs(err)
+	if !r.Flags.PasswordRecovery {
+		if err := user.VerifyPassword(r.User.OldPassword); err != nil {
+			return errors.ErrChangeUserPassword.WithArgs(err)
+		}
 	}
 	if err := user.AddPassword(r.User.Password, keepVersions); err != nil {
 		return errors.ErrChangeUserPassword.WithArgs(err)
@@ -539,6 +694,12 @@ func (user *User) GetMetadata() *UserMetadata {
 	if user.Name != nil {
 		m.Name = user.Name.ToString()
 	}
+	m.Roles = user.GetRolesClaim()
+	m.Disabled = user.Disabled
+	if user.Lockout.IsLocked(time.Now().UTC()) {
+		m.Locked = true
+		m.LockedUntil = user.Lockout.EndTime
+	}
 	return m
 }
 
@@ -553,6 +714,51 @@ func (user *User) GetChallenges() []string {
 	return challenges
 }
 
+// Disable disables user identity.
+func (user *User) Disable() {
+	if user.Disabled {
+		return
+	}
+	user.Disabled = true
+	user.DisabledAt = time.Now().UTC()
+	user.Revise()
+}
+
+// Enable enables previously disabled user identity.
+func (user *User) Enable() {
+	if !user.Disabled {
+		return
+	}
+	user.Disabled = false
+	user.DisabledAt = time.Time{}
+	user.Revise()
+}
+
+// SetRoles replaces the roles of a user with the provided roles.
+func (user *User) SetRoles(roles []string) error {
+	var entries []*Role
+	for _, s := range roles {
+		role, err := NewRole(s)
+		if err != nil {
+			return err
+		}
+		var found bool
+		for _, entry := range entries {
+			if (entry.Name == role.Name) && (entry.Organization == role.Organization) {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		entries = append(entries, role)
+	}
+	user.Roles = entries
+	user.Revise()
+	return nil
+}
+
 // Revise increments revision number and last modified timestamp.
 func (user *User) Revise() {
 	user.Revision++
@@ -20,6 +20,7 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/errors"
 	cfgutil "github.com/greenpau/go-authcrunch/pkg/util/cfg"
 
+	"net/url"
 	"os"
 	"sort"
 	"strconv"
@@ -30,6 +31,8 @@ const (
 	defaultKeyID             = "0"
 	defaultTokenName         = "access_token"
 	defaultTokenLifetime int = 900
+	// The default lifetime of refresh tokens is 7 days.
+	defaultRefreshTokenLifetime int = 604800
 )
 
 var (
@@ -66,9 +69,9 @@ type CryptoKeyConfig struct {
 	Usage string `json:"usage,omitempty" xml:"usage,omitempty" yaml:"usage,omitempty"`
 	// TokenName is the token name associated with the key.
 	TokenName string `json:"token_name,omitempty" xml:"token_name,omitempty" yaml:"token_name,omitempty"`
-	// Source is either config or env.
+	// Source is either config, env, or jwks.
 	Source string `json:"source,omitempty" xml:"source,omitempty" yaml:"source,omitempty"`
-	// Algorithm is either hmac, rsa, or ecdsa.
+	// Algorithm is either hmac, rsa, ecdsa, or eddsa.
 	Algorithm string `json:"algorithm,omitempty" xml:"algorithm,omitempty" yaml:"algorithm,omitempty"`
 	// EnvVarName is the name of environment variables holding either the value of
 	// a key or the path a directory or file containing a key.
@@ -83,6 +86,11 @@ type CryptoKeyConfig struct {
 	FilePath string `json:"file_path,omitempty" xml:"file_path,omitempty" yaml:"file_path,omitempty"`
 	// DirPath is the path to a directory containing crypto keys.
 	DirPath string `json:"dir_path,omitempty" xml:"dir_path,omitempty" yaml:"dir_path,omitempty"`
+	// JwksURL is the URL of a remote JSON Web Key Set with verification keys.
+	JwksURL string `json:"jwks_url,omitempty" xml:"jwks_url,omitempty" yaml:"jwks_url,omitempty"`
+	// JwksRefreshInterval is the interval, in seconds, for refreshing the
+	// keys fetched from JwksURL.
+	JwksRefreshInterval int `json:"jwks_refresh_interval,omitempty" xml:"jwks_refresh_interval,omitempty" yaml:"jwks_refresh_interval,omitempty"`
 	// TokenLifetime is the expected token grant lifetime in seconds.
 	TokenLifetime int `json:"token_lifetime,omitempty" xml:"token_lifetime,omitempty" yaml:"token_lifetime,omitempty"`
 	// Secret is the shared key used with HMAC algorithm.
@@ -125,6 +133,9 @@ func (k *CryptoKeyConfig) ToString() string {
 	if k.DirPath != "" {
 		sb.WriteString(", dir path: " + k.DirPath)
 	}
+	if k.JwksURL != "" {
+		sb.WriteString(", jwks url: " + k.JwksURL)
+	}
 	if k.validated || k.parsed {
 		sb.WriteString(", flags:")
 		if k.parsed {
@@ -174,12 +185,26 @@ func (k *CryptoKeyConfig) validate() error {
 		default:
 			return fmt.Errorf("key source type %q for env is invalid", k.EnvVarType)
 		}
+	case "jwks":
+		if k.Usage != "verify" {
+			return fmt.Errorf("key source jwks supports verify usage only")
+		}
+		u, err := url.Parse(k.JwksURL)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("key source jwks url %q is invalid", k.JwksURL)
+		}
+		if u.Scheme != "https" {
+			return fmt.Errorf("key source jwks url %q is not https", k.JwksURL)
+		}
+		if k.JwksRefreshInterval < 0 {
+			return fmt.Errorf("key source jwks refresh interval %d is invalid", k.JwksRefreshInterval)
+		}
 	default:
 		return fmt.Errorf("key source %q is invalid", k.Source)
 	}
 
 	switch k.Algorithm {
-	case "hmac", "rsa", "ecdsa", "":
+	case "hmac", "rsa", "ecdsa", "eddsa", "":
 	default:
 		return fmt.Errorf("key algorithm %q is invalid", k.Algorithm)
 	}
@@ -213,9 +238,41 @@ func ParseCryptoKeyStoreConfig(cfg string) (map[string]interface{}, error) {
 					return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, err)
 				}
 				m["token_lifetime"] = lifetime
+			case "refresh_lifetime":
+				lifetime, err := strconv.Atoi(args[3])
+				if err != nil {
+					return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, err)
+				}
+				m["refresh_token_lifetime"] = lifetime
 			default:
 				return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, "contains unsupported 'crypto default token' parameter: %s", args[2])
 			}
+		case "key":
+			if len(args) != 5 || args[2] != "rotation" {
+				return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, "must be 'crypto default key rotation' followed by parameter and value")
+			}
+			switch args[3] {
+			case "interval":
+				interval, err := strconv.Atoi(args[4])
+				if err != nil {
+					return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, err)
+				}
+				if interval < 1 {
+					return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, "key rotation interval must be positive")
+				}
+				m["key_rotation_interval"] = interval
+			case "algorithm":
+				switch args[4] {
+				case "ES512", "EdDSA":
+				default:
+					return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, fmt.Sprintf("unsupported key rotation algorithm: %s", args[4]))
+				}
+				m["key_rotation_algorithm"] = args[4]
+			case "directory":
+				m["key_rotation_directory"] = args[4]
+			default:
+				return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, fmt.Sprintf("contains unsupported 'crypto default key rotation' parameter: %s", args[3]))
+			}
 		default:
 			return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, fmt.Sprintf("contains unsupported 'crypto default' keyword: %s", args[1]))
 		}
@@ -404,11 +461,25 @@ func ParseCryptoKeyConfigs(cfg string) ([]*CryptoKeyConfig, error) {
 						if err := key.loadEnvVar(); err != nil {
 							return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, err)
 						}
+					case "jwks":
+						key.Source = "jwks"
+						key.JwksURL = args[i+3]
 					default:
 						return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, "bad syntax")
 					}
 					i += 3
 				case 5:
+					if args[i+2] == "jwks" && args[i+4] == "refresh" {
+						interval, err := strconv.Atoi(args[i+5])
+						if err != nil {
+							return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, err)
+						}
+						key.Source = "jwks"
+						key.JwksURL = args[i+3]
+						key.JwksRefreshInterval = interval
+						i += 5
+						break
+					}
 					if args[i+2] != "env" || args[i+4] != "as" {
 						return nil, errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(line, "bad syntax")
 					}
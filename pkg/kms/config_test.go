@@ -851,6 +851,65 @@ func TestParseCryptoKeyConfigs(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "remote jwks key set for verify",
+			config: `
+                crypto key token name access_token
+                crypto key verify from jwks https://auth.example.com/.well-known/jwks.json
+                crypto key remote2 verify from jwks https://idp.example.com/keys refresh 600
+            `,
+			want: map[string]interface{}{
+				"config_count": 2,
+				"configs": []*CryptoKeyConfig{
+					{
+						ID:            "0",
+						Usage:         "verify",
+						TokenName:     "access_token",
+						Source:        "jwks",
+						JwksURL:       "https://auth.example.com/.well-known/jwks.json",
+						TokenLifetime: 900,
+						parsed:        true,
+						validated:     true,
+					},
+					{
+						ID:                  "remote2",
+						Seq:                 1,
+						Usage:               "verify",
+						TokenName:           "access_token",
+						Source:              "jwks",
+						JwksURL:             "https://idp.example.com/keys",
+						JwksRefreshInterval: 600,
+						TokenLifetime:       900,
+						parsed:              true,
+						validated:           true,
+					},
+				},
+			},
+		},
+		{
+			name: "remote jwks key set for signing",
+			config: `
+                crypto key sign from jwks https://auth.example.com/.well-known/jwks.json
+            `,
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyConfigKeyInvalid.WithArgs(0, "key source jwks supports verify usage only"),
+		},
+		{
+			name: "remote jwks key set with invalid url",
+			config: `
+                crypto key verify from jwks /.well-known/jwks.json
+            `,
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyConfigKeyInvalid.WithArgs(0, `key source jwks url "/.well-known/jwks.json" is invalid`),
+		},
+		{
+			name: "remote jwks key set with plain http url",
+			config: `
+                crypto key verify from jwks http://auth.example.com/.well-known/jwks.json
+            `,
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyConfigKeyInvalid.WithArgs(0, `key source jwks url "http://auth.example.com/.well-known/jwks.json" is not https`),
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
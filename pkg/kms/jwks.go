@@ -0,0 +1,195 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"math/big"
+	"strings"
+)
+
+// JWK is the public part of CryptoKey in JSON Web Key format.
+// See https://tools.ietf.org/html/rfc7517#section-4,
+// https://tools.ietf.org/html/rfc8037#section-2
+type JWK struct {
+	KeyType      string `json:"kty,omitempty" xml:"kty,omitempty" yaml:"kty,omitempty"`
+	KeyID        string `json:"kid,omitempty" xml:"kid,omitempty" yaml:"kid,omitempty"`
+	PublicKeyUse string `json:"use,omitempty" xml:"use,omitempty" yaml:"use,omitempty"`
+	Algorithm    string `json:"alg,omitempty" xml:"alg,omitempty" yaml:"alg,omitempty"`
+
+	Modulus  string `json:"n,omitempty" xml:"n,omitempty" yaml:"n,omitempty"`
+	Exponent string `json:"e,omitempty" xml:"e,omitempty" yaml:"e,omitempty"`
+
+	Curve  string `json:"crv,omitempty" xml:"crv,omitempty" yaml:"crv,omitempty"`
+	CoordX string `json:"x,omitempty" xml:"x,omitempty" yaml:"x,omitempty"`
+	CoordY string `json:"y,omitempty" xml:"y,omitempty" yaml:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []*JWK `json:"keys" xml:"keys" yaml:"keys"`
+}
+
+// GetJWK returns the public key used for token verification in JSON Web
+// Key format. The shared keys are never published.
+func (k *CryptoKey) GetJWK() (*JWK, error) {
+	if k.Verify == nil || !k.Verify.Token.Capable {
+		return nil, errors.ErrCryptoKeyJWKNotVerifyKey.WithArgs(k.Config.ID)
+	}
+	jwk := &JWK{
+		KeyID:        k.Verify.Token.ID,
+		PublicKeyUse: "sig",
+		Algorithm:    k.Verify.Token.DefaultMethod,
+	}
+	switch pk := k.Verify.Secret.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.Modulus = encodeJWKInt(pk.N, 0)
+		jwk.Exponent = encodeJWKInt(big.NewInt(int64(pk.E)), 0)
+	case *ecdsa.PublicKey:
+		params := pk.Curve.Params()
+		size := (params.BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = params.Name
+		jwk.CoordX = encodeJWKInt(pk.X, size)
+		jwk.CoordY = encodeJWKInt(pk.Y, size)
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.CoordX = base64.RawURLEncoding.EncodeToString(pk)
+	default:
+		return nil, errors.ErrCryptoKeyJWKUnsupportedKey.WithArgs(k.Config.ID, k.Verify.Secret)
+	}
+	return jwk, nil
+}
+
+// encodeJWKInt returns base64url encoding of the big-endian representation
+// of the integer padded to the provided size.
+func encodeJWKInt(i *big.Int, size int) string {
+	b := i.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeJWKInt returns the integer encoded with base64url. The keys
+// published with padding or standard alphabet are accepted as well.
+func decodeJWKInt(s string) (*big.Int, error) {
+	s = strings.TrimRight(s, "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// getPublicKey returns the public key described by the JWK.
+func (jwk *JWK) getPublicKey() (interface{}, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		if jwk.Modulus == "" || jwk.Exponent == "" {
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, "modulus or exponent not found")
+		}
+		n, err := decodeJWKInt(jwk.Modulus)
+		if err != nil {
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, err)
+		}
+		e, err := decodeJWKInt(jwk.Exponent)
+		if err != nil {
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, err)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, "exponent is out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, "unsupported curve "+jwk.Curve)
+		}
+		x, err := decodeJWKInt(jwk.CoordX)
+		if err != nil {
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, err)
+		}
+		y, err := decodeJWKInt(jwk.CoordY)
+		if err != nil {
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, "point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, "unsupported curve "+jwk.Curve)
+		}
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(jwk.CoordX, "="))
+		if err != nil {
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, err)
+		}
+		if len(b) != ed25519.PublicKeySize {
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, "bad public key size")
+		}
+		return ed25519.PublicKey(b), nil
+	}
+	return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, "unsupported key type "+jwk.KeyType)
+}
+
+// GetJWKS returns the public verification keys of CryptoKeyStore in JSON Web
+// Key Set format. The keys not suitable for publishing, e.g. shared
+// secrets, are skipped.
+func (ks *CryptoKeyStore) GetJWKS() *JWKS {
+	jwks := &JWKS{
+		Keys: []*JWK{},
+	}
+	for _, k := range ks.GetVerifyKeys() {
+		jwk, err := k.GetJWK()
+		if err != nil {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// GetSigningMethods returns the token signing methods used by the signing
+// keys of CryptoKeyStore.
+func (ks *CryptoKeyStore) GetSigningMethods() []string {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, k := range ks.GetSignKeys() {
+		m := k.Sign.Token.DefaultMethod
+		if m == "" || seen[m] {
+			continue
+		}
+		seen[m] = true
+		methods = append(methods, m)
+	}
+	return methods
+}
@@ -0,0 +1,215 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"encoding/json"
+	"fmt"
+	jwtlib "github.com/golang-jwt/jwt/v4"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJwksRefreshInterval = 3600
+	defaultJwksRetryInterval   = 30
+	defaultJwksFetchTimeout    = 10
+	maxJwksResponseSize        = 1 << 20
+)
+
+// JwksKeySource is a set of verification keys fetched from a remote JSON
+// Web Key Set, e.g. the jwks_uri of a separately deployed portal or a
+// third-party identity provider. The keys are cached and refreshed
+// periodically, or when a token references an unknown key id.
+type JwksKeySource struct {
+	url             string
+	config          *CryptoKeyConfig
+	client          *http.Client
+	refreshInterval time.Duration
+	retryInterval   time.Duration
+	mu              sync.RWMutex
+	keys            map[string]*CryptoKey
+	fetchedAt       time.Time
+	attemptedAt     time.Time
+}
+
+// NewJwksKeySource returns an instance of JwksKeySource for the provided
+// key config. The keys are fetched on first use.
+func NewJwksKeySource(cfg *CryptoKeyConfig) *JwksKeySource {
+	interval := cfg.JwksRefreshInterval
+	if interval == 0 {
+		interval = defaultJwksRefreshInterval
+	}
+	return &JwksKeySource{
+		url:    cfg.JwksURL,
+		config: cfg,
+		client: &http.Client{
+			Timeout: time.Duration(defaultJwksFetchTimeout) * time.Second,
+		},
+		refreshInterval: time.Duration(interval) * time.Second,
+		retryInterval:   time.Duration(defaultJwksRetryInterval) * time.Second,
+		keys:            make(map[string]*CryptoKey),
+	}
+}
+
+// Refresh fetches the key set and replaces the cached keys.
+func (s *JwksKeySource) Refresh() error {
+	s.mu.Lock()
+	s.attemptedAt = time.Now()
+	s.mu.Unlock()
+
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return errors.ErrJwksKeySourceFetch.WithArgs(s.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, resp.Body)
+		return errors.ErrJwksKeySourceFetch.WithArgs(s.url, fmt.Sprintf("status code %d", resp.StatusCode))
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxJwksResponseSize))
+	if err != nil {
+		return errors.ErrJwksKeySourceFetch.WithArgs(s.url, err)
+	}
+	jwks := &JWKS{}
+	if err := json.Unmarshal(b, jwks); err != nil {
+		return errors.ErrJwksKeySourceFetch.WithArgs(s.url, err)
+	}
+
+	keys := make(map[string]*CryptoKey)
+	for _, jwk := range jwks.Keys {
+		if jwk == nil {
+			continue
+		}
+		k, err := s.newKey(jwk)
+		if err != nil {
+			// The keys of unsupported types, e.g. encryption keys, are skipped.
+			continue
+		}
+		keys[jwk.KeyID] = k
+	}
+	if len(keys) == 0 {
+		return errors.ErrJwksKeySourceNoKeys.WithArgs(s.url)
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// newKey returns verification CryptoKey for the provided JWK.
+func (s *JwksKeySource) newKey(jwk *JWK) (*CryptoKey, error) {
+	if jwk.PublicKeyUse != "" && jwk.PublicKeyUse != "sig" {
+		return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, "not a signature key")
+	}
+	pk, err := jwk.getPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	k := newCryptoKey()
+	kcfg := *s.config
+	kcfg.ID = jwk.KeyID
+	k.Config = &kcfg
+	k.Verify.Capable = true
+	k.Verify.Secret = pk
+	switch jwk.KeyType {
+	case "RSA":
+		k.Config.Algorithm = "rsa"
+	case "EC":
+		k.Config.Algorithm = "ecdsa"
+		switch jwk.Curve {
+		case "P-256":
+			k.Verify.Token.PreferredMethods = []string{"ES256"}
+		case "P-384":
+			k.Verify.Token.PreferredMethods = []string{"ES384"}
+		case "P-521":
+			k.Verify.Token.PreferredMethods = []string{"ES512"}
+		}
+	case "OKP":
+		k.Config.Algorithm = "eddsa"
+	}
+	if jwk.Algorithm != "" {
+		if signingMethods[jwk.Algorithm] != k.Config.Algorithm {
+			return nil, errors.ErrCryptoKeyJWKInvalid.WithArgs(jwk.KeyID, "algorithm "+jwk.Algorithm+" does not match key type")
+		}
+		k.Verify.Token.PreferredMethods = []string{jwk.Algorithm}
+	}
+	k.enableUsage()
+	return k, nil
+}
+
+// GetKey returns the cached key with the provided key id. The key set is
+// refreshed when it is stale or the key id is unknown. The refreshes
+// triggered by unknown key ids are rate limited.
+func (s *JwksKeySource) GetKey(kid string) (*CryptoKey, error) {
+	k, stale := s.lookup(kid)
+	if k != nil && !stale {
+		return k, nil
+	}
+	if s.canRefresh() {
+		if err := s.Refresh(); err != nil && k == nil {
+			return nil, err
+		}
+		k, _ = s.lookup(kid)
+	}
+	if k == nil {
+		return nil, errors.ErrJwksKeySourceKeyNotFound.WithArgs(kid, s.url)
+	}
+	return k, nil
+}
+
+// lookup returns the cached key and whether the cache is stale. When the
+// key id is empty, the key is found only if the set has a single key.
+func (s *JwksKeySource) lookup(kid string) (*CryptoKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stale := s.fetchedAt.IsZero() || time.Since(s.fetchedAt) > s.refreshInterval
+	if kid == "" {
+		if len(s.keys) != 1 {
+			return nil, stale
+		}
+		for _, k := range s.keys {
+			return k, stale
+		}
+	}
+	return s.keys[kid], stale
+}
+
+// canRefresh reserves a refresh attempt unless one was made recently.
+func (s *JwksKeySource) canRefresh() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.attemptedAt.IsZero() && time.Since(s.attemptedAt) < s.retryInterval {
+		return false
+	}
+	s.attemptedAt = time.Now()
+	return true
+}
+
+// ProvideKey returns the key referenced by the kid header of the token.
+func (s *JwksKeySource) ProvideKey(token *jwtlib.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, err := s.GetKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	return k.ProvideKey(token)
+}
@@ -0,0 +1,219 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestSigningKeyStore(t *testing.T, cfg string) *CryptoKeyStore {
+	configs, err := ParseCryptoKeyConfigs(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewCryptoKeyStore()
+	if err := ks.AddKeysWithConfigs(configs); err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func TestJwksKeySource(t *testing.T) {
+	issuers := []*CryptoKeyStore{
+		newTestSigningKeyStore(t, `crypto key ec1 sign-verify from file ./../../testdata/ecdsakeys/test_1_pri.pem`),
+		newTestSigningKeyStore(t, `crypto key rsa1 sign-verify from file ./../../testdata/rskeys/test_1_pri.pem`),
+		newTestSigningKeyStore(t, `crypto key ed1 sign-verify from file ./../../testdata/edkeys/test_1_pri.pem`),
+	}
+
+	var mu sync.Mutex
+	var current *CryptoKeyStore
+	var fetches int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		json.NewEncoder(w).Encode(current.GetJWKS())
+	}))
+	defer srv.Close()
+
+	setIssuer := func(ks *CryptoKeyStore) {
+		mu.Lock()
+		defer mu.Unlock()
+		current = ks
+	}
+	getFetches := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetches
+	}
+
+	configs, err := ParseCryptoKeyConfigs(`crypto key verify from jwks ` + srv.URL + `/.well-known/jwks.json`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewCryptoKeyStore()
+	if err := ks.AddKeysWithConfigs(configs); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.HasVerifyKeys(); err != nil {
+		t.Fatal(err)
+	}
+	src := ks.GetVerifyKeys()[0].Verify.Secret.(*JwksKeySource)
+	src.client.Transport = srv.Client().Transport
+	if getFetches() != 0 {
+		t.Fatalf("unexpected fetch before first use")
+	}
+
+	parse := func(issuer *CryptoKeyStore) error {
+		usr := newTestUser()
+		if err := issuer.SignToken(nil, nil, usr); err != nil {
+			t.Fatalf("unexpected sign error: %v", err)
+		}
+		ar := requests.NewAuthorizationRequest()
+		ar.Token.Name = "access_token"
+		ar.Token.Payload = usr.Token
+		_, err := ks.ParseToken(ar)
+		return err
+	}
+
+	// The keys are fetched on first use and then served from cache.
+	setIssuer(issuers[0])
+	for i := 0; i < 3; i++ {
+		if err := parse(issuers[0]); err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+	}
+	tests.EvalObjects(t, "fetches after first use", 1, getFetches())
+
+	// The tokens signed with unknown keys fail while the refreshes are
+	// rate limited.
+	tests.EvalErr(t, parse(issuers[1]), nil, true, errors.ErrCryptoKeyStoreParseTokenFailed)
+	tests.EvalObjects(t, "fetches with rate limit", 1, getFetches())
+
+	// The rotated key is discovered when a token references unknown kid.
+	src.retryInterval = 0
+	setIssuer(issuers[1])
+	if err := parse(issuers[1]); err != nil {
+		t.Fatalf("unexpected parse error after rotation: %v", err)
+	}
+	tests.EvalObjects(t, "fetches after rotation", 2, getFetches())
+	tests.EvalErr(t, parse(issuers[0]), nil, true, errors.ErrCryptoKeyStoreParseTokenFailed)
+
+	// The stale key set is refreshed periodically.
+	src.retryInterval = time.Hour
+	src.refreshInterval = 0
+	src.attemptedAt = time.Time{}
+	setIssuer(issuers[2])
+	if err := parse(issuers[2]); err != nil {
+		t.Fatalf("unexpected parse error after refresh: %v", err)
+	}
+	tests.EvalObjects(t, "fetches after refresh", 4, getFetches())
+}
+
+func TestJwksKeySourceRefresh(t *testing.T) {
+	var testcases = []struct {
+		name      string
+		status    int
+		body      string
+		want      []string
+		shouldErr bool
+		err       func(string) error
+	}{
+		{
+			name:   "key set with supported and unsupported keys",
+			status: http.StatusOK,
+			body: `{"keys":[
+			  {"kty":"EC","kid":"ec1","use":"sig","crv":"P-256",
+			   "x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU","y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"},
+			  {"kty":"RSA","kid":"enc1","use":"enc","n":"0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw","e":"AQAB"},
+			  {"kty":"oct","kid":"hs1","k":"Zm9vYmFy"},
+			  {"kty":"OKP","kid":"ed1","crv":"Ed25519","alg":"EdDSA","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+			]}`,
+			want: []string{"ec1 ecdsa ES256", "ed1 eddsa EdDSA"},
+		},
+		{
+			name:   "key with mismatched algorithm",
+			status: http.StatusOK,
+			body: `{"keys":[
+			  {"kty":"OKP","kid":"ed1","crv":"Ed25519","alg":"RS256","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+			]}`,
+			shouldErr: true,
+			err: func(u string) error {
+				return errors.ErrJwksKeySourceNoKeys.WithArgs(u)
+			},
+		},
+		{
+			name:      "key set not found",
+			status:    http.StatusNotFound,
+			shouldErr: true,
+			err: func(u string) error {
+				return errors.ErrJwksKeySourceFetch.WithArgs(u, "status code 404")
+			},
+		},
+		{
+			name:      "malformed key set",
+			status:    http.StatusOK,
+			body:      `{"keys":`,
+			shouldErr: true,
+			err: func(u string) error {
+				return errors.ErrJwksKeySourceFetch.WithArgs(u, "unexpected end of JSON input")
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			src := NewJwksKeySource(&CryptoKeyConfig{
+				Usage:         "verify",
+				Source:        "jwks",
+				JwksURL:       srv.URL,
+				TokenName:     "access_token",
+				TokenLifetime: 900,
+			})
+			var wantErr error
+			if tc.err != nil {
+				wantErr = tc.err(srv.URL)
+			}
+			err := src.Refresh()
+			if tests.EvalErrWithLog(t, err, nil, tc.shouldErr, wantErr, msgs) {
+				return
+			}
+			var got []string
+			for _, kid := range []string{"ec1", "enc1", "hs1", "ed1"} {
+				k, _ := src.lookup(kid)
+				if k == nil {
+					continue
+				}
+				got = append(got, fmt.Sprintf("%s %s %s", k.Config.ID, k.Config.Algorithm, k.Verify.Token.DefaultMethod))
+			}
+			tests.EvalObjectsWithLog(t, "keys", tc.want, got, msgs)
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/rsa"
@@ -154,6 +155,16 @@ func GetKeysFromConfig(cfg *CryptoKeyConfig) ([]*CryptoKey, error) {
 		default:
 			return nil, fmt.Errorf("unsupported env config type %s", cfg.EnvVarType)
 		}
+	case "jwks":
+		// Discovered remote key set. The keys are resolved when a token
+		// is being verified.
+		k := newCryptoKey()
+		k.Config = cfg
+		k.Verify.Capable = true
+		k.Verify.Secret = NewJwksKeySource(cfg)
+		k.Verify.Token.PreferredMethods = jwksSigningMethods
+		k.enableUsage()
+		return []*CryptoKey{k}, nil
 	}
 
 	for _, k := range keys {
@@ -163,7 +174,7 @@ func GetKeysFromConfig(cfg *CryptoKeyConfig) ([]*CryptoKey, error) {
 			k.Verify.Capable = true
 			k.Sign.Secret = []byte(k.Config.Secret)
 			k.Verify.Secret = []byte(k.Config.Secret)
-		case "rsa", "ecdsa":
+		case "rsa", "ecdsa", "eddsa":
 		default:
 			return nil, fmt.Errorf("unsupported config algorithm %s", k.Config.Algorithm)
 		}
@@ -253,6 +264,8 @@ func (k *CryptoKey) sign(signMethod, data interface{}) (interface{}, error) {
 		return k.signRSA(method, s)
 	case "ecdsa":
 		return k.signECDSA(method, s)
+	case "eddsa":
+		return k.signEdDSA(method, s)
 	}
 
 	return nil, errors.ErrDataSigningFailed.WithArgs(method, "unsupported method")
@@ -260,6 +273,9 @@ func (k *CryptoKey) sign(signMethod, data interface{}) (interface{}, error) {
 
 // ProvideKey returns the appropriate encryption key.
 func (k *CryptoKey) ProvideKey(token *jwtlib.Token) (interface{}, error) {
+	if src, ok := k.Verify.Secret.(*JwksKeySource); ok {
+		return src.ProvideKey(token)
+	}
 	switch k.Config.Algorithm {
 	case "hmac":
 		if _, validMethod := token.Method.(*jwtlib.SigningMethodHMAC); !validMethod {
@@ -273,6 +289,10 @@ func (k *CryptoKey) ProvideKey(token *jwtlib.Token) (interface{}, error) {
 		if _, validMethod := token.Method.(*jwtlib.SigningMethodECDSA); !validMethod {
 			return nil, errors.ErrUnexpectedSigningMethod.WithArgs("ES", token.Header["alg"])
 		}
+	case "eddsa":
+		if _, validMethod := token.Method.(*jwtlib.SigningMethodEd25519); !validMethod {
+			return nil, errors.ErrUnexpectedSigningMethod.WithArgs("EdDSA", token.Header["alg"])
+		}
 	}
 	return k.Verify.Secret, nil
 }
@@ -384,8 +404,17 @@ func extractKey(kb []byte, cfg *CryptoKeyConfig) (*CryptoKey, error) {
 				k.Verify.Capable = true
 				k.Verify.Secret = privKey.Public()
 			}
+		case ed25519.PrivateKey:
+			k.Config.Algorithm = "eddsa"
+			k.Sign.Capable = true
+			k.Sign.Secret = privKey
+			switch k.Config.Usage {
+			case "sign":
+			default:
+				k.Verify.Capable = true
+				k.Verify.Secret = privKey.Public()
+			}
 		default:
-			// case ed25519.PrivateKey
 			return nil, errors.ErrCryptoKeyConfigUnsupportedPrivateKeyAlgo.WithArgs(privKey)
 		}
 	case bytes.Contains(kb, []byte("RSA PUBLIC KEY")):
@@ -414,9 +443,11 @@ func extractKey(kb []byte, cfg *CryptoKeyConfig) (*CryptoKey, error) {
 				return nil, errors.ErrNoECDSACurveParamsFound
 			}
 			curveName = curve.Name
+		case ed25519.PublicKey:
+			k.Config.Algorithm = "eddsa"
+			k.Verify.Secret = pubKey
 		default:
 			// case *dsa.PublicKey
-			// case ed25519.PublicKey
 			return nil, errors.ErrCryptoKeyConfigUnsupportedPublicKeyAlgo.WithArgs(pubKey)
 		}
 	default:
@@ -540,6 +571,18 @@ func (k *CryptoKey) signECDSA(method, data string) (interface{}, error) {
 	return data + "." + base64.RawURLEncoding.EncodeToString(b), nil
 }
 
+func (k *CryptoKey) signEdDSA(method, data string) (interface{}, error) {
+	if method != "EdDSA" {
+		return nil, errors.ErrDataSigningFailed.WithArgs("EdDSA", "unsupported method")
+	}
+	pk, ok := k.Sign.Secret.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.ErrDataSigningFailed.WithArgs("EdDSA", "unsupported key")
+	}
+	b := ed25519.Sign(pk, []byte(data))
+	return data + "." + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 func (k *CryptoKey) signRSA(method, data string) (interface{}, error) {
 	var h crypto.Hash
 	switch method {
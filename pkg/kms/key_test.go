@@ -169,6 +169,57 @@ func TestGetKeysFromConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "load private and public eddsa keys from file path",
+			config: `
+                crypto key k9738a405e99 sign from file ./../../testdata/edkeys/test_2_pri.pem
+                crypto key k9738a405e99 verify from file ./../../testdata/edkeys/test_2_pub.pem
+            `,
+			keyPair: []int{0, 1},
+			want: map[string]interface{}{
+				"config_count": 2,
+				"key_count":    2,
+				"keys": []string{
+					"0: sign   k9738a405e99: ed25519.PrivateKey",
+					"1: verify k9738a405e99: ed25519.PublicKey",
+				},
+			},
+		},
+		{
+			name: "eddsa key embedded in environment variable",
+			config: `
+                crypto key cb315f43c868 sign-verify from env JWT_SHARED_SECRET
+            `,
+			env: map[string]string{
+				"JWT_SHARED_SECRET": "file:./../../testdata/edkeys/test_1_pri.pem",
+			},
+			keyPair: []int{0, 0},
+			want: map[string]interface{}{
+				"config_count": 1,
+				"key_count":    1,
+				"keys": []string{
+					"0: sign   cb315f43c868: ed25519.PrivateKey",
+					"0: verify cb315f43c868: ed25519.PublicKey",
+				},
+			},
+		},
+		{
+			name: "load keys from eddsa directory path",
+			config: `
+                crypto key k9738a405e99 verify from directory ./../../testdata/edkeys
+            `,
+			want: map[string]interface{}{
+				"config_count": 1,
+				"key_count":    3,
+				"keys": []string{
+					"0: sign   test_1_pri: ed25519.PrivateKey",
+					"0: verify test_1_pri: ed25519.PublicKey",
+					"1: sign   test_2_pri: ed25519.PrivateKey",
+					"1: verify test_2_pri: ed25519.PublicKey",
+					"2: verify test_2_pub: ed25519.PublicKey",
+				},
+			},
+		},
 		{
 			name: "load private rsa key from file path for both sign and verify",
 			config: `
@@ -16,6 +16,7 @@ package kms
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
@@ -27,6 +28,7 @@ import (
 	"github.com/greenpau/go-authcrunch/pkg/user"
 	"go.uber.org/zap"
 	"strings"
+	"sync"
 )
 
 var (
@@ -40,11 +42,13 @@ var (
 // CryptoKeyStore constains keys assembled for a specific purpose, i.e. signing or
 // validation.
 type CryptoKeyStore struct {
+	mu         sync.RWMutex
 	keys       []*CryptoKey
 	signKeys   []*CryptoKey
 	verifyKeys []*CryptoKey
 	logger     *zap.Logger
 	defaults   map[string]interface{}
+	rotation   *keyRotation
 }
 
 // NewCryptoKeyStore returns a new instance of CryptoKeyStore
@@ -73,6 +77,24 @@ func (ks *CryptoKeyStore) AddDefaults(m map[string]interface{}) error {
 			ks.defaults[k] = v.(string)
 		case "token_lifetime":
 			ks.defaults[k] = int(v.(float64))
+		case "refresh_token_lifetime":
+			switch lifetime := v.(type) {
+			case int:
+				ks.defaults[k] = lifetime
+			case float64:
+				ks.defaults[k] = int(lifetime)
+			default:
+				return errors.ErrCryptoKeyStoreRefreshTokenLifetime.WithArgs(v)
+			}
+		case "key_rotation_interval":
+			switch interval := v.(type) {
+			case int:
+				ks.defaults[k] = interval
+			case float64:
+				ks.defaults[k] = int(interval)
+			default:
+				return errors.ErrCryptoKeyStoreKeyRotationInterval.WithArgs(v)
+			}
 		default:
 			ks.defaults[k] = v
 		}
@@ -83,8 +105,6 @@ func (ks *CryptoKeyStore) AddDefaults(m map[string]interface{}) error {
 // AutoGenerate auto-generates public-private key pair capable of both
 // signing and verifying tokens.
 func (ks *CryptoKeyStore) AutoGenerate(tag, algo string) error {
-	var generated bool
-	var kb string
 	cfg := &CryptoKeyConfig{
 		ID:            "0",
 		Usage:         "sign-verify",
@@ -103,10 +123,41 @@ func (ks *CryptoKeyStore) AutoGenerate(tag, algo string) error {
 		}
 	}
 
-	if len(ks.keys) > 0 {
+	if len(ks.GetKeys()) > 0 {
 		return errors.ErrCryptoKeyStoreAutoGenerateNotAvailable
 	}
 
+	kb, err := generatePrivateKeyPEM(algo)
+	if err != nil {
+		return err
+	}
+
+	if err := shared.Buffer.Add(tag, kb); err != nil {
+		if err.Error() != "not empty" {
+			return errors.ErrCryptoKeyStoreAutoGenerateFailed.WithArgs(err)
+		}
+		kb, err = shared.Buffer.Get(tag)
+		if err != nil {
+			return errors.ErrCryptoKeyStoreAutoGenerateFailed.WithArgs(err)
+		}
+	}
+	key, err := extractKey([]byte(kb), cfg)
+	if err != nil {
+		return errors.ErrCryptoKeyStoreAutoGenerateFailed.WithArgs(err)
+	}
+
+	key.enableUsage()
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = append(ks.keys, key)
+	ks.signKeys = append(ks.signKeys, key)
+	ks.verifyKeys = append(ks.verifyKeys, key)
+	return nil
+}
+
+// generatePrivateKeyPEM returns PEM-encoded private key generated for the
+// provided signing method.
+func generatePrivateKeyPEM(algo string) (string, error) {
 	for i := 1; i < 5; i++ {
 		switch algo {
 		case "ES512":
@@ -131,52 +182,53 @@ func (ks *CryptoKeyStore) AutoGenerate(tag, algo string) error {
 			if pemBytes == nil {
 				break
 			}
-			kb = string(pemBytes)
-			generated = true
+			return string(pemBytes), nil
+		case "EdDSA":
+			_, priv, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				break
+			}
+			derBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+			if err != nil {
+				break
+			}
+			pemBytes := pem.EncodeToMemory(
+				&pem.Block{
+					Type:  "PRIVATE KEY",
+					Bytes: derBytes,
+				},
+			)
+			if pemBytes == nil {
+				break
+			}
+			return string(pemBytes), nil
 		default:
-			return errors.ErrCryptoKeyStoreAutoGenerateAlgo.WithArgs(algo)
+			return "", errors.ErrCryptoKeyStoreAutoGenerateAlgo.WithArgs(algo)
 		}
 	}
-
-	if !generated {
-		return errors.ErrCryptoKeyStoreAutoGenerateFailed.WithArgs("failed")
-	}
-
-	if err := shared.Buffer.Add(tag, kb); err != nil {
-		if err.Error() != "not empty" {
-			return errors.ErrCryptoKeyStoreAutoGenerateFailed.WithArgs(err)
-		}
-		kb, err = shared.Buffer.Get(tag)
-		if err != nil {
-			return errors.ErrCryptoKeyStoreAutoGenerateFailed.WithArgs(err)
-		}
-	}
-	key, err := extractKey([]byte(kb), cfg)
-	if err != nil {
-		return errors.ErrCryptoKeyStoreAutoGenerateFailed.WithArgs(err)
-	}
-
-	key.enableUsage()
-	ks.keys = append(ks.keys, key)
-	ks.signKeys = append(ks.signKeys, key)
-	ks.verifyKeys = append(ks.verifyKeys, key)
-	return nil
+	return "", errors.ErrCryptoKeyStoreAutoGenerateFailed.WithArgs("failed")
 }
 
 // GetKeys returns CryptoKey instances from CryptoKeyStore.
 func (ks *CryptoKeyStore) GetKeys() []*CryptoKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
 	return ks.keys
 }
 
 // GetSignKeys returns CryptoKey instances with key signing capabilities
 // from CryptoKeyStore.
 func (ks *CryptoKeyStore) GetSignKeys() []*CryptoKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
 	return ks.signKeys
 }
 
 // GetVerifyKeys returns CryptoKey instances with key verification capabilities
 // from CryptoKeyStore.
 func (ks *CryptoKeyStore) GetVerifyKeys() []*CryptoKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
 	return ks.verifyKeys
 }
 
@@ -198,7 +250,7 @@ func (ks *CryptoKeyStore) AddKeysWithConfigs(cfgs []*CryptoKeyConfig) error {
 // HasVerifyKeys returns true if CryptoKeyStore has key verification
 // capabilities.
 func (ks *CryptoKeyStore) HasVerifyKeys() error {
-	if len(ks.verifyKeys) > 0 {
+	if len(ks.GetVerifyKeys()) > 0 {
 		return nil
 	}
 	return errors.ErrCryptoKeyStoreNoVerifyKeysFound
@@ -207,7 +259,7 @@ func (ks *CryptoKeyStore) HasVerifyKeys() error {
 // HasSignKeys returns true if CryptoKeyStore has key signing
 // capabilities.
 func (ks *CryptoKeyStore) HasSignKeys() error {
-	if len(ks.signKeys) > 0 {
+	if len(ks.GetSignKeys()) > 0 {
 		return nil
 	}
 	return errors.ErrCryptoKeyStoreNoSignKeysFound
@@ -228,6 +280,8 @@ func (ks *CryptoKeyStore) AddKey(k *CryptoKey) error {
 	if k == nil {
 		return errors.ErrCryptoKeyStoreAddKeyNil
 	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
 	if k.Sign != nil {
 		if k.Sign.Capable {
 			ks.signKeys = append(ks.signKeys, k)
@@ -245,9 +299,33 @@ func (ks *CryptoKeyStore) AddKey(k *CryptoKey) error {
 	return nil
 }
 
+// ReplaceKeys replaces CryptoKey instances of CryptoKeyStore with the
+// provided keys.
+func (ks *CryptoKeyStore) ReplaceKeys(keys []*CryptoKey) error {
+	var allKeys, signKeys, verifyKeys []*CryptoKey
+	for _, k := range keys {
+		if k == nil || (k.Verify == nil && k.Sign == nil) {
+			return errors.ErrCryptoKeyStoreAddKeyNil
+		}
+		if k.Sign != nil && k.Sign.Capable {
+			signKeys = append(signKeys, k)
+		}
+		if k.Verify != nil && k.Verify.Capable {
+			verifyKeys = append(verifyKeys, k)
+		}
+		allKeys = append(allKeys, k)
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = allKeys
+	ks.signKeys = signKeys
+	ks.verifyKeys = verifyKeys
+	return nil
+}
+
 // ParseToken parses JWT token and returns User instance.
 func (ks *CryptoKeyStore) ParseToken(ar *requests.AuthorizationRequest) (*user.User, error) {
-	for _, k := range ks.verifyKeys {
+	for _, k := range resolveRotatedKeys(ks.GetVerifyKeys()) {
 		if _, exists := reservedTokenNames[ar.Token.Name]; !exists {
 			if ar.Token.Name != k.Verify.Token.Name {
 				continue
@@ -291,7 +369,7 @@ func (ks *CryptoKeyStore) ParseToken(ar *requests.AuthorizationRequest) (*user.U
 
 // SignToken signs user claims and add signed token to user identity.
 func (ks *CryptoKeyStore) SignToken(tokenName, signMethod interface{}, usr *user.User) error {
-	for _, k := range ks.signKeys {
+	for _, k := range ks.GetSignKeys() {
 		if tokenName != nil {
 			if tokenName.(string) != k.Sign.Token.Name {
 				continue
@@ -308,9 +386,21 @@ func (ks *CryptoKeyStore) SignToken(tokenName, signMethod interface{}, usr *user
 	return errors.ErrCryptoKeyStoreSignTokenFailed
 }
 
+// GetRefreshTokenLifetime returns lifetime for refresh tokens.
+func (ks *CryptoKeyStore) GetRefreshTokenLifetime() int {
+	if ks.defaults != nil {
+		if v, exists := ks.defaults["refresh_token_lifetime"]; exists {
+			if lifetime := v.(int); lifetime > 0 {
+				return lifetime
+			}
+		}
+	}
+	return defaultRefreshTokenLifetime
+}
+
 // GetTokenLifetime returns lifetime for a signed token.
 func (ks *CryptoKeyStore) GetTokenLifetime(tokenName, signMethod interface{}) int {
-	for _, k := range ks.signKeys {
+	for _, k := range ks.GetSignKeys() {
 		if tokenName != nil {
 			if tokenName.(string) != k.Sign.Token.Name {
 				continue
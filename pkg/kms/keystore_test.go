@@ -310,6 +310,11 @@ func TestCryptoKeyStoreAutoGenerate(t *testing.T) {
 			// shouldErr: true,
 			//err:       fmt.Errorf(`kms: file "foo" is not supported due to extension type`),
 		},
+		{
+			name:      "generate eddsa key pair",
+			tag:       "eddsa",
+			algorithm: "EdDSA",
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -320,6 +325,88 @@ func TestCryptoKeyStoreAutoGenerate(t *testing.T) {
 			if tests.EvalErrWithLog(t, err, nil, tc.shouldErr, tc.err, msgs) {
 				return
 			}
+			usr := newTestUser()
+			if err := ks.SignToken(nil, nil, usr); err != nil {
+				t.Fatalf("unexpected sign error: %v", err)
+			}
+			ar := requests.NewAuthorizationRequest()
+			ar.Token.Name = "access_token"
+			ar.Token.Payload = usr.Token
+			if _, err := ks.ParseToken(ar); err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCryptoKeyStoreRefreshTokenLifetime(t *testing.T) {
+	var testcases = []struct {
+		name      string
+		config    string
+		want      int
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "default refresh token lifetime",
+			want: 604800,
+		},
+		{
+			name:   "custom refresh token lifetime",
+			config: "default token refresh_lifetime 86400",
+			want:   86400,
+		},
+		{
+			name:      "malformed refresh token lifetime",
+			config:    "default token refresh_lifetime foo",
+			shouldErr: true,
+			err: errors.ErrCryptoKeyConfigEntryInvalid.WithArgs(
+				"default token refresh_lifetime foo",
+				fmt.Errorf(`strconv.Atoi: parsing "foo": invalid syntax`),
+			),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			ks := NewCryptoKeyStore()
+			if tc.config != "" {
+				m, err := ParseCryptoKeyStoreConfig(tc.config)
+				if tests.EvalErrWithLog(t, err, nil, tc.shouldErr, tc.err, msgs) {
+					return
+				}
+				if err := ks.AddDefaults(m); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			tests.EvalObjectsWithLog(t, "refresh token lifetime", tc.want, ks.GetRefreshTokenLifetime(), msgs)
 		})
 	}
 }
+
+func TestCryptoKeyStoreJWKS(t *testing.T) {
+	configs, err := ParseCryptoKeyConfigs(`
+        crypto key rsa1 verify from file ./../../testdata/rskeys/test_2_pub.pem
+        crypto key ec1 sign-verify from file ./../../testdata/ecdsakeys/test_1_pri.pem
+        crypto key ed1 sign-verify from file ./../../testdata/edkeys/test_1_pri.pem
+        crypto key hs1 sign-verify foobar
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewCryptoKeyStore()
+	if err := ks.AddKeysWithConfigs(configs); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, k := range ks.GetJWKS().Keys {
+		got = append(got, fmt.Sprintf("%s %s %s %s", k.KeyID, k.KeyType, k.Curve, k.Algorithm))
+	}
+	want := []string{
+		"rsa1 RSA  RS512",
+		"ec1 EC P-256 ES256",
+		"ed1 OKP Ed25519 EdDSA",
+	}
+	tests.EvalObjects(t, "jwks", want, got)
+	tests.EvalObjects(t, "signing methods", []string{"ES256", "EdDSA", "HS512"}, ks.GetSigningMethods())
+}
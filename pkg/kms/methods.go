@@ -30,18 +30,26 @@ var (
 		"ES256": "ecdsa",
 		"ES384": "ecdsa",
 		"ES512": "ecdsa",
+		"EdDSA": "eddsa",
 	}
 
 	algoMethodMap = map[string][]string{
 		"hmac":  []string{"HS512", "HS384", "HS256"},
 		"rsa":   []string{"RS512", "RS384", "RS256"},
 		"ecdsa": []string{"ES512", "ES384", "ES256"},
+		"eddsa": []string{"EdDSA"},
 	}
+
+	// jwksSigningMethods are the methods of the keys found in remote key sets.
+	jwksSigningMethods = []string{"RS512", "RS384", "RS256", "ES512", "ES384", "ES256", "EdDSA"}
 )
 
 // getSigningMethodAlias returns alias for the provided signing method.
 func getSigningMethodAlias(s string) string {
 	s = strings.ToUpper(s)
+	if s == "EDDSA" {
+		s = "EdDSA"
+	}
 	if v, exists := signingMethods[s]; exists {
 		arr := strings.SplitN(v, ",", 2)
 		return strings.TrimSpace(arr[0])
@@ -0,0 +1,410 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultKeyRotationAlgorithm = "ES512"
+	rotatedKeyFilePrefix        = "k"
+	rotatedKeyFileExt           = ".pem"
+)
+
+// rotatedKeys holds the verification keys of the signing keys rotated by
+// the keystores of the process. The keystores without configured keys,
+// e.g. the ones of gatekeepers, verify the tokens signed by the rotated
+// keys, in the same way they share the automatically generated key.
+var rotatedKeys = &rotatedKeySet{
+	keys: make(map[*CryptoKeyStore][]*CryptoKey),
+}
+
+type rotatedKeySet struct {
+	mu   sync.RWMutex
+	keys map[*CryptoKeyStore][]*CryptoKey
+}
+
+func (s *rotatedKeySet) set(ks *CryptoKeyStore, keys []*CryptoKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[ks] = keys
+}
+
+func (s *rotatedKeySet) remove(ks *CryptoKeyStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, ks)
+}
+
+func (s *rotatedKeySet) get() []*CryptoKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var keys []*CryptoKey
+	for _, k := range s.keys {
+		keys = append(keys, k...)
+	}
+	return keys
+}
+
+// keyRotation holds the state of scheduled signing key rotation. A new
+// signing key is generated at the start of each rotation period. The key
+// ID is derived from the start of the period, i.e. the replicas sharing
+// the key directory agree on the key used for signing.
+type keyRotation struct {
+	mu        sync.Mutex
+	interval  int64
+	algorithm string
+	directory string
+	keys      map[int64]*CryptoKey
+	handlers  []func([]*CryptoKey)
+	now       func() time.Time
+	running   bool
+	stop      chan struct{}
+}
+
+// KeyRotationEnabled returns true when the defaults of CryptoKeyStore
+// include key rotation interval.
+func (ks *CryptoKeyStore) KeyRotationEnabled() bool {
+	if ks.defaults == nil {
+		return false
+	}
+	if v, exists := ks.defaults["key_rotation_interval"]; exists {
+		if interval, ok := v.(int); ok && interval > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (ks *CryptoKeyStore) getKeyRotation() (*keyRotation, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.rotation != nil {
+		return ks.rotation, nil
+	}
+	if !ks.KeyRotationEnabled() {
+		return nil, errors.ErrCryptoKeyStoreKeyRotationDisabled
+	}
+	r := &keyRotation{
+		interval:  int64(ks.defaults["key_rotation_interval"].(int)),
+		algorithm: defaultKeyRotationAlgorithm,
+		keys:      make(map[int64]*CryptoKey),
+		now:       time.Now,
+	}
+	if v, exists := ks.defaults["key_rotation_algorithm"]; exists {
+		r.algorithm = v.(string)
+	}
+	if v, exists := ks.defaults["key_rotation_directory"]; exists {
+		r.directory = v.(string)
+	}
+	ks.rotation = r
+	return r, nil
+}
+
+// OnKeyRotation adds a function called with the verification keys of
+// CryptoKeyStore after each key rotation.
+func (ks *CryptoKeyStore) OnKeyRotation(fn func([]*CryptoKey)) error {
+	r, err := ks.getKeyRotation()
+	if err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	r.handlers = append(r.handlers, fn)
+	return nil
+}
+
+// RotateKeys makes the key of the current rotation period the signing key
+// of CryptoKeyStore. The key is loaded from the key rotation directory or
+// generated. The keys of previous periods remain available for
+// verification until the tokens signed by them expire.
+func (ks *CryptoKeyStore) RotateKeys() error {
+	r, err := ks.getKeyRotation()
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cfg := ks.newRotatedKeyConfig()
+	now := r.now().Unix()
+	current := now - now%r.interval
+	retention := r.interval + int64(cfg.TokenLifetime)
+
+	if r.directory != "" {
+		if err := os.MkdirAll(r.directory, 0700); err != nil {
+			return errors.ErrCryptoKeyStoreKeyRotationFailed.WithArgs(err)
+		}
+		if err := r.loadKeys(cfg, now, retention); err != nil {
+			return errors.ErrCryptoKeyStoreKeyRotationFailed.WithArgs(err)
+		}
+	}
+
+	if _, exists := r.keys[current]; !exists {
+		k, err := r.generateKey(cfg, current)
+		if err != nil {
+			return errors.ErrCryptoKeyStoreKeyRotationFailed.WithArgs(err)
+		}
+		r.keys[current] = k
+	}
+
+	var periods []int64
+	for period := range r.keys {
+		if period+retention <= now {
+			delete(r.keys, period)
+			r.removeKeyFile(period)
+			continue
+		}
+		periods = append(periods, period)
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i] > periods[j]
+	})
+
+	var keys, verifyKeys []*CryptoKey
+	for _, period := range periods {
+		k := r.keys[period]
+		keys = append(keys, k)
+		if k.Verify.Capable {
+			verifyKeys = append(verifyKeys, k)
+		}
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.signKeys = []*CryptoKey{r.keys[current]}
+	ks.verifyKeys = verifyKeys
+	handlers := r.handlers
+	ks.mu.Unlock()
+	rotatedKeys.set(ks, verifyKeys)
+
+	if ks.logger != nil {
+		ks.logger.Debug(
+			"Rotated signing keys",
+			zap.String("signing_key_id", r.keys[current].Config.ID),
+			zap.Int("key_count", len(keys)),
+		)
+	}
+
+	for _, fn := range handlers {
+		fn(verifyKeys)
+	}
+	return nil
+}
+
+// StartKeyRotation starts rotating the keys of CryptoKeyStore at the start
+// of each rotation period.
+func (ks *CryptoKeyStore) StartKeyRotation() error {
+	r, err := ks.getKeyRotation()
+	if err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if r.running {
+		return nil
+	}
+	r.running = true
+	r.stop = make(chan struct{})
+	go ks.runKeyRotation(r, r.stop)
+	return nil
+}
+
+// StopKeyRotation stops the key rotation started by StartKeyRotation. The
+// rotated keys are no longer shared with the other keystores.
+func (ks *CryptoKeyStore) StopKeyRotation() {
+	rotatedKeys.remove(ks)
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.rotation == nil || !ks.rotation.running {
+		return
+	}
+	close(ks.rotation.stop)
+	ks.rotation.running = false
+}
+
+// AddRotatedKeys adds the verification keys of the signing keys rotated by
+// the other keystores of the process to CryptoKeyStore. The keys are
+// resolved when a token is being parsed, i.e. the keys rotated later are
+// included.
+func (ks *CryptoKeyStore) AddRotatedKeys() error {
+	cfg := ks.newRotatedKeyConfig()
+	cfg.ID = "rotated"
+	cfg.Usage = "verify"
+	k := newCryptoKey()
+	k.Config = cfg
+	k.Verify.Capable = true
+	k.Verify.Secret = rotatedKeys
+	k.Verify.Token.PreferredMethods = jwksSigningMethods
+	k.enableUsage()
+	return ks.AddKey(k)
+}
+
+// resolveRotatedKeys replaces the keys added by AddRotatedKeys with the
+// verification keys of the signing keys rotated in the process.
+func resolveRotatedKeys(keys []*CryptoKey) []*CryptoKey {
+	var resolved []*CryptoKey
+	for _, k := range keys {
+		if s, ok := k.Verify.Secret.(*rotatedKeySet); ok {
+			resolved = append(resolved, s.get()...)
+			continue
+		}
+		resolved = append(resolved, k)
+	}
+	return resolved
+}
+
+func (ks *CryptoKeyStore) runKeyRotation(r *keyRotation, stop chan struct{}) {
+	for {
+		now := r.now()
+		next := time.Unix(now.Unix()-now.Unix()%r.interval+r.interval, 0)
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := ks.RotateKeys(); err != nil && ks.logger != nil {
+			ks.logger.Error("Failed rotating signing keys", zap.Error(err))
+		}
+	}
+}
+
+func (ks *CryptoKeyStore) newRotatedKeyConfig() *CryptoKeyConfig {
+	cfg := &CryptoKeyConfig{
+		Usage:         "sign-verify",
+		TokenName:     defaultTokenName,
+		Source:        "config",
+		TokenLifetime: defaultTokenLifetime,
+		parsed:        true,
+	}
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if v, exists := ks.defaults["token_name"]; exists {
+		cfg.TokenName = v.(string)
+	}
+	if v, exists := ks.defaults["token_lifetime"]; exists {
+		cfg.TokenLifetime = v.(int)
+	}
+	return cfg
+}
+
+// getRotatedKeyID returns the ID of the key of a rotation period.
+func getRotatedKeyID(period int64) string {
+	return rotatedKeyFilePrefix + strconv.FormatInt(period, 10)
+}
+
+func (r *keyRotation) getKeyFilePath(period int64) string {
+	return filepath.Join(r.directory, getRotatedKeyID(period)+rotatedKeyFileExt)
+}
+
+func (r *keyRotation) newKey(kb []byte, cfg *CryptoKeyConfig, period int64) (*CryptoKey, error) {
+	kcfg := *cfg
+	kcfg.ID = getRotatedKeyID(period)
+	k, err := extractKey(kb, &kcfg)
+	if err != nil {
+		return nil, err
+	}
+	k.enableUsage()
+	return k, nil
+}
+
+// loadKeys loads the keys persisted in the key rotation directory, e.g.
+// by other replicas or before restart.
+func (r *keyRotation) loadKeys(cfg *CryptoKeyConfig, now, retention int64) error {
+	entries, err := ioutil.ReadDir(r.directory)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, rotatedKeyFilePrefix) || !strings.HasSuffix(name, rotatedKeyFileExt) {
+			continue
+		}
+		period, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, rotatedKeyFilePrefix), rotatedKeyFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		if _, exists := r.keys[period]; exists {
+			continue
+		}
+		if period+retention <= now {
+			continue
+		}
+		kb, err := ioutil.ReadFile(filepath.Join(r.directory, name))
+		if err != nil {
+			return err
+		}
+		k, err := r.newKey(kb, cfg, period)
+		if err != nil {
+			return err
+		}
+		r.keys[period] = k
+	}
+	return nil
+}
+
+// generateKey generates the key of a rotation period. When the key
+// rotation directory is configured, the key is persisted. If another
+// replica persisted the key first, that key is used instead.
+func (r *keyRotation) generateKey(cfg *CryptoKeyConfig, period int64) (*CryptoKey, error) {
+	kb, err := generatePrivateKeyPEM(r.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if r.directory != "" {
+		fp := r.getKeyFilePath(period)
+		tmp, err := ioutil.TempFile(r.directory, ".tmp-"+getRotatedKeyID(period))
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := tmp.WriteString(kb); err != nil {
+			tmp.Close()
+			return nil, err
+		}
+		if err := tmp.Close(); err != nil {
+			return nil, err
+		}
+		if err := os.Link(tmp.Name(), fp); err != nil {
+			if !os.IsExist(err) {
+				return nil, err
+			}
+			b, err := ioutil.ReadFile(fp)
+			if err != nil {
+				return nil, err
+			}
+			kb = string(b)
+		}
+	}
+	return r.newKey([]byte(kb), cfg, period)
+}
+
+func (r *keyRotation) removeKeyFile(period int64) {
+	if r.directory == "" {
+		return
+	}
+	os.Remove(r.getKeyFilePath(period))
+}
@@ -0,0 +1,267 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"fmt"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"github.com/greenpau/go-authcrunch/pkg/requests"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRotatingKeyStore(t *testing.T, cfg string, now *time.Time) *CryptoKeyStore {
+	m, err := ParseCryptoKeyStoreConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewCryptoKeyStore()
+	if err := ks.AddDefaults(m); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ks.getKeyRotation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.now = func() time.Time {
+		return *now
+	}
+	return ks
+}
+
+func signTestToken(t *testing.T, ks *CryptoKeyStore) string {
+	usr := newTestUser()
+	if err := ks.SignToken(nil, nil, usr); err != nil {
+		t.Fatalf("unexpected sign error: %v", err)
+	}
+	return usr.Token
+}
+
+func parseTestToken(ks *CryptoKeyStore, token string) error {
+	ar := requests.NewAuthorizationRequest()
+	ar.Token.Name = "access_token"
+	ar.Token.Payload = token
+	_, err := ks.ParseToken(ar)
+	return err
+}
+
+func getTestKeyIDs(keys []*CryptoKey) []string {
+	var ids []string
+	for _, k := range keys {
+		ids = append(ids, k.Config.ID)
+	}
+	return ids
+}
+
+func TestCryptoKeyStoreRotateKeys(t *testing.T) {
+	now := time.Unix(998000, 0)
+	ks := newTestRotatingKeyStore(t, strings.Join([]string{
+		"default key rotation interval 3600",
+		"default key rotation algorithm EdDSA",
+	}, "\n"), &now)
+	if err := ks.AddDefaults(map[string]interface{}{"token_lifetime": float64(600)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var rotations [][]string
+	if err := ks.OnKeyRotation(func(keys []*CryptoKey) {
+		rotations = append(rotations, getTestKeyIDs(keys))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	tests.EvalObjects(t, "initial sign keys", []string{"k997200"}, getTestKeyIDs(ks.GetSignKeys()))
+	tests.EvalObjects(t, "initial sign method", "EdDSA", ks.GetSignKeys()[0].Sign.Token.DefaultMethod)
+	oldToken := signTestToken(t, ks)
+
+	// The key is not replaced within the rotation period.
+	now = now.Add(30 * time.Minute)
+	if err := ks.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	tests.EvalObjects(t, "sign keys within period", []string{"k997200"}, getTestKeyIDs(ks.GetSignKeys()))
+
+	// The previous key remains for verification after rotation.
+	now = time.Unix(1001000, 0)
+	if err := ks.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	tests.EvalObjects(t, "sign keys after rotation", []string{"k1000800"}, getTestKeyIDs(ks.GetSignKeys()))
+	tests.EvalObjects(t, "verify keys after rotation", []string{"k1000800", "k997200"}, getTestKeyIDs(ks.GetVerifyKeys()))
+	tests.EvalObjects(t, "jwks keys after rotation", 2, len(ks.GetJWKS().Keys))
+	if err := parseTestToken(ks, oldToken); err != nil {
+		t.Fatalf("unexpected parse error for token signed by previous key: %v", err)
+	}
+	newToken := signTestToken(t, ks)
+	if err := parseTestToken(ks, newToken); err != nil {
+		t.Fatalf("unexpected parse error for token signed by current key: %v", err)
+	}
+
+	// The previous key is removed once its tokens have expired.
+	now = time.Unix(997200+3600+600, 0)
+	if err := ks.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	tests.EvalObjects(t, "verify keys after expiry", []string{"k1000800"}, getTestKeyIDs(ks.GetVerifyKeys()))
+	tests.EvalErr(t, parseTestToken(ks, oldToken), nil, true, errors.ErrCryptoKeyStoreParseTokenFailed)
+
+	tests.EvalObjects(t, "rotations", [][]string{
+		{"k997200"},
+		{"k997200"},
+		{"k1000800", "k997200"},
+		{"k1000800"},
+	}, rotations)
+}
+
+func TestCryptoKeyStoreRotateKeysWithDirectory(t *testing.T) {
+	dir, err := tests.TempDir("TestCryptoKeyStoreRotateKeysWithDirectory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := strings.Join([]string{
+		"default key rotation interval 3600",
+		"default key rotation directory " + dir,
+	}, "\n")
+	now := time.Unix(998000, 0)
+
+	// The replicas sharing the directory sign with the same key.
+	replicas := []*CryptoKeyStore{
+		newTestRotatingKeyStore(t, cfg, &now),
+		newTestRotatingKeyStore(t, cfg, &now),
+	}
+	for _, ks := range replicas {
+		if err := ks.RotateKeys(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests.EvalObjects(t, "replica sign keys", getTestKeyIDs(replicas[0].GetSignKeys()), getTestKeyIDs(replicas[1].GetSignKeys()))
+	if err := parseTestToken(replicas[1], signTestToken(t, replicas[0])); err != nil {
+		t.Fatalf("unexpected parse error for token signed by replica: %v", err)
+	}
+
+	// The keys survive restart.
+	now = now.Add(time.Hour)
+	if err := replicas[0].RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	token := signTestToken(t, replicas[0])
+	restarted := newTestRotatingKeyStore(t, cfg, &now)
+	if err := restarted.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	tests.EvalObjects(t, "restarted verify keys", []string{"k1000800", "k997200"}, getTestKeyIDs(restarted.GetVerifyKeys()))
+	if err := parseTestToken(restarted, token); err != nil {
+		t.Fatalf("unexpected parse error after restart: %v", err)
+	}
+
+	// The expired keys are removed from the directory.
+	now = now.Add(3 * time.Hour)
+	if err := restarted.RotateKeys(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	var got []string
+	for _, fp := range files {
+		got = append(got, filepath.Base(fp))
+		if _, err := ioutil.ReadFile(fp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests.EvalObjects(t, "key files", []string{"k1011600.pem"}, got)
+}
+
+func TestCryptoKeyStoreKeyRotationConfig(t *testing.T) {
+	var testcases = []struct {
+		name      string
+		config    string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name: "key rotation settings",
+			config: strings.Join([]string{
+				"default key rotation interval 86400",
+				"default key rotation algorithm ES512",
+				"default key rotation directory /var/lib/authp/keys",
+			}, "\n"),
+			want: map[string]interface{}{
+				"key_rotation_interval":  86400,
+				"key_rotation_algorithm": "ES512",
+				"key_rotation_directory": "/var/lib/authp/keys",
+			},
+		},
+		{
+			name:      "key rotation with unsupported algorithm",
+			config:    `default key rotation algorithm HS512`,
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyConfigEntryInvalid.WithArgs("default key rotation algorithm HS512", "unsupported key rotation algorithm: HS512"),
+		},
+		{
+			name:      "key rotation with invalid interval",
+			config:    `default key rotation interval 0`,
+			shouldErr: true,
+			err:       errors.ErrCryptoKeyConfigEntryInvalid.WithArgs("default key rotation interval 0", "key rotation interval must be positive"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := []string{fmt.Sprintf("test name: %s", tc.name)}
+			got, err := ParseCryptoKeyStoreConfig(tc.config)
+			if tests.EvalErrWithLog(t, err, nil, tc.shouldErr, tc.err, msgs) {
+				return
+			}
+			tests.EvalObjectsWithLog(t, "config", tc.want, got, msgs)
+		})
+	}
+}
+
+func TestCryptoKeyStoreStartKeyRotation(t *testing.T) {
+	ks := NewCryptoKeyStore()
+	if err := ks.StartKeyRotation(); err == nil {
+		t.Fatalf("expected error when key rotation is not configured")
+	}
+	if err := ks.AddDefaults(map[string]interface{}{"key_rotation_interval": float64(1)}); err != nil {
+		t.Fatal(err)
+	}
+	rotated := make(chan []string, 10)
+	ks.OnKeyRotation(func(keys []*CryptoKey) {
+		rotated <- getTestKeyIDs(keys)
+	})
+	if err := ks.StartKeyRotation(); err != nil {
+		t.Fatal(err)
+	}
+	defer ks.StopKeyRotation()
+	select {
+	case ids := <-rotated:
+		if len(ids) == 0 {
+			t.Fatalf("expected rotated keys")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("keys were not rotated")
+	}
+}
@@ -159,6 +159,7 @@ func (e *EmailProvider) Validate() error {
 		for k := range e.Templates {
 			switch k {
 			case "password_recovery":
+			case "email_verification":
 			case "registration_confirmation":
 			case "registration_ready":
 			case "registration_verdict":
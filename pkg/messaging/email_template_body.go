@@ -67,6 +67,9 @@ var EmailTemplateBody = map[string]string{
       Your registration has been declined.
     {{- end -}}
     </p>
+    {{- if .reason }}
+    <p>Reason: {{ .reason }}</p>
+    {{- end }}
     <p>The registation metadata follows:</p>
     <ul style="list-style-type: disc">
       <li>Username: <code>{{ .username }}</code></li>
@@ -74,5 +77,55 @@ var EmailTemplateBody = map[string]string{
       <li>Timestamp: {{ .timestamp }}</li>
     </ul>
   </body>
+</html>`,
+	"en/password_recovery": `<html>
+  <body>
+    <p>
+      A password reset was requested for your account. Please reset your
+      password by clicking this
+      <a href="{{ .recovery_url }}/{{ .recovery_id }}">link</a>
+      within the next 15 minutes. The link can be used only once.
+    </p>
+
+    <p>
+      If you did not request a password reset, please ignore this email.
+      Your password will not be changed.
+    </p>
+
+    <p>The request metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Session ID: {{ .session_id }}</li>
+      <li>Request ID: {{ .request_id }}</li>
+      <li>Username: <code>{{ .username }}</code></li>
+      <li>Email: <code>{{ .email }}</code></li>
+      <li>IP Address: <code>{{ .src_ip }}</code></li>
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+  </body>
+</html>`,
+	"en/email_verification": `<html>
+  <body>
+    <p>
+      Please confirm that this email address belongs to your account by
+      clicking this
+      <a href="{{ .verification_url }}/{{ .verification_token }}">link</a>
+      within the next 24 hours.
+    </p>
+
+    <p>
+      If you did not add this email address to your account, please ignore
+      this email.
+    </p>
+
+    <p>The request metadata follows:</p>
+    <ul style="list-style-type: disc">
+      <li>Session ID: {{ .session_id }}</li>
+      <li>Request ID: {{ .request_id }}</li>
+      <li>Username: <code>{{ .username }}</code></li>
+      <li>Email: <code>{{ .email }}</code></li>
+      <li>IP Address: <code>{{ .src_ip }}</code></li>
+      <li>Timestamp: {{ .timestamp }}</li>
+    </ul>
+  </body>
 </html>`,
 }
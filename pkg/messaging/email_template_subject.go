@@ -23,4 +23,6 @@ User Registration Approved
 {{- else -}}
 User Registration Declined
 {{- end -}}`,
+	"en/password_recovery":  `Password Recovery`,
+	"en/email_verification": `Email Address Verification`,
 }
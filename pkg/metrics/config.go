@@ -0,0 +1,94 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"crypto/subtle"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"net/http"
+	"strings"
+)
+
+const defaultPath = "/metrics"
+
+// Config is the configuration of metrics endpoint.
+type Config struct {
+	// Path is the path of the request URL at which the metrics are being
+	// served, e.g. /auth/metrics. When it is not set, the metrics are served
+	// at /metrics.
+	Path string `json:"path,omitempty" xml:"path,omitempty" yaml:"path,omitempty"`
+	// Token is the bearer token a scraper must present in the Authorization
+	// header.
+	Token string `json:"token,omitempty" xml:"token,omitempty" yaml:"token,omitempty"`
+	// AllowUnauthenticated enables the scraping without the token. The
+	// metrics include the login counts of every portal in the process.
+	AllowUnauthenticated bool `json:"allow_unauthenticated,omitempty" xml:"allow_unauthenticated,omitempty" yaml:"allow_unauthenticated,omitempty"`
+}
+
+// Validate validates Config.
+func (cfg *Config) Validate() error {
+	if cfg == nil {
+		return errors.ErrMetricsConfigNil
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+	if !strings.HasPrefix(cfg.Path, "/") {
+		return errors.ErrMetricsPathInvalid.WithArgs(cfg.Path)
+	}
+	if cfg.Token == "" && !cfg.AllowUnauthenticated {
+		return errors.ErrMetricsTokenNotFound
+	}
+	return nil
+}
+
+// Match returns true when the request is for the metrics endpoint.
+func (cfg *Config) Match(r *http.Request) bool {
+	if cfg == nil {
+		return false
+	}
+	path := cfg.Path
+	if path == "" {
+		path = defaultPath
+	}
+	return r.URL.Path == path
+}
+
+// Authorized returns true when the request carries the configured bearer
+// token, or when no token is configured and the unauthenticated access is
+// allowed.
+func (cfg *Config) Authorized(r *http.Request) bool {
+	if cfg.Token == "" {
+		return cfg.AllowUnauthenticated
+	}
+	hdr := r.Header.Get("Authorization")
+	if !strings.HasPrefix(hdr, "Bearer ") {
+		return false
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(hdr, "Bearer "))
+	return subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) == 1
+}
+
+// Serve writes the metrics of the registry to the response, provided the
+// request is authorized.
+func (cfg *Config) Serve(w http.ResponseWriter, r *http.Request, reg *Registry) {
+	if !cfg.Authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`401 Unauthorized`))
+		return
+	}
+	reg.ServeHTTP(w, r)
+}
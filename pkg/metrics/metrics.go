@@ -0,0 +1,164 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// The types of metrics.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultRegistry is the registry the portals and gatekeepers report to.
+var DefaultRegistry = NewRegistry()
+
+// Label is a name-value pair identifying a sample.
+type Label struct {
+	Name  string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Value string `json:"value,omitempty" xml:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// Sample is a single value of a metric.
+type Sample struct {
+	// Suffix is appended to the name of the metric, e.g. _bucket.
+	Suffix string   `json:"suffix,omitempty" xml:"suffix,omitempty" yaml:"suffix,omitempty"`
+	Labels []*Label `json:"labels,omitempty" xml:"labels,omitempty" yaml:"labels,omitempty"`
+	Value  float64  `json:"value,omitempty" xml:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// Metric is a family of samples sharing name, help and type.
+type Metric struct {
+	Name    string    `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	Help    string    `json:"help,omitempty" xml:"help,omitempty" yaml:"help,omitempty"`
+	Type    string    `json:"type,omitempty" xml:"type,omitempty" yaml:"type,omitempty"`
+	Samples []*Sample `json:"samples,omitempty" xml:"samples,omitempty" yaml:"samples,omitempty"`
+}
+
+// Collector produces metrics at the time they are being gathered.
+type Collector interface {
+	Collect() []*Metric
+}
+
+// CollectorFunc is a function implementing Collector.
+type CollectorFunc func() []*Metric
+
+// Collect returns the metrics produced by the function.
+func (f CollectorFunc) Collect() []*Metric {
+	return f()
+}
+
+// NewGauge returns a gauge with a single sample.
+func NewGauge(name, help string, value float64, labels ...*Label) *Metric {
+	return &Metric{
+		Name:    name,
+		Help:    help,
+		Type:    TypeGauge,
+		Samples: []*Sample{{Labels: labels, Value: value}},
+	}
+}
+
+// Registry holds the collectors exposed at metrics endpoints.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+// NewRegistry returns an instance of Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]Collector),
+	}
+}
+
+// Register adds the collector to the registry. The collector registered
+// earlier with the same key is replaced.
+func (reg *Registry) Register(key string, c Collector) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.collectors[key] = c
+}
+
+// Unregister removes the collector from the registry.
+func (reg *Registry) Unregister(key string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	delete(reg.collectors, key)
+}
+
+// Gather collects the metrics from the registered collectors. The samples
+// of the metrics with the same name are merged into a single family.
+func (reg *Registry) Gather() []*Metric {
+	reg.mu.RLock()
+	var keys []string
+	for k := range reg.collectors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var collectors []Collector
+	for _, k := range keys {
+		collectors = append(collectors, reg.collectors[k])
+	}
+	reg.mu.RUnlock()
+
+	families := make(map[string]*Metric)
+	var names []string
+	for _, c := range collectors {
+		for _, m := range c.Collect() {
+			if m == nil {
+				continue
+			}
+			family, exists := families[m.Name]
+			if !exists {
+				family = &Metric{Name: m.Name, Help: m.Help, Type: m.Type}
+				families[m.Name] = family
+				names = append(names, m.Name)
+			}
+			family.Samples = append(family.Samples, m.Samples...)
+		}
+	}
+	sort.Strings(names)
+	var metrics []*Metric
+	for _, name := range names {
+		metrics = append(metrics, families[name])
+	}
+	return metrics
+}
+
+// WriteText writes the metrics in Prometheus text exposition format.
+func (reg *Registry) WriteText(w io.Writer) error {
+	var b bytes.Buffer
+	for _, m := range reg.Gather() {
+		writeMetric(&b, m)
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// ServeHTTP writes the metrics to HTTP response.
+func (reg *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	reg.WriteText(w)
+}
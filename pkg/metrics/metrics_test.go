@@ -0,0 +1,201 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"github.com/greenpau/go-authcrunch/internal/tests"
+	"github.com/greenpau/go-authcrunch/pkg/errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	var testcases = []struct {
+		name string
+		init func(*Registry)
+		want string
+	}{
+		{
+			name: "counter with labels",
+			init: func(reg *Registry) {
+				c := NewCounterVec("test_logins_total", "The number of logins.", "realm", "outcome")
+				c.Inc("local", "success")
+				c.Inc("local", "success")
+				c.Add(3, "ldap", "failure")
+				c.Add(-1, "ldap", "failure")
+				reg.Register("logins", c)
+			},
+			want: strings.Join([]string{
+				"# HELP test_logins_total The number of logins.",
+				"# TYPE test_logins_total counter",
+				`test_logins_total{realm="ldap",outcome="failure"} 3`,
+				`test_logins_total{realm="local",outcome="success"} 2`,
+				"",
+			}, "\n"),
+		},
+		{
+			name: "histogram",
+			init: func(reg *Registry) {
+				h := NewHistogramVec("test_duration_seconds", "The duration.", []float64{1, 0.1}, "op")
+				h.Observe(0.05, "auth")
+				h.Observe(0.5, "auth")
+				h.Observe(2, "auth")
+				reg.Register("duration", h)
+			},
+			want: strings.Join([]string{
+				"# HELP test_duration_seconds The duration.",
+				"# TYPE test_duration_seconds histogram",
+				`test_duration_seconds_bucket{op="auth",le="0.1"} 1`,
+				`test_duration_seconds_bucket{op="auth",le="1"} 2`,
+				`test_duration_seconds_bucket{op="auth",le="+Inf"} 3`,
+				`test_duration_seconds_sum{op="auth"} 2.55`,
+				`test_duration_seconds_count{op="auth"} 3`,
+				"",
+			}, "\n"),
+		},
+		{
+			name: "gauges from multiple collectors merged and escaped",
+			init: func(reg *Registry) {
+				reg.Register("b", CollectorFunc(func() []*Metric {
+					return []*Metric{NewGauge("test_entries", "The number of\nentries.", 2, &Label{Name: "name", Value: `b"\`})}
+				}))
+				reg.Register("a", CollectorFunc(func() []*Metric {
+					return []*Metric{NewGauge("test_entries", "The number of\nentries.", 1, &Label{Name: "name", Value: "a"})}
+				}))
+				reg.Register("c", CollectorFunc(func() []*Metric {
+					return []*Metric{NewGauge("test_other", "", 5)}
+				}))
+				reg.Unregister("c")
+			},
+			want: strings.Join([]string{
+				`# HELP test_entries The number of\nentries.`,
+				"# TYPE test_entries gauge",
+				`test_entries{name="a"} 1`,
+				`test_entries{name="b\"\\"} 2`,
+				"",
+			}, "\n"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := NewRegistry()
+			tc.init(reg)
+			var b bytes.Buffer
+			if err := reg.WriteText(&b); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tests.EvalObjectsWithLog(t, "output", tc.want, b.String(), []string{b.String()})
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	var testcases = []struct {
+		name      string
+		config    *Config
+		path      string
+		token     string
+		want      map[string]interface{}
+		shouldErr bool
+		err       error
+	}{
+		{
+			name:   "serve metrics without token",
+			config: &Config{Path: "/auth/metrics", AllowUnauthenticated: true},
+			path:   "/auth/metrics",
+			want: map[string]interface{}{
+				"match":        true,
+				"status_code":  200,
+				"content_type": contentType,
+			},
+		},
+		{
+			name:      "reject config without token",
+			config:    &Config{},
+			shouldErr: true,
+			err:       errors.ErrMetricsTokenNotFound,
+		},
+		{
+			name:   "path suffix does not match",
+			config: &Config{Token: "foobar"},
+			path:   "/app/metrics",
+			want: map[string]interface{}{
+				"match": false,
+			},
+		},
+		{
+			name:   "serve metrics with valid token",
+			config: &Config{Path: "/stats", Token: "foobar"},
+			path:   "/stats",
+			token:  "foobar",
+			want: map[string]interface{}{
+				"match":        true,
+				"status_code":  200,
+				"content_type": contentType,
+			},
+		},
+		{
+			name:   "reject metrics request with invalid token",
+			config: &Config{Token: "foobar"},
+			path:   "/metrics",
+			token:  "barfoo",
+			want: map[string]interface{}{
+				"match":       true,
+				"status_code": 401,
+			},
+		},
+		{
+			name:   "path does not match",
+			config: &Config{Token: "foobar"},
+			path:   "/metrics/foo",
+			want: map[string]interface{}{
+				"match": false,
+			},
+		},
+		{
+			name:      "invalid path",
+			config:    &Config{Path: "metrics"},
+			shouldErr: true,
+			err:       errors.ErrMetricsPathInvalid.WithArgs("metrics"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tests.EvalErr(t, err, "config", tc.shouldErr, tc.err) {
+				return
+			}
+			reg := NewRegistry()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			got := make(map[string]interface{})
+			got["match"] = tc.config.Match(req)
+			if got["match"].(bool) {
+				w := httptest.NewRecorder()
+				tc.config.Serve(w, req, reg)
+				got["status_code"] = w.Code
+				if ct := w.Header().Get("Content-Type"); ct != "" {
+					got["content_type"] = ct
+				}
+			}
+			tests.EvalObjects(t, "response", tc.want, got)
+		})
+	}
+}
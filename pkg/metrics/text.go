@@ -0,0 +1,62 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func writeMetric(b *bytes.Buffer, m *Metric) {
+	if m.Help != "" {
+		b.WriteString("# HELP " + m.Name + " " + helpEscaper.Replace(m.Help) + "\n")
+	}
+	if m.Type != "" {
+		b.WriteString("# TYPE " + m.Name + " " + m.Type + "\n")
+	}
+	for _, s := range m.Samples {
+		b.WriteString(m.Name + s.Suffix)
+		if len(s.Labels) > 0 {
+			b.WriteString("{")
+			for i, l := range s.Labels {
+				if i > 0 {
+					b.WriteString(",")
+				}
+				b.WriteString(l.Name + `="` + labelEscaper.Replace(l.Value) + `"`)
+			}
+			b.WriteString("}")
+		}
+		b.WriteString(" " + formatValue(s.Value) + "\n")
+	}
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
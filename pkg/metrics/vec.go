@@ -0,0 +1,201 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the histogram
+// buckets used for request latencies.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type vecEntry struct {
+	labels  []*Label
+	value   float64
+	buckets []uint64
+	count   uint64
+}
+
+type vec struct {
+	mu      sync.Mutex
+	name    string
+	help    string
+	labels  []string
+	entries map[string]*vecEntry
+}
+
+func newVec(name, help string, labels []string) vec {
+	return vec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		entries: make(map[string]*vecEntry),
+	}
+}
+
+// entry returns the entry for the label values. The missing values are
+// treated as empty and the extra ones are ignored. The caller must hold
+// the lock.
+func (v *vec) entry(labelValues []string) (*vecEntry, bool) {
+	values := padValues(labelValues, len(v.labels))
+	key := strings.Join(values, "\xff")
+	if e, exists := v.entries[key]; exists {
+		return e, true
+	}
+	e := &vecEntry{}
+	for i, name := range v.labels {
+		e.labels = append(e.labels, &Label{Name: name, Value: values[i]})
+	}
+	v.entries[key] = e
+	return e, false
+}
+
+// sortedEntries returns the entries ordered by their label values. The
+// caller must hold the lock.
+func (v *vec) sortedEntries() []*vecEntry {
+	var keys []string
+	for k := range v.entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var entries []*vecEntry
+	for _, k := range keys {
+		entries = append(entries, v.entries[k])
+	}
+	return entries
+}
+
+// CounterVec is a set of counters partitioned by label values.
+type CounterVec struct {
+	vec
+}
+
+// NewCounterVec returns an instance of CounterVec.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{vec: newVec(name, help, labels)}
+}
+
+// Inc increments the counter for the label values by one.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds the value to the counter for the label values. Negative values
+// are ignored, because counters only go up.
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, _ := c.entry(labelValues)
+	e.value += value
+}
+
+// Get returns the value of the counter for the label values.
+func (c *CounterVec) Get(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, exists := c.entries[strings.Join(padValues(labelValues, len(c.labels)), "\xff")]
+	if !exists {
+		return 0
+	}
+	return e.value
+}
+
+// Collect returns the counters.
+func (c *CounterVec) Collect() []*Metric {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := &Metric{Name: c.name, Help: c.help, Type: TypeCounter}
+	for _, e := range c.sortedEntries() {
+		m.Samples = append(m.Samples, &Sample{Labels: e.labels, Value: e.value})
+	}
+	return []*Metric{m}
+}
+
+// HistogramVec is a set of histograms partitioned by label values.
+type HistogramVec struct {
+	vec
+	bounds []float64
+}
+
+// NewHistogramVec returns an instance of HistogramVec. When no bucket
+// bounds are provided, DefaultBuckets are used.
+func NewHistogramVec(name, help string, bounds []float64, labels ...string) *HistogramVec {
+	if len(bounds) == 0 {
+		bounds = DefaultBuckets
+	}
+	sorted := make([]float64, len(bounds))
+	copy(sorted, bounds)
+	sort.Float64s(sorted)
+	return &HistogramVec{vec: newVec(name, help, labels), bounds: sorted}
+}
+
+// Observe records the value in the histogram for the label values.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e, _ := h.entry(labelValues)
+	if e.buckets == nil {
+		e.buckets = make([]uint64, len(h.bounds))
+	}
+	for i, bound := range h.bounds {
+		if value <= bound {
+			e.buckets[i]++
+		}
+	}
+	e.count++
+	e.value += value
+}
+
+// Collect returns the histograms.
+func (h *HistogramVec) Collect() []*Metric {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	m := &Metric{Name: h.name, Help: h.help, Type: TypeHistogram}
+	for _, e := range h.sortedEntries() {
+		for i, bound := range h.bounds {
+			m.Samples = append(m.Samples, &Sample{
+				Suffix: "_bucket",
+				Labels: withLabel(e.labels, "le", formatValue(bound)),
+				Value:  float64(e.buckets[i]),
+			})
+		}
+		m.Samples = append(m.Samples, &Sample{
+			Suffix: "_bucket",
+			Labels: withLabel(e.labels, "le", "+Inf"),
+			Value:  float64(e.count),
+		})
+		m.Samples = append(m.Samples, &Sample{Suffix: "_sum", Labels: e.labels, Value: e.value})
+		m.Samples = append(m.Samples, &Sample{Suffix: "_count", Labels: e.labels, Value: float64(e.count)})
+	}
+	return []*Metric{m}
+}
+
+func withLabel(labels []*Label, name, value string) []*Label {
+	l := make([]*Label, 0, len(labels)+1)
+	l = append(l, labels...)
+	return append(l, &Label{Name: name, Value: value})
+}
+
+func padValues(values []string, n int) []string {
+	padded := make([]string, n)
+	copy(padded, values)
+	return padded
+}
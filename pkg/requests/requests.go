@@ -21,17 +21,18 @@ import (
 
 // Request hold the data associated with identity database
 type Request struct {
-	ID       string      `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
-	Upstream Upstream    `json:"upstream,omitempty" xml:"upstream,omitempty" yaml:"upstream,omitempty"`
-	Sandbox  Sandbox     `json:"sandbox,omitempty" xml:"sandbox,omitempty" yaml:"sandbox,omitempty"`
-	User     User        `json:"user,omitempty" xml:"user,omitempty" yaml:"user,omitempty"`
-	Query    Query       `json:"query,omitempty" xml:"query,omitempty" yaml:"query,omitempty"`
-	Key      Key         `json:"key,omitempty" xml:"key,omitempty" yaml:"key,omitempty"`
-	MfaToken MfaToken    `json:"mfa_token,omitempty" xml:"mfa_token,omitempty" yaml:"mfa_token,omitempty"`
-	WebAuthn WebAuthn    `json:"web_authn,omitempty" xml:"web_authn,omitempty" yaml:"web_authn,omitempty"`
-	Flags    Flags       `json:"flags,omitempty" xml:"flags,omitempty" yaml:"flags,omitempty"`
-	Response Response    `json:"response,omitempty" xml:"response,omitempty" yaml:"response,omitempty"`
-	Logger   *zap.Logger `json:"-"`
+	ID           string       `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	Upstream     Upstream     `json:"upstream,omitempty" xml:"upstream,omitempty" yaml:"upstream,omitempty"`
+	Sandbox      Sandbox      `json:"sandbox,omitempty" xml:"sandbox,omitempty" yaml:"sandbox,omitempty"`
+	User         User         `json:"user,omitempty" xml:"user,omitempty" yaml:"user,omitempty"`
+	Query        Query        `json:"query,omitempty" xml:"query,omitempty" yaml:"query,omitempty"`
+	Key          Key          `json:"key,omitempty" xml:"key,omitempty" yaml:"key,omitempty"`
+	MfaToken     MfaToken     `json:"mfa_token,omitempty" xml:"mfa_token,omitempty" yaml:"mfa_token,omitempty"`
+	WebAuthn     WebAuthn     `json:"web_authn,omitempty" xml:"web_authn,omitempty" yaml:"web_authn,omitempty"`
+	Registration Registration `json:"registration,omitempty" xml:"registration,omitempty" yaml:"registration,omitempty"`
+	Flags        Flags        `json:"flags,omitempty" xml:"flags,omitempty" yaml:"flags,omitempty"`
+	Response     Response     `json:"response,omitempty" xml:"response,omitempty" yaml:"response,omitempty"`
+	Logger       *zap.Logger  `json:"-"`
 }
 
 // Response hold the response associated with identity database
@@ -72,18 +73,23 @@ type Sandbox struct {
 type Query struct {
 	ID   string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
 	Name string `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty"`
+	// Offset and Limit are used for paging through the query results.
+	Offset int `json:"offset,omitempty" xml:"offset,omitempty" yaml:"offset,omitempty"`
+	Limit  int `json:"limit,omitempty" xml:"limit,omitempty" yaml:"limit,omitempty"`
 }
 
 // User hold user attributes.
 type User struct {
-	Username    string   `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
-	Email       string   `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
-	Password    string   `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
-	OldPassword string   `json:"old_password,omitempty" xml:"old_password,omitempty" yaml:"old_password,omitempty"`
-	FullName    string   `json:"full_name,omitempty" xml:"full_name,omitempty" yaml:"full_name,omitempty"`
-	Roles       []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
-	Disabled    bool     `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
-	Challenges  []string `json:"challenges,omitempty" xml:"challenges,omitempty" yaml:"challenges,omitempty"`
+	Username     string   `json:"username,omitempty" xml:"username,omitempty" yaml:"username,omitempty"`
+	Email        string   `json:"email,omitempty" xml:"email,omitempty" yaml:"email,omitempty"`
+	Password     string   `json:"password,omitempty" xml:"password,omitempty" yaml:"password,omitempty"`
+	OldPassword  string   `json:"old_password,omitempty" xml:"old_password,omitempty" yaml:"old_password,omitempty"`
+	PasswordHash string   `json:"password_hash,omitempty" xml:"password_hash,omitempty" yaml:"password_hash,omitempty"`
+	FullName     string   `json:"full_name,omitempty" xml:"full_name,omitempty" yaml:"full_name,omitempty"`
+	Roles        []string `json:"roles,omitempty" xml:"roles,omitempty" yaml:"roles,omitempty"`
+	Disabled     bool     `json:"disabled,omitempty" xml:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Challenges   []string `json:"challenges,omitempty" xml:"challenges,omitempty" yaml:"challenges,omitempty"`
+	NewEmail     string   `json:"new_email,omitempty" xml:"new_email,omitempty" yaml:"new_email,omitempty"`
 }
 
 // Key holds crypto key attributes.
@@ -116,6 +122,12 @@ type WebAuthn struct {
 	Request   string `json:"request,omitempty" xml:"request,omitempty" yaml:"request,omitempty"`
 }
 
+// Registration holds user registration review attributes.
+type Registration struct {
+	Note   string `json:"note,omitempty" xml:"note,omitempty" yaml:"note,omitempty"`
+	Reason string `json:"reason,omitempty" xml:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
 // Flags holds various flags.
 type Flags struct {
 	Enabled       bool `json:"enabled,omitempty" xml:"enabled,omitempty" yaml:"enabled,omitempty"`
@@ -123,6 +135,9 @@ type Flags struct {
 	MfaConfigured bool `json:"mfa_configured,omitempty" xml:"mfa_configured,omitempty" yaml:"mfa_configured,omitempty"`
 	MfaApp        bool `json:"mfa_app,omitempty" xml:"mfa_app,omitempty" yaml:"mfa_app,omitempty"`
 	MfaUniversal  bool `json:"mfa_universal,omitempty" xml:"mfa_universal,omitempty" yaml:"mfa_universal,omitempty"`
+	// PasswordRecovery indicates that the password is being reset via
+	// password recovery, i.e. the current password is not verified.
+	PasswordRecovery bool `json:"password_recovery,omitempty" xml:"password_recovery,omitempty" yaml:"password_recovery,omitempty"`
 }
 
 // NewRequest returns an instance of Request.
@@ -419,6 +419,9 @@ func NewCheckpoint(s string) (*Checkpoint, error) {
 	case "password":
 		c.Name = "Authenticate with password"
 		c.Type = "password"
+	case "password_change":
+		c.Name = "Change expired password"
+		c.Type = "password_change"
 	//case "consent":
 	//	c.Name = "Acceptance and consent"
 	//	c.Type = "consent"
@@ -847,6 +850,7 @@ func (c *Claims) unpackMetadata(k string, v interface{}, mkv, tkv map[string]int
 		return errors.ErrInvalidMetadataClaimType.WithArgs(v)
 	}
 	mkv[k] = c.Metadata
+	tkv[k] = c.Metadata
 	return nil
 }
 
@@ -950,6 +954,7 @@ func NewUser(data interface{}) (*User, error) {
 			}
 			c.custom[k] = v
 			mkv[k] = v
+			tkv[k] = v
 		}
 	}
 